@@ -0,0 +1,456 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	metalcloud "github.com/bigstepinc/metal-cloud-sdk-go"
+	interfaces "github.com/bigstepinc/metalcloud-cli/interfaces"
+)
+
+//cassetteInfoFile holds the settings of the recording session needed to replay it without credentials
+const cassetteInfoFile = "cassette.json"
+
+//cassetteInfo is the content of the cassetteInfoFile
+type cassetteInfo struct {
+	UserID     int    `json:"user_id"`
+	UserEmail  string `json:"user_email"`
+	Datacenter string `json:"datacenter"`
+}
+
+//cassetteInteraction is a JSON-RPC call and its response. Each is saved in its own file of the cassette directory.
+type cassetteInteraction struct {
+	Path       string          `json:"path"`
+	Method     string          `json:"method"`
+	Params     json.RawMessage `json:"params,omitempty"`
+	StatusCode int             `json:"status_code"`
+	Response   json.RawMessage `json:"response"`
+}
+
+//jsonRPCRequest is the part of a JSON-RPC request used to match recorded calls
+type jsonRPCRequest struct {
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params,omitempty"`
+}
+
+//matches returns true if the interaction is a recording of the given call. Params are compared as values so formatting does not matter.
+func (i cassetteInteraction) matches(path string, request jsonRPCRequest) bool {
+
+	if i.Path != path || i.Method != request.Method {
+		return false
+	}
+
+	var recorded, requested interface{}
+	if len(i.Params) > 0 && json.Unmarshal(i.Params, &recorded) != nil {
+		return false
+	}
+	if len(request.Params) > 0 && json.Unmarshal(request.Params, &requested) != nil {
+		return false
+	}
+
+	//secrets are redacted when recording so they are ignored when matching
+	return reflect.DeepEqual(recorded, redactCassetteValue(requested))
+}
+
+//cassettePath returns the endpoint suffix of a request path so that recordings do not depend on the host or on a path prefix
+func cassettePath(path string) string {
+	ret := ""
+	for _, suffix := range endpointSuffixes {
+		if strings.HasSuffix(path, suffix) && len(suffix) > len(ret) {
+			ret = suffix
+		}
+	}
+	if ret == "" {
+		return path
+	}
+	return ret
+}
+
+//cassetteInteractionFiles returns the files of the recorded interactions in the order they were recorded
+func cassetteInteractionFiles(dir string) ([]string, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "[0-9]*-*.json"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+//cassetteSecretFields are the parts of the field names whose string values are not written to cassettes.
+//Names are compared in lower case and without underscores.
+var cassetteSecretFields = []string{"password", "passphrase", "privatekey", "apikey", "secretbase64"}
+
+//cassetteRedacted replaces the values of secret fields in cassettes
+const cassetteRedacted = "REDACTED"
+
+func isCassetteSecretField(name string) bool {
+	name = strings.ToLower(strings.Replace(name, "_", "", -1))
+	for _, f := range cassetteSecretFields {
+		if strings.Contains(name, f) {
+			return true
+		}
+	}
+	return false
+}
+
+//redactCassetteValue replaces the string values of secret fields at any depth of a decoded json value
+func redactCassetteValue(v interface{}) interface{} {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		for k, value := range t {
+			if s, ok := value.(string); ok && s != "" && isCassetteSecretField(k) {
+				t[k] = cassetteRedacted
+				continue
+			}
+			t[k] = redactCassetteValue(value)
+		}
+	case []interface{}:
+		for i := range t {
+			t[i] = redactCassetteValue(t[i])
+		}
+	}
+	return v
+}
+
+//redactCassetteJSON returns the json with the values of secret fields replaced. Numbers are kept as they are.
+func redactCassetteJSON(data json.RawMessage) (json.RawMessage, error) {
+	if len(data) == 0 {
+		return data, nil
+	}
+
+	var v interface{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&v); err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(redactCassetteValue(v))
+}
+
+//cassetteServer is the local server the clients connect to while recording or replaying a cassette
+var cassetteServer *httptest.Server
+
+//startCassetteServer starts a local server with the given handler, stopping the previous one, and returns its URL
+func startCassetteServer(handler http.Handler) string {
+	stopCassetteServer()
+	cassetteServer = httptest.NewServer(handler)
+	return cassetteServer.URL
+}
+
+//stopCassetteServer stops the local server of a cassette, if any
+func stopCassetteServer() {
+	if cassetteServer != nil {
+		cassetteServer.Close()
+		cassetteServer = nil
+	}
+}
+
+//cassetteRecorder is a http.Handler that forwards the calls it receives to the API and saves them in a cassette directory
+type cassetteRecorder struct {
+	dir       string
+	count     int
+	endpoint  string
+	transport http.RoundTripper
+	mutex     sync.Mutex
+}
+
+//ServeHTTP forwards the call to the endpoint using the transport of the recorder and records it
+func (r *cassetteRecorder) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+
+	body, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	forward, err := http.NewRequest(req.Method, r.endpoint+req.URL.RequestURI(), bytes.NewBuffer(body))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	for k, v := range req.Header {
+		forward.Header[k] = append([]string(nil), v...)
+	}
+
+	resp, err := r.transport.RoundTrip(forward)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	response, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	if err := r.record(req.URL.Path, body, resp.StatusCode, response); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", resp.Header.Get("Content-Type"))
+	w.WriteHeader(resp.StatusCode)
+	w.Write(response)
+}
+
+//record saves a call in the cassette directory. The values of secret fields are redacted.
+func (r *cassetteRecorder) record(path string, body []byte, statusCode int, response []byte) error {
+
+	var request jsonRPCRequest
+	if err := json.Unmarshal(body, &request); err != nil {
+		return fmt.Errorf("Could not record call to %s: %v", path, err)
+	}
+
+	if !json.Valid(response) {
+		return fmt.Errorf("Could not record call to %s: the response is not json", request.Method)
+	}
+
+	params, err := redactCassetteJSON(request.Params)
+	if err != nil {
+		return fmt.Errorf("Could not record call to %s: %v", request.Method, err)
+	}
+
+	redacted, err := redactCassetteJSON(response)
+	if err != nil {
+		return fmt.Errorf("Could not record call to %s: %v", request.Method, err)
+	}
+
+	interaction := cassetteInteraction{
+		Path:       cassettePath(path),
+		Method:     request.Method,
+		Params:     params,
+		StatusCode: statusCode,
+		Response:   redacted,
+	}
+
+	content, err := json.MarshalIndent(interaction, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.count++
+	fileName := filepath.Join(r.dir, fmt.Sprintf("%04d-%s.json", r.count, request.Method))
+	return ioutil.WriteFile(fileName, content, 0600)
+}
+
+//initRecordingClients returns clients that connect to the API through a local server recording the calls in the given directory.
+//Recordings are appended to the ones already in it.
+func initRecordingClients(dir string) (map[string]interfaces.MetalCloudClient, error) {
+
+	//the env variables are validated as when not recording
+	for _, suffix := range endpointSuffixes {
+		if _, err := initClient(suffix); err != nil {
+			return nil, err
+		}
+	}
+
+	err := os.MkdirAll(dir, 0700)
+	if err != nil {
+		return nil, err
+	}
+
+	files, err := cassetteInteractionFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	apiKey := os.Getenv("METALCLOUD_API_KEY")
+
+	info := cassetteInfo{
+		UserEmail:  GetUserEmail(),
+		Datacenter: GetDatacenter(),
+	}
+	if components := strings.Split(apiKey, ":"); len(components) > 1 {
+		info.UserID, _ = strconv.Atoi(components[0])
+	}
+
+	content, err := json.MarshalIndent(info, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	err = ioutil.WriteFile(filepath.Join(dir, cassetteInfoFile), content, 0600)
+	if err != nil {
+		return nil, err
+	}
+
+	url := startCassetteServer(&cassetteRecorder{
+		dir:       dir,
+		count:     len(files),
+		endpoint:  strings.TrimRight(os.Getenv("METALCLOUD_ENDPOINT"), "/"),
+		transport: http.DefaultTransport,
+	})
+
+	return initCassetteClients(url, apiKey)
+}
+
+//cassettePlayer is a http.Handler that answers with the responses of a cassette
+type cassettePlayer struct {
+	dir          string
+	info         cassetteInfo
+	interactions []cassetteInteraction
+	played       []bool
+	mutex        sync.Mutex
+}
+
+//newCassettePlayer loads the interactions recorded in a directory
+func newCassettePlayer(dir string) (*cassettePlayer, error) {
+
+	player := cassettePlayer{
+		dir: dir,
+	}
+
+	content, err := ioutil.ReadFile(filepath.Join(dir, cassetteInfoFile))
+	if err != nil {
+		return nil, fmt.Errorf("Could not read cassette %s: %v", dir, err)
+	}
+
+	err = json.Unmarshal(content, &player.info)
+	if err != nil {
+		return nil, fmt.Errorf("Could not read cassette %s: %v", dir, err)
+	}
+
+	files, err := cassetteInteractionFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, f := range files {
+		content, err := ioutil.ReadFile(f)
+		if err != nil {
+			return nil, err
+		}
+
+		var interaction cassetteInteraction
+		err = json.Unmarshal(content, &interaction)
+		if err != nil {
+			return nil, fmt.Errorf("Could not read cassette file %s: %v", f, err)
+		}
+
+		player.interactions = append(player.interactions, interaction)
+	}
+
+	player.played = make([]bool, len(player.interactions))
+
+	return &player, nil
+}
+
+//next returns the first recording of a call not yet played. Once all were played the last one is repeated, as when polling.
+func (p *cassettePlayer) next(path string, request jsonRPCRequest) *cassetteInteraction {
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	last := -1
+	for i, interaction := range p.interactions {
+		if !interaction.matches(path, request) {
+			continue
+		}
+		if !p.played[i] {
+			p.played[i] = true
+			return &p.interactions[i]
+		}
+		last = i
+	}
+
+	if last == -1 {
+		return nil
+	}
+
+	return &p.interactions[last]
+}
+
+//ServeHTTP answers a JSON-RPC call with its recorded response or with an error if it was not recorded
+func (p *cassettePlayer) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+
+	w.Header().Set("Content-Type", "application/json")
+
+	body, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var request jsonRPCRequest
+	if err := json.Unmarshal(body, &request); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	interaction := p.next(cassettePath(req.URL.Path), request)
+	if interaction == nil {
+		response, _ := json.Marshal(map[string]interface{}{
+			"jsonrpc": "2.0",
+			"id":      0,
+			"error": map[string]interface{}{
+				"code":    -32601,
+				"message": fmt.Sprintf("No recorded response for %s(%s) in cassette %s", request.Method, string(request.Params), p.dir),
+			},
+		})
+		w.Write(response)
+		return
+	}
+
+	w.WriteHeader(interaction.StatusCode)
+	w.Write(interaction.Response)
+}
+
+//initReplayClients returns clients that are answered by a local server replaying a cassette.
+//No credentials are needed, the user and the datacenter default to the ones used when recording.
+func initReplayClients(dir string) (map[string]interfaces.MetalCloudClient, error) {
+
+	player, err := newCassettePlayer(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	if GetUserEmail() == "" {
+		os.Setenv("METALCLOUD_USER_EMAIL", player.info.UserEmail)
+	}
+
+	if GetDatacenter() == "" {
+		os.Setenv("METALCLOUD_DATACENTER", player.info.Datacenter)
+	}
+
+	url := startCassetteServer(player)
+
+	return initCassetteClients(url, fmt.Sprintf("%d:replay", player.info.UserID))
+}
+
+//initCassetteClients returns clients connecting to the local server of a cassette
+func initCassetteClients(url string, apiKey string) (map[string]interfaces.MetalCloudClient, error) {
+
+	clients := map[string]interfaces.MetalCloudClient{}
+	for clientName, suffix := range endpointSuffixes {
+
+		if (clientName == DeveloperEndpoint || clientName == ExtendedEndpoint) && !isAdmin() {
+			continue
+		}
+
+		client, err := metalcloud.GetMetalcloudClient(GetUserEmail(), apiKey, url+suffix, isLoggingEnabled())
+		if err != nil {
+			stopCassetteServer()
+			return nil, err
+		}
+		clients[clientName] = client
+	}
+
+	return clients, nil
+}
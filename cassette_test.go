@@ -0,0 +1,208 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	interfaces "github.com/bigstepinc/metalcloud-cli/interfaces"
+	. "github.com/onsi/gomega"
+)
+
+var updateGolden = flag.Bool("update", false, "rewrite the golden files of the replay tests")
+
+//cassetteEnvs are the env variables changed by the cassette tests
+var cassetteEnvs = []string{
+	"METALCLOUD_USER_EMAIL",
+	"METALCLOUD_API_KEY",
+	"METALCLOUD_ENDPOINT",
+	"METALCLOUD_ADMIN",
+	"METALCLOUD_DATACENTER",
+	"METALCLOUD_RECORD",
+	"METALCLOUD_REPLAY",
+}
+
+//clearEnvs unsets the given env variables and returns a function that puts back their values
+func clearEnvs(envs []string) func() {
+	currentEnvVals := map[string]string{}
+	for _, e := range envs {
+		if v, ok := os.LookupEnv(e); ok {
+			currentEnvVals[e] = v
+		}
+		os.Unsetenv(e)
+	}
+
+	return func() {
+		for _, e := range envs {
+			os.Unsetenv(e)
+		}
+		for k, v := range currentEnvVals {
+			os.Setenv(k, v)
+		}
+	}
+}
+
+func TestCassetteRecordAndReplay(t *testing.T) {
+	RegisterTestingT(t)
+
+	defer clearEnvs(cassetteEnvs)()
+	defer stopCassetteServer()
+
+	transport := http.DefaultTransport
+
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"jsonrpc":"2.0","id":0,"result":{"infrastructure_id":100,"infrastructure_label":"demo","infrastructure_custom_variables":{"db_password":"hunter2"}}}`))
+	}))
+
+	dir, err := ioutil.TempDir("", "cassette")
+	Expect(err).To(BeNil())
+	defer os.RemoveAll(dir)
+
+	os.Setenv("METALCLOUD_USER_EMAIL", "user@example.com")
+	os.Setenv("METALCLOUD_DATACENTER", "uk-reading")
+	os.Setenv("METALCLOUD_API_KEY", "42:secret")
+	os.Setenv("METALCLOUD_ENDPOINT", api.URL)
+	os.Setenv("METALCLOUD_RECORD", dir)
+
+	clients, err := initClients()
+	Expect(err).To(BeNil())
+
+	infra, err := clients[UserEndpoint].InfrastructureGet(100)
+	Expect(err).To(BeNil())
+	Expect(infra.InfrastructureLabel).To(Equal("demo"))
+	Expect(http.DefaultTransport).To(Equal(transport))
+
+	files, err := cassetteInteractionFiles(dir)
+	Expect(err).To(BeNil())
+	Expect(files).To(HaveLen(1))
+	Expect(filepath.Base(files[0])).To(Equal("0001-infrastructure_get.json"))
+
+	content, err := ioutil.ReadFile(files[0])
+	Expect(err).To(BeNil())
+	Expect(string(content)).NotTo(ContainSubstring("secret"))
+	Expect(string(content)).NotTo(ContainSubstring("hunter2"))
+	Expect(string(content)).To(ContainSubstring(cassetteRedacted))
+
+	//replaying must work without credentials or network access
+	api.Close()
+	for _, e := range cassetteEnvs {
+		os.Unsetenv(e)
+	}
+	os.Setenv("METALCLOUD_REPLAY", dir)
+
+	clients, err = initClients()
+	Expect(err).To(BeNil())
+	Expect(GetUserEmail()).To(Equal("user@example.com"))
+	Expect(GetDatacenter()).To(Equal("uk-reading"))
+
+	//calls are repeated once all recordings were played
+	for i := 0; i < 2; i++ {
+		infra, err = clients[UserEndpoint].InfrastructureGet(100)
+		Expect(err).To(BeNil())
+		Expect(infra.InfrastructureLabel).To(Equal("demo"))
+	}
+
+	_, err = clients[UserEndpoint].InfrastructureGet(101)
+	Expect(err).NotTo(BeNil())
+	Expect(err.Error()).To(ContainSubstring("No recorded response for infrastructure_get"))
+
+	//the local server is stopped once the command is done
+	url := cassetteServer.URL
+	stopCassetteServer()
+	_, err = http.Get(url)
+	Expect(err).NotTo(BeNil())
+
+	os.Setenv("METALCLOUD_REPLAY", filepath.Join(dir, "missing"))
+	_, err = initClients()
+	Expect(err).NotTo(BeNil())
+}
+
+func TestRedactCassetteJSON(t *testing.T) {
+	RegisterTestingT(t)
+
+	ret, err := redactCassetteJSON([]byte(`{"rows":[{"server_id":10,"server_ipmi_internal_password":"p","server_ipmi_internal_username":"admin"}],"privateKey":"k","secret_base64":"s","secret_name":"n","os_template_change_password_after_deploy":true,"big":12345678901234567890}`))
+	Expect(err).To(BeNil())
+
+	var m map[string]interface{}
+	err = json.Unmarshal(ret, &m)
+	Expect(err).To(BeNil())
+
+	row := m["rows"].([]interface{})[0].(map[string]interface{})
+	Expect(row["server_ipmi_internal_password"]).To(Equal(cassetteRedacted))
+	Expect(row["server_ipmi_internal_username"]).To(Equal("admin"))
+	Expect(m["privateKey"]).To(Equal(cassetteRedacted))
+	Expect(m["secret_base64"]).To(Equal(cassetteRedacted))
+	Expect(m["secret_name"]).To(Equal("n"))
+	Expect(m["os_template_change_password_after_deploy"]).To(Equal(true))
+	Expect(string(ret)).To(ContainSubstring("12345678901234567890"))
+
+	//calls with secrets in their params match their redacted recordings
+	interaction := cassetteInteraction{
+		Path:   "/metal-cloud",
+		Method: "secret_create",
+		Params: json.RawMessage(`[{"secret_name":"n","secret_base64":"REDACTED"}]`),
+	}
+	Expect(interaction.matches("/metal-cloud", jsonRPCRequest{
+		Method: "secret_create",
+		Params: json.RawMessage(`[{"secret_name":"n","secret_base64":"c2VjcmV0"}]`),
+	})).To(BeTrue())
+}
+
+func TestCassettePath(t *testing.T) {
+	RegisterTestingT(t)
+
+	Expect(cassettePath("/metal-cloud")).To(Equal("/metal-cloud"))
+	Expect(cassettePath("/prefix/metal-cloud/extended")).To(Equal("/metal-cloud/extended"))
+	Expect(cassettePath("/api/developer/developer")).To(Equal("/api/developer/developer"))
+	Expect(cassettePath("/other")).To(Equal("/other"))
+}
+
+//TestGoldenOutput runs commands against the cassettes in testdata/cassettes and compares their output with testdata/golden.
+//Use go test -run TestGoldenOutput -update to rewrite the golden files after a change of the output.
+func TestGoldenOutput(t *testing.T) {
+	RegisterTestingT(t)
+
+	defer clearEnvs(cassetteEnvs)()
+	defer stopCassetteServer()
+
+	cases := []struct {
+		name        string
+		arguments   map[string]interface{}
+		executeFunc func(c *Command, client interfaces.MetalCloudClient) (string, error)
+		endpoint    string
+	}{
+		{"infrastructure-get", map[string]interface{}{"infrastructure_id_or_label": 100}, infrastructureGetCmd, UserEndpoint},
+		{"server-list", map[string]interface{}{"filter": "*"}, serversListCmd, DeveloperEndpoint},
+	}
+
+	for _, c := range cases {
+		for _, e := range cassetteEnvs {
+			os.Unsetenv(e)
+		}
+		os.Setenv("METALCLOUD_ADMIN", "true")
+		os.Setenv("METALCLOUD_REPLAY", filepath.Join("testdata", "cassettes", c.name))
+
+		clients, err := initClients()
+		Expect(err).To(BeNil())
+
+		cmd := MakeCommand(c.arguments)
+		ret, err := c.executeFunc(&cmd, clients[c.endpoint])
+		Expect(err).To(BeNil())
+
+		goldenFile := filepath.Join("testdata", "golden", c.name+".txt")
+		if *updateGolden {
+			Expect(os.MkdirAll(filepath.Dir(goldenFile), 0755)).To(BeNil())
+			Expect(ioutil.WriteFile(goldenFile, []byte(ret), 0644)).To(BeNil())
+		}
+
+		golden, err := ioutil.ReadFile(goldenFile)
+		Expect(err).To(BeNil())
+		Expect(ret).To(Equal(string(golden)), c.name)
+	}
+}
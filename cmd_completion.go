@@ -0,0 +1,415 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	interfaces "github.com/bigstepinc/metalcloud-cli/interfaces"
+)
+
+//completeCommand is the hidden command called by the completion scripts. It prints the candidates for the last word, one per line.
+const completeCommand = "__complete"
+
+//completionCacheTTL is how long the labels retrieved for completions are reused
+const completionCacheTTL = 30 * time.Second
+
+var completionCmds = []Command{
+
+	{
+		Description:  "Outputs the bash completion script. Use with: source <(metalcloud-cli completion bash)",
+		Subject:      "completion",
+		AltSubject:   "completion",
+		Predicate:    "bash",
+		AltPredicate: "bash",
+		FlagSet:      flag.NewFlagSet("bash completion", flag.ExitOnError),
+		InitFunc: func(c *Command) {
+			c.Arguments = map[string]interface{}{}
+		},
+		ExecuteFunc: completionBashCmd,
+		Endpoint:    LocalEndpoint,
+	},
+	{
+		Description:  "Outputs the zsh completion script. Use with: source <(metalcloud-cli completion zsh)",
+		Subject:      "completion",
+		AltSubject:   "completion",
+		Predicate:    "zsh",
+		AltPredicate: "zsh",
+		FlagSet:      flag.NewFlagSet("zsh completion", flag.ExitOnError),
+		InitFunc: func(c *Command) {
+			c.Arguments = map[string]interface{}{}
+		},
+		ExecuteFunc: completionZshCmd,
+		Endpoint:    LocalEndpoint,
+	},
+	{
+		Description:  "Outputs the fish completion script. Use with: metalcloud-cli completion fish | source",
+		Subject:      "completion",
+		AltSubject:   "completion",
+		Predicate:    "fish",
+		AltPredicate: "fish",
+		FlagSet:      flag.NewFlagSet("fish completion", flag.ExitOnError),
+		InitFunc: func(c *Command) {
+			c.Arguments = map[string]interface{}{}
+		},
+		ExecuteFunc: completionFishCmd,
+		Endpoint:    LocalEndpoint,
+	},
+}
+
+const bashCompletionScript = `# bash completion for %[1]s
+_metalcloud_cli_complete() {
+    local cur="${COMP_WORDS[COMP_CWORD]}"
+    local IFS=$'\n'
+    COMPREPLY=( $(compgen -W "$(%[1]s %[2]s "${COMP_WORDS[@]:1:COMP_CWORD}" 2>/dev/null)" -- "$cur") )
+}
+complete -o default -F _metalcloud_cli_complete %[1]s
+`
+
+const zshCompletionScript = `#compdef %[1]s
+# zsh completion for %[1]s
+_metalcloud_cli_complete() {
+    local -a completions
+    completions=("${(@f)$(%[1]s %[2]s "${(@)words[2,$CURRENT]}" 2>/dev/null)}")
+    completions=(${completions:#})
+    if (( ${#completions} )); then
+        compadd -a completions
+    else
+        _files
+    fi
+}
+compdef _metalcloud_cli_complete %[1]s
+`
+
+const fishCompletionScript = `# fish completion for %[1]s
+function __metalcloud_cli_complete
+    set -l completions (%[1]s %[2]s (commandline -opc)[2..-1] (commandline -ct) 2>/dev/null)
+    if test (count $completions) -eq 0
+        __fish_complete_path (commandline -ct)
+    else
+        printf '%%s\n' $completions
+    end
+end
+complete -c %[1]s -f -a '(__metalcloud_cli_complete)'
+`
+
+func completionProgramName() string {
+	return filepath.Base(os.Args[0])
+}
+
+func completionBashCmd(c *Command, client interfaces.MetalCloudClient) (string, error) {
+	return fmt.Sprintf(bashCompletionScript, completionProgramName(), completeCommand), nil
+}
+
+func completionZshCmd(c *Command, client interfaces.MetalCloudClient) (string, error) {
+	return fmt.Sprintf(zshCompletionScript, completionProgramName(), completeCommand), nil
+}
+
+func completionFishCmd(c *Command, client interfaces.MetalCloudClient) (string, error) {
+	return fmt.Sprintf(fishCompletionScript, completionProgramName(), completeCommand), nil
+}
+
+//completionSources retrieve the labels that can be given to an argument, keyed by the argument's name
+var completionSources = map[string]func(c *Command, client interfaces.MetalCloudClient) ([]string, error){
+	"infrastructure_id_or_label":  completeInfrastructures,
+	"instance_array_id_or_label":  completeInstanceArrays,
+	"volume_template_id_or_label": completeVolumeTemplates,
+	"template_id_or_name":         completeOSTemplates,
+	"workflow_id_or_label":        completeWorkflows,
+}
+
+//getCompletions returns the candidates for the last of the words, which are the arguments given after the program name.
+//Errors are not reported as they would be shown by the shell while typing.
+func getCompletions(words []string, clients map[string]interfaces.MetalCloudClient) []string {
+
+	if len(words) == 0 {
+		words = []string{""}
+	}
+
+	current := words[len(words)-1]
+
+	_, previous, err := parseGlobalFlags(append([]string{""}, words[:len(words)-1]...))
+	if err != nil {
+		return []string{}
+	}
+	previous = previous[1:]
+
+	commands := getCommands(clients)
+
+	switch len(previous) {
+	case 0:
+		if strings.HasPrefix(current, "-") {
+			return flagNames(newGlobalFlagSet(&GlobalFlags{}))
+		}
+
+		subjects := append([]string{"help"}, getSubjects(commands)...)
+		return uniqueSorted(subjects)
+	case 1:
+		predicates := []string{}
+		for _, c := range commands {
+			if !c.Hidden && (c.Subject == previous[0] || c.AltSubject == previous[0]) {
+				predicates = append(predicates, c.Predicate)
+			}
+		}
+		return uniqueSorted(predicates)
+	}
+
+	cmd := locateCommand(previous[1], previous[0], commands)
+	if cmd == nil {
+		return []string{}
+	}
+
+	//the copy parses with flag.ContinueOnError so that the words typed so far cannot exit the program.
+	//It also has the format and table flags that are added to formatted commands when they are executed.
+	c := initCommandCopy(*cmd)
+
+	if strings.HasPrefix(current, "-") {
+		return flagNames(c.FlagSet)
+	}
+
+	args := previous[2:]
+	if len(args) == 0 {
+		return []string{}
+	}
+
+	name := strings.TrimLeft(args[len(args)-1], "-")
+	f := c.FlagSet.Lookup(name)
+	if !strings.HasPrefix(args[len(args)-1], "-") || f == nil || isBoolFlag(f) {
+		return []string{}
+	}
+
+	//the flags given so far are needed to complete labels that depend on them, such as the infrastructure of an instance array.
+	//Errors are ignored as the line is still being typed.
+	c.FlagSet.Parse(args[:len(args)-1])
+
+	client, ok := clients[c.Endpoint]
+	if !ok {
+		return []string{}
+	}
+
+	for argument, source := range completionSources {
+		if v, ok := c.Arguments[argument]; ok && reflect.ValueOf(v).Pointer() == reflect.ValueOf(f.Value).Pointer() {
+			labels, err := source(&c, client)
+			if err != nil {
+				return []string{}
+			}
+			return uniqueSorted(labels)
+		}
+	}
+
+	return []string{}
+}
+
+func flagNames(fs *flag.FlagSet) []string {
+	names := []string{}
+	fs.VisitAll(func(f *flag.Flag) {
+		names = append(names, "-"+f.Name)
+	})
+	return names
+}
+
+func isBoolFlag(f *flag.Flag) bool {
+	b, ok := f.Value.(interface{ IsBoolFlag() bool })
+	return ok && b.IsBoolFlag()
+}
+
+func uniqueSorted(values []string) []string {
+	m := map[string]bool{}
+	ret := []string{}
+	for _, v := range values {
+		if v != "" && !m[v] {
+			m[v] = true
+			ret = append(ret, v)
+		}
+	}
+	sort.Strings(ret)
+	return ret
+}
+
+//cachedInfrastructures returns the ids of the infrastructures keyed by label
+func cachedInfrastructures(client interfaces.MetalCloudClient) (map[string]int, error) {
+
+	values, err := cachedCompletions("infrastructures", func() ([]string, error) {
+		list, err := client.Infrastructures()
+		if err != nil {
+			return nil, err
+		}
+
+		values := []string{}
+		for _, i := range *list {
+			values = append(values, fmt.Sprintf("%d %s", i.InfrastructureID, i.InfrastructureLabel))
+		}
+		return values, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	ret := map[string]int{}
+	for _, v := range values {
+		var id int
+		var label string
+		if _, err := fmt.Sscanf(v, "%d %s", &id, &label); err == nil {
+			ret[label] = id
+		}
+	}
+
+	return ret, nil
+}
+
+func completeInfrastructures(c *Command, client interfaces.MetalCloudClient) ([]string, error) {
+
+	infrastructures, err := cachedInfrastructures(client)
+	if err != nil {
+		return nil, err
+	}
+
+	labels := []string{}
+	for label := range infrastructures {
+		labels = append(labels, label)
+	}
+	return labels, nil
+}
+
+//completeInstanceArrays returns the labels of the instance arrays of the infrastructure given to the command or of all infrastructures
+func completeInstanceArrays(c *Command, client interfaces.MetalCloudClient) ([]string, error) {
+
+	infrastructures, err := cachedInfrastructures(client)
+	if err != nil {
+		return nil, err
+	}
+
+	infra := getStringParam(c.Arguments["infrastructure_id_or_label"])
+
+	labels := []string{}
+	for label, id := range infrastructures {
+		if infra != "" && infra != label && infra != fmt.Sprintf("%d", id) {
+			continue
+		}
+
+		infrastructureID := id
+		ret, err := cachedCompletions(fmt.Sprintf("instance_arrays %d", infrastructureID), func() ([]string, error) {
+			iaList, err := client.InstanceArrays(infrastructureID)
+			if err != nil {
+				return nil, err
+			}
+
+			labels := []string{}
+			for _, ia := range *iaList {
+				labels = append(labels, ia.InstanceArrayLabel)
+			}
+			return labels, nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		labels = append(labels, ret...)
+	}
+
+	return labels, nil
+}
+
+func completeVolumeTemplates(c *Command, client interfaces.MetalCloudClient) ([]string, error) {
+	return cachedCompletions("volume_templates", func() ([]string, error) {
+		list, err := client.VolumeTemplates()
+		if err != nil {
+			return nil, err
+		}
+
+		labels := []string{}
+		for _, t := range *list {
+			labels = append(labels, t.VolumeTemplateLabel)
+		}
+		return labels, nil
+	})
+}
+
+func completeOSTemplates(c *Command, client interfaces.MetalCloudClient) ([]string, error) {
+	return cachedCompletions("os_templates", func() ([]string, error) {
+		list, err := client.OSTemplates()
+		if err != nil {
+			return nil, err
+		}
+
+		labels := []string{}
+		for _, t := range *list {
+			labels = append(labels, t.VolumeTemplateLabel)
+		}
+		return labels, nil
+	})
+}
+
+func completeWorkflows(c *Command, client interfaces.MetalCloudClient) ([]string, error) {
+	return cachedCompletions("workflows", func() ([]string, error) {
+		list, err := client.Workflows()
+		if err != nil {
+			return nil, err
+		}
+
+		labels := []string{}
+		for _, w := range *list {
+			labels = append(labels, w.WorkflowLabel)
+		}
+		return labels, nil
+	})
+}
+
+//completionCacheEntry holds labels retrieved for completions
+type completionCacheEntry struct {
+	Time   time.Time `json:"time"`
+	Values []string  `json:"values"`
+}
+
+//completionCachePath returns the path of the cache file, next to the configuration file
+func completionCachePath() string {
+	return filepath.Join(filepath.Dir(GetConfigFilePath()), "completion_cache.json")
+}
+
+//cachedCompletions returns the values stored under the key if they are recent enough, otherwise calls fetch and stores its result.
+//Keys are specific to the endpoint and user so that profiles do not share entries.
+func cachedCompletions(key string, fetch func() ([]string, error)) ([]string, error) {
+
+	key = fmt.Sprintf("%s %s %s", os.Getenv("METALCLOUD_ENDPOINT"), GetUserEmail(), key)
+	path := completionCachePath()
+
+	cache := map[string]completionCacheEntry{}
+	if content, err := ioutil.ReadFile(path); err == nil {
+		json.Unmarshal(content, &cache)
+	}
+
+	if entry, ok := cache[key]; ok && time.Since(entry.Time) < completionCacheTTL {
+		return entry.Values, nil
+	}
+
+	values, err := fetch()
+	if err != nil {
+		return nil, err
+	}
+
+	for k, entry := range cache {
+		if time.Since(entry.Time) >= completionCacheTTL {
+			delete(cache, k)
+		}
+	}
+
+	cache[key] = completionCacheEntry{
+		Time:   time.Now(),
+		Values: values,
+	}
+
+	//the cache is an optimization, failing to save it does not prevent completion
+	if content, err := json.Marshal(cache); err == nil {
+		if os.MkdirAll(filepath.Dir(path), 0700) == nil {
+			ioutil.WriteFile(path, content, 0600)
+		}
+	}
+
+	return values, nil
+}
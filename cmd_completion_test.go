@@ -0,0 +1,199 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	metalcloud "github.com/bigstepinc/metal-cloud-sdk-go"
+	mock_metalcloud "github.com/bigstepinc/metalcloud-cli/helpers"
+	interfaces "github.com/bigstepinc/metalcloud-cli/interfaces"
+	gomock "github.com/golang/mock/gomock"
+	. "github.com/onsi/gomega"
+)
+
+func TestGetCompletions(t *testing.T) {
+	RegisterTestingT(t)
+
+	dir, err := ioutil.TempDir("", "metalcloud-completion")
+	Expect(err).To(BeNil())
+	defer os.RemoveAll(dir)
+
+	currentConfigFile, currentConfigFileSet := os.LookupEnv("METALCLOUD_CONFIG_FILE")
+	os.Setenv("METALCLOUD_CONFIG_FILE", filepath.Join(dir, "config.yaml"))
+
+	ctrl := gomock.NewController(t)
+	client := mock_metalcloud.NewMockMetalCloudClient(ctrl)
+
+	infras := map[string]metalcloud.Infrastructure{
+		"demo":  {InfrastructureID: 10, InfrastructureLabel: "demo"},
+		"other": {InfrastructureID: 11, InfrastructureLabel: "other"},
+	}
+
+	//the labels are cached so the list is retrieved only once
+	client.EXPECT().
+		Infrastructures().
+		Return(&infras, nil).
+		Times(1)
+
+	iaList := map[string]metalcloud.InstanceArray{
+		"master": {InstanceArrayID: 100, InstanceArrayLabel: "master"},
+	}
+
+	client.EXPECT().
+		InstanceArrays(10).
+		Return(&iaList, nil).
+		Times(1)
+
+	client.EXPECT().
+		InstanceArrays(11).
+		Return(&map[string]metalcloud.InstanceArray{}, nil).
+		Times(1)
+
+	clients := map[string]interfaces.MetalCloudClient{
+		UserEndpoint: client,
+		"":           client,
+	}
+
+	ret := getCompletions([]string{""}, clients)
+	Expect(ret).To(ContainElement("infrastructure"))
+	Expect(ret).To(ContainElement("completion"))
+	Expect(ret).To(ContainElement("help"))
+
+	ret = getCompletions([]string{"-"}, clients)
+	Expect(ret).To(ContainElement("-format"))
+
+	ret = getCompletions([]string{"infra", ""}, clients)
+	Expect(ret).To(ContainElement("get"))
+	Expect(ret).To(ContainElement("list"))
+
+	ret = getCompletions([]string{"-format", "json", "infra", "get", "-"}, clients)
+	Expect(ret).To(ContainElement("-id"))
+	Expect(ret).To(ContainElement("-format"))
+	Expect(ret).To(ContainElement("-columns"))
+	Expect(ret).To(ContainElement("-sort-by"))
+	Expect(ret).To(ContainElement("-where"))
+
+	ret = getCompletions([]string{"infra", "list", "-"}, clients)
+	Expect(ret).To(ContainElement("-format"))
+	Expect(ret).To(ContainElement("-where"))
+
+	ret = getCompletions([]string{"infra", "get", "-id", ""}, clients)
+	Expect(ret).To(Equal([]string{"demo", "other"}))
+
+	ret = getCompletions([]string{"infra", "show", "-id", "d"}, clients)
+	Expect(ret).To(Equal([]string{"demo", "other"}))
+
+	ret = getCompletions([]string{"da", "create", "-infra", "demo", "-ia", ""}, clients)
+	Expect(ret).To(Equal([]string{"master"}))
+
+	ret = getCompletions([]string{"ia", "get", "-id", "m"}, clients)
+	Expect(ret).To(Equal([]string{"master"}))
+
+	//flags that do not take labels and unknown commands have no candidates
+	ret = getCompletions([]string{"infra", "create", "-label", ""}, clients)
+	Expect(ret).To(BeEmpty())
+
+	ret = getCompletions([]string{"infra", "unknown", "-id", ""}, clients)
+	Expect(ret).To(BeEmpty())
+
+	//flags that cannot be parsed do not exit the program
+	ret = getCompletions([]string{"da", "create", "-unknown", "-ia", ""}, clients)
+	Expect(ret).NotTo(BeNil())
+
+	ret = getCompletions([]string{"infra", "list", "-format", "json", "-sort-by", "LABEL", "-where", ""}, clients)
+	Expect(ret).To(BeEmpty())
+
+	if currentConfigFileSet {
+		os.Setenv("METALCLOUD_CONFIG_FILE", currentConfigFile)
+	} else {
+		os.Unsetenv("METALCLOUD_CONFIG_FILE")
+	}
+}
+
+func TestCachedCompletions(t *testing.T) {
+	RegisterTestingT(t)
+
+	dir, err := ioutil.TempDir("", "metalcloud-completion")
+	Expect(err).To(BeNil())
+	defer os.RemoveAll(dir)
+
+	currentConfigFile, currentConfigFileSet := os.LookupEnv("METALCLOUD_CONFIG_FILE")
+	os.Setenv("METALCLOUD_CONFIG_FILE", filepath.Join(dir, "config.yaml"))
+
+	calls := 0
+	fetch := func() ([]string, error) {
+		calls++
+		return []string{"a", "b"}, nil
+	}
+
+	ret, err := cachedCompletions("test", fetch)
+	Expect(err).To(BeNil())
+	Expect(ret).To(Equal([]string{"a", "b"}))
+
+	ret, err = cachedCompletions("test", fetch)
+	Expect(err).To(BeNil())
+	Expect(ret).To(Equal([]string{"a", "b"}))
+	Expect(calls).To(Equal(1))
+
+	//expired entries are retrieved again
+	content, err := ioutil.ReadFile(completionCachePath())
+	Expect(err).To(BeNil())
+
+	cache := map[string]completionCacheEntry{}
+	Expect(json.Unmarshal(content, &cache)).To(BeNil())
+	Expect(cache).To(HaveLen(1))
+
+	for k, entry := range cache {
+		entry.Time = time.Now().Add(-2 * completionCacheTTL)
+		cache[k] = entry
+	}
+
+	content, err = json.Marshal(cache)
+	Expect(err).To(BeNil())
+	Expect(ioutil.WriteFile(completionCachePath(), content, 0600)).To(BeNil())
+
+	_, err = cachedCompletions("test", fetch)
+	Expect(err).To(BeNil())
+	Expect(calls).To(Equal(2))
+
+	if currentConfigFileSet {
+		os.Setenv("METALCLOUD_CONFIG_FILE", currentConfigFile)
+	} else {
+		os.Unsetenv("METALCLOUD_CONFIG_FILE")
+	}
+}
+
+func TestCompletionScriptCmds(t *testing.T) {
+	RegisterTestingT(t)
+
+	cmd := MakeEmptyCommand()
+
+	ret, err := completionBashCmd(&cmd, nil)
+	Expect(err).To(BeNil())
+	Expect(ret).To(ContainSubstring("complete -o default -F _metalcloud_cli_complete"))
+	Expect(ret).To(ContainSubstring(completeCommand))
+
+	ret, err = completionZshCmd(&cmd, nil)
+	Expect(err).To(BeNil())
+	Expect(ret).To(ContainSubstring("#compdef"))
+	Expect(ret).To(ContainSubstring(completeCommand))
+
+	ret, err = completionFishCmd(&cmd, nil)
+	Expect(err).To(BeNil())
+	Expect(ret).To(ContainSubstring("complete -c"))
+	Expect(ret).To(ContainSubstring("printf '%s\\n'"))
+}
+
+func TestIsLocalCommand(t *testing.T) {
+	RegisterTestingT(t)
+
+	Expect(isLocalCommand([]string{"metalcloud-cli", completeCommand, "infra"})).To(BeTrue())
+	Expect(isLocalCommand([]string{"metalcloud-cli", "completion", "bash"})).To(BeTrue())
+	Expect(isLocalCommand([]string{"metalcloud-cli", "config", "list-profiles"})).To(BeTrue())
+	Expect(isLocalCommand([]string{"metalcloud-cli", "infra", "list"})).To(BeFalse())
+	Expect(isLocalCommand([]string{"metalcloud-cli", "infra"})).To(BeFalse())
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"sort"
+
+	interfaces "github.com/bigstepinc/metalcloud-cli/interfaces"
+)
+
+//configCmds commands affecting the configuration file
+var configCmds = []Command{
+
+	{
+		Description:  "Creates or updates a configuration profile.",
+		Subject:      "config",
+		AltSubject:   "cfg",
+		Predicate:    "set-profile",
+		AltPredicate: "set",
+		FlagSet:      flag.NewFlagSet("set configuration profile", flag.ExitOnError),
+		InitFunc: func(c *Command) {
+			c.Arguments = map[string]interface{}{
+				"profile_name": c.FlagSet.String("name", _nilDefaultStr, "(Required) Profile's name"),
+				"endpoint":     c.FlagSet.String("endpoint", _nilDefaultStr, "Metal Cloud endpoint"),
+				"user":         c.FlagSet.String("user", _nilDefaultStr, "The API key's owner email"),
+				"api_key":      c.FlagSet.String("api-key", _nilDefaultStr, "The API key"),
+				"datacenter":   c.FlagSet.String("datacenter", _nilDefaultStr, "The default datacenter"),
+				"admin":        c.FlagSet.String("admin", _nilDefaultStr, "Set to 'true' to enable admin commands, 'false' to disable them"),
+			}
+		},
+		ExecuteFunc: configSetProfileCmd,
+		Endpoint:    LocalEndpoint,
+	},
+	{
+		Description:  "Sets the profile used when no other profile is given.",
+		Subject:      "config",
+		AltSubject:   "cfg",
+		Predicate:    "use-profile",
+		AltPredicate: "use",
+		FlagSet:      flag.NewFlagSet("use configuration profile", flag.ExitOnError),
+		InitFunc: func(c *Command) {
+			c.Arguments = map[string]interface{}{
+				"profile_name": c.FlagSet.String("name", _nilDefaultStr, "(Required) Profile's name"),
+			}
+		},
+		ExecuteFunc: configUseProfileCmd,
+		Endpoint:    LocalEndpoint,
+	},
+	{
+		Description:  "Lists configuration profiles.",
+		Subject:      "config",
+		AltSubject:   "cfg",
+		Predicate:    "list-profiles",
+		AltPredicate: "ls",
+		FlagSet:      flag.NewFlagSet("list configuration profiles", flag.ExitOnError),
+		InitFunc: func(c *Command) {
+			c.Arguments = map[string]interface{}{}
+		},
+		ExecuteFunc: configListProfilesCmd,
+		Endpoint:    LocalEndpoint,
+		Formatted:   true,
+	},
+}
+
+func configSetProfileCmd(c *Command, client interfaces.MetalCloudClient) (string, error) {
+
+	name, err := getParam(c, "profile_name", "name")
+	if err != nil {
+		return "", err
+	}
+
+	profileName := *name.(*string)
+
+	path := GetConfigFilePath()
+
+	config, err := loadConfig(path)
+	if err != nil {
+		return "", err
+	}
+
+	profile := config.Profiles[profileName]
+
+	if v, ok := getStringParamOk(c.Arguments["api_key"]); ok {
+		err = validateAPIKey(v)
+		if err != nil {
+			return "", err
+		}
+		profile.APIKey = v
+	}
+
+	if v, ok := getStringParamOk(c.Arguments["admin"]); ok {
+		switch v {
+		case "true":
+			profile.Admin = true
+		case "false":
+			profile.Admin = false
+		default:
+			return "", fmt.Errorf("-admin must be 'true' or 'false'")
+		}
+	}
+
+	updateIfStringParamSet(c.Arguments["endpoint"], &profile.Endpoint)
+	updateIfStringParamSet(c.Arguments["user"], &profile.User)
+	updateIfStringParamSet(c.Arguments["datacenter"], &profile.Datacenter)
+
+	config.Profiles[profileName] = profile
+
+	//the first profile becomes the current one
+	if config.CurrentProfile == "" {
+		config.CurrentProfile = profileName
+	}
+
+	return "", saveConfig(path, config)
+}
+
+func configUseProfileCmd(c *Command, client interfaces.MetalCloudClient) (string, error) {
+
+	name, err := getParam(c, "profile_name", "name")
+	if err != nil {
+		return "", err
+	}
+
+	profileName := *name.(*string)
+
+	path := GetConfigFilePath()
+
+	config, err := loadConfig(path)
+	if err != nil {
+		return "", err
+	}
+
+	if _, ok := config.Profiles[profileName]; !ok {
+		return "", fmt.Errorf("Profile %s not found in %s", profileName, path)
+	}
+
+	config.CurrentProfile = profileName
+
+	return "", saveConfig(path, config)
+}
+
+func configListProfilesCmd(c *Command, client interfaces.MetalCloudClient) (string, error) {
+
+	path := GetConfigFilePath()
+
+	config, err := loadConfig(path)
+	if err != nil {
+		return "", err
+	}
+
+	schema := []SchemaField{
+		{
+			FieldName: "NAME",
+			FieldType: TypeString,
+			FieldSize: 10,
+		},
+		{
+			FieldName: "CURRENT",
+			FieldType: TypeBool,
+			FieldSize: 5,
+		},
+		{
+			FieldName: "ENDPOINT",
+			FieldType: TypeString,
+			FieldSize: 20,
+		},
+		{
+			FieldName: "USER",
+			FieldType: TypeString,
+			FieldSize: 20,
+		},
+		{
+			FieldName: "DATACENTER",
+			FieldType: TypeString,
+			FieldSize: 10,
+		},
+		{
+			FieldName: "ADMIN",
+			FieldType: TypeBool,
+			FieldSize: 5,
+		},
+	}
+
+	names := []string{}
+	for name := range config.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	data := [][]interface{}{}
+	for _, name := range names {
+		p := config.Profiles[name]
+		data = append(data, []interface{}{
+			name,
+			name == config.CurrentProfile,
+			p.Endpoint,
+			p.User,
+			p.Datacenter,
+			p.Admin,
+		})
+	}
+
+	topLine := fmt.Sprintf("Profiles in %s:", path)
+
+	return renderTable("Profiles", topLine, getStringParam(c.Arguments["format"]), data, schema)
+}
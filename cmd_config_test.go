@@ -0,0 +1,127 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestConfigProfileCmds(t *testing.T) {
+	RegisterTestingT(t)
+
+	dir, err := ioutil.TempDir("", "metalcloud-config")
+	Expect(err).To(BeNil())
+	defer os.RemoveAll(dir)
+
+	currentConfigFile, currentConfigFileSet := os.LookupEnv("METALCLOUD_CONFIG_FILE")
+
+	path := filepath.Join(dir, "config.yaml")
+	os.Setenv("METALCLOUD_CONFIG_FILE", path)
+
+	cmd := MakeCommand(map[string]interface{}{
+		"profile_name": "staging",
+		"endpoint":     "https://staging.example.com",
+		"user":         "user@example.com",
+		"api_key":      "1:staging",
+		"datacenter":   "dc-staging",
+	})
+
+	_, err = configSetProfileCmd(&cmd, nil)
+	Expect(err).To(BeNil())
+
+	cmd = MakeCommand(map[string]interface{}{
+		"profile_name": "production",
+		"endpoint":     "https://production.example.com",
+		"admin":        "true",
+	})
+
+	_, err = configSetProfileCmd(&cmd, nil)
+	Expect(err).To(BeNil())
+
+	//updating a profile only changes the given settings
+	cmd = MakeCommand(map[string]interface{}{
+		"profile_name": "staging",
+		"datacenter":   "dc-staging-2",
+	})
+
+	_, err = configSetProfileCmd(&cmd, nil)
+	Expect(err).To(BeNil())
+
+	config, err := loadConfig(path)
+	Expect(err).To(BeNil())
+	Expect(config.CurrentProfile).To(Equal("staging"))
+	Expect(config.Profiles["staging"].Endpoint).To(Equal("https://staging.example.com"))
+	Expect(config.Profiles["staging"].Datacenter).To(Equal("dc-staging-2"))
+	Expect(config.Profiles["production"].Admin).To(BeTrue())
+
+	fi, err := os.Stat(path)
+	Expect(err).To(BeNil())
+	Expect(fi.Mode().Perm()).To(Equal(os.FileMode(0600)))
+
+	//invalid values
+	cmd = MakeCommand(map[string]interface{}{
+		"profile_name": "staging",
+		"api_key":      "invalid",
+	})
+
+	_, err = configSetProfileCmd(&cmd, nil)
+	Expect(err).NotTo(BeNil())
+
+	cmd = MakeCommand(map[string]interface{}{
+		"profile_name": "staging",
+		"admin":        "maybe",
+	})
+
+	_, err = configSetProfileCmd(&cmd, nil)
+	Expect(err).NotTo(BeNil())
+
+	cmd = MakeEmptyCommand()
+	_, err = configSetProfileCmd(&cmd, nil)
+	Expect(err).NotTo(BeNil())
+
+	//use-profile
+	cmd = MakeCommand(map[string]interface{}{
+		"profile_name": "production",
+	})
+
+	_, err = configUseProfileCmd(&cmd, nil)
+	Expect(err).To(BeNil())
+
+	cmd = MakeCommand(map[string]interface{}{
+		"profile_name": "missing",
+	})
+
+	_, err = configUseProfileCmd(&cmd, nil)
+	Expect(err).NotTo(BeNil())
+
+	//list-profiles
+	cmd = MakeCommand(map[string]interface{}{
+		"format": "json",
+	})
+
+	ret, err := configListProfilesCmd(&cmd, nil)
+	Expect(err).To(BeNil())
+
+	var m []interface{}
+	err = json.Unmarshal([]byte(ret), &m)
+	Expect(err).To(BeNil())
+	Expect(len(m)).To(Equal(2))
+
+	r := m[0].(map[string]interface{})
+	Expect(r["NAME"].(string)).To(Equal("production"))
+	Expect(r["CURRENT"].(bool)).To(BeTrue())
+
+	r = m[1].(map[string]interface{})
+	Expect(r["NAME"].(string)).To(Equal("staging"))
+	Expect(r["CURRENT"].(bool)).To(BeFalse())
+
+	if currentConfigFileSet {
+		os.Setenv("METALCLOUD_CONFIG_FILE", currentConfigFile)
+	} else {
+		os.Unsetenv("METALCLOUD_CONFIG_FILE")
+	}
+}
@@ -25,10 +25,11 @@ var datacenterCmds = []Command{
 				"user_id":       c.FlagSet.String("user", _nilDefaultStr, "List only specific user's datacenters"),
 				"show_inactive": c.FlagSet.Bool("show-inactive", false, "(Flag) Set flag if inactive datacenters are to be returned"),
 				"show_hidden":   c.FlagSet.Bool("show-hidden", false, "(Flag) Set flag if hidden datacenters are to be returned"),
-				"format":        c.FlagSet.String("format", "", "The output format. Supported values are 'json','csv'. The default format is human readable."),
 			}
 		},
 		ExecuteFunc: datacenterListCmd,
+		Example:     "metalcloud-cli datacenter list -show-inactive",
+		Formatted:   true,
 	},
 	{
 		Description:  "Create datacenter",
@@ -52,6 +53,7 @@ var datacenterCmds = []Command{
 		},
 		ExecuteFunc: datacenterCreateCmd,
 		Endpoint:    DeveloperEndpoint,
+		Example:     "metalcloud-cli datacenter create -label uk-london -title \"UK,London\" -config datacenter.json",
 	},
 	{
 		Description:  "Get datacenter",
@@ -66,11 +68,12 @@ var datacenterCmds = []Command{
 				"show_secret_config_url": c.FlagSet.Bool("show-config-url", false, "(Flag) If set returns the secret config url for datacenter agents."),
 				"show_datacenter_config": c.FlagSet.Bool("show-config", false, "(Flag) If set returns the config of the datacenter."),
 				"return_config_url":      c.FlagSet.Bool("return-config-url", false, "(Flag) If set prints the config url of the datacenter. Ignores all other flags. Useful in automation."),
-				"format":                 c.FlagSet.String("format", "", "The output format. Supported values are 'json','csv'. The default format is human readable."),
 			}
 		},
 		ExecuteFunc: datacenterGetCmd,
 		Endpoint:    DeveloperEndpoint,
+		Example:     "metalcloud-cli datacenter get -label uk-reading -show-config",
+		Formatted:   true,
 	},
 }
 
@@ -340,9 +343,10 @@ func datacenterGetCmd(c *Command, client interfaces.MetalCloudClient) (string, e
 	configStr := ""
 	config := metalcloud.DatacenterConfig{}
 	if showConfig {
+		//kept as an object so that json and yaml output it as a nested document
 		schema = append(schema, SchemaField{
 			FieldName: "CONFIG",
-			FieldType: TypeString,
+			FieldType: TypeInterface,
 			FieldSize: 15,
 		})
 
@@ -360,18 +364,24 @@ func datacenterGetCmd(c *Command, client interfaces.MetalCloudClient) (string, e
 		configStr = string(configBytes)
 	}
 
-	data := [][]interface{}{
-		{
-			retDC.DatacenterName,
-			retDC.DatacenterDisplayName,
-			userStr,
-			retDC.DatacenterNameParent,
-			strings.Join(flags, " "),
-			secretConfigURL,
-			config,
-		},
+	row := []interface{}{
+		retDC.DatacenterName,
+		retDC.DatacenterDisplayName,
+		userStr,
+		retDC.DatacenterNameParent,
+		strings.Join(flags, " "),
+	}
+
+	if showSecretURL || getBoolParam(c.Arguments["return_config_url"]) {
+		row = append(row, secretConfigURL)
+	}
+
+	if showConfig {
+		row = append(row, config)
 	}
 
+	data := [][]interface{}{row}
+
 	var sb strings.Builder
 
 	format := c.Arguments["format"]
@@ -381,6 +391,15 @@ func datacenterGetCmd(c *Command, client interfaces.MetalCloudClient) (string, e
 		format = &f
 	}
 
+	data, schema, err = applyTableOptions(data, schema)
+	if err != nil {
+		return "", err
+	}
+
+	if ret, ok, err := getTableAsExpressionString(*format.(*string), data, schema); ok {
+		return ret, err
+	}
+
 	switch *format.(*string) {
 	case "json", "JSON":
 		ret, err := GetTableAsJSONString(data, schema)
@@ -394,6 +413,12 @@ func datacenterGetCmd(c *Command, client interfaces.MetalCloudClient) (string, e
 			return "", err
 		}
 		sb.WriteString(ret)
+	case "yaml", "YAML":
+		ret, err := GetTableAsYAMLString(data, schema)
+		if err != nil {
+			return "", err
+		}
+		sb.WriteString(ret)
 
 	default:
 
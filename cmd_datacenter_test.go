@@ -10,6 +10,7 @@ import (
 	mock_metalcloud "github.com/bigstepinc/metalcloud-cli/helpers"
 	gomock "github.com/golang/mock/gomock"
 	. "github.com/onsi/gomega"
+	yaml "gopkg.in/yaml.v2"
 )
 
 func TestDatacenterListCmd(t *testing.T) {
@@ -217,7 +218,25 @@ func TestDatacenterGet(t *testing.T) {
 	r := m[0].(map[string]interface{})
 	Expect(r["LABEL"].(string)).To(Equal(_dcFixture1.DatacenterName))
 	Expect(r["TITLE"].(string)).To(Equal(_dcFixture2.DatacenterDisplayName))
+	Expect(r["CONFIG_URL"].(string)).To(Equal("https:/asasd/asdasd"))
+	Expect(r["CONFIG"].(map[string]interface{})["VLANProvisioner"]).NotTo(BeNil())
 
+	//verify yaml format, the config is a nested document
+	cmd = MakeCommand(map[string]interface{}{
+		"datacenter_name":        _dcFixture1.DatacenterName,
+		"show_datacenter_config": true,
+		"format":                 "yaml",
+	})
+	ret, err = datacenterGetCmd(&cmd, client)
+	Expect(err).To(BeNil())
+
+	var y []map[string]interface{}
+	err = yaml.Unmarshal([]byte(ret), &y)
+	Expect(err).To(BeNil())
+
+	Expect(y[0]["LABEL"]).To(Equal(_dcFixture1.DatacenterName))
+	config := y[0]["CONFIG"].(map[interface{}]interface{})
+	Expect(config["VLANProvisioner"].(map[interface{}]interface{})["LANVLANRange"]).To(Equal(dcConf.VLANProvisioner.LANVLANRange))
 }
 
 var _dcFixture1 metalcloud.Datacenter = metalcloud.Datacenter{
@@ -0,0 +1,299 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	interfaces "github.com/bigstepinc/metalcloud-cli/interfaces"
+)
+
+//docsProgramName is the name of the program used in the generated documentation
+const docsProgramName = "metalcloud-cli"
+
+var docsCmds = []Command{
+
+	{
+		Description:  "Generates the reference documentation of all commands, one page per command.",
+		Subject:      "docs",
+		AltSubject:   "docs",
+		Predicate:    "generate",
+		AltPredicate: "gen",
+		FlagSet:      flag.NewFlagSet("generate docs", flag.ExitOnError),
+		InitFunc: func(c *Command) {
+			c.Arguments = map[string]interface{}{
+				"format": c.FlagSet.String("format", _nilDefaultStr, "(Required) The format of the pages. Supported values are 'man' and 'markdown'."),
+				"out":    c.FlagSet.String("out", _nilDefaultStr, "(Required) The directory in which to write the pages. It is created if it does not exist."),
+			}
+		},
+		Endpoint: LocalEndpoint,
+		Example:  "metalcloud-cli docs generate -format markdown -out docs",
+		Hidden:   true,
+	},
+}
+
+func init() {
+	//set here as the command lists all commands, itself included, which cannot be done while initializing docsCmds
+	docsCmds[0].ExecuteFunc = docsGenerateCmd
+}
+
+func docsGenerateCmd(c *Command, client interfaces.MetalCloudClient) (string, error) {
+
+	format, ok := getStringParamOk(c.Arguments["format"])
+	if !ok {
+		return "", fmt.Errorf("-format is required")
+	}
+
+	dir, ok := getStringParamOk(c.Arguments["out"])
+	if !ok {
+		return "", fmt.Errorf("-out is required")
+	}
+
+	var pageFunc func(cmd Command) string
+	var indexFunc func(commands []Command) string
+	var extension string
+
+	switch format {
+	case "man":
+		pageFunc = getManPage
+		indexFunc = getManIndexPage
+		extension = ".1"
+	case "markdown", "md":
+		pageFunc = getMarkdownPage
+		indexFunc = getMarkdownIndexPage
+		extension = ".md"
+	default:
+		return "", fmt.Errorf("Invalid format %s. Supported values are 'man' and 'markdown'", format)
+	}
+
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		return "", err
+	}
+
+	commands := []Command{}
+	for _, commandSet := range getCommandSets() {
+		for _, cmd := range commandSet {
+			if !cmd.Hidden {
+				commands = append(commands, initCommandCopy(cmd))
+			}
+		}
+	}
+
+	for _, cmd := range commands {
+		err := ioutil.WriteFile(filepath.Join(dir, getDocsPageName(cmd)+extension), []byte(pageFunc(cmd)), 0644)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	err = ioutil.WriteFile(filepath.Join(dir, docsProgramName+extension), []byte(indexFunc(commands)), 0644)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("Generated %d pages in %s\n", len(commands)+1, dir), nil
+}
+
+//getDocsPageName returns the name of the page of a command, without extension
+func getDocsPageName(cmd Command) string {
+	return fmt.Sprintf("%s-%s-%s", docsProgramName, cmd.Subject, cmd.Predicate)
+}
+
+//getEndpointName returns the API endpoint used by a command as shown in the documentation
+func getEndpointName(endpoint string) string {
+	switch endpoint {
+	case "", UserEndpoint:
+		return UserEndpoint
+	case LocalEndpoint:
+		return "none (the command does not connect to the API)"
+	}
+	return endpoint
+}
+
+//getAlternateNames returns the other ways the command can be given, if any
+func getAlternateNames(cmd Command) []string {
+	names := []string{}
+	for _, subject := range []string{cmd.Subject, cmd.AltSubject} {
+		for _, predicate := range []string{cmd.Predicate, cmd.AltPredicate} {
+			name := fmt.Sprintf("%s %s %s", docsProgramName, subject, predicate)
+			if (subject != cmd.Subject || predicate != cmd.Predicate) && !contains(names, name) {
+				names = append(names, name)
+			}
+		}
+	}
+	return names
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+//getMarkdownPage returns the markdown page of a command
+func getMarkdownPage(cmd Command) string {
+	var sb strings.Builder
+
+	sb.WriteString(fmt.Sprintf("# %s %s %s\n\n", docsProgramName, cmd.Subject, cmd.Predicate))
+	sb.WriteString(fmt.Sprintf("%s\n\n", cmd.Description))
+
+	sb.WriteString(fmt.Sprintf("## Usage\n\n```\n%s\n```\n\n", getCommandUsage(cmd, docsProgramName)))
+
+	if names := getAlternateNames(cmd); len(names) > 0 {
+		sb.WriteString("Alternate names: ")
+		for i, name := range names {
+			if i > 0 {
+				sb.WriteString(", ")
+			}
+			sb.WriteString(fmt.Sprintf("`%s`", name))
+		}
+		sb.WriteString("\n\n")
+	}
+
+	sb.WriteString(fmt.Sprintf("Endpoint: %s\n\n", getEndpointName(cmd.Endpoint)))
+
+	sb.WriteString("## Flags\n\n")
+	sb.WriteString("| Flag | Required | Default | Description |\n")
+	sb.WriteString("|------|----------|---------|-------------|\n")
+	cmd.FlagSet.VisitAll(func(f *flag.Flag) {
+		required := "no"
+		if isRequiredFlag(f) {
+			required = "yes"
+		}
+		sb.WriteString(fmt.Sprintf("| `%s` | %s | %s | %s |\n",
+			getFlagSyntax(f),
+			required,
+			getMarkdownDefault(f),
+			strings.ReplaceAll(f.Usage, "|", "\\|")))
+	})
+	sb.WriteString("\n")
+
+	if cmd.Example != "" {
+		sb.WriteString(fmt.Sprintf("## Example\n\n```\n%s\n```\n\n", cmd.Example))
+	}
+
+	sb.WriteString(fmt.Sprintf("See the [list of commands](%s.md).\n", docsProgramName))
+
+	return sb.String()
+}
+
+func getMarkdownDefault(f *flag.Flag) string {
+	if f.DefValue == "" || f.DefValue == _nilDefaultStr || f.DefValue == fmt.Sprintf("%d", _nilDefaultInt) || isBoolFlag(f) && f.DefValue == "false" {
+		return ""
+	}
+	return fmt.Sprintf("`%s`", f.DefValue)
+}
+
+//getMarkdownIndexPage returns the markdown page listing all commands, grouped by subject
+func getMarkdownIndexPage(commands []Command) string {
+	var sb strings.Builder
+
+	sb.WriteString(fmt.Sprintf("# %s\n\n", docsProgramName))
+	sb.WriteString("This tool allows the manipulation of all Bigstep Metal Cloud elements via the command line.\n\n")
+	sb.WriteString(fmt.Sprintf("```\n%s [global flags] <subject> <predicate> [args]\n```\n\n", docsProgramName))
+
+	for _, subject := range getSubjects(commands) {
+		sb.WriteString(fmt.Sprintf("## %s\n\n", subject))
+		for _, cmd := range commands {
+			if cmd.Subject == subject {
+				sb.WriteString(fmt.Sprintf("* [%s %s](%s.md) - %s\n", cmd.Subject, cmd.Predicate, getDocsPageName(cmd), cmd.Description))
+			}
+		}
+		sb.WriteString("\n")
+	}
+
+	return sb.String()
+}
+
+//escapeRoff escapes text so that it is shown as is in a man page
+func escapeRoff(s string) string {
+	s = strings.ReplaceAll(s, "\\", "\\e")
+	s = strings.ReplaceAll(s, "-", "\\-")
+
+	lines := strings.Split(s, "\n")
+	for i, l := range lines {
+		if strings.HasPrefix(l, ".") || strings.HasPrefix(l, "'") {
+			lines[i] = "\\&" + l
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func getManHeader(name string, description string) string {
+	var sb strings.Builder
+
+	source := strings.TrimSpace(fmt.Sprintf("%s %s", docsProgramName, version))
+	sb.WriteString(fmt.Sprintf(".TH \"%s\" \"1\" \"\" \"%s\" \"%s manual\"\n", strings.ToUpper(name), source, docsProgramName))
+	sb.WriteString(".SH NAME\n")
+	sb.WriteString(fmt.Sprintf("%s \\- %s\n", escapeRoff(name), escapeRoff(description)))
+
+	return sb.String()
+}
+
+//getManPage returns the man page of a command
+func getManPage(cmd Command) string {
+	var sb strings.Builder
+
+	sb.WriteString(getManHeader(getDocsPageName(cmd), cmd.Description))
+
+	sb.WriteString(".SH SYNOPSIS\n")
+	sb.WriteString(fmt.Sprintf("%s\n", escapeRoff(getCommandUsage(cmd, docsProgramName))))
+
+	sb.WriteString(".SH DESCRIPTION\n")
+	sb.WriteString(fmt.Sprintf("%s\n", escapeRoff(cmd.Description)))
+
+	if names := getAlternateNames(cmd); len(names) > 0 {
+		sb.WriteString(".SH ALTERNATE NAMES\n")
+		for _, name := range names {
+			sb.WriteString(fmt.Sprintf("%s\n.br\n", escapeRoff(name)))
+		}
+	}
+
+	sb.WriteString(".SH ENDPOINT\n")
+	sb.WriteString(fmt.Sprintf("%s\n", escapeRoff(getEndpointName(cmd.Endpoint))))
+
+	sb.WriteString(".SH OPTIONS\n")
+	cmd.FlagSet.VisitAll(func(f *flag.Flag) {
+		sb.WriteString(fmt.Sprintf(".TP\n.B %s\n%s\n", escapeRoff(getFlagSyntax(f)), escapeRoff(f.Usage)))
+	})
+
+	if cmd.Example != "" {
+		sb.WriteString(".SH EXAMPLE\n.nf\n")
+		sb.WriteString(fmt.Sprintf("%s\n", escapeRoff(cmd.Example)))
+		sb.WriteString(".fi\n")
+	}
+
+	sb.WriteString(".SH SEE ALSO\n")
+	sb.WriteString(fmt.Sprintf("%s(1)\n", escapeRoff(docsProgramName)))
+
+	return sb.String()
+}
+
+//getManIndexPage returns the man page listing all commands, grouped by subject
+func getManIndexPage(commands []Command) string {
+	var sb strings.Builder
+
+	sb.WriteString(getManHeader(docsProgramName, "manipulates Bigstep Metal Cloud elements via the command line"))
+
+	sb.WriteString(".SH SYNOPSIS\n")
+	sb.WriteString(fmt.Sprintf("%s\n", escapeRoff(docsProgramName+" [global flags] <subject> <predicate> [args]")))
+
+	sb.WriteString(".SH COMMANDS\n")
+	for _, subject := range getSubjects(commands) {
+		sb.WriteString(fmt.Sprintf(".SS %s\n", escapeRoff(subject)))
+		for _, cmd := range commands {
+			if cmd.Subject == subject {
+				sb.WriteString(fmt.Sprintf(".TP\n.BR %s (1)\n%s\n", escapeRoff(getDocsPageName(cmd)), escapeRoff(cmd.Description)))
+			}
+		}
+	}
+
+	return sb.String()
+}
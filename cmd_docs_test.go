@@ -0,0 +1,114 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	mock_metalcloud "github.com/bigstepinc/metalcloud-cli/helpers"
+	interfaces "github.com/bigstepinc/metalcloud-cli/interfaces"
+	gomock "github.com/golang/mock/gomock"
+	. "github.com/onsi/gomega"
+)
+
+func TestDocsGenerateCmd(t *testing.T) {
+	RegisterTestingT(t)
+
+	dir, err := ioutil.TempDir("", "metalcloud-docs")
+	Expect(err).To(BeNil())
+	defer os.RemoveAll(dir)
+
+	cmd := MakeCommand(map[string]interface{}{
+		"format": "markdown",
+		"out":    dir,
+	})
+
+	ret, err := docsGenerateCmd(&cmd, nil)
+	Expect(err).To(BeNil())
+	Expect(ret).To(ContainSubstring("Generated"))
+
+	content, err := ioutil.ReadFile(filepath.Join(dir, "metalcloud-cli-infrastructure-get.md"))
+	Expect(err).To(BeNil())
+	s := string(content)
+	Expect(s).To(HavePrefix("# metalcloud-cli infrastructure get\n"))
+	Expect(s).To(ContainSubstring("metalcloud-cli infrastructure get -id <string> [flags]"))
+	Expect(s).To(ContainSubstring("`metalcloud-cli infra show`"))
+	Expect(s).To(ContainSubstring("Endpoint: user"))
+	Expect(s).To(ContainSubstring("| `-id <string>` | yes |"))
+	Expect(s).To(ContainSubstring("## Example"))
+
+	content, err = ioutil.ReadFile(filepath.Join(dir, "metalcloud-cli-server-list.md"))
+	Expect(err).To(BeNil())
+	Expect(string(content)).To(ContainSubstring("Endpoint: developer"))
+
+	content, err = ioutil.ReadFile(filepath.Join(dir, "metalcloud-cli.md"))
+	Expect(err).To(BeNil())
+	Expect(string(content)).To(ContainSubstring("[infrastructure get](metalcloud-cli-infrastructure-get.md)"))
+
+	//hidden commands are not documented
+	_, err = os.Stat(filepath.Join(dir, "metalcloud-cli-docs-generate.md"))
+	Expect(os.IsNotExist(err)).To(BeTrue())
+
+	cmd = MakeCommand(map[string]interface{}{
+		"format": "man",
+		"out":    dir,
+	})
+
+	_, err = docsGenerateCmd(&cmd, nil)
+	Expect(err).To(BeNil())
+
+	content, err = ioutil.ReadFile(filepath.Join(dir, "metalcloud-cli-instance-array-create.1"))
+	Expect(err).To(BeNil())
+	s = string(content)
+	Expect(s).To(HavePrefix(".TH \"METALCLOUD-CLI-INSTANCE-ARRAY-CREATE\" \"1\""))
+	Expect(s).To(ContainSubstring(".SH ALTERNATE NAMES\nmetalcloud\\-cli instance\\-array new"))
+	Expect(s).To(ContainSubstring(".SH ENDPOINT\nuser"))
+	Expect(s).To(ContainSubstring(".B \\-infra <string>"))
+
+	_, err = os.Stat(filepath.Join(dir, "metalcloud-cli.1"))
+	Expect(err).To(BeNil())
+
+	cmd = MakeCommand(map[string]interface{}{
+		"format": "pdf",
+		"out":    dir,
+	})
+
+	_, err = docsGenerateCmd(&cmd, nil)
+	Expect(err).NotTo(BeNil())
+
+	cmd = MakeCommand(map[string]interface{}{
+		"format": "man",
+	})
+
+	_, err = docsGenerateCmd(&cmd, nil)
+	Expect(err).NotTo(BeNil())
+}
+
+func TestEscapeRoff(t *testing.T) {
+	RegisterTestingT(t)
+
+	Expect(escapeRoff("a-b")).To(Equal("a\\-b"))
+	Expect(escapeRoff("c:\\dir")).To(Equal("c:\\edir"))
+	Expect(escapeRoff(".start\n'quote")).To(Equal("\\&.start\n\\&'quote"))
+}
+
+func TestHiddenCommands(t *testing.T) {
+	RegisterTestingT(t)
+	ctrl := gomock.NewController(t)
+	client := mock_metalcloud.NewMockMetalCloudClient(ctrl)
+	clients := map[string]interfaces.MetalCloudClient{
+		"":           client,
+		UserEndpoint: client,
+	}
+
+	Expect(getHelp(clients, false)).NotTo(ContainSubstring("docs"))
+	Expect(getCompletions([]string{""}, clients)).NotTo(ContainElement("docs"))
+
+	//hidden commands can still be executed and have their own help
+	Expect(locateCommand("generate", "docs", getCommands(clients))).NotTo(BeNil())
+
+	s, err := getHelpForArgs([]string{"docs", "generate"}, clients)
+	Expect(err).To(BeNil())
+	Expect(s).To(ContainSubstring("docs generate -format <string> -out <string> [flags]"))
+}
@@ -34,6 +34,7 @@ var driveArrayCmds = []Command{
 			}
 		},
 		ExecuteFunc: driveArrayCreateCmd,
+		Example:     "metalcloud-cli drive-array create -infra complex-demo -ia master -label master-da -template centos7-6",
 	},
 	{
 		Description:  "Edit a drive array.",
@@ -66,10 +67,11 @@ var driveArrayCmds = []Command{
 		InitFunc: func(c *Command) {
 			c.Arguments = map[string]interface{}{
 				"infrastructure_id_or_label": c.FlagSet.String("infra", _nilDefaultStr, "(Required) Infrastructure's id or label. Note that the 'label' this be ambiguous in certain situations."),
-				"format":                     c.FlagSet.String("format", "", "The output format. Supported values are 'json','csv'. The default format is human readable."),
 			}
 		},
 		ExecuteFunc: driveArrayListCmd,
+		Example:     "metalcloud-cli drive-array list -infra complex-demo",
+		Formatted:   true,
 	},
 	{
 		Description:  "Delete a drive array.",
@@ -97,10 +99,11 @@ var driveArrayCmds = []Command{
 			c.Arguments = map[string]interface{}{
 				"drive_array_id_or_label": c.FlagSet.String("id", _nilDefaultStr, "(Required) Drive Array's ID or label. Note that using the label can be ambiguous and is slower."),
 				"show_iscsi_credentials":  c.FlagSet.Bool("show-iscsi-credentials", false, "(Flag) If set returns the drives' iscsi credentials"),
-				"format":                  c.FlagSet.String("format", "", "The output format. Supported values are 'json','csv'. The default format is human readable."),
 			}
 		},
 		ExecuteFunc: driveArrayGetCmd,
+		Example:     "metalcloud-cli drive-array get -id master-da",
+		Formatted:   true,
 	},
 }
 
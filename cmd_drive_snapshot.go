@@ -26,6 +26,7 @@ var driveSnapshotCmds = []Command{
 			}
 		},
 		ExecuteFunc: driveSnapshotCreateCmd,
+		Example:     "metalcloud-cli drive-snapshot create -id 1234",
 	},
 	{
 		Description:  "Lists drive snapshots",
@@ -37,10 +38,11 @@ var driveSnapshotCmds = []Command{
 		InitFunc: func(c *Command) {
 			c.Arguments = map[string]interface{}{
 				"drive_id": c.FlagSet.Int("id", _nilDefaultInt, "(Required) The id of the drive to create a snapshot from"),
-				"format":   c.FlagSet.String("format", "", "The output format. Supported values are 'json','csv'. The default format is human readable."),
 			}
 		},
 		ExecuteFunc: driveSnapshotListCmd,
+		Example:     "metalcloud-cli drive-snapshot list -id 1234",
+		Formatted:   true,
 	},
 	{
 		Description:  "Delete snapshot",
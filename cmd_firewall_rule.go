@@ -23,10 +23,11 @@ var firewallRuleCmds = []Command{
 		InitFunc: func(c *Command) {
 			c.Arguments = map[string]interface{}{
 				"instance_array_id": c.FlagSet.Int("ia", _nilDefaultInt, "(Required) The instance array id"),
-				"format":            c.FlagSet.String("format", _nilDefaultStr, "The output format. Supported values are 'json','csv'. The default format is human readable."),
 			}
 		},
 		ExecuteFunc: firewallRuleListCmd,
+		Example:     "metalcloud-cli firewall-rule list -ia 12345",
+		Formatted:   true,
 	},
 	{
 		Description:  "Add instance array firewall rule",
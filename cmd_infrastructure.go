@@ -1,13 +1,17 @@
 package main
 
 import (
+	"encoding/json"
 	"flag"
 	"fmt"
 	"os"
+	"sort"
 	"strings"
+	"time"
 
 	metalcloud "github.com/bigstepinc/metal-cloud-sdk-go"
 	interfaces "github.com/bigstepinc/metalcloud-cli/interfaces"
+	yaml "gopkg.in/yaml.v2"
 )
 
 //infrastructureCmds commands affecting infrastructures
@@ -28,6 +32,26 @@ var infrastructureCmds = []Command{
 			}
 		},
 		ExecuteFunc: infrastructureCreateCmd,
+		Example:     "metalcloud-cli infrastructure create -label complex-demo -datacenter uk-reading -return-id",
+	},
+	{
+		Description:  "Edit an infrastructure. The deploy status, type and ids are managed by deploys and cannot be edited.",
+		Subject:      "infrastructure",
+		AltSubject:   "infra",
+		Predicate:    "edit",
+		AltPredicate: "alter",
+		FlagSet:      flag.NewFlagSet("edit infrastructure", flag.ExitOnError),
+		InitFunc: func(c *Command) {
+			c.Arguments = map[string]interface{}{
+				"infrastructure_id_or_label": c.FlagSet.String("id", _nilDefaultStr, "(Required) Infrastructure's id or label. Note that using the 'label' might be ambiguous in certain situations."),
+				"infrastructure_label":       c.FlagSet.String("label", _nilDefaultStr, "(Optional) Infrastructure's new label"),
+				"infrastructure_subdomain":   c.FlagSet.String("subdomain", _nilDefaultStr, "(Optional) Infrastructure's new subdomain"),
+				"datacenter":                 c.FlagSet.String("datacenter", _nilDefaultStr, "(Optional) Infrastructure's new datacenter"),
+				"user_id_owner":              c.FlagSet.Int("owner-id", _nilDefaultInt, "(Optional) Id of the user the infrastructure is transferred to"),
+			}
+		},
+		ExecuteFunc: infrastructureEditCmd,
+		Example:     "metalcloud-cli infrastructure edit -id complex-demo -label complex-demo-2 -subdomain complex-demo-2",
 	},
 	{
 		Description:  "Lists all infrastructures.",
@@ -38,10 +62,13 @@ var infrastructureCmds = []Command{
 		FlagSet:      flag.NewFlagSet("list infrastructure", flag.ExitOnError),
 		InitFunc: func(c *Command) {
 			c.Arguments = map[string]interface{}{
-				"format": c.FlagSet.String("format", "", "The output format. Supported values are 'json','csv'. The default format is human readable."),
+				"owner":    c.FlagSet.String("owner", _nilDefaultStr, "If set only infrastructures owned by this user (id or email) are returned."),
+				"relation": c.FlagSet.String("relation", _nilDefaultStr, "If set only infrastructures with this relation to the current user are returned. Supported values are 'owner','delegate'."),
 			}
 		},
 		ExecuteFunc: infrastructureListCmd,
+		Example:     "metalcloud-cli infrastructure list -relation owner",
+		Formatted:   true,
 	},
 	{
 		Description:  "Delete an infrastructure.",
@@ -59,7 +86,7 @@ var infrastructureCmds = []Command{
 		ExecuteFunc: infrastructureDeleteCmd,
 	},
 	{
-		Description:  "Deploy an infrastructure.",
+		Description:  "Deploy an infrastructure, or create or update one from a manifest file with -f.",
 		Subject:      "infrastructure",
 		AltSubject:   "infra",
 		Predicate:    "deploy",
@@ -67,16 +94,86 @@ var infrastructureCmds = []Command{
 		FlagSet:      flag.NewFlagSet("deploy infrastructure", flag.ExitOnError),
 		InitFunc: func(c *Command) {
 			c.Arguments = map[string]interface{}{
-				"infrastructure_id_or_label":     c.FlagSet.String("id", _nilDefaultStr, "(Required) Infrastructure's id or label. Note that using the 'label' might be ambiguous in certain situations."),
+				"infrastructure_id_or_label":     c.FlagSet.String("id", _nilDefaultStr, "(Required unless -f is set) Infrastructure's id or label. Note that using the 'label' might be ambiguous in certain situations."),
+				"manifest":                       c.FlagSet.String("f", _nilDefaultStr, "(Optional) The manifest file describing the infrastructure, in yaml or json (.json extension) format. If set the infrastructure is created or updated to match it instead of being deployed. Objects not in the manifest are left untouched."),
+				"deploy":                         c.FlagSet.Bool("deploy", false, "(Flag) If set together with -f the infrastructure is deployed after the changes are applied."),
 				"no_hard_shutdown_after_timeout": c.FlagSet.Bool("no-hard-shutdown-after-timeout", false, "(Flag) If set do not force a hard power off after timeout expired and the server is not powered off."),
 				"no_attempt_soft_shutdown":       c.FlagSet.Bool("no-attempt-soft-shutdown", false, "(Flag) If set,do not atempt a soft (ACPI) power off of all the servers in the infrastructure before the deploy"),
 				"soft_shutdown_timeout_seconds":  c.FlagSet.Int("soft-shutdown-timeout-seconds", 180, "(Optional, default 180) Timeout to wait if hard_shutdown_after_timeout is set."),
 				"allow_data_loss":                c.FlagSet.Bool("allow-data-loss", false, "(Flag) If set, deploy will throw error if data loss is expected."),
 				"skip_ansible":                   c.FlagSet.Bool("skip-ansible", false, "(Flag) If set, some automatic provisioning steps will be skipped. This parameter should generally be ignored."),
 				"autoconfirm":                    c.FlagSet.Bool("autoconfirm", false, "(Flag) If set operation procedes without asking for confirmation"),
+				"wait":                           c.FlagSet.Bool("wait", false, "(Flag) If set the command waits for the deploy to finish and the infrastructure to become active."),
+				"wait_timeout":                   c.FlagSet.Duration("wait-timeout", defaultWaitTimeout, "(Optional, default 1h) Maximum duration to wait when -wait is set such as '30m'."),
 			}
 		},
 		ExecuteFunc: infrastructureDeployCmd,
+		Example:     "metalcloud-cli infrastructure deploy -id complex-demo -autoconfirm -wait\nmetalcloud-cli infrastructure apply -f infra.yaml -deploy",
+		Formatted:   true,
+	},
+	{
+		Description:  "Export an infrastructure as a manifest file.",
+		Subject:      "infrastructure",
+		AltSubject:   "infra",
+		Predicate:    "export",
+		AltPredicate: "dump",
+		FlagSet:      flag.NewFlagSet("export infrastructure manifest", flag.ExitOnError),
+		InitFunc: func(c *Command) {
+			c.Arguments = map[string]interface{}{
+				"infrastructure_id_or_label": c.FlagSet.String("id", _nilDefaultStr, "(Required) Infrastructure's id or label. Note that using the 'label' might be ambiguous in certain situations."),
+				"format":                     c.FlagSet.String("format", _nilDefaultStr, "The output format. Supported values are 'yaml','json'. The default format is yaml."),
+			}
+		},
+		ExecuteFunc: infrastructureExportCmd,
+		Example:     "metalcloud-cli infrastructure export -id complex-demo -format yaml > infra.yaml",
+	},
+	{
+		Description:  "Clone an infrastructure, possibly into another datacenter.",
+		Subject:      "infrastructure",
+		AltSubject:   "infra",
+		Predicate:    "clone",
+		AltPredicate: "copy",
+		FlagSet:      flag.NewFlagSet("clone infrastructure", flag.ExitOnError),
+		InitFunc: func(c *Command) {
+			c.Arguments = map[string]interface{}{
+				"infrastructure_id_or_label": c.FlagSet.String("id", _nilDefaultStr, "(Required) Id or label of the infrastructure to clone. Note that using the 'label' might be ambiguous in certain situations."),
+				"label":                      c.FlagSet.String("label", _nilDefaultStr, "(Required) The label of the new infrastructure."),
+				"datacenter":                 c.FlagSet.String("datacenter", _nilDefaultStr, "(Optional) The datacenter of the new infrastructure. Defaults to the datacenter of the cloned infrastructure."),
+			}
+		},
+		ExecuteFunc: infrastructureCloneCmd,
+		Formatted:   true,
+	},
+	{
+		Description:  "Show the changes the next deploy of an infrastructure will apply.",
+		Subject:      "infrastructure",
+		AltSubject:   "infra",
+		Predicate:    "plan",
+		AltPredicate: "diff",
+		FlagSet:      flag.NewFlagSet("plan infrastructure", flag.ExitOnError),
+		InitFunc: func(c *Command) {
+			c.Arguments = map[string]interface{}{
+				"infrastructure_id_or_label": c.FlagSet.String("id", _nilDefaultStr, "(Required) Infrastructure's id or label. Note that using the 'label' might be ambiguous in certain situations."),
+			}
+		},
+		ExecuteFunc: infrastructurePlanCmd,
+		Formatted:   true,
+	},
+	{
+		Description:  "Wait for an infrastructure to reach a status.",
+		Subject:      "infrastructure",
+		AltSubject:   "infra",
+		Predicate:    "wait",
+		AltPredicate: "await",
+		FlagSet:      flag.NewFlagSet("wait infrastructure", flag.ExitOnError),
+		InitFunc: func(c *Command) {
+			c.Arguments = map[string]interface{}{
+				"infrastructure_id_or_label": c.FlagSet.String("id", _nilDefaultStr, "(Required) Infrastructure's id or label. Note that using the 'label' might be ambiguous in certain situations."),
+				"status":                     c.FlagSet.String("status", "active", "(Optional, default active) The service status to wait for."),
+				"wait_timeout":               c.FlagSet.Duration("wait-timeout", defaultWaitTimeout, "(Optional, default 1h) Maximum duration to wait such as '30m'."),
+			}
+		},
+		ExecuteFunc: infrastructureWaitCmd,
 	},
 	{
 		Description:  "Get an infrastructure.",
@@ -88,10 +185,26 @@ var infrastructureCmds = []Command{
 		InitFunc: func(c *Command) {
 			c.Arguments = map[string]interface{}{
 				"infrastructure_id_or_label": c.FlagSet.String("id", _nilDefaultStr, "(Required) Infrastructure's id or label. Note that using the 'label' might be ambiguous in certain situations."),
-				"format":                     c.FlagSet.String("format", "", "The output format. Supported values are 'json','csv'. The default format is human readable."),
 			}
 		},
 		ExecuteFunc: infrastructureGetCmd,
+		Example:     "metalcloud-cli infrastructure get -id complex-demo",
+		Formatted:   true,
+	},
+	{
+		Description:  "Show the limits of an infrastructure and their current usage.",
+		Subject:      "infrastructure",
+		AltSubject:   "infra",
+		Predicate:    "limits",
+		AltPredicate: "quota",
+		FlagSet:      flag.NewFlagSet("infrastructure limits", flag.ExitOnError),
+		InitFunc: func(c *Command) {
+			c.Arguments = map[string]interface{}{
+				"infrastructure_id_or_label": c.FlagSet.String("id", _nilDefaultStr, "(Required) Infrastructure's id or label. Note that using the 'label' might be ambiguous in certain situations."),
+			}
+		},
+		ExecuteFunc: infrastructureLimitsCmd,
+		Formatted:   true,
 	},
 	{
 		Description:  "Revert all changes of an infrastructure.",
@@ -108,6 +221,21 @@ var infrastructureCmds = []Command{
 		},
 		ExecuteFunc: infrastructureRevertCmd,
 	},
+	{
+		Description:  "Get the power status of all instances of an infrastructure.",
+		Subject:      "infrastructure",
+		AltSubject:   "infra",
+		Predicate:    "power-status",
+		AltPredicate: "pwr",
+		FlagSet:      flag.NewFlagSet("infrastructure power status", flag.ExitOnError),
+		InitFunc: func(c *Command) {
+			c.Arguments = map[string]interface{}{
+				"infrastructure_id_or_label": c.FlagSet.String("id", _nilDefaultStr, "(Required) Infrastructure's id or label. Note that using the 'label' might be ambiguous in certain situations."),
+			}
+		},
+		ExecuteFunc: infrastructurePowerStatusCmd,
+		Formatted:   true,
+	},
 	{
 		Description:  "list stages of a workflow",
 		Subject:      "infrastructure",
@@ -152,8 +280,49 @@ func infrastructureCreateCmd(c *Command, client interfaces.MetalCloudClient) (st
 	return "", nil
 }
 
+func infrastructureEditCmd(c *Command, client interfaces.MetalCloudClient) (string, error) {
+
+	retInfra, err := getInfrastructureFromCommand("id", c, client)
+	if err != nil {
+		return "", err
+	}
+
+	io := retInfra.InfrastructureOperation
+
+	updateIfStringParamSet(c.Arguments["infrastructure_label"], &io.InfrastructureLabel)
+	updateIfStringParamSet(c.Arguments["infrastructure_subdomain"], &io.InfrastructureSubdomain)
+	updateIfStringParamSet(c.Arguments["datacenter"], &io.DatacenterName)
+	updateIfIntParamSet(c.Arguments["user_id_owner"], &io.UserIDOwner)
+
+	_, err = client.InfrastructureEdit(retInfra.InfrastructureID, io)
+
+	return "", err
+}
+
 func infrastructureListCmd(c *Command, client interfaces.MetalCloudClient) (string, error) {
 
+	relationFilter := ""
+	if v, ok := getStringParamOk(c.Arguments["relation"]); ok {
+		switch strings.ToLower(v) {
+		case "owner":
+			relationFilter = "OWNER"
+		case "delegate":
+			relationFilter = "_DELEGATE"
+		default:
+			return "", fmt.Errorf("invalid relation %s. Supported values are 'owner','delegate'", v)
+		}
+	}
+
+	ownerID := 0
+	ownerEmail := ""
+	if v, ok := getStringParamOk(c.Arguments["owner"]); ok {
+		if id, email, isID := idOrLabelString(v); isID {
+			ownerID = id
+		} else {
+			ownerEmail = email
+		}
+	}
+
 	iList, err := client.Infrastructures()
 	if err != nil {
 		return "", err
@@ -200,6 +369,19 @@ func infrastructureListCmd(c *Command, client interfaces.MetalCloudClient) (stri
 		if i.UserEmailOwner != user {
 			relation = "_DELEGATE"
 		}
+
+		if relationFilter != "" && relation != relationFilter {
+			continue
+		}
+
+		if ownerID != 0 && i.UserIDowner != ownerID {
+			continue
+		}
+
+		if ownerEmail != "" && !strings.EqualFold(i.UserEmailOwner, ownerEmail) {
+			continue
+		}
+
 		data = append(data, []interface{}{
 			i.InfrastructureID,
 			i.InfrastructureOperation.InfrastructureLabel,
@@ -221,10 +403,100 @@ func infrastructureListCmd(c *Command, client interfaces.MetalCloudClient) (stri
 	return renderTable("Infrastructures", topLine, getStringParam(c.Arguments["format"]), data, schema)
 }
 
+func infrastructurePowerStatusCmd(c *Command, client interfaces.MetalCloudClient) (string, error) {
+
+	retInfra, err := getInfrastructureFromCommand("id", c, client)
+	if err != nil {
+		return "", err
+	}
+
+	iaList, err := client.InstanceArrays(retInfra.InfrastructureID)
+	if err != nil {
+		return "", err
+	}
+
+	schema := []SchemaField{
+		{
+			FieldName: "ID",
+			FieldType: TypeInt,
+			FieldSize: 6,
+		},
+		{
+			FieldName: "LABEL",
+			FieldType: TypeString,
+			FieldSize: 15,
+		},
+		{
+			FieldName: "INSTANCE_ARRAY",
+			FieldType: TypeString,
+			FieldSize: 15,
+		},
+		{
+			FieldName: "SERVER_ID",
+			FieldType: TypeInt,
+			FieldSize: 6,
+		},
+		{
+			FieldName: "POWER",
+			FieldType: TypeString,
+			FieldSize: 5,
+		},
+	}
+
+	data := [][]interface{}{}
+	instanceIDs := []int{}
+
+	for _, ia := range *iaList {
+
+		iList, err := client.InstanceArrayInstances(ia.InstanceArrayID)
+		if err != nil {
+			return "", err
+		}
+
+		for _, i := range *iList {
+			instanceIDs = append(instanceIDs, i.InstanceID)
+			data = append(data, []interface{}{
+				i.InstanceID,
+				i.InstanceLabel,
+				fmt.Sprintf("%s (#%d)", ia.InstanceArrayLabel, ia.InstanceArrayID),
+				i.ServerID,
+				"",
+			})
+		}
+	}
+
+	//retrieve the power status of all instances in a single call
+	if len(instanceIDs) > 0 {
+		powerStatus, err := client.InstanceServerPowerGetBatch(retInfra.InfrastructureID, instanceIDs)
+		if err != nil {
+			return "", err
+		}
+
+		for _, row := range data {
+			if status, ok := (*powerStatus)[fmt.Sprintf("%d", row[0].(int))]; ok {
+				row[4] = status
+			} else {
+				row[4] = "unknown"
+			}
+		}
+	}
+
+	TableSorter(schema).OrderBy(schema[2].FieldName, schema[0].FieldName).Sort(data)
+
+	topLine := fmt.Sprintf("Power status of instances of infrastructure %s (#%d):", retInfra.InfrastructureLabel, retInfra.InfrastructureID)
+
+	return renderTable("Instances", topLine, getStringParam(c.Arguments["format"]), data, schema)
+}
+
 type infrastructureConfirmAndDoFunc func(infraID int, c *Command, client interfaces.MetalCloudClient) (string, error)
 
 //infrastructureConfirmAndDo asks for confirmation and executes the given function
 func infrastructureConfirmAndDo(operation string, c *Command, client interfaces.MetalCloudClient, f infrastructureConfirmAndDoFunc) (string, error) {
+	return infrastructureConfirmWithDetailsAndDo(operation, c, client, nil, f)
+}
+
+//infrastructureConfirmWithDetailsAndDo asks for confirmation, showing the text returned by details if not nil, and executes the given function
+func infrastructureConfirmWithDetailsAndDo(operation string, c *Command, client interfaces.MetalCloudClient, details func(infra *metalcloud.Infrastructure) (string, error), f infrastructureConfirmAndDoFunc) (string, error) {
 
 	val, err := getParam(c, "infrastructure_id_or_label", "infra")
 	if err != nil {
@@ -255,6 +527,14 @@ func infrastructureConfirmAndDo(operation string, c *Command, client interfaces.
 
 		confirmationMessage := fmt.Sprintf("%s infrastructure %s (%d). Are you sure? Type \"yes\" to continue:", operation, retInfra.InfrastructureLabel, retInfra.InfrastructureID)
 
+		if details != nil {
+			d, err := details(retInfra)
+			if err != nil {
+				return "", err
+			}
+			confirmationMessage = d + confirmationMessage
+		}
+
 		//this is simply so that we don't output a text on the command line under go test
 		if strings.HasSuffix(os.Args[0], ".test") {
 			confirmationMessage = ""
@@ -282,30 +562,391 @@ func infrastructureDeleteCmd(c *Command, client interfaces.MetalCloudClient) (st
 
 func infrastructureDeployCmd(c *Command, client interfaces.MetalCloudClient) (string, error) {
 
-	return infrastructureConfirmAndDo("Deploy", c, client,
-		func(infraID int, c *Command, client interfaces.MetalCloudClient) (string, error) {
+	if _, ok := getStringParamOk(c.Arguments["manifest"]); ok {
+		return infrastructureApplyCmd(c, client)
+	}
 
-			timeout := 180
-			if c.Arguments["soft_shutdown_timeout_seconds"] != nil {
-				timeout = *c.Arguments["soft_shutdown_timeout_seconds"].(*int)
+	return infrastructureConfirmWithDetailsAndDo("Deploy", c, client,
+		func(infra *metalcloud.Infrastructure) (string, error) {
+			plan, err := getInfrastructurePlan(infra, client)
+			if err != nil {
+				return "", err
 			}
+			return renderInfrastructurePlan(infra, plan, "", formatTable)
+		},
+		deployInfrastructure)
+}
 
-			NoHardShutdownAfterTimeout := c.Arguments["no_hard_shutdown_after_timeout"] != nil && *c.Arguments["no_hard_shutdown_after_timeout"].(*bool)
-			NoAttemptSoftShutdown := c.Arguments["no_attempt_soft_shutdown"] != nil && *c.Arguments["no_attempt_soft_shutdown"].(*bool)
+func infrastructurePlanCmd(c *Command, client interfaces.MetalCloudClient) (string, error) {
 
-			shutDownOptions := metalcloud.ShutdownOptions{
-				HardShutdownAfterTimeout:   !NoHardShutdownAfterTimeout,
-				AttemptSoftShutdown:        !NoAttemptSoftShutdown,
-				SoftShutdownTimeoutSeconds: timeout,
-			}
+	retInfra, err := getInfrastructureFromCommand("id", c, client)
+	if err != nil {
+		return "", err
+	}
+
+	plan, err := getInfrastructurePlan(retInfra, client)
+	if err != nil {
+		return "", err
+	}
+
+	return renderInfrastructurePlan(retInfra, plan, getStringParam(c.Arguments["format"]), renderTable)
+}
+
+//deployInfrastructure deploys the infrastructure using the shutdown options of the command and waits for it if -wait is set
+func deployInfrastructure(infraID int, c *Command, client interfaces.MetalCloudClient) (string, error) {
+
+	timeout := 180
+	if c.Arguments["soft_shutdown_timeout_seconds"] != nil {
+		timeout = *c.Arguments["soft_shutdown_timeout_seconds"].(*int)
+	}
+
+	NoHardShutdownAfterTimeout := c.Arguments["no_hard_shutdown_after_timeout"] != nil && *c.Arguments["no_hard_shutdown_after_timeout"].(*bool)
+	NoAttemptSoftShutdown := c.Arguments["no_attempt_soft_shutdown"] != nil && *c.Arguments["no_attempt_soft_shutdown"].(*bool)
 
-			return "", client.InfrastructureDeploy(
-				infraID,
-				shutDownOptions,
-				c.Arguments["allow_data_loss"] != nil && *c.Arguments["allow_data_loss"].(*bool),
-				c.Arguments["skip_ansible"] != nil && *c.Arguments["skip_ansible"].(*bool),
-			)
+	shutDownOptions := metalcloud.ShutdownOptions{
+		HardShutdownAfterTimeout:   !NoHardShutdownAfterTimeout,
+		AttemptSoftShutdown:        !NoAttemptSoftShutdown,
+		SoftShutdownTimeoutSeconds: timeout,
+	}
+
+	err := client.InfrastructureDeploy(
+		infraID,
+		shutDownOptions,
+		c.Arguments["allow_data_loss"] != nil && *c.Arguments["allow_data_loss"].(*bool),
+		c.Arguments["skip_ansible"] != nil && *c.Arguments["skip_ansible"].(*bool),
+	)
+	if err != nil {
+		return "", err
+	}
+
+	if !getBoolParam(c.Arguments["wait"]) {
+		return "", nil
+	}
+
+	return "", waitForInfrastructure(infraID, "active", getWaitTimeout(c), client)
+}
+
+//renderManifestChanges returns the objects created, updated or left unchanged by an apply as a table rendered with render. The note, if any, is added to the title.
+func renderManifestChanges(applier *manifestApplier, note string, format string, render tableRenderer) (string, error) {
+
+	schema := []SchemaField{
+		{
+			FieldName: "ID",
+			FieldType: TypeInt,
+			FieldSize: 6,
+		},
+		{
+			FieldName: "OBJECT_TYPE",
+			FieldType: TypeString,
+			FieldSize: 15,
+		},
+		{
+			FieldName: "LABEL",
+			FieldType: TypeString,
+			FieldSize: 10,
+		},
+		{
+			FieldName: "ACTION",
+			FieldType: TypeString,
+			FieldSize: 10,
+		},
+	}
+
+	data := [][]interface{}{}
+	for _, change := range applier.changes {
+		data = append(data, []interface{}{
+			change.ID,
+			change.ObjectType,
+			change.Label,
+			change.Action,
 		})
+	}
+
+	topLine := fmt.Sprintf("Infrastructure %s (%d) - datacenter %s%s", applier.infra.InfrastructureLabel, applier.infra.InfrastructureID, applier.infra.DatacenterName, note)
+
+	return render("changes", topLine, format, data, schema)
+}
+
+func infrastructureApplyCmd(c *Command, client interfaces.MetalCloudClient) (string, error) {
+
+	path, err := getParam(c, "manifest", "f")
+	if err != nil {
+		return "", err
+	}
+
+	manifest, err := loadInfrastructureManifest(*path.(*string))
+	if err != nil {
+		return "", err
+	}
+
+	applier := manifestApplier{
+		manifest: manifest,
+		client:   client,
+	}
+
+	err = applier.apply()
+	if err != nil {
+		return "", err
+	}
+
+	if !getBoolParam(c.Arguments["deploy"]) {
+		return renderManifestChanges(&applier, "", getStringParam(c.Arguments["format"]), renderTable)
+	}
+
+	//an infrastructure that already matches the manifest is not deployed again
+	if !applier.changed() {
+		return renderManifestChanges(&applier, " - no changes, the deploy was skipped", getStringParam(c.Arguments["format"]), renderTable)
+	}
+
+	ret, err := renderManifestChanges(&applier, "", getStringParam(c.Arguments["format"]), renderTable)
+	if err != nil {
+		return "", err
+	}
+
+	confirm, err := confirmCommand(c, func() string {
+		changes, _ := renderManifestChanges(&applier, "", "", formatTable)
+
+		confirmationMessage := fmt.Sprintf("%sDeploy infrastructure %s (%d). Are you sure? Type \"yes\" to continue:", changes, applier.infra.InfrastructureLabel, applier.infra.InfrastructureID)
+
+		//this is simply so that we don't output a text on the command line under go test
+		if strings.HasSuffix(os.Args[0], ".test") {
+			confirmationMessage = ""
+		}
+
+		return confirmationMessage
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if !confirm {
+		return "", fmt.Errorf("Operation not confirmed. Aborting")
+	}
+
+	_, err = deployInfrastructure(applier.infra.InfrastructureID, c, client)
+	if err != nil {
+		return "", err
+	}
+
+	return ret, nil
+}
+
+func infrastructureExportCmd(c *Command, client interfaces.MetalCloudClient) (string, error) {
+
+	retInfra, err := getInfrastructureFromCommand("id", c, client)
+	if err != nil {
+		return "", err
+	}
+
+	manifest, err := exportInfrastructureManifest(retInfra, client)
+	if err != nil {
+		return "", err
+	}
+
+	switch getStringParam(c.Arguments["format"]) {
+	case "json", "JSON":
+		ret, err := json.MarshalIndent(manifest, "", "\t")
+		if err != nil {
+			return "", err
+		}
+		return string(ret) + "\n", nil
+	case "", "yaml", "YAML":
+		ret, err := yaml.Marshal(manifest)
+		if err != nil {
+			return "", err
+		}
+		return string(ret), nil
+	}
+
+	return "", fmt.Errorf("Unsupported format %s. Supported values are 'yaml','json'", getStringParam(c.Arguments["format"]))
+}
+
+func infrastructureCloneCmd(c *Command, client interfaces.MetalCloudClient) (string, error) {
+
+	retInfra, err := getInfrastructureFromCommand("id", c, client)
+	if err != nil {
+		return "", err
+	}
+
+	label, err := getParam(c, "label", "label")
+	if err != nil {
+		return "", err
+	}
+
+	manifest, err := exportInfrastructureManifest(retInfra, client)
+	if err != nil {
+		return "", err
+	}
+
+	manifest.Label = *label.(*string)
+
+	if datacenter, ok := getStringParamOk(c.Arguments["datacenter"]); ok {
+		manifest.Datacenter = datacenter
+	}
+
+	infras, err := client.Infrastructures()
+	if err != nil {
+		return "", err
+	}
+
+	for _, i := range *infras {
+		if i.InfrastructureLabel == manifest.Label {
+			return "", fmt.Errorf("Infrastructure %s already exists", manifest.Label)
+		}
+	}
+
+	applier := manifestApplier{
+		manifest: manifest,
+		client:   client,
+	}
+
+	err = applier.apply()
+	if err != nil {
+		if created := applier.createdObjects(); created != "" {
+			return "", fmt.Errorf("%v. The clone is incomplete, the following objects were created: %s", err, created)
+		}
+		return "", err
+	}
+
+	return renderManifestChanges(&applier, "", getStringParam(c.Arguments["format"]), renderTable)
+}
+
+const defaultWaitTimeout = time.Hour
+
+//waitInitialInterval and waitMaxInterval control the backoff used when polling an infrastructure. Tests shorten them.
+var waitInitialInterval = 5 * time.Second
+var waitMaxInterval = time.Minute
+
+const deployStatusOngoing = "ongoing"
+const deployStatusFailed = "failed"
+
+func getWaitTimeout(c *Command) time.Duration {
+	if v, ok := c.Arguments["wait_timeout"].(*time.Duration); ok && v != nil && *v > 0 {
+		return *v
+	}
+	return defaultWaitTimeout
+}
+
+func infrastructureWaitCmd(c *Command, client interfaces.MetalCloudClient) (string, error) {
+
+	retInfra, err := getInfrastructureFromCommand("id", c, client)
+	if err != nil {
+		return "", err
+	}
+
+	status := "active"
+	if v, ok := getStringParamOk(c.Arguments["status"]); ok && v != "" {
+		status = v
+	}
+
+	return "", waitForInfrastructure(retInfra.InfrastructureID, status, getWaitTimeout(c), client)
+}
+
+//waitForInfrastructure polls the infrastructure with an exponential backoff until it has the given service status and no deploy is ongoing.
+//Progress is printed whenever the status of the infrastructure or of its instance and drive arrays changes.
+func waitForInfrastructure(infraID int, status string, timeout time.Duration, client interfaces.MetalCloudClient) error {
+
+	start := time.Now()
+	deadline := start.Add(timeout)
+	interval := waitInitialInterval
+	lastProgress := ""
+
+	for {
+		infra, err := client.InfrastructureGet(infraID)
+		if err != nil {
+			return err
+		}
+
+		progress, failed, err := getInfrastructureDeployProgress(infra, client)
+		if err != nil {
+			return err
+		}
+
+		if progress != lastProgress && !globalFlags.Quiet {
+			fmt.Fprintf(GetStdout(), "[%s] %s\n", time.Since(start).Round(time.Second), progress)
+			lastProgress = progress
+		}
+
+		if failed != "" {
+			return fmt.Errorf("Deploy of infrastructure %s (%d) failed: %s", infra.InfrastructureLabel, infra.InfrastructureID, failed)
+		}
+
+		if infra.InfrastructureServiceStatus == status && infra.InfrastructureOperation.InfrastructureDeployStatus != deployStatusOngoing {
+			return nil
+		}
+
+		if infra.InfrastructureServiceStatus == "deleted" && status != "deleted" {
+			return fmt.Errorf("Infrastructure %s (%d) was deleted while waiting for status %s", infra.InfrastructureLabel, infra.InfrastructureID, status)
+		}
+
+		if time.Now().Add(interval).After(deadline) {
+			return fmt.Errorf("Infrastructure %s (%d) did not reach status %s within %s", infra.InfrastructureLabel, infra.InfrastructureID, status, timeout)
+		}
+
+		time.Sleep(interval)
+
+		interval *= 2
+		if interval > waitMaxInterval {
+			interval = waitMaxInterval
+		}
+	}
+}
+
+//getInfrastructureDeployProgress returns a line describing the service and deploy status of the infrastructure and its elements.
+//The second value names the elements whose deploy failed, if any.
+func getInfrastructureDeployProgress(infra *metalcloud.Infrastructure, client interfaces.MetalCloudClient) (string, string, error) {
+
+	failed := []string{}
+
+	deployStatus := infra.InfrastructureOperation.InfrastructureDeployStatus
+	if deployStatus == deployStatusFailed {
+		failed = append(failed, fmt.Sprintf("infrastructure %s", infra.InfrastructureLabel))
+	}
+
+	elements := []string{}
+
+	iaList, err := client.InstanceArrays(infra.InfrastructureID)
+	if err != nil {
+		return "", "", err
+	}
+
+	for _, ia := range *iaList {
+		iaDeployStatus := ""
+		if ia.InstanceArrayOperation != nil {
+			iaDeployStatus = ia.InstanceArrayOperation.InstanceArrayDeployStatus
+		}
+		if iaDeployStatus == deployStatusFailed {
+			failed = append(failed, fmt.Sprintf("instance array %s", ia.InstanceArrayLabel))
+		}
+		elements = append(elements, fmt.Sprintf("ia %s %s/%s", ia.InstanceArrayLabel, ia.InstanceArrayServiceStatus, iaDeployStatus))
+	}
+
+	daList, err := client.DriveArrays(infra.InfrastructureID)
+	if err != nil {
+		return "", "", err
+	}
+
+	for _, da := range *daList {
+		daDeployStatus := ""
+		if da.DriveArrayOperation != nil {
+			daDeployStatus = da.DriveArrayOperation.DriveArrayDeployStatus
+		}
+		if daDeployStatus == deployStatusFailed {
+			failed = append(failed, fmt.Sprintf("drive array %s", da.DriveArrayLabel))
+		}
+		elements = append(elements, fmt.Sprintf("da %s %s/%s", da.DriveArrayLabel, da.DriveArrayServiceStatus, daDeployStatus))
+	}
+
+	//the arrays are returned as maps so they are sorted for a stable output
+	sort.Strings(elements)
+	sort.Strings(failed)
+
+	progress := fmt.Sprintf("infrastructure %s %s/%s", infra.InfrastructureLabel, infra.InfrastructureServiceStatus, deployStatus)
+	if len(elements) > 0 {
+		progress = fmt.Sprintf("%s: %s", progress, strings.Join(elements, ", "))
+	}
+
+	return progress, strings.Join(failed, ", "), nil
 }
 
 func infrastructureRevertCmd(c *Command, client interfaces.MetalCloudClient) (string, error) {
@@ -316,6 +957,147 @@ func infrastructureRevertCmd(c *Command, client interfaces.MetalCloudClient) (st
 		})
 }
 
+//infrastructureUsage is the amount of a resource used by the design of an infrastructure, including pending changes.
+//Limit is the key of the user limit of the resource.
+type infrastructureUsage struct {
+	Name  string
+	Limit string
+	Used  int
+}
+
+//getInfrastructureUsage computes the resources used by the instance arrays and drive arrays of an infrastructure
+func getInfrastructureUsage(infra *metalcloud.Infrastructure, client interfaces.MetalCloudClient) ([]infrastructureUsage, error) {
+
+	iaList, err := client.InstanceArrays(infra.InfrastructureID)
+	if err != nil {
+		return nil, err
+	}
+
+	instanceArrays := 0
+	instances := 0
+	for _, ia := range *iaList {
+		count := ia.InstanceArrayInstanceCount
+		if ia.InstanceArrayOperation != nil {
+			if ia.InstanceArrayOperation.InstanceArrayDeployType == "delete" {
+				continue
+			}
+			count = ia.InstanceArrayOperation.InstanceArrayInstanceCount
+		}
+		instanceArrays++
+		instances += count
+	}
+
+	daList, err := client.DriveArrays(infra.InfrastructureID)
+	if err != nil {
+		return nil, err
+	}
+
+	driveArrays := 0
+	drives := 0
+	driveMBytes := 0
+	for _, da := range *daList {
+		count := da.DriveArrayCount
+		size := da.DriveSizeMBytesDefault
+		if da.DriveArrayOperation != nil {
+			if da.DriveArrayOperation.DriveArrayDeployType == "delete" {
+				continue
+			}
+			count = da.DriveArrayOperation.DriveArrayCount
+			size = da.DriveArrayOperation.DriveSizeMBytesDefault
+		}
+		driveArrays++
+		drives += count
+		driveMBytes += count * size
+	}
+
+	return []infrastructureUsage{
+		{Name: "instance_arrays", Limit: "instance_arrays_max", Used: instanceArrays},
+		{Name: "instances", Limit: "instances_max", Used: instances},
+		{Name: "drive_arrays", Limit: "drive_arrays_max", Used: driveArrays},
+		{Name: "drives", Limit: "drives_max", Used: drives},
+		{Name: "drive_gbytes", Limit: "drive_gbytes_max", Used: driveMBytes / 1000},
+	}, nil
+}
+
+//getLimitInt returns the value of a numeric limit. Limits are decoded from json so numbers are float64.
+func getLimitInt(v interface{}) (int, bool) {
+	switch t := v.(type) {
+	case float64:
+		return int(t), true
+	case int:
+		return t, true
+	case json.Number:
+		i, err := t.Int64()
+		return int(i), err == nil
+	}
+	return 0, false
+}
+
+func infrastructureLimitsCmd(c *Command, client interfaces.MetalCloudClient) (string, error) {
+
+	retInfra, err := getInfrastructureFromCommand("id", c, client)
+	if err != nil {
+		return "", err
+	}
+
+	limits, err := client.InfrastructureUserLimits(retInfra.InfrastructureID)
+	if err != nil {
+		return "", err
+	}
+
+	usage, err := getInfrastructureUsage(retInfra, client)
+	if err != nil {
+		return "", err
+	}
+
+	schema := []SchemaField{}
+	row := []interface{}{}
+
+	add := func(name string, fieldType int, value interface{}) {
+		schema = append(schema, SchemaField{
+			FieldName: name,
+			FieldType: fieldType,
+			FieldSize: 5,
+		})
+		row = append(row, value)
+	}
+
+	//each known limit is followed by the usage and by the amount still available
+	for _, u := range usage {
+		limit, hasLimit := (*limits)[u.Limit]
+		if hasLimit {
+			add(u.Limit, TypeInterface, limit)
+		}
+
+		add(u.Name+"_used", TypeInt, u.Used)
+
+		if max, ok := getLimitInt(limit); hasLimit && ok {
+			add(u.Name+"_available", TypeInt, max-u.Used)
+		}
+	}
+
+	//the other limits are shown as returned by the API
+	keys := []string{}
+	for k := range *limits {
+		known := false
+		for _, u := range usage {
+			known = known || u.Limit == k
+		}
+		if !known {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		add(k, TypeInterface, (*limits)[k])
+	}
+
+	topLine := fmt.Sprintf("Limits of infrastructure %s (%d)", retInfra.InfrastructureLabel, retInfra.InfrastructureID)
+
+	return renderTransposedTable("limits", topLine, getStringParam(c.Arguments["format"]), [][]interface{}{row}, schema)
+}
+
 func infrastructureGetCmd(c *Command, client interfaces.MetalCloudClient) (string, error) {
 
 	retInfra, err := getInfrastructureFromCommand("id", c, client)
@@ -450,6 +1232,15 @@ func infrastructureGetCmd(c *Command, client interfaces.MetalCloudClient) (strin
 		format = &f
 	}
 
+	data, schema, err = applyTableOptions(data, schema)
+	if err != nil {
+		return "", err
+	}
+
+	if ret, ok, err := getTableAsExpressionString(*format.(*string), data, schema); ok {
+		return ret, err
+	}
+
 	switch *format.(*string) {
 	case "json", "JSON":
 		ret, err := GetTableAsJSONString(data, schema)
@@ -463,6 +1254,12 @@ func infrastructureGetCmd(c *Command, client interfaces.MetalCloudClient) (strin
 			return "", err
 		}
 		sb.WriteString(ret)
+	case "yaml", "YAML":
+		ret, err := GetTableAsYAMLString(data, schema)
+		if err != nil {
+			return "", err
+		}
+		sb.WriteString(ret)
 
 	default:
 
@@ -566,6 +1363,19 @@ func listWorkflowStagesCmd(c *Command, client interfaces.MetalCloudClient) (stri
 		format = &f
 	}
 
+	TableSorter(schema).OrderBy(
+		schema[0].FieldName,
+		schema[1].FieldName).Sort(data)
+
+	data, schema, err = applyTableOptions(data, schema)
+	if err != nil {
+		return "", err
+	}
+
+	if ret, ok, err := getTableAsExpressionString(*format.(*string), data, schema); ok {
+		return ret, err
+	}
+
 	switch *format.(*string) {
 	case "json", "JSON":
 		ret, err := GetTableAsJSONString(data, schema)
@@ -579,19 +1389,21 @@ func listWorkflowStagesCmd(c *Command, client interfaces.MetalCloudClient) (stri
 			return "", err
 		}
 		sb.WriteString(ret)
+	case "yaml", "YAML":
+		ret, err := GetTableAsYAMLString(data, schema)
+		if err != nil {
+			return "", err
+		}
+		sb.WriteString(ret)
 
 	default:
 		sb.WriteString(fmt.Sprintf("Stage Definitions:\n"))
 
-		TableSorter(schema).OrderBy(
-			schema[0].FieldName,
-			schema[1].FieldName).Sort(data)
-
 		AdjustFieldSizes(data, &schema)
 
 		sb.WriteString(GetTableAsString(data, schema))
 
-		sb.WriteString(fmt.Sprintf("Total: %d \n\n", len(*list)))
+		sb.WriteString(fmt.Sprintf("Total: %d \n\n", len(data)))
 	}
 
 	return sb.String(), nil
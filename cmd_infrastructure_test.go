@@ -1,16 +1,21 @@
 package main
 
 import (
+	"bytes"
 	"encoding/csv"
 	"encoding/json"
 	"fmt"
+	"os"
 	"strings"
 	"testing"
+	"time"
 
 	metalcloud "github.com/bigstepinc/metal-cloud-sdk-go"
+	fake "github.com/bigstepinc/metalcloud-cli/fake"
 	helper "github.com/bigstepinc/metalcloud-cli/helpers"
 	gomock "github.com/golang/mock/gomock"
 	. "github.com/onsi/gomega"
+	yaml "gopkg.in/yaml.v2"
 )
 
 func TestInfrastructureRevertCmd(t *testing.T) {
@@ -114,6 +119,21 @@ func TestInfrastructureDeployCmd(t *testing.T) {
 		InstanceArrayGet(ia.InstanceArrayID).
 		Return(&ia, nil).
 		AnyTimes()
+
+	client.EXPECT().
+		InstanceArrays(infra.InfrastructureID).
+		Return(&map[string]metalcloud.InstanceArray{ia.InstanceArrayLabel: ia}, nil).
+		AnyTimes()
+
+	client.EXPECT().
+		DriveArrays(infra.InfrastructureID).
+		Return(&map[string]metalcloud.DriveArray{}, nil).
+		AnyTimes()
+
+	client.EXPECT().
+		Networks(infra.InfrastructureID).
+		Return(&map[string]metalcloud.Network{}, nil).
+		AnyTimes()
 	//bFalse := true
 	bTrue := true
 	timeout := 256
@@ -127,29 +147,1005 @@ func TestInfrastructureDeployCmd(t *testing.T) {
 		},
 	}
 
-	expectedShutdownOptions := metalcloud.ShutdownOptions{
-		HardShutdownAfterTimeout:   true,
-		AttemptSoftShutdown:        false,
-		SoftShutdownTimeoutSeconds: timeout,
+	expectedShutdownOptions := metalcloud.ShutdownOptions{
+		HardShutdownAfterTimeout:   true,
+		AttemptSoftShutdown:        false,
+		SoftShutdownTimeoutSeconds: timeout,
+	}
+
+	client.EXPECT().
+		InfrastructureDeploy(gomock.Any(), expectedShutdownOptions, true, false).
+		Return(nil).
+		Times(1)
+
+	//test first without confirmation
+	ret, err := infrastructureDeployCmd(&cmd, client)
+	Expect(ret).To(Equal(""))
+	Expect(err).NotTo(BeNil()) //should throw error indicating confirmation not given
+	Expect(err.Error()).To(Equal("Operation not confirmed. Aborting"))
+
+	cmd.Arguments["autoconfirm"] = &bTrue
+
+	ret, err = infrastructureDeployCmd(&cmd, client)
+	Expect(ret).To(Equal(""))
+	Expect(err).To(BeNil()) //should be nil
+
+}
+
+func TestInfrastructureDeployWithWaitCmd(t *testing.T) {
+	RegisterTestingT(t)
+	ctrl := gomock.NewController(t)
+
+	defer func(i time.Duration) { waitInitialInterval = i }(waitInitialInterval)
+	waitInitialInterval = time.Millisecond
+
+	var stdin, stdout bytes.Buffer
+	SetConsoleIOChannel(&stdin, &stdout)
+	defer SetConsoleIOChannel(os.Stdin, os.Stdout)
+
+	ongoing := metalcloud.Infrastructure{
+		InfrastructureID:            10002,
+		InfrastructureLabel:         "testinfra",
+		InfrastructureServiceStatus: "ordered",
+		InfrastructureOperation: metalcloud.InfrastructureOperation{
+			InfrastructureDeployStatus: "ongoing",
+		},
+	}
+
+	finished := ongoing
+	finished.InfrastructureServiceStatus = "active"
+	finished.InfrastructureOperation.InfrastructureDeployStatus = "finished"
+
+	ia := metalcloud.InstanceArray{
+		InstanceArrayID:            11,
+		InstanceArrayLabel:         "testia",
+		InstanceArrayServiceStatus: "ordered",
+		InstanceArrayOperation: &metalcloud.InstanceArrayOperation{
+			InstanceArrayDeployStatus: "ongoing",
+		},
+	}
+
+	da := metalcloud.DriveArray{
+		DriveArrayID:            12,
+		DriveArrayLabel:         "testda",
+		DriveArrayServiceStatus: "ordered",
+	}
+
+	client := helper.NewMockMetalCloudClient(ctrl)
+
+	gomock.InOrder(
+		client.EXPECT().InfrastructureGet(10002).Return(&ongoing, nil).Times(2),
+		client.EXPECT().InfrastructureGet(10002).Return(&finished, nil).Times(1),
+	)
+
+	client.EXPECT().
+		InstanceArrays(10002).
+		Return(&map[string]metalcloud.InstanceArray{"testia": ia}, nil).
+		AnyTimes()
+
+	client.EXPECT().
+		DriveArrays(10002).
+		Return(&map[string]metalcloud.DriveArray{"testda": da}, nil).
+		AnyTimes()
+
+	client.EXPECT().
+		InfrastructureDeploy(10002, gomock.Any(), false, false).
+		Return(nil).
+		Times(1)
+
+	bTrue := true
+	id := "10002"
+	cmd := Command{
+		Arguments: map[string]interface{}{
+			"infrastructure_id_or_label": &id,
+			"autoconfirm":                &bTrue,
+			"wait":                       &bTrue,
+		},
+	}
+
+	ret, err := infrastructureDeployCmd(&cmd, client)
+	Expect(err).To(BeNil())
+	Expect(ret).To(Equal(""))
+	Expect(stdout.String()).To(ContainSubstring("infrastructure testinfra ordered/ongoing: da testda ordered/, ia testia ordered/ongoing"))
+	Expect(stdout.String()).To(ContainSubstring("infrastructure testinfra active/finished"))
+	//progress is printed only when it changes
+	Expect(strings.Count(stdout.String(), "ordered/ongoing:")).To(Equal(1))
+}
+
+func TestInfrastructureWaitCmd(t *testing.T) {
+	RegisterTestingT(t)
+	ctrl := gomock.NewController(t)
+
+	defer func(i time.Duration) { waitInitialInterval = i }(waitInitialInterval)
+	waitInitialInterval = time.Millisecond
+
+	var stdin, stdout bytes.Buffer
+	SetConsoleIOChannel(&stdin, &stdout)
+	defer SetConsoleIOChannel(os.Stdin, os.Stdout)
+
+	infra := metalcloud.Infrastructure{
+		InfrastructureID:            10002,
+		InfrastructureLabel:         "testinfra",
+		InfrastructureServiceStatus: "ordered",
+		InfrastructureOperation: metalcloud.InfrastructureOperation{
+			InfrastructureDeployStatus: "ongoing",
+		},
+	}
+
+	ia := metalcloud.InstanceArray{
+		InstanceArrayID:            11,
+		InstanceArrayLabel:         "testia",
+		InstanceArrayServiceStatus: "ordered",
+		InstanceArrayOperation: &metalcloud.InstanceArrayOperation{
+			InstanceArrayDeployStatus: "ongoing",
+		},
+	}
+
+	client := helper.NewMockMetalCloudClient(ctrl)
+
+	client.EXPECT().
+		InfrastructureGet(10002).
+		Return(&infra, nil).
+		AnyTimes()
+
+	client.EXPECT().
+		InstanceArrays(10002).
+		DoAndReturn(func(int) (*map[string]metalcloud.InstanceArray, error) {
+			return &map[string]metalcloud.InstanceArray{"testia": ia}, nil
+		}).
+		AnyTimes()
+
+	client.EXPECT().
+		DriveArrays(10002).
+		Return(&map[string]metalcloud.DriveArray{}, nil).
+		AnyTimes()
+
+	id := "10002"
+	status := "active"
+	timeout := 10 * time.Millisecond
+	cmd := Command{
+		Arguments: map[string]interface{}{
+			"infrastructure_id_or_label": &id,
+			"status":                     &status,
+			"wait_timeout":               &timeout,
+		},
+	}
+
+	//times out while the deploy is ongoing
+	_, err := infrastructureWaitCmd(&cmd, client)
+	Expect(err).NotTo(BeNil())
+	Expect(err.Error()).To(ContainSubstring("did not reach status active"))
+
+	//fails when the deploy of an element fails
+	ia.InstanceArrayOperation = &metalcloud.InstanceArrayOperation{
+		InstanceArrayDeployStatus: "failed",
+	}
+
+	_, err = infrastructureWaitCmd(&cmd, client)
+	Expect(err).NotTo(BeNil())
+	Expect(err.Error()).To(ContainSubstring("instance array testia"))
+
+	//returns immediately when the status is already reached
+	ia.InstanceArrayOperation = nil
+	infra.InfrastructureServiceStatus = "active"
+	infra.InfrastructureOperation.InfrastructureDeployStatus = "finished"
+
+	_, err = infrastructureWaitCmd(&cmd, client)
+	Expect(err).To(BeNil())
+}
+
+func TestInfrastructureApplyCmd(t *testing.T) {
+	RegisterTestingT(t)
+	ctrl := gomock.NewController(t)
+
+	path, err := writeTempFile(`
+label: testinfra
+networks:
+  - label: lan
+    type: lan
+  - label: lan2
+    type: lan
+instance_arrays:
+  - label: master
+    instance_count: 3
+  - label: worker
+    instance_count: 1
+    volume_template: centos7
+drive_arrays:
+  - label: worker-da
+    instance_array: worker
+    drive_size_mbytes: 40960
+custom_stages:
+  - stage: "300"
+    runlevel: 1
+`, ".yaml")
+	Expect(err).To(BeNil())
+	defer os.Remove(path)
+
+	infra := metalcloud.Infrastructure{
+		InfrastructureID:    10002,
+		InfrastructureLabel: "testinfra",
+	}
+
+	master := metalcloud.InstanceArray{
+		InstanceArrayID:    11,
+		InstanceArrayLabel: "master",
+		InstanceArrayOperation: &metalcloud.InstanceArrayOperation{
+			InstanceArrayID:            11,
+			InstanceArrayLabel:         "master",
+			InstanceArrayInstanceCount: 2,
+			InstanceArrayRAMGbytes:     16,
+		},
+	}
+
+	worker := metalcloud.InstanceArray{
+		InstanceArrayID:    12,
+		InstanceArrayLabel: "worker",
+	}
+
+	client := helper.NewMockMetalCloudClient(ctrl)
+
+	client.EXPECT().
+		Infrastructures().
+		Return(&map[string]metalcloud.Infrastructure{"testinfra": infra}, nil).
+		Times(1)
+
+	client.EXPECT().
+		Networks(10002).
+		Return(&map[string]metalcloud.Network{"lan": {NetworkID: 1, NetworkLabel: "lan", NetworkType: "lan"}}, nil).
+		Times(1)
+
+	client.EXPECT().
+		NetworkCreate(10002, metalcloud.Network{NetworkLabel: "lan2", NetworkType: "lan"}).
+		Return(&metalcloud.Network{NetworkID: 2}, nil).
+		Times(1)
+
+	gomock.InOrder(
+		client.EXPECT().
+			InstanceArrays(10002).
+			Return(&map[string]metalcloud.InstanceArray{"master": master}, nil).
+			Times(1),
+		client.EXPECT().
+			InstanceArrays(10002).
+			Return(&map[string]metalcloud.InstanceArray{"master": master, "worker": worker}, nil).
+			Times(1),
+	)
+
+	client.EXPECT().
+		VolumeTemplateGetByLabel("centos7").
+		Return(&metalcloud.VolumeTemplate{VolumeTemplateID: 100}, nil).
+		Times(1)
+
+	//only the changed field is altered, the others are kept
+	expectedOperation := *master.InstanceArrayOperation
+	expectedOperation.InstanceArrayInstanceCount = 3
+
+	client.EXPECT().
+		InstanceArrayEdit(11, expectedOperation, nil, nil, nil, nil).
+		Return(&master, nil).
+		Times(1)
+
+	client.EXPECT().
+		InstanceArrayCreate(10002, metalcloud.InstanceArray{
+			InstanceArrayLabel:         "worker",
+			InstanceArrayInstanceCount: 1,
+			VolumeTemplateID:           100,
+		}).
+		Return(&worker, nil).
+		Times(1)
+
+	client.EXPECT().
+		DriveArrays(10002).
+		Return(&map[string]metalcloud.DriveArray{}, nil).
+		Times(1)
+
+	client.EXPECT().
+		DriveArrayCreate(10002, metalcloud.DriveArray{
+			DriveArrayLabel:        "worker-da",
+			InstanceArrayID:        12,
+			DriveSizeMBytesDefault: 40960,
+		}).
+		Return(&metalcloud.DriveArray{DriveArrayID: 13}, nil).
+		Times(1)
+
+	client.EXPECT().
+		StageDefinitionGet(300).
+		Return(&metalcloud.StageDefinition{StageDefinitionID: 300, StageDefinitionLabel: "setup"}, nil).
+		Times(1)
+
+	client.EXPECT().
+		InfrastructureDeployCustomStages(10002, "post_deploy").
+		Return(&[]metalcloud.WorkflowStageAssociation{}, nil).
+		Times(1)
+
+	client.EXPECT().
+		InfrastructureDeployCustomStageAddIntoRunlevel(10002, 300, 1, "post_deploy").
+		Return(nil).
+		Times(1)
+
+	format := "json"
+	cmd := Command{
+		Arguments: map[string]interface{}{
+			"manifest": &path,
+			"format":   &format,
+		},
+	}
+
+	ret, err := infrastructureApplyCmd(&cmd, client)
+	Expect(err).To(BeNil())
+
+	var m []interface{}
+	err = json.Unmarshal([]byte(ret), &m)
+	Expect(err).To(BeNil())
+
+	actions := map[string]string{}
+	for _, r := range m {
+		row := r.(map[string]interface{})
+		actions[row["LABEL"].(string)] = row["ACTION"].(string)
+	}
+
+	Expect(actions).To(Equal(map[string]string{
+		"testinfra":                       "unchanged",
+		"lan":                             "unchanged",
+		"lan2":                            "created",
+		"master":                          "updated",
+		"worker":                          "created",
+		"worker-da":                       "created",
+		"setup (post_deploy, runlevel 1)": "created",
+	}))
+}
+
+func TestInfrastructureExportCmd(t *testing.T) {
+	RegisterTestingT(t)
+	ctrl := gomock.NewController(t)
+
+	infra := metalcloud.Infrastructure{
+		InfrastructureID:    10002,
+		InfrastructureLabel: "testinfra",
+		DatacenterName:      "uk-reading",
+	}
+
+	master := metalcloud.InstanceArray{
+		InstanceArrayID:    11,
+		InstanceArrayLabel: "master",
+		InstanceArrayOperation: &metalcloud.InstanceArrayOperation{
+			InstanceArrayID:              11,
+			InstanceArrayLabel:           "master",
+			InstanceArrayInstanceCount:   2,
+			VolumeTemplateID:             100,
+			InstanceArrayFirewallManaged: true,
+			InstanceArrayFirewallRules: []metalcloud.FirewallRule{
+				{
+					FirewallRuleProtocol:       "tcp",
+					FirewallRulePortRangeStart: 22,
+					FirewallRulePortRangeEnd:   22,
+					FirewallRuleEnabled:        true,
+				},
+			},
+		},
+	}
+
+	da := metalcloud.DriveArray{
+		DriveArrayID:    12,
+		DriveArrayLabel: "master-da",
+		DriveArrayOperation: &metalcloud.DriveArrayOperation{
+			DriveArrayLabel:        "master-da",
+			InstanceArrayID:        11,
+			VolumeTemplateID:       100,
+			DriveSizeMBytesDefault: 40960,
+		},
+	}
+
+	client := helper.NewMockMetalCloudClient(ctrl)
+
+	client.EXPECT().
+		InfrastructureGet(10002).
+		Return(&infra, nil).
+		AnyTimes()
+
+	client.EXPECT().
+		Networks(10002).
+		Return(&map[string]metalcloud.Network{"lan": {NetworkID: 1, NetworkLabel: "lan", NetworkType: "lan"}}, nil).
+		AnyTimes()
+
+	client.EXPECT().
+		InstanceArrays(10002).
+		Return(&map[string]metalcloud.InstanceArray{"master": master}, nil).
+		AnyTimes()
+
+	client.EXPECT().
+		InstanceArrayInstances(11).
+		Return(&map[string]metalcloud.Instance{"a": {ServerTypeID: 5}, "b": {ServerTypeID: 5}}, nil).
+		AnyTimes()
+
+	client.EXPECT().
+		ServerTypeGet(5).
+		Return(&metalcloud.ServerType{ServerTypeID: 5, ServerTypeName: "M.8.16", ServerTypeLabel: "m-8-16"}, nil).
+		AnyTimes()
+
+	//the volume template is looked up once per export
+	client.EXPECT().
+		VolumeTemplateGet(100).
+		Return(&metalcloud.VolumeTemplate{VolumeTemplateID: 100, VolumeTemplateLabel: "centos7"}, nil).
+		Times(3)
+
+	client.EXPECT().
+		DriveArrays(10002).
+		Return(&map[string]metalcloud.DriveArray{"master-da": da}, nil).
+		AnyTimes()
+
+	client.EXPECT().
+		InfrastructureDeployCustomStages(10002, "pre_deploy").
+		Return(&[]metalcloud.WorkflowStageAssociation{}, nil).
+		AnyTimes()
+
+	client.EXPECT().
+		InfrastructureDeployCustomStages(10002, "post_deploy").
+		Return(&[]metalcloud.WorkflowStageAssociation{{StageDefinitionID: 300, InfrastructureDeployCustomStageRunLevel: 1}}, nil).
+		AnyTimes()
+
+	client.EXPECT().
+		StageDefinitionGet(300).
+		Return(&metalcloud.StageDefinition{StageDefinitionID: 300, StageDefinitionLabel: "setup"}, nil).
+		AnyTimes()
+
+	id := "10002"
+	format := "yaml"
+	cmd := Command{
+		Arguments: map[string]interface{}{
+			"infrastructure_id_or_label": &id,
+			"format":                     &format,
+		},
+	}
+
+	ret, err := infrastructureExportCmd(&cmd, client)
+	Expect(err).To(BeNil())
+	Expect(ret).NotTo(ContainSubstring("instance_array_id"))
+
+	var m InfrastructureManifest
+	err = yaml.UnmarshalStrict([]byte(ret), &m)
+	Expect(err).To(BeNil())
+	Expect(m.validate()).To(BeNil())
+
+	Expect(m.Label).To(Equal("testinfra"))
+	Expect(m.Datacenter).To(Equal("uk-reading"))
+	Expect(m.Networks).To(Equal([]NetworkManifest{{Label: "lan", Type: "lan"}}))
+	Expect(m.InstanceArrays[0].InstanceCount).To(Equal(2))
+	Expect(m.InstanceArrays[0].VolumeTemplate).To(Equal("centos7"))
+	Expect(m.InstanceArrays[0].ServerType).To(Equal("m-8-16"))
+	Expect(m.InstanceArrays[0].FirewallRules[0].PortRangeStart).To(Equal(22))
+	Expect(m.InstanceArrays[0].FirewallRules[0].Enabled).To(BeNil())
+	Expect(m.DriveArrays[0].InstanceArray).To(Equal("master"))
+	Expect(m.DriveArrays[0].VolumeTemplate).To(Equal("centos7"))
+	Expect(m.CustomStages).To(Equal([]CustomStageManifest{{Stage: "setup", Runlevel: 1, RunMoment: "post_deploy"}}))
+
+	format = "json"
+	ret, err = infrastructureExportCmd(&cmd, client)
+	Expect(err).To(BeNil())
+
+	err = json.Unmarshal([]byte(ret), &m)
+	Expect(err).To(BeNil())
+	Expect(m.Label).To(Equal("testinfra"))
+
+	format = "csv"
+	_, err = infrastructureExportCmd(&cmd, client)
+	Expect(err).NotTo(BeNil())
+}
+
+func TestInfrastructureCloneCmd(t *testing.T) {
+	RegisterTestingT(t)
+	ctrl := gomock.NewController(t)
+
+	infra := metalcloud.Infrastructure{
+		InfrastructureID:    10002,
+		InfrastructureLabel: "testinfra",
+		DatacenterName:      "uk-reading",
+	}
+
+	clone := metalcloud.Infrastructure{
+		InfrastructureID:    20002,
+		InfrastructureLabel: "testclone",
+		DatacenterName:      "us-santaclara",
+	}
+
+	master := metalcloud.InstanceArray{
+		InstanceArrayID:            11,
+		InstanceArrayLabel:         "master",
+		InstanceArrayInstanceCount: 2,
+		VolumeTemplateID:           100,
+		InstanceArrayInterfaces: []metalcloud.InstanceArrayInterface{
+			{InstanceArrayInterfaceIndex: 0, NetworkID: 1},
+			{InstanceArrayInterfaceIndex: 1, NetworkID: 2},
+			{InstanceArrayInterfaceIndex: 2},
+		},
+	}
+
+	clonedMaster := metalcloud.InstanceArray{
+		InstanceArrayID:    31,
+		InstanceArrayLabel: "master",
+		InstanceArrayOperation: &metalcloud.InstanceArrayOperation{
+			InstanceArrayID:    31,
+			InstanceArrayLabel: "master",
+			InstanceArrayInterfaces: []metalcloud.InstanceArrayInterfaceOperation{
+				{InstanceArrayInterfaceIndex: 0},
+				{InstanceArrayInterfaceIndex: 1},
+			},
+		},
+	}
+
+	da := metalcloud.DriveArray{
+		DriveArrayID:           12,
+		DriveArrayLabel:        "master-da",
+		InstanceArrayID:        11,
+		VolumeTemplateID:       100,
+		DriveSizeMBytesDefault: 40960,
+	}
+
+	client := helper.NewMockMetalCloudClient(ctrl)
+
+	//source infrastructure
+	client.EXPECT().
+		InfrastructureGet(infra.InfrastructureID).
+		Return(&infra, nil).
+		AnyTimes()
+
+	client.EXPECT().
+		Networks(infra.InfrastructureID).
+		Return(&map[string]metalcloud.Network{
+			"wan-10002": {NetworkID: 1, NetworkLabel: "wan-10002", NetworkType: "wan"},
+			"lan":       {NetworkID: 2, NetworkLabel: "lan", NetworkType: "lan"},
+		}, nil).
+		AnyTimes()
+
+	client.EXPECT().
+		InstanceArrays(infra.InfrastructureID).
+		Return(&map[string]metalcloud.InstanceArray{"master": master}, nil).
+		AnyTimes()
+
+	client.EXPECT().
+		InstanceArrayInstances(master.InstanceArrayID).
+		Return(&map[string]metalcloud.Instance{}, nil).
+		AnyTimes()
+
+	client.EXPECT().
+		VolumeTemplateGet(100).
+		Return(&metalcloud.VolumeTemplate{VolumeTemplateID: 100, VolumeTemplateLabel: "centos7"}, nil).
+		AnyTimes()
+
+	client.EXPECT().
+		DriveArrays(infra.InfrastructureID).
+		Return(&map[string]metalcloud.DriveArray{"master-da": da}, nil).
+		AnyTimes()
+
+	client.EXPECT().
+		InfrastructureDeployCustomStages(infra.InfrastructureID, gomock.Any()).
+		Return(&[]metalcloud.WorkflowStageAssociation{}, nil).
+		AnyTimes()
+
+	client.EXPECT().
+		Infrastructures().
+		Return(&map[string]metalcloud.Infrastructure{"testinfra": infra}, nil).
+		AnyTimes()
+
+	//new infrastructure
+	client.EXPECT().
+		InfrastructureCreate(metalcloud.Infrastructure{
+			InfrastructureLabel: "testclone",
+			DatacenterName:      "us-santaclara",
+		}).
+		Return(&clone, nil).
+		Times(1)
+
+	gomock.InOrder(
+		client.EXPECT().
+			Networks(clone.InfrastructureID).
+			Return(&map[string]metalcloud.Network{
+				"wan-20002": {NetworkID: 21, NetworkLabel: "wan-20002", NetworkType: "wan"},
+			}, nil).
+			Times(1),
+		client.EXPECT().
+			Networks(clone.InfrastructureID).
+			Return(&map[string]metalcloud.Network{
+				"wan-20002": {NetworkID: 21, NetworkLabel: "wan-20002", NetworkType: "wan"},
+				"lan":       {NetworkID: 22, NetworkLabel: "lan", NetworkType: "lan"},
+			}, nil).
+			Times(1),
+	)
+
+	//the wan network is matched by type so only the lan network is created
+	client.EXPECT().
+		NetworkCreate(clone.InfrastructureID, metalcloud.Network{NetworkLabel: "lan", NetworkType: "lan"}).
+		Return(&metalcloud.Network{NetworkID: 22}, nil).
+		Times(1)
+
+	gomock.InOrder(
+		client.EXPECT().
+			InstanceArrays(clone.InfrastructureID).
+			Return(&map[string]metalcloud.InstanceArray{}, nil).
+			Times(1),
+		client.EXPECT().
+			InstanceArrays(clone.InfrastructureID).
+			Return(&map[string]metalcloud.InstanceArray{"master": clonedMaster}, nil).
+			AnyTimes(),
+	)
+
+	client.EXPECT().
+		VolumeTemplateGetByLabel("centos7").
+		Return(&metalcloud.VolumeTemplate{VolumeTemplateID: 100}, nil).
+		AnyTimes()
+
+	client.EXPECT().
+		InstanceArrayCreate(clone.InfrastructureID, gomock.Any()).
+		Return(&clonedMaster, nil).
+		Times(1)
+
+	client.EXPECT().
+		InstanceArrayInterfaceAttachNetwork(clonedMaster.InstanceArrayID, 0, 21).
+		Return(&clonedMaster, nil).
+		Times(1)
+
+	client.EXPECT().
+		InstanceArrayInterfaceAttachNetwork(clonedMaster.InstanceArrayID, 1, 22).
+		Return(&clonedMaster, nil).
+		Times(1)
+
+	client.EXPECT().
+		DriveArrays(clone.InfrastructureID).
+		Return(&map[string]metalcloud.DriveArray{}, nil).
+		Times(1)
+
+	client.EXPECT().
+		DriveArrayCreate(clone.InfrastructureID, gomock.Any()).
+		DoAndReturn(func(infrastructureID int, driveArray metalcloud.DriveArray) (*metalcloud.DriveArray, error) {
+			Expect(driveArray.InstanceArrayID).To(Equal(clonedMaster.InstanceArrayID))
+			Expect(driveArray.VolumeTemplateID).To(Equal(100))
+			return &metalcloud.DriveArray{DriveArrayID: 32}, nil
+		}).
+		Times(1)
+
+	cmd := MakeCommand(map[string]interface{}{
+		"infrastructure_id_or_label": infra.InfrastructureID,
+		"label":                      "testclone",
+		"datacenter":                 "us-santaclara",
+		"format":                     "json",
+	})
+
+	ret, err := infrastructureCloneCmd(&cmd, client)
+	Expect(err).To(BeNil())
+
+	var m []interface{}
+	err = json.Unmarshal([]byte(ret), &m)
+	Expect(err).To(BeNil())
+
+	actions := map[string]string{}
+	for _, r := range m {
+		row := r.(map[string]interface{})
+		actions[row["LABEL"].(string)] = row["ACTION"].(string)
+	}
+
+	Expect(actions).To(Equal(map[string]string{
+		"testclone": "created",
+		"wan-10002": "unchanged",
+		"lan":       "created",
+		"master":    "created",
+		"master/0":  "updated",
+		"master/1":  "updated",
+		"master-da": "created",
+	}))
+
+	//the label of the clone must not be in use
+	cmd = MakeCommand(map[string]interface{}{
+		"infrastructure_id_or_label": infra.InfrastructureID,
+		"label":                      "testinfra",
+	})
+
+	_, err = infrastructureCloneCmd(&cmd, client)
+	Expect(err).NotTo(BeNil())
+}
+
+func TestInfrastructurePlanCmd(t *testing.T) {
+	RegisterTestingT(t)
+	ctrl := gomock.NewController(t)
+
+	infra := metalcloud.Infrastructure{
+		InfrastructureID:            10002,
+		InfrastructureLabel:         "testinfra",
+		InfrastructureServiceStatus: "active",
+		InfrastructureOperation: metalcloud.InfrastructureOperation{
+			InfrastructureLabel:        "testinfra",
+			InfrastructureDeployType:   "edit",
+			InfrastructureDeployStatus: "finished",
+		},
+	}
+
+	ia := metalcloud.InstanceArray{
+		InstanceArrayID:            11,
+		InstanceArrayLabel:         "testia",
+		InstanceArrayInstanceCount: 1,
+		InstanceArrayServiceStatus: "active",
+		InstanceArrayOperation: &metalcloud.InstanceArrayOperation{
+			InstanceArrayID:            11,
+			InstanceArrayLabel:         "testia",
+			InstanceArrayInstanceCount: 3,
+			InstanceArrayDeployType:    "edit",
+			InstanceArrayDeployStatus:  "not_started",
+		},
+	}
+
+	da := metalcloud.DriveArray{
+		DriveArrayID:            12,
+		DriveArrayServiceStatus: "ordered",
+		DriveArrayOperation: &metalcloud.DriveArrayOperation{
+			DriveArrayID:           12,
+			DriveArrayLabel:        "testda",
+			DriveArrayDeployType:   "create",
+			DriveArrayDeployStatus: "not_started",
+		},
+	}
+
+	unchanged := metalcloud.InstanceArray{
+		InstanceArrayID:            13,
+		InstanceArrayLabel:         "unchanged",
+		InstanceArrayServiceStatus: "active",
+		InstanceArrayOperation: &metalcloud.InstanceArrayOperation{
+			InstanceArrayID:           13,
+			InstanceArrayLabel:        "unchanged",
+			InstanceArrayDeployType:   "edit",
+			InstanceArrayDeployStatus: "finished",
+		},
+	}
+
+	client := helper.NewMockMetalCloudClient(ctrl)
+
+	client.EXPECT().
+		InfrastructureGet(infra.InfrastructureID).
+		Return(&infra, nil).
+		AnyTimes()
+
+	client.EXPECT().
+		InstanceArrays(infra.InfrastructureID).
+		Return(&map[string]metalcloud.InstanceArray{ia.InstanceArrayLabel: ia, unchanged.InstanceArrayLabel: unchanged}, nil).
+		AnyTimes()
+
+	client.EXPECT().
+		DriveArrays(infra.InfrastructureID).
+		Return(&map[string]metalcloud.DriveArray{"testda": da}, nil).
+		AnyTimes()
+
+	client.EXPECT().
+		Networks(infra.InfrastructureID).
+		Return(&map[string]metalcloud.Network{}, nil).
+		AnyTimes()
+
+	cmd := MakeCommand(map[string]interface{}{
+		"infrastructure_id_or_label": infra.InfrastructureID,
+		"format":                     "json",
+	})
+
+	ret, err := infrastructurePlanCmd(&cmd, client)
+	Expect(err).To(BeNil())
+
+	var m []interface{}
+	err = json.Unmarshal([]byte(ret), &m)
+	Expect(err).To(BeNil())
+	Expect(len(m)).To(Equal(2))
+
+	r := m[0].(map[string]interface{})
+	Expect(int(r["ID"].(float64))).To(Equal(da.DriveArrayID))
+	Expect(r["CHANGE"].(string)).To(Equal("create"))
+	Expect(r["LABEL"].(string)).To(Equal("testda"))
+
+	r = m[1].(map[string]interface{})
+	Expect(int(r["ID"].(float64))).To(Equal(ia.InstanceArrayID))
+	Expect(r["CHANGE"].(string)).To(Equal("edit"))
+	Expect(r["FIELD"].(string)).To(Equal("instance_count"))
+	Expect(r["CURRENT"].(string)).To(Equal("1"))
+	Expect(r["PENDING"].(string)).To(Equal("3"))
+
+	//no pending changes
+	infra.InfrastructureID = 10003
+	client.EXPECT().
+		InfrastructureGet(infra.InfrastructureID).
+		Return(&infra, nil).
+		AnyTimes()
+	client.EXPECT().
+		InstanceArrays(infra.InfrastructureID).
+		Return(&map[string]metalcloud.InstanceArray{unchanged.InstanceArrayLabel: unchanged}, nil).
+		AnyTimes()
+	client.EXPECT().
+		DriveArrays(infra.InfrastructureID).
+		Return(&map[string]metalcloud.DriveArray{}, nil).
+		AnyTimes()
+	client.EXPECT().
+		Networks(infra.InfrastructureID).
+		Return(&map[string]metalcloud.Network{}, nil).
+		AnyTimes()
+
+	cmd = MakeCommand(map[string]interface{}{
+		"infrastructure_id_or_label": infra.InfrastructureID,
+	})
+
+	ret, err = infrastructurePlanCmd(&cmd, client)
+	Expect(err).To(BeNil())
+	Expect(ret).To(ContainSubstring("has no pending changes"))
+}
+
+func TestInfrastructureEditCmd(t *testing.T) {
+	RegisterTestingT(t)
+	ctrl := gomock.NewController(t)
+
+	infra := metalcloud.Infrastructure{
+		InfrastructureID:    10002,
+		InfrastructureLabel: "testinfra",
+		DatacenterName:      "uk-reading",
+		InfrastructureOperation: metalcloud.InfrastructureOperation{
+			InfrastructureID:        10002,
+			InfrastructureLabel:     "testinfra",
+			DatacenterName:          "uk-reading",
+			InfrastructureSubdomain: "testinfra.demo",
+		},
+	}
+
+	client := helper.NewMockMetalCloudClient(ctrl)
+
+	client.EXPECT().
+		InfrastructureGet(infra.InfrastructureID).
+		Return(&infra, nil).
+		AnyTimes()
+
+	//only the fields given as flags are changed
+	expectedOperation := infra.InfrastructureOperation
+	expectedOperation.InfrastructureLabel = "newlabel"
+
+	client.EXPECT().
+		InfrastructureEdit(infra.InfrastructureID, expectedOperation).
+		Return(&infra, nil).
+		Times(1)
+
+	cmd := MakeCommand(map[string]interface{}{
+		"infrastructure_id_or_label": infra.InfrastructureID,
+		"infrastructure_label":       "newlabel",
+	})
+
+	_, err := infrastructureEditCmd(&cmd, client)
+	Expect(err).To(BeNil())
+
+	expectedOperation = infra.InfrastructureOperation
+	expectedOperation.InfrastructureSubdomain = "other.demo"
+	expectedOperation.DatacenterName = "us-santaclara"
+
+	client.EXPECT().
+		InfrastructureEdit(infra.InfrastructureID, expectedOperation).
+		Return(&infra, nil).
+		Times(1)
+
+	cmd = MakeCommand(map[string]interface{}{
+		"infrastructure_id_or_label": infra.InfrastructureID,
+		"infrastructure_subdomain":   "other.demo",
+		"datacenter":                 "us-santaclara",
+	})
+
+	_, err = infrastructureEditCmd(&cmd, client)
+	Expect(err).To(BeNil())
+
+	expectedOperation = infra.InfrastructureOperation
+	expectedOperation.UserIDOwner = 12
+
+	client.EXPECT().
+		InfrastructureEdit(infra.InfrastructureID, expectedOperation).
+		Return(&infra, nil).
+		Times(1)
+
+	cmd = MakeCommand(map[string]interface{}{
+		"infrastructure_id_or_label": infra.InfrastructureID,
+		"user_id_owner":              12,
+	})
+
+	_, err = infrastructureEditCmd(&cmd, client)
+	Expect(err).To(BeNil())
+}
+
+func TestInfrastructureLimitsCmd(t *testing.T) {
+	RegisterTestingT(t)
+	ctrl := gomock.NewController(t)
+
+	infra := metalcloud.Infrastructure{
+		InfrastructureID:    10002,
+		InfrastructureLabel: "testinfra",
+	}
+
+	iaList := map[string]metalcloud.InstanceArray{
+		"master": {
+			InstanceArrayID:            11,
+			InstanceArrayInstanceCount: 1,
+			InstanceArrayOperation: &metalcloud.InstanceArrayOperation{
+				InstanceArrayInstanceCount: 3,
+			},
+		},
+		"deleted": {
+			InstanceArrayID:            12,
+			InstanceArrayInstanceCount: 2,
+			InstanceArrayOperation: &metalcloud.InstanceArrayOperation{
+				InstanceArrayInstanceCount: 2,
+				InstanceArrayDeployType:    "delete",
+			},
+		},
+	}
+
+	daList := map[string]metalcloud.DriveArray{
+		"master-da": {
+			DriveArrayID:           13,
+			DriveArrayCount:        3,
+			DriveSizeMBytesDefault: 40960,
+		},
+	}
+
+	limits := map[string]interface{}{
+		"instances_max":    float64(10),
+		"drive_gbytes_max": float64(100),
+		"other_limit":      "value",
 	}
 
+	client := helper.NewMockMetalCloudClient(ctrl)
+
 	client.EXPECT().
-		InfrastructureDeploy(gomock.Any(), expectedShutdownOptions, true, false).
-		Return(nil).
-		Times(1)
+		InfrastructureGet(infra.InfrastructureID).
+		Return(&infra, nil).
+		AnyTimes()
 
-	//test first without confirmation
-	ret, err := infrastructureDeployCmd(&cmd, client)
-	Expect(ret).To(Equal(""))
-	Expect(err).NotTo(BeNil()) //should throw error indicating confirmation not given
-	Expect(err.Error()).To(Equal("Operation not confirmed. Aborting"))
+	client.EXPECT().
+		InfrastructureUserLimits(infra.InfrastructureID).
+		Return(&limits, nil).
+		AnyTimes()
 
-	cmd.Arguments["autoconfirm"] = &bTrue
+	client.EXPECT().
+		InstanceArrays(infra.InfrastructureID).
+		Return(&iaList, nil).
+		AnyTimes()
 
-	ret, err = infrastructureDeployCmd(&cmd, client)
-	Expect(ret).To(Equal(""))
-	Expect(err).To(BeNil()) //should be nil
+	client.EXPECT().
+		DriveArrays(infra.InfrastructureID).
+		Return(&daList, nil).
+		AnyTimes()
+
+	cmd := MakeCommand(map[string]interface{}{
+		"infrastructure_id_or_label": infra.InfrastructureID,
+		"format":                     "json",
+	})
+
+	ret, err := infrastructureLimitsCmd(&cmd, client)
+	Expect(err).To(BeNil())
+
+	var m []interface{}
+	err = json.Unmarshal([]byte(ret), &m)
+	Expect(err).To(BeNil())
+	Expect(len(m)).To(Equal(1))
 
+	r := m[0].(map[string]interface{})
+	Expect(r["other_limit"]).To(Equal("value"))
+	Expect(r["instances_max"]).To(Equal(float64(10)))
+	Expect(r["instances_used"]).To(Equal(float64(3)))
+	Expect(r["instances_available"]).To(Equal(float64(7)))
+	Expect(r["instance_arrays_used"]).To(Equal(float64(1)))
+	Expect(r["drive_gbytes_used"]).To(Equal(float64(122)))
+	Expect(r["drive_gbytes_available"]).To(Equal(float64(-22)))
+	Expect(r).NotTo(HaveKey("drives_available"))
+
+	cmd = MakeCommand(map[string]interface{}{
+		"infrastructure_id_or_label": infra.InfrastructureID,
+	})
+
+	ret, err = infrastructureLimitsCmd(&cmd, client)
+	Expect(err).To(BeNil())
+	Expect(ret).To(ContainSubstring("instances_used"))
+
+	//each limit is followed by its usage, the limits without a known usage come last
+	limitsIndex := strings.Index(ret, "instances_max")
+	Expect(limitsIndex).To(BeNumerically(">", 0))
+	Expect(strings.Index(ret, "instances_used")).To(BeNumerically(">", limitsIndex))
+	Expect(strings.Index(ret, "instances_available")).To(BeNumerically(">", strings.Index(ret, "instances_used")))
+	Expect(strings.Index(ret, "other_limit")).To(BeNumerically(">", strings.Index(ret, "drive_gbytes_available")))
+	Expect(ret).To(ContainSubstring("KEY"))
 }
 
 func TestInfrastructureDeleteCmd(t *testing.T) {
@@ -479,3 +1475,429 @@ func TestInfrastructureListCmd(t *testing.T) {
 		Equal(infra2.InfrastructureOperation.InfrastructureLabel),
 	))
 }
+
+func TestInfrastructureListWithOwnerFilterCmd(t *testing.T) {
+	RegisterTestingT(t)
+	ctrl := gomock.NewController(t)
+
+	os.Setenv("METALCLOUD_USER_EMAIL", "user@test.com")
+
+	owned := metalcloud.Infrastructure{
+		InfrastructureID: 10002,
+		UserIDowner:      1,
+		UserEmailOwner:   "user@test.com",
+		InfrastructureOperation: metalcloud.InfrastructureOperation{
+			InfrastructureLabel: "owned",
+		},
+	}
+
+	delegated1 := metalcloud.Infrastructure{
+		InfrastructureID: 10003,
+		UserIDowner:      2,
+		UserEmailOwner:   "alice@test.com",
+		InfrastructureOperation: metalcloud.InfrastructureOperation{
+			InfrastructureLabel: "delegated1",
+		},
+	}
+
+	delegated2 := metalcloud.Infrastructure{
+		InfrastructureID: 10004,
+		UserIDowner:      3,
+		UserEmailOwner:   "bob@test.com",
+		InfrastructureOperation: metalcloud.InfrastructureOperation{
+			InfrastructureLabel: "delegated2",
+		},
+	}
+
+	infraList := map[string]metalcloud.Infrastructure{
+		"owned":      owned,
+		"delegated1": delegated1,
+		"delegated2": delegated2,
+	}
+
+	client := helper.NewMockMetalCloudClient(ctrl)
+
+	client.EXPECT().
+		Infrastructures().
+		Return(&infraList, nil).
+		AnyTimes()
+
+	cases := []struct {
+		args     map[string]interface{}
+		expected []int
+	}{
+		{
+			args:     map[string]interface{}{"relation": "owner"},
+			expected: []int{owned.InfrastructureID},
+		},
+		{
+			args:     map[string]interface{}{"relation": "delegate"},
+			expected: []int{delegated1.InfrastructureID, delegated2.InfrastructureID},
+		},
+		{
+			args:     map[string]interface{}{"owner": "ALICE@test.com"},
+			expected: []int{delegated1.InfrastructureID},
+		},
+		{
+			args:     map[string]interface{}{"owner": "3", "relation": "delegate"},
+			expected: []int{delegated2.InfrastructureID},
+		},
+	}
+
+	for _, tc := range cases {
+		tc.args["format"] = "json"
+		cmd := MakeCommand(tc.args)
+
+		ret, err := infrastructureListCmd(&cmd, client)
+		Expect(err).To(BeNil())
+
+		var m []interface{}
+		err = json.Unmarshal([]byte(ret), &m)
+		Expect(err).To(BeNil())
+
+		ids := []int{}
+		for _, r := range m {
+			ids = append(ids, int(r.(map[string]interface{})["ID"].(float64)))
+		}
+		Expect(ids).To(ConsistOf(tc.expected))
+	}
+
+	cmd := MakeCommand(map[string]interface{}{"relation": "friend"})
+	_, err := infrastructureListCmd(&cmd, client)
+	Expect(err).NotTo(BeNil())
+}
+
+func TestInfrastructurePowerStatusCmd(t *testing.T) {
+	RegisterTestingT(t)
+	ctrl := gomock.NewController(t)
+
+	infra := metalcloud.Infrastructure{
+		InfrastructureID:    10002,
+		InfrastructureLabel: "testinfra",
+	}
+
+	ia := metalcloud.InstanceArray{
+		InstanceArrayID:    11,
+		InstanceArrayLabel: "testia",
+		InfrastructureID:   infra.InfrastructureID,
+	}
+
+	iaList := map[string]metalcloud.InstanceArray{
+		ia.InstanceArrayLabel: ia,
+	}
+
+	i1 := metalcloud.Instance{
+		InstanceID:      101,
+		InstanceLabel:   "instance-101",
+		InstanceArrayID: ia.InstanceArrayID,
+		ServerID:        200,
+	}
+
+	i2 := metalcloud.Instance{
+		InstanceID:      102,
+		InstanceLabel:   "instance-102",
+		InstanceArrayID: ia.InstanceArrayID,
+	}
+
+	iList := map[string]metalcloud.Instance{
+		i1.InstanceLabel: i1,
+		i2.InstanceLabel: i2,
+	}
+
+	powerStatus := map[string]string{
+		"101": "on",
+		"102": "off",
+	}
+
+	client := helper.NewMockMetalCloudClient(ctrl)
+
+	client.EXPECT().
+		InfrastructureGet(infra.InfrastructureID).
+		Return(&infra, nil).
+		AnyTimes()
+
+	client.EXPECT().
+		InstanceArrays(infra.InfrastructureID).
+		Return(&iaList, nil).
+		AnyTimes()
+
+	client.EXPECT().
+		InstanceArrayInstances(ia.InstanceArrayID).
+		Return(&iList, nil).
+		AnyTimes()
+
+	client.EXPECT().
+		InstanceServerPowerGetBatch(infra.InfrastructureID, gomock.Any()).
+		Return(&powerStatus, nil).
+		Times(1)
+
+	cmd := MakeCommand(map[string]interface{}{
+		"infrastructure_id_or_label": infra.InfrastructureID,
+		"format":                     "json",
+	})
+
+	ret, err := infrastructurePowerStatusCmd(&cmd, client)
+	Expect(err).To(BeNil())
+
+	var m []interface{}
+	err = json.Unmarshal([]byte(ret), &m)
+	Expect(err).To(BeNil())
+	Expect(len(m)).To(Equal(2))
+
+	r := m[0].(map[string]interface{})
+	Expect(int(r["ID"].(float64))).To(Equal(i1.InstanceID))
+	Expect(r["POWER"].(string)).To(Equal("on"))
+
+	r = m[1].(map[string]interface{})
+	Expect(int(r["ID"].(float64))).To(Equal(i2.InstanceID))
+	Expect(r["POWER"].(string)).To(Equal("off"))
+}
+
+func TestInfrastructureApplyCmdWithoutChanges(t *testing.T) {
+	RegisterTestingT(t)
+	ctrl := gomock.NewController(t)
+
+	path, err := writeTempFile("label: testinfra\ndatacenter: uk-reading\n", ".yaml")
+	Expect(err).To(BeNil())
+	defer os.Remove(path)
+
+	infra := metalcloud.Infrastructure{
+		InfrastructureID:    10002,
+		InfrastructureLabel: "testinfra",
+		DatacenterName:      "uk-reading",
+	}
+
+	client := helper.NewMockMetalCloudClient(ctrl)
+
+	client.EXPECT().
+		Infrastructures().
+		Return(&map[string]metalcloud.Infrastructure{"testinfra": infra}, nil).
+		AnyTimes()
+
+	client.EXPECT().
+		Networks(10002).
+		Return(&map[string]metalcloud.Network{}, nil).
+		AnyTimes()
+
+	client.EXPECT().
+		InstanceArrays(10002).
+		Return(&map[string]metalcloud.InstanceArray{}, nil).
+		AnyTimes()
+
+	client.EXPECT().
+		DriveArrays(10002).
+		Return(&map[string]metalcloud.DriveArray{}, nil).
+		AnyTimes()
+
+	//the deploy is skipped as nothing was changed, InfrastructureDeploy is not expected
+	cmd := MakeCommand(map[string]interface{}{
+		"manifest":    path,
+		"deploy":      true,
+		"autoconfirm": true,
+	})
+
+	ret, err := infrastructureApplyCmd(&cmd, client)
+	Expect(err).To(BeNil())
+	Expect(ret).To(ContainSubstring("no changes, the deploy was skipped"))
+
+	//the datacenter of an existing infrastructure cannot be changed
+	path, err = writeTempFile("label: testinfra\ndatacenter: us-santaclara\n", ".yaml")
+	Expect(err).To(BeNil())
+	defer os.Remove(path)
+
+	cmd = MakeCommand(map[string]interface{}{
+		"manifest": path,
+	})
+
+	_, err = infrastructureApplyCmd(&cmd, client)
+	Expect(err).NotTo(BeNil())
+	Expect(err.Error()).To(ContainSubstring("us-santaclara"))
+}
+
+func TestInfrastructureApplyCmdKeepsLiveSettings(t *testing.T) {
+	RegisterTestingT(t)
+	ctrl := gomock.NewController(t)
+
+	path, err := writeTempFile(`
+label: testinfra
+instance_arrays:
+  - label: master
+    instance_count: 3
+drive_arrays:
+  - label: master-da
+    instance_array: master
+    drive_size_mbytes: 81920
+`, ".yaml")
+	Expect(err).To(BeNil())
+	defer os.Remove(path)
+
+	infra := metalcloud.Infrastructure{
+		InfrastructureID:    10002,
+		InfrastructureLabel: "testinfra",
+	}
+
+	//deployed objects without pending operations
+	master := metalcloud.InstanceArray{
+		InstanceArrayID:            11,
+		InstanceArrayLabel:         "master",
+		InstanceArrayInstanceCount: 2,
+		InstanceArrayRAMGbytes:     16,
+		VolumeTemplateID:           100,
+	}
+
+	masterDA := metalcloud.DriveArray{
+		DriveArrayID:           13,
+		DriveArrayLabel:        "master-da",
+		InstanceArrayID:        11,
+		InfrastructureID:       10002,
+		DriveArrayCount:        2,
+		DriveSizeMBytesDefault: 40960,
+		DriveArrayStorageType:  "iscsi_ssd",
+	}
+
+	client := helper.NewMockMetalCloudClient(ctrl)
+
+	client.EXPECT().
+		Infrastructures().
+		Return(&map[string]metalcloud.Infrastructure{"testinfra": infra}, nil).
+		AnyTimes()
+
+	client.EXPECT().
+		Networks(10002).
+		Return(&map[string]metalcloud.Network{}, nil).
+		AnyTimes()
+
+	client.EXPECT().
+		InstanceArrays(10002).
+		Return(&map[string]metalcloud.InstanceArray{"master": master}, nil).
+		AnyTimes()
+
+	client.EXPECT().
+		DriveArrays(10002).
+		Return(&map[string]metalcloud.DriveArray{"master-da": masterDA}, nil).
+		AnyTimes()
+
+	//the settings not in the manifest are those of the live objects
+	client.EXPECT().
+		InstanceArrayEdit(11, metalcloud.InstanceArrayOperation{
+			InstanceArrayID:            11,
+			InstanceArrayLabel:         "master",
+			InstanceArrayInstanceCount: 3,
+			InstanceArrayRAMGbytes:     16,
+			VolumeTemplateID:           100,
+		}, nil, nil, nil, nil).
+		Return(&master, nil).
+		Times(1)
+
+	client.EXPECT().
+		DriveArrayEdit(13, metalcloud.DriveArrayOperation{
+			DriveArrayID:           13,
+			DriveArrayLabel:        "master-da",
+			InstanceArrayID:        11,
+			InfrastructureID:       10002,
+			DriveArrayCount:        2,
+			DriveSizeMBytesDefault: 81920,
+			DriveArrayStorageType:  "iscsi_ssd",
+		}).
+		Return(&masterDA, nil).
+		Times(1)
+
+	cmd := MakeCommand(map[string]interface{}{
+		"manifest": path,
+	})
+
+	_, err = infrastructureApplyCmd(&cmd, client)
+	Expect(err).To(BeNil())
+}
+
+func TestInfrastructureCommandsWithFakeClient(t *testing.T) {
+	RegisterTestingT(t)
+
+	client := fake.NewClient("user@user.com", "uk-reading")
+
+	path, err := writeTempFile(`
+label: fake-infra
+datacenter: uk-reading
+networks:
+  - label: lan2
+    type: lan
+instance_arrays:
+  - label: master
+    instance_count: 2
+    volume_template: centos7-6
+    interfaces:
+      - index: 1
+        network: lan2
+drive_arrays:
+  - label: master-da
+    instance_array: master
+`, ".yaml")
+	Expect(err).To(BeNil())
+	defer os.Remove(path)
+
+	//the manifest is applied by infrastructure apply when -f is set
+	cmd := initCommandCopy(*locateCommand("apply", "infra", infrastructureCmds))
+	err = cmd.FlagSet.Parse([]string{"-f", path, "-deploy", "-autoconfirm"})
+	Expect(err).To(BeNil())
+	_, err = cmd.ExecuteFunc(&cmd, client)
+	Expect(err).To(BeNil())
+
+	infra, err := client.InfrastructureGetByLabel("fake-infra")
+	Expect(err).To(BeNil())
+	Expect(infra.InfrastructureServiceStatus).To(Equal("active"))
+
+	cmd = MakeCommand(map[string]interface{}{
+		"instance_array_id_or_label":    "master",
+		"instance_array_instance_count": 3,
+		"autoconfirm":                   true,
+	})
+	_, err = instanceArrayEditCmd(&cmd, client)
+	Expect(err).To(BeNil())
+
+	cmd = MakeCommand(map[string]interface{}{
+		"infrastructure_id_or_label": "fake-infra",
+		"format":                     "json",
+	})
+	ret, err := infrastructurePlanCmd(&cmd, client)
+	Expect(err).To(BeNil())
+
+	var m []interface{}
+	err = json.Unmarshal([]byte(ret), &m)
+	Expect(err).To(BeNil())
+	Expect(m).To(HaveLen(1))
+	Expect(m[0].(map[string]interface{})["FIELD"]).To(Equal("instance_count"))
+
+	cmd = MakeCommand(map[string]interface{}{
+		"infrastructure_id_or_label": "fake-infra",
+		"autoconfirm":                true,
+	})
+	_, err = infrastructureDeployCmd(&cmd, client)
+	Expect(err).To(BeNil())
+
+	instances, err := client.InstanceArrayInstancesByLabel("master")
+	Expect(err).To(BeNil())
+	Expect(*instances).To(HaveLen(3))
+
+	//the exported manifest matches the one applied
+	cmd = MakeCommand(map[string]interface{}{
+		"infrastructure_id_or_label": "fake-infra",
+		"format":                     "yaml",
+	})
+	ret, err = infrastructureExportCmd(&cmd, client)
+	Expect(err).To(BeNil())
+	Expect(ret).To(ContainSubstring("network: lan2"))
+	Expect(ret).To(ContainSubstring("instance_count: 3"))
+
+	//server types are exported by label as they are looked up by label when applied
+	Expect(ret).To(ContainSubstring("server_type: m-8-16-v2"))
+
+	cmd = MakeCommand(map[string]interface{}{
+		"infrastructure_id_or_label": "fake-infra",
+		"autoconfirm":                true,
+	})
+	_, err = infrastructureDeleteCmd(&cmd, client)
+	Expect(err).To(BeNil())
+
+	_, err = client.InfrastructureGetByLabel("fake-infra")
+	Expect(err).NotTo(BeNil())
+}
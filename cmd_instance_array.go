@@ -4,6 +4,7 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"sort"
 	"strings"
 
 	metalcloud "github.com/bigstepinc/metal-cloud-sdk-go"
@@ -41,6 +42,7 @@ var instanceArrayCmds = []Command{
 			}
 		},
 		ExecuteFunc: instanceArrayCreateCmd,
+		Example:     "metalcloud-cli instance-array create -infra complex-demo -label master -instance-count 2 -ram 16 -proc-core-count 8",
 	},
 	{
 		Description:  "Lists all instance arrays of an infrastructure.",
@@ -52,10 +54,11 @@ var instanceArrayCmds = []Command{
 		InitFunc: func(c *Command) {
 			c.Arguments = map[string]interface{}{
 				"infrastructure_id_or_label": c.FlagSet.String("infra", _nilDefaultStr, "(Required) Infrastructure's id or label. Note that the 'label' this be ambiguous in certain situations."),
-				"format":                     c.FlagSet.String("format", "", "The output format. Supported values are 'json','csv'. The default format is human readable."),
 			}
 		},
 		ExecuteFunc: instanceArrayListCmd,
+		Example:     "metalcloud-cli instance-array list -infra complex-demo",
+		Formatted:   true,
 	},
 	{
 		Description:  "Delete instance array.",
@@ -72,6 +75,36 @@ var instanceArrayCmds = []Command{
 		},
 		ExecuteFunc: instanceArrayDeleteCmd,
 	},
+	{
+		Description:  "Starts an instance array.",
+		Subject:      "instance-array",
+		AltSubject:   "ia",
+		Predicate:    "start",
+		AltPredicate: "power-on",
+		FlagSet:      flag.NewFlagSet("start instance array", flag.ExitOnError),
+		InitFunc: func(c *Command) {
+			c.Arguments = map[string]interface{}{
+				"instance_array_id_or_label": c.FlagSet.String("id", _nilDefaultStr, "(Required) InstanceArray's id or label. Note that the label can be ambigous."),
+				"autoconfirm":                c.FlagSet.Bool("autoconfirm", false, "If true it does not ask for confirmation anymore"),
+			}
+		},
+		ExecuteFunc: instanceArrayStartCmd,
+	},
+	{
+		Description:  "Stops an instance array.",
+		Subject:      "instance-array",
+		AltSubject:   "ia",
+		Predicate:    "stop",
+		AltPredicate: "power-off",
+		FlagSet:      flag.NewFlagSet("stop instance array", flag.ExitOnError),
+		InitFunc: func(c *Command) {
+			c.Arguments = map[string]interface{}{
+				"instance_array_id_or_label": c.FlagSet.String("id", _nilDefaultStr, "(Required) InstanceArray's id or label. Note that the label can be ambigous."),
+				"autoconfirm":                c.FlagSet.Bool("autoconfirm", false, "If true it does not ask for confirmation anymore"),
+			}
+		},
+		ExecuteFunc: instanceArrayStopCmd,
+	},
 	{
 		Description:  "Edits an instance array.",
 		Subject:      "instance-array",
@@ -112,10 +145,43 @@ var instanceArrayCmds = []Command{
 				"show_credentials":           c.FlagSet.Bool("show-credentials", false, "(Flag) If set returns the instances' credentials"),
 				"show_power_status":          c.FlagSet.Bool("show-power-status", false, "(Flag) If set returns the instances' power status"),
 				"show_iscsi_credentials":     c.FlagSet.Bool("show-iscsi-credentials", false, "(Flag) If set returns the instances' iscsi credentials"),
-				"format":                     c.FlagSet.String("format", "", "The output format. Supported values are 'json','csv'. The default format is human readable."),
+				"show_interfaces":            c.FlagSet.Bool("show-interfaces", false, "(Flag) If set returns the networks each of the instances' interfaces is attached to"),
 			}
 		},
 		ExecuteFunc: instanceArrayGetCmd,
+		Example:     "metalcloud-cli instance-array get -id master -show-interfaces",
+		Formatted:   true,
+	},
+	{
+		Description:  "Attaches an instance array interface to a network.",
+		Subject:      "instance-array",
+		AltSubject:   "ia",
+		Predicate:    "interface-attach",
+		AltPredicate: "attach",
+		FlagSet:      flag.NewFlagSet("attach instance array interface", flag.ExitOnError),
+		InitFunc: func(c *Command) {
+			c.Arguments = map[string]interface{}{
+				"instance_array_id_or_label":     c.FlagSet.String("id", _nilDefaultStr, "(Required) InstanceArray's id or label. Note that the label can be ambigous."),
+				"instance_array_interface_index": c.FlagSet.Int("interface", _nilDefaultInt, "(Required) The index of the interface, starting with 0"),
+				"network_id_or_label":            c.FlagSet.String("network", _nilDefaultStr, "(Required) Network's id or label. Note that the label can be ambigous."),
+			}
+		},
+		ExecuteFunc: instanceArrayInterfaceAttachCmd,
+	},
+	{
+		Description:  "Detaches an instance array interface from the network it is attached to.",
+		Subject:      "instance-array",
+		AltSubject:   "ia",
+		Predicate:    "interface-detach",
+		AltPredicate: "detach",
+		FlagSet:      flag.NewFlagSet("detach instance array interface", flag.ExitOnError),
+		InitFunc: func(c *Command) {
+			c.Arguments = map[string]interface{}{
+				"instance_array_id_or_label":     c.FlagSet.String("id", _nilDefaultStr, "(Required) InstanceArray's id or label. Note that the label can be ambigous."),
+				"instance_array_interface_index": c.FlagSet.Int("interface", _nilDefaultInt, "(Required) The index of the interface, starting with 0"),
+			}
+		},
+		ExecuteFunc: instanceArrayInterfaceDetachCmd,
 	},
 }
 
@@ -321,6 +387,55 @@ func instanceArrayDeleteCmd(c *Command, client interfaces.MetalCloudClient) (str
 	return "", err
 }
 
+func instanceArrayStartCmd(c *Command, client interfaces.MetalCloudClient) (string, error) {
+	return instanceArrayConfirmAndDo("Starting", c, client, client.InstanceArrayStart)
+}
+
+func instanceArrayStopCmd(c *Command, client interfaces.MetalCloudClient) (string, error) {
+	return instanceArrayConfirmAndDo("Stopping", c, client, client.InstanceArrayStop)
+}
+
+//instanceArrayConfirmAndDo asks for confirmation and executes the given function on the instance array
+func instanceArrayConfirmAndDo(operation string, c *Command, client interfaces.MetalCloudClient, f func(instanceArrayID int) (*metalcloud.InstanceArray, error)) (string, error) {
+
+	retIA, err := getInstanceArrayFromCommand("id", c, client)
+	if err != nil {
+		return "", err
+	}
+
+	retInfra, err := client.InfrastructureGet(retIA.InfrastructureID)
+	if err != nil {
+		return "", err
+	}
+
+	confirm, err := confirmCommand(c, func() string {
+
+		confirmationMessage := fmt.Sprintf("%s instance array %s (%d) of infrastructure %s (%d). All %d instances will be affected. Are you sure? Type \"yes\" to continue:",
+			operation,
+			retIA.InstanceArrayLabel, retIA.InstanceArrayID,
+			retInfra.InfrastructureLabel, retInfra.InfrastructureID,
+			retIA.InstanceArrayInstanceCount)
+
+		//this is simply so that we don't output a text on the command line under go test
+		if strings.HasSuffix(os.Args[0], ".test") {
+			confirmationMessage = ""
+		}
+
+		return confirmationMessage
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if !confirm {
+		return "", fmt.Errorf("Operation not confirmed. Aborting")
+	}
+
+	_, err = f(retIA.InstanceArrayID)
+
+	return "", err
+}
+
 func instanceArrayGetCmd(c *Command, client interfaces.MetalCloudClient) (string, error) {
 
 	retIA, err := getInstanceArrayFromCommand("id", c, client)
@@ -384,6 +499,15 @@ func instanceArrayGetCmd(c *Command, client interfaces.MetalCloudClient) (string
 		})
 	}
 
+	if getBoolParam(c.Arguments["show_interfaces"]) {
+
+		schema = append(schema, SchemaField{
+			FieldName: "INTERFACES",
+			FieldType: TypeString,
+			FieldSize: 5,
+		})
+	}
+
 	data := [][]interface{}{}
 
 	iList, err := client.InstanceArrayInstances(retIA.InstanceArrayID)
@@ -391,6 +515,9 @@ func instanceArrayGetCmd(c *Command, client interfaces.MetalCloudClient) (string
 		return "", err
 	}
 
+	//instances share the networks of the infrastructure so each is retrieved once
+	networks := newNetworkCache(client)
+
 	for _, i := range *iList {
 		status := i.InstanceServiceStatus
 		if i.InstanceServiceStatus != "ordered" && i.InstanceOperation.InstanceDeployType == "edit" && i.InstanceOperation.InstanceDeployStatus == "not_started" {
@@ -425,7 +552,7 @@ func instanceArrayGetCmd(c *Command, client interfaces.MetalCloudClient) (string
 		for _, p := range i.InstanceInterfaces {
 			if p.NetworkID != 0 {
 
-				n, err := client.NetworkGet(p.NetworkID)
+				n, err := networks.get(p.NetworkID)
 				if err != nil {
 					return "", err
 				}
@@ -487,6 +614,14 @@ func instanceArrayGetCmd(c *Command, client interfaces.MetalCloudClient) (string
 			dataRow = append(dataRow, iscsiCreds)
 		}
 
+		if getBoolParam(c.Arguments["show_interfaces"]) {
+			itfs, err := instanceInterfacesToString(i.InstanceInterfaces, networks)
+			if err != nil {
+				return "", err
+			}
+			dataRow = append(dataRow, itfs)
+		}
+
 		data = append(data, dataRow)
 
 	}
@@ -506,6 +641,124 @@ func instanceArrayGetCmd(c *Command, client interfaces.MetalCloudClient) (string
 	return renderTable("Instances", subtitle, getStringParam(c.Arguments["format"]), data, schema)
 }
 
+func instanceArrayInterfaceAttachCmd(c *Command, client interfaces.MetalCloudClient) (string, error) {
+
+	retIA, err := getInstanceArrayFromCommand("id", c, client)
+	if err != nil {
+		return "", err
+	}
+
+	index, err := getInstanceArrayInterfaceIndexFromCommand(c)
+	if err != nil {
+		return "", err
+	}
+
+	retNetwork, err := getNetworkFromCommand("network", "network_id_or_label", c, client)
+	if err != nil {
+		return "", err
+	}
+
+	if retNetwork.InfrastructureID != 0 && retNetwork.InfrastructureID != retIA.InfrastructureID {
+		return "", fmt.Errorf("network %s (#%d) does not belong to the infrastructure of instance array %s (#%d)",
+			retNetwork.NetworkLabel, retNetwork.NetworkID,
+			retIA.InstanceArrayLabel, retIA.InstanceArrayID)
+	}
+
+	_, err = client.InstanceArrayInterfaceAttachNetwork(retIA.InstanceArrayID, index, retNetwork.NetworkID)
+
+	return "", err
+}
+
+func instanceArrayInterfaceDetachCmd(c *Command, client interfaces.MetalCloudClient) (string, error) {
+
+	retIA, err := getInstanceArrayFromCommand("id", c, client)
+	if err != nil {
+		return "", err
+	}
+
+	index, err := getInstanceArrayInterfaceIndexFromCommand(c)
+	if err != nil {
+		return "", err
+	}
+
+	_, err = client.InstanceArrayInterfaceDetach(retIA.InstanceArrayID, index)
+
+	return "", err
+}
+
+//getInstanceArrayInterfaceIndexFromCommand returns the interface index. Unlike getParam it accepts 0 as a valid value.
+func getInstanceArrayInterfaceIndexFromCommand(c *Command) (int, error) {
+	index, ok := getIntParamOk(c.Arguments["instance_array_interface_index"])
+	if !ok {
+		return 0, fmt.Errorf("-interface is required")
+	}
+	if index < 0 {
+		return 0, fmt.Errorf("-interface cannot be <0")
+	}
+	return index, nil
+}
+
+//instanceInterfacesToString returns a description of the networks each interface is attached to, ordered by interface index.
+func instanceInterfacesToString(instanceInterfaces []metalcloud.InstanceInterface, networks *networkCache) (string, error) {
+
+	itfs := make([]metalcloud.InstanceInterface, len(instanceInterfaces))
+	copy(itfs, instanceInterfaces)
+
+	sort.Slice(itfs, func(a, b int) bool {
+		return itfs[a].InstanceInterfaceIndex < itfs[b].InstanceInterfaceIndex
+	})
+
+	descriptions := []string{}
+	for _, p := range itfs {
+
+		networkID := p.NetworkID
+		if p.InstanceInterfaceOperation.InstanceInterfaceID != 0 {
+			networkID = p.InstanceInterfaceOperation.NetworkID
+		}
+
+		if networkID == 0 {
+			descriptions = append(descriptions, fmt.Sprintf("#%d: unattached", p.InstanceInterfaceIndex))
+			continue
+		}
+
+		n, err := networks.get(networkID)
+		if err != nil {
+			return "", err
+		}
+
+		descriptions = append(descriptions, fmt.Sprintf("#%d: %s %s (#%d)", p.InstanceInterfaceIndex, n.NetworkType, n.NetworkLabel, n.NetworkID))
+	}
+
+	return strings.Join(descriptions, ", "), nil
+}
+
+//networkCache retrieves networks by id, calling the API once for each network
+type networkCache struct {
+	client   interfaces.MetalCloudClient
+	networks map[int]*metalcloud.Network
+}
+
+func newNetworkCache(client interfaces.MetalCloudClient) *networkCache {
+	return &networkCache{
+		client:   client,
+		networks: map[int]*metalcloud.Network{},
+	}
+}
+
+func (nc *networkCache) get(networkID int) (*metalcloud.Network, error) {
+	if n, ok := nc.networks[networkID]; ok {
+		return n, nil
+	}
+
+	n, err := nc.client.NetworkGet(networkID)
+	if err != nil {
+		return nil, err
+	}
+
+	nc.networks[networkID] = n
+	return n, nil
+}
+
 func argsToInstanceArray(m map[string]interface{}) *metalcloud.InstanceArray {
 	ia := metalcloud.InstanceArray{}
 
@@ -611,6 +864,7 @@ func copyInstanceArrayToOperation(ia metalcloud.InstanceArray, iao *metalcloud.I
 	iao.InstanceArrayInstanceCount = ia.InstanceArrayInstanceCount
 	iao.InstanceArrayRAMGbytes = ia.InstanceArrayRAMGbytes
 	iao.InstanceArrayProcessorCount = ia.InstanceArrayProcessorCount
+	iao.InstanceArrayProcessorCoreCount = ia.InstanceArrayProcessorCoreCount
 	iao.InstanceArrayProcessorCoreMHZ = ia.InstanceArrayProcessorCoreMHZ
 	iao.InstanceArrayDiskCount = ia.InstanceArrayDiskCount
 	iao.InstanceArrayDiskSizeMBytes = ia.InstanceArrayDiskSizeMBytes
@@ -516,3 +516,285 @@ func TestInstanceArrayGet(t *testing.T) {
 	Expect(csv[1][2]).To(Equal(ips[0].IPHumanReadable))
 
 }
+
+func TestInstanceArrayGetWithInterfaces(t *testing.T) {
+	RegisterTestingT(t)
+	ctrl := gomock.NewController(t)
+	client := mock_metalcloud.NewMockMetalCloudClient(ctrl)
+
+	ia := metalcloud.InstanceArray{
+		InstanceArrayID:            11,
+		InstanceArrayLabel:         "testia",
+		InstanceArrayServiceStatus: "active",
+	}
+
+	wan := metalcloud.Network{
+		NetworkID:    105,
+		NetworkType:  "wan",
+		NetworkLabel: "wan-net",
+	}
+
+	lan := metalcloud.Network{
+		NetworkID:    106,
+		NetworkType:  "lan",
+		NetworkLabel: "lan-net",
+	}
+
+	i := metalcloud.Instance{
+		InstanceID: 100,
+		InstanceInterfaces: []metalcloud.InstanceInterface{
+			{
+				InstanceInterfaceIndex: 2,
+			},
+			{
+				InstanceInterfaceIndex: 1,
+				NetworkID:              lan.NetworkID,
+			},
+			{
+				InstanceInterfaceIndex: 0,
+				NetworkID:              wan.NetworkID,
+			},
+		},
+	}
+
+	other := i
+	other.InstanceID = 101
+
+	ilist := map[string]metalcloud.Instance{
+		"instance-100": i,
+		"instance-101": other,
+	}
+
+	client.EXPECT().
+		InstanceArrayGet(ia.InstanceArrayID).
+		Return(&ia, nil).
+		AnyTimes()
+
+	client.EXPECT().
+		InstanceArrayInstances(ia.InstanceArrayID).
+		Return(&ilist, nil).
+		AnyTimes()
+
+	//networks are retrieved once even if used by several interfaces and columns
+	client.EXPECT().
+		NetworkGet(wan.NetworkID).
+		Return(&wan, nil).
+		Times(1)
+
+	client.EXPECT().
+		NetworkGet(lan.NetworkID).
+		Return(&lan, nil).
+		Times(1)
+
+	client.EXPECT().
+		InfrastructureGet(gomock.Any()).
+		Return(&metalcloud.Infrastructure{}, nil).
+		AnyTimes()
+
+	cmd := MakeCommand(map[string]interface{}{
+		"instance_array_id_or_label": ia.InstanceArrayID,
+		"show_interfaces":            true,
+		"format":                     "json",
+	})
+
+	ret, err := instanceArrayGetCmd(&cmd, client)
+	Expect(err).To(BeNil())
+
+	var m []interface{}
+	err = json.Unmarshal([]byte(ret), &m)
+	Expect(err).To(BeNil())
+
+	Expect(m).To(HaveLen(2))
+	for _, row := range m {
+		r := row.(map[string]interface{})
+		Expect(r["INTERFACES"]).To(Equal("#0: wan wan-net (#105), #1: lan lan-net (#106), #2: unattached"))
+	}
+}
+
+func TestInstanceArrayInterfaceAttachCmd(t *testing.T) {
+	RegisterTestingT(t)
+	ctrl := gomock.NewController(t)
+	client := mock_metalcloud.NewMockMetalCloudClient(ctrl)
+
+	ia := metalcloud.InstanceArray{
+		InstanceArrayID:    11,
+		InstanceArrayLabel: "testia",
+		InfrastructureID:   100,
+	}
+
+	n := metalcloud.Network{
+		NetworkID:        105,
+		NetworkType:      "lan",
+		NetworkLabel:     "lan-net",
+		InfrastructureID: 100,
+	}
+
+	otherInfraNetwork := metalcloud.Network{
+		NetworkID:        106,
+		NetworkType:      "lan",
+		NetworkLabel:     "other-lan-net",
+		InfrastructureID: 101,
+	}
+
+	client.EXPECT().
+		InstanceArrayGet(ia.InstanceArrayID).
+		Return(&ia, nil).
+		AnyTimes()
+
+	client.EXPECT().
+		NetworkGetByLabel(n.NetworkLabel).
+		Return(&n, nil).
+		AnyTimes()
+
+	client.EXPECT().
+		NetworkGet(otherInfraNetwork.NetworkID).
+		Return(&otherInfraNetwork, nil).
+		AnyTimes()
+
+	client.EXPECT().
+		InstanceArrayInterfaceAttachNetwork(ia.InstanceArrayID, 0, n.NetworkID).
+		Return(&ia, nil).
+		Times(1)
+
+	cases := []CommandTestCase{
+		{
+			name: "ia-interface-attach-good1",
+			cmd: MakeCommand(map[string]interface{}{
+				"instance_array_id_or_label":     ia.InstanceArrayID,
+				"instance_array_interface_index": 0,
+				"network_id_or_label":            n.NetworkLabel,
+			}),
+			good: true,
+		},
+		{
+			name: "ia-interface-attach-other-infra",
+			cmd: MakeCommand(map[string]interface{}{
+				"instance_array_id_or_label":     ia.InstanceArrayID,
+				"instance_array_interface_index": 0,
+				"network_id_or_label":            otherInfraNetwork.NetworkID,
+			}),
+			good: false,
+		},
+		{
+			name: "ia-interface-attach-missing-index",
+			cmd: MakeCommand(map[string]interface{}{
+				"instance_array_id_or_label": ia.InstanceArrayID,
+				"network_id_or_label":        n.NetworkLabel,
+			}),
+			good: false,
+		},
+		{
+			name: "ia-interface-attach-missing-network",
+			cmd: MakeCommand(map[string]interface{}{
+				"instance_array_id_or_label":     ia.InstanceArrayID,
+				"instance_array_interface_index": 0,
+			}),
+			good: false,
+		},
+	}
+
+	testGetCommand(instanceArrayInterfaceAttachCmd, cases, client, nil, t)
+}
+
+func TestInstanceArrayInterfaceDetachCmd(t *testing.T) {
+	RegisterTestingT(t)
+	ctrl := gomock.NewController(t)
+	client := mock_metalcloud.NewMockMetalCloudClient(ctrl)
+
+	ia := metalcloud.InstanceArray{
+		InstanceArrayID:    11,
+		InstanceArrayLabel: "testia",
+	}
+
+	client.EXPECT().
+		InstanceArrayGetByLabel(ia.InstanceArrayLabel).
+		Return(&ia, nil).
+		AnyTimes()
+
+	client.EXPECT().
+		InstanceArrayInterfaceDetach(ia.InstanceArrayID, 1).
+		Return(&ia, nil).
+		Times(1)
+
+	cases := []CommandTestCase{
+		{
+			name: "ia-interface-detach-good1",
+			cmd: MakeCommand(map[string]interface{}{
+				"instance_array_id_or_label":     ia.InstanceArrayLabel,
+				"instance_array_interface_index": 1,
+			}),
+			good: true,
+		},
+		{
+			name: "ia-interface-detach-negative-index",
+			cmd: MakeCommand(map[string]interface{}{
+				"instance_array_id_or_label":     ia.InstanceArrayLabel,
+				"instance_array_interface_index": -1,
+			}),
+			good: false,
+		},
+	}
+
+	testGetCommand(instanceArrayInterfaceDetachCmd, cases, client, nil, t)
+}
+
+func TestInstanceArrayStartStopCmd(t *testing.T) {
+	RegisterTestingT(t)
+	ctrl := gomock.NewController(t)
+
+	infra := metalcloud.Infrastructure{
+		InfrastructureID:    10002,
+		InfrastructureLabel: "testinfra",
+	}
+
+	ia := metalcloud.InstanceArray{
+		InstanceArrayID:            11,
+		InstanceArrayLabel:         "testia",
+		InstanceArrayInstanceCount: 2,
+		InfrastructureID:           infra.InfrastructureID,
+	}
+
+	client := mock_metalcloud.NewMockMetalCloudClient(ctrl)
+
+	client.EXPECT().
+		InstanceArrayGet(ia.InstanceArrayID).
+		Return(&ia, nil).
+		AnyTimes()
+
+	client.EXPECT().
+		InfrastructureGet(infra.InfrastructureID).
+		Return(&infra, nil).
+		AnyTimes()
+
+	client.EXPECT().
+		InstanceArrayStart(ia.InstanceArrayID).
+		Return(&ia, nil).
+		Times(1)
+
+	client.EXPECT().
+		InstanceArrayStop(ia.InstanceArrayID).
+		Return(&ia, nil).
+		Times(1)
+
+	for _, f := range []CommandExecuteFunc{instanceArrayStartCmd, instanceArrayStopCmd} {
+
+		cmd := MakeCommand(map[string]interface{}{
+			"instance_array_id_or_label": ia.InstanceArrayID,
+		})
+
+		//test without autoconfirm
+		_, err := f(&cmd, client)
+		Expect(err.Error()).To(Equal("Operation not confirmed. Aborting"))
+
+		bTrue := true
+		cmd.Arguments["autoconfirm"] = &bTrue
+
+		ret, err := f(&cmd, client)
+		Expect(err).To(BeNil())
+		Expect(ret).To(BeEmpty())
+
+		cmd = MakeEmptyCommand()
+		_, err = f(&cmd, client)
+		Expect(err).NotTo(BeNil())
+	}
+}
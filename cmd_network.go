@@ -0,0 +1,406 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	metalcloud "github.com/bigstepinc/metal-cloud-sdk-go"
+	interfaces "github.com/bigstepinc/metalcloud-cli/interfaces"
+)
+
+//networkCmds commands affecting networks
+var networkCmds = []Command{
+
+	{
+		Description:  "Creates a network.",
+		Subject:      "network",
+		AltSubject:   "net",
+		Predicate:    "create",
+		AltPredicate: "new",
+		FlagSet:      flag.NewFlagSet("create network", flag.ExitOnError),
+		InitFunc: func(c *Command) {
+			c.Arguments = map[string]interface{}{
+				"infrastructure_id_or_label":   c.FlagSet.String("infra", _nilDefaultStr, "(Required) Infrastructure's id or label. Note that the 'label' this be ambiguous in certain situations."),
+				"network_type":                 c.FlagSet.String("type", _nilDefaultStr, "(Required) Network's type. Possible values: wan, lan, san"),
+				"network_label":                c.FlagSet.String("label", _nilDefaultStr, "Network's label"),
+				"network_lan_autoallocate_ips": c.FlagSet.Bool("lan-autoallocate-ips", false, "(Flag) If set IPs are automatically allocated on LAN networks"),
+				"return_id":                    c.FlagSet.Bool("return-id", false, "(Flag) If set will print the ID of the created network. Useful for automating tasks."),
+			}
+		},
+		ExecuteFunc: networkCreateCmd,
+		Example:     "metalcloud-cli network create -infra complex-demo -type lan -label lan2",
+	},
+	{
+		Description:  "Lists all networks of an infrastructure.",
+		Subject:      "network",
+		AltSubject:   "net",
+		Predicate:    "list",
+		AltPredicate: "ls",
+		FlagSet:      flag.NewFlagSet("list network", flag.ExitOnError),
+		InitFunc: func(c *Command) {
+			c.Arguments = map[string]interface{}{
+				"infrastructure_id_or_label": c.FlagSet.String("infra", _nilDefaultStr, "(Required) Infrastructure's id or label. Note that the 'label' this be ambiguous in certain situations."),
+			}
+		},
+		ExecuteFunc: networkListCmd,
+		Example:     "metalcloud-cli network list -infra complex-demo",
+		Formatted:   true,
+	},
+	{
+		Description:  "Get a network.",
+		Subject:      "network",
+		AltSubject:   "net",
+		Predicate:    "get",
+		AltPredicate: "show",
+		FlagSet:      flag.NewFlagSet("get network", flag.ExitOnError),
+		InitFunc: func(c *Command) {
+			c.Arguments = map[string]interface{}{
+				"network_id_or_label": c.FlagSet.String("id", _nilDefaultStr, "(Required) Network's id or label. Note that using the 'label' might be ambiguous in certain situations."),
+			}
+		},
+		ExecuteFunc: networkGetCmd,
+		Example:     "metalcloud-cli network get -id 12345",
+		Formatted:   true,
+	},
+	{
+		Description:  "Edits a network.",
+		Subject:      "network",
+		AltSubject:   "net",
+		Predicate:    "edit",
+		AltPredicate: "alter",
+		FlagSet:      flag.NewFlagSet("edit network", flag.ExitOnError),
+		InitFunc: func(c *Command) {
+			c.Arguments = map[string]interface{}{
+				"network_id_or_label":          c.FlagSet.String("id", _nilDefaultStr, "(Required) Network's id or label. Note that using the 'label' might be ambiguous in certain situations."),
+				"network_label":                c.FlagSet.String("label", _nilDefaultStr, "Network's new label"),
+				"network_lan_autoallocate_ips": c.FlagSet.String("lan-autoallocate-ips", _nilDefaultStr, "Set to 'true' to automatically allocate IPs on LAN networks, 'false' to stop allocating them"),
+			}
+		},
+		ExecuteFunc: networkEditCmd,
+	},
+	{
+		Description:  "Delete a network.",
+		Subject:      "network",
+		AltSubject:   "net",
+		Predicate:    "delete",
+		AltPredicate: "rm",
+		FlagSet:      flag.NewFlagSet("delete network", flag.ExitOnError),
+		InitFunc: func(c *Command) {
+			c.Arguments = map[string]interface{}{
+				"network_id_or_label": c.FlagSet.String("id", _nilDefaultStr, "(Required) Network's id or label. Note that using the 'label' might be ambiguous in certain situations."),
+				"autoconfirm":         c.FlagSet.Bool("autoconfirm", false, "If true it does not ask for confirmation anymore"),
+			}
+		},
+		ExecuteFunc: networkDeleteCmd,
+	},
+	{
+		Description:  "Joins two networks. The second network is merged into the first one and deleted.",
+		Subject:      "network",
+		AltSubject:   "net",
+		Predicate:    "join",
+		AltPredicate: "merge",
+		FlagSet:      flag.NewFlagSet("join network", flag.ExitOnError),
+		InitFunc: func(c *Command) {
+			c.Arguments = map[string]interface{}{
+				"network_id_or_label":               c.FlagSet.String("id", _nilDefaultStr, "(Required) Network's id or label. Note that using the 'label' might be ambiguous in certain situations."),
+				"network_to_be_deleted_id_or_label": c.FlagSet.String("network-to-be-deleted", _nilDefaultStr, "(Required) The id or label of the network that will be merged into the first one and then deleted."),
+				"autoconfirm":                       c.FlagSet.Bool("autoconfirm", false, "If true it does not ask for confirmation anymore"),
+			}
+		},
+		ExecuteFunc: networkJoinCmd,
+	},
+}
+
+func networkCreateCmd(c *Command, client interfaces.MetalCloudClient) (string, error) {
+
+	infra, err := getInfrastructureFromCommand("infra", c, client)
+	if err != nil {
+		return "", err
+	}
+
+	networkType, ok := getStringParamOk(c.Arguments["network_type"])
+	if !ok {
+		return "", fmt.Errorf("-type <network_type> is required")
+	}
+
+	n := metalcloud.Network{
+		NetworkType:               networkType,
+		NetworkLabel:              getStringParam(c.Arguments["network_label"]),
+		NetworkLANAutoAllocateIPs: getBoolParam(c.Arguments["network_lan_autoallocate_ips"]),
+	}
+
+	retNetwork, err := client.NetworkCreate(infra.InfrastructureID, n)
+	if err != nil {
+		return "", err
+	}
+
+	if getBoolParam(c.Arguments["return_id"]) {
+		return fmt.Sprintf("%d", retNetwork.NetworkID), nil
+	}
+
+	return "", err
+}
+
+func networkListCmd(c *Command, client interfaces.MetalCloudClient) (string, error) {
+
+	infra, err := getInfrastructureFromCommand("infra", c, client)
+	if err != nil {
+		return "", err
+	}
+
+	nList, err := client.Networks(infra.InfrastructureID)
+	if err != nil {
+		return "", err
+	}
+
+	schema := []SchemaField{
+		{
+			FieldName: "ID",
+			FieldType: TypeInt,
+			FieldSize: 6,
+		},
+		{
+			FieldName: "LABEL",
+			FieldType: TypeString,
+			FieldSize: 15,
+		},
+		{
+			FieldName: "TYPE",
+			FieldType: TypeString,
+			FieldSize: 5,
+		},
+		{
+			FieldName: "SUBDOMAIN",
+			FieldType: TypeString,
+			FieldSize: 20,
+		},
+	}
+
+	data := [][]interface{}{}
+	for _, n := range *nList {
+		data = append(data, networkToRow(n))
+	}
+
+	TableSorter(schema).OrderBy(schema[0].FieldName).Sort(data)
+
+	topLine := fmt.Sprintf("Networks of infrastructure %s (#%d):", infra.InfrastructureLabel, infra.InfrastructureID)
+
+	return renderTable("Networks", topLine, getStringParam(c.Arguments["format"]), data, schema)
+}
+
+func networkGetCmd(c *Command, client interfaces.MetalCloudClient) (string, error) {
+
+	retNetwork, err := getNetworkFromCommand("id", "network_id_or_label", c, client)
+	if err != nil {
+		return "", err
+	}
+
+	schema := []SchemaField{
+		{
+			FieldName: "ID",
+			FieldType: TypeInt,
+			FieldSize: 6,
+		},
+		{
+			FieldName: "LABEL",
+			FieldType: TypeString,
+			FieldSize: 15,
+		},
+		{
+			FieldName: "TYPE",
+			FieldType: TypeString,
+			FieldSize: 5,
+		},
+		{
+			FieldName: "SUBDOMAIN",
+			FieldType: TypeString,
+			FieldSize: 20,
+		},
+		{
+			FieldName: "LAN_AUTOALLOCATE_IPS",
+			FieldType: TypeBool,
+			FieldSize: 5,
+		},
+		{
+			FieldName: "INFRASTRUCTURE_ID",
+			FieldType: TypeInt,
+			FieldSize: 6,
+		},
+		{
+			FieldName: "CREATED",
+			FieldType: TypeString,
+			FieldSize: 20,
+		},
+		{
+			FieldName: "UPDATED",
+			FieldType: TypeString,
+			FieldSize: 20,
+		},
+	}
+
+	row := networkToRow(*retNetwork)
+	row = append(row,
+		retNetwork.NetworkLANAutoAllocateIPs,
+		retNetwork.InfrastructureID,
+		retNetwork.NetworkCreatedTimestamp,
+		retNetwork.NetworkUpdatedTimestamp,
+	)
+
+	data := [][]interface{}{row}
+
+	topLine := fmt.Sprintf("Network %s (#%d)", retNetwork.NetworkLabel, retNetwork.NetworkID)
+
+	return renderTransposedTable("network", topLine, getStringParam(c.Arguments["format"]), data, schema)
+}
+
+func networkEditCmd(c *Command, client interfaces.MetalCloudClient) (string, error) {
+
+	retNetwork, err := getNetworkFromCommand("id", "network_id_or_label", c, client)
+	if err != nil {
+		return "", err
+	}
+
+	no := metalcloud.NetworkOperation{}
+	if retNetwork.NetworkOperation != nil {
+		no = *retNetwork.NetworkOperation
+	} else {
+		copyNetworkToOperation(*retNetwork, &no)
+	}
+
+	updateIfStringParamSet(c.Arguments["network_label"], &no.NetworkLabel)
+
+	if v, ok := getStringParamOk(c.Arguments["network_lan_autoallocate_ips"]); ok {
+		switch v {
+		case "true":
+			no.NetworkLANAutoAllocateIPs = true
+		case "false":
+			no.NetworkLANAutoAllocateIPs = false
+		default:
+			return "", fmt.Errorf("-lan-autoallocate-ips must be 'true' or 'false'")
+		}
+	}
+
+	_, err = client.NetworkEdit(retNetwork.NetworkID, no)
+
+	return "", err
+}
+
+func networkDeleteCmd(c *Command, client interfaces.MetalCloudClient) (string, error) {
+
+	retNetwork, err := getNetworkFromCommand("id", "network_id_or_label", c, client)
+	if err != nil {
+		return "", err
+	}
+
+	retInfra, err := client.InfrastructureGet(retNetwork.InfrastructureID)
+	if err != nil {
+		return "", err
+	}
+
+	confirm, err := confirmCommand(c, func() string {
+
+		confirmationMessage := fmt.Sprintf("Deleting network %s (%d) - from infrastructure %s (%d).  Are you sure? Type \"yes\" to continue:",
+			retNetwork.NetworkLabel, retNetwork.NetworkID,
+			retInfra.InfrastructureLabel, retInfra.InfrastructureID)
+
+		//this is simply so that we don't output a text on the command line under go test
+		if strings.HasSuffix(os.Args[0], ".test") {
+			confirmationMessage = ""
+		}
+
+		return confirmationMessage
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if !confirm {
+		return "", fmt.Errorf("Operation not confirmed. Aborting")
+	}
+
+	return "", client.NetworkDelete(retNetwork.NetworkID)
+}
+
+func networkJoinCmd(c *Command, client interfaces.MetalCloudClient) (string, error) {
+
+	retNetwork, err := getNetworkFromCommand("id", "network_id_or_label", c, client)
+	if err != nil {
+		return "", err
+	}
+
+	retNetworkToBeDeleted, err := getNetworkFromCommand("network-to-be-deleted", "network_to_be_deleted_id_or_label", c, client)
+	if err != nil {
+		return "", err
+	}
+
+	if retNetwork.NetworkID == retNetworkToBeDeleted.NetworkID {
+		return "", fmt.Errorf("cannot join a network with itself")
+	}
+
+	confirm, err := confirmCommand(c, func() string {
+
+		confirmationMessage := fmt.Sprintf("Joining network %s (%d) into network %s (%d). Network %s (%d) will be deleted.  Are you sure? Type \"yes\" to continue:",
+			retNetworkToBeDeleted.NetworkLabel, retNetworkToBeDeleted.NetworkID,
+			retNetwork.NetworkLabel, retNetwork.NetworkID,
+			retNetworkToBeDeleted.NetworkLabel, retNetworkToBeDeleted.NetworkID)
+
+		//this is simply so that we don't output a text on the command line under go test
+		if strings.HasSuffix(os.Args[0], ".test") {
+			confirmationMessage = ""
+		}
+
+		return confirmationMessage
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if !confirm {
+		return "", fmt.Errorf("Operation not confirmed. Aborting")
+	}
+
+	return "", client.NetworkJoin(retNetwork.NetworkID, retNetworkToBeDeleted.NetworkID)
+}
+
+//networkToRow returns the common columns of the network tables
+func networkToRow(n metalcloud.Network) []interface{} {
+	label := n.NetworkLabel
+	if n.NetworkOperation != nil && n.NetworkOperation.NetworkLabel != "" {
+		label = n.NetworkOperation.NetworkLabel
+	}
+
+	return []interface{}{
+		n.NetworkID,
+		label,
+		n.NetworkType,
+		n.NetworkSubdomain,
+	}
+}
+
+func copyNetworkToOperation(n metalcloud.Network, no *metalcloud.NetworkOperation) {
+	no.NetworkID = n.NetworkID
+	no.NetworkLabel = n.NetworkLabel
+	no.NetworkSubdomain = n.NetworkSubdomain
+	no.NetworkType = n.NetworkType
+	no.InfrastructureID = n.InfrastructureID
+	no.NetworkLANAutoAllocateIPs = n.NetworkLANAutoAllocateIPs
+}
+
+//getNetworkFromCommand returns a Network object using the argument stored under the given key
+func getNetworkFromCommand(paramName string, argName string, c *Command, client interfaces.MetalCloudClient) (*metalcloud.Network, error) {
+
+	m, err := getParam(c, argName, paramName)
+	if err != nil {
+		return nil, err
+	}
+
+	id, label, isID := idOrLabel(m)
+
+	if isID {
+		return client.NetworkGet(id)
+	}
+
+	return client.NetworkGetByLabel(label)
+}
@@ -0,0 +1,357 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	metalcloud "github.com/bigstepinc/metal-cloud-sdk-go"
+	mock_metalcloud "github.com/bigstepinc/metalcloud-cli/helpers"
+	gomock "github.com/golang/mock/gomock"
+	. "github.com/onsi/gomega"
+)
+
+func TestNetworkCreateCmd(t *testing.T) {
+	RegisterTestingT(t)
+	ctrl := gomock.NewController(t)
+
+	infra := metalcloud.Infrastructure{
+		InfrastructureID:    10002,
+		InfrastructureLabel: "testinfra",
+	}
+
+	n := metalcloud.Network{
+		NetworkType:  "lan",
+		NetworkLabel: "testnet",
+	}
+
+	retN := n
+	retN.NetworkID = 120
+
+	client := mock_metalcloud.NewMockMetalCloudClient(ctrl)
+
+	client.EXPECT().
+		InfrastructureGet(infra.InfrastructureID).
+		Return(&infra, nil).
+		AnyTimes()
+
+	client.EXPECT().
+		NetworkCreate(infra.InfrastructureID, n).
+		Return(&retN, nil).
+		AnyTimes()
+
+	cases := []CommandTestCase{
+		{
+			name: "network-create-good1",
+			cmd: MakeCommand(map[string]interface{}{
+				"infrastructure_id_or_label": infra.InfrastructureID,
+				"network_type":               n.NetworkType,
+				"network_label":              n.NetworkLabel,
+			}),
+			good: true,
+			id:   retN.NetworkID,
+		},
+		{
+			name: "network-create-missing-type",
+			cmd: MakeCommand(map[string]interface{}{
+				"infrastructure_id_or_label": infra.InfrastructureID,
+				"network_label":              n.NetworkLabel,
+			}),
+			good: false,
+		},
+		{
+			name: "network-create-missing-infra",
+			cmd: MakeCommand(map[string]interface{}{
+				"network_type":  n.NetworkType,
+				"network_label": n.NetworkLabel,
+			}),
+			good: false,
+		},
+	}
+
+	testCreateCommand(networkCreateCmd, cases, client, t)
+}
+
+func TestNetworkListCmd(t *testing.T) {
+	RegisterTestingT(t)
+	ctrl := gomock.NewController(t)
+
+	infra := metalcloud.Infrastructure{
+		InfrastructureID:    10002,
+		InfrastructureLabel: "testinfra",
+	}
+
+	n := metalcloud.Network{
+		NetworkID:        120,
+		NetworkType:      "lan",
+		NetworkLabel:     "testnet",
+		InfrastructureID: infra.InfrastructureID,
+		NetworkOperation: &metalcloud.NetworkOperation{
+			NetworkID:    120,
+			NetworkLabel: "testnet-edited",
+			NetworkType:  "lan",
+		},
+	}
+
+	nList := map[string]metalcloud.Network{
+		n.NetworkLabel: n,
+	}
+
+	client := mock_metalcloud.NewMockMetalCloudClient(ctrl)
+
+	client.EXPECT().
+		InfrastructureGet(infra.InfrastructureID).
+		Return(&infra, nil).
+		AnyTimes()
+
+	client.EXPECT().
+		Networks(infra.InfrastructureID).
+		Return(&nList, nil).
+		AnyTimes()
+
+	format := "json"
+	cmd := Command{
+		Arguments: map[string]interface{}{
+			"infrastructure_id_or_label": &infra.InfrastructureID,
+			"format":                     &format,
+		},
+	}
+
+	ret, err := networkListCmd(&cmd, client)
+	Expect(err).To(BeNil())
+
+	var m []interface{}
+	err = json.Unmarshal([]byte(ret), &m)
+	Expect(err).To(BeNil())
+
+	r := m[0].(map[string]interface{})
+	Expect(int(r["ID"].(float64))).To(Equal(n.NetworkID))
+	Expect(r["LABEL"].(string)).To(Equal("testnet-edited"))
+	Expect(r["TYPE"].(string)).To(Equal("lan"))
+}
+
+func TestNetworkGetCmd(t *testing.T) {
+	RegisterTestingT(t)
+	ctrl := gomock.NewController(t)
+
+	n := metalcloud.Network{
+		NetworkID:        120,
+		NetworkType:      "wan",
+		NetworkLabel:     "testnet",
+		InfrastructureID: 10002,
+	}
+
+	client := mock_metalcloud.NewMockMetalCloudClient(ctrl)
+
+	client.EXPECT().
+		NetworkGet(n.NetworkID).
+		Return(&n, nil).
+		AnyTimes()
+
+	client.EXPECT().
+		NetworkGetByLabel(n.NetworkLabel).
+		Return(&n, nil).
+		AnyTimes()
+
+	cases := []CommandTestCase{
+		{
+			name: "network-get-id",
+			cmd: MakeCommand(map[string]interface{}{
+				"network_id_or_label": n.NetworkID,
+			}),
+			good: true,
+		},
+		{
+			name: "network-get-label",
+			cmd: MakeCommand(map[string]interface{}{
+				"network_id_or_label": n.NetworkLabel,
+			}),
+			good: true,
+		},
+		{
+			name: "network-get-missing-id",
+			cmd:  MakeEmptyCommand(),
+			good: false,
+		},
+	}
+
+	testGetCommand(networkGetCmd, cases, client, nil, t)
+}
+
+func TestNetworkEditCmd(t *testing.T) {
+	RegisterTestingT(t)
+	ctrl := gomock.NewController(t)
+
+	no := metalcloud.NetworkOperation{
+		NetworkID:                 120,
+		NetworkLabel:              "testnet",
+		NetworkType:               "lan",
+		NetworkLANAutoAllocateIPs: true,
+	}
+
+	n := metalcloud.Network{
+		NetworkID:        120,
+		NetworkType:      "lan",
+		NetworkLabel:     "testnet",
+		InfrastructureID: 10002,
+		NetworkOperation: &no,
+	}
+
+	client := mock_metalcloud.NewMockMetalCloudClient(ctrl)
+
+	client.EXPECT().
+		NetworkGet(n.NetworkID).
+		Return(&n, nil).
+		AnyTimes()
+
+	expectedOperation := no
+	expectedOperation.NetworkLabel = "newlabel"
+
+	client.EXPECT().
+		NetworkEdit(n.NetworkID, expectedOperation).
+		Return(&n, nil).
+		Times(1)
+
+	newLabel := "newlabel"
+	cmd := Command{
+		Arguments: map[string]interface{}{
+			"network_id_or_label": &n.NetworkID,
+			"network_label":       &newLabel,
+		},
+	}
+
+	ret, err := networkEditCmd(&cmd, client)
+	Expect(err).To(BeNil())
+	Expect(ret).To(BeEmpty())
+
+	//the automatic allocation of IPs is changed only when given
+	expectedOperation = no
+	expectedOperation.NetworkLANAutoAllocateIPs = false
+
+	client.EXPECT().
+		NetworkEdit(n.NetworkID, expectedOperation).
+		Return(&n, nil).
+		Times(1)
+
+	cmd = MakeCommand(map[string]interface{}{
+		"network_id_or_label":          120,
+		"network_label":                _nilDefaultStr,
+		"network_lan_autoallocate_ips": "false",
+	})
+
+	_, err = networkEditCmd(&cmd, client)
+	Expect(err).To(BeNil())
+
+	cmd = MakeCommand(map[string]interface{}{
+		"network_id_or_label":          120,
+		"network_lan_autoallocate_ips": "no",
+	})
+
+	_, err = networkEditCmd(&cmd, client)
+	Expect(err).NotTo(BeNil())
+}
+
+func TestNetworkDeleteCmd(t *testing.T) {
+	RegisterTestingT(t)
+	ctrl := gomock.NewController(t)
+
+	infra := metalcloud.Infrastructure{
+		InfrastructureID:    10002,
+		InfrastructureLabel: "testinfra",
+	}
+
+	n := metalcloud.Network{
+		NetworkID:        120,
+		NetworkType:      "lan",
+		NetworkLabel:     "testnet",
+		InfrastructureID: infra.InfrastructureID,
+	}
+
+	client := mock_metalcloud.NewMockMetalCloudClient(ctrl)
+
+	client.EXPECT().
+		InfrastructureGet(infra.InfrastructureID).
+		Return(&infra, nil).
+		AnyTimes()
+
+	client.EXPECT().
+		NetworkGet(n.NetworkID).
+		Return(&n, nil).
+		AnyTimes()
+
+	client.EXPECT().
+		NetworkDelete(n.NetworkID).
+		Return(nil).
+		Times(1)
+
+	cmd := Command{
+		Arguments: map[string]interface{}{
+			"network_id_or_label": &n.NetworkID,
+		},
+	}
+
+	//test without autoconfirm
+	_, err := networkDeleteCmd(&cmd, client)
+	Expect(err.Error()).To(Equal("Operation not confirmed. Aborting"))
+
+	bTrue := true
+	cmd.Arguments["autoconfirm"] = &bTrue
+
+	ret, err := networkDeleteCmd(&cmd, client)
+	Expect(err).To(BeNil())
+	Expect(ret).To(BeEmpty())
+}
+
+func TestNetworkJoinCmd(t *testing.T) {
+	RegisterTestingT(t)
+	ctrl := gomock.NewController(t)
+
+	n1 := metalcloud.Network{
+		NetworkID:    120,
+		NetworkType:  "lan",
+		NetworkLabel: "testnet1",
+	}
+
+	n2 := metalcloud.Network{
+		NetworkID:    121,
+		NetworkType:  "lan",
+		NetworkLabel: "testnet2",
+	}
+
+	client := mock_metalcloud.NewMockMetalCloudClient(ctrl)
+
+	client.EXPECT().
+		NetworkGet(n1.NetworkID).
+		Return(&n1, nil).
+		AnyTimes()
+
+	client.EXPECT().
+		NetworkGetByLabel(n2.NetworkLabel).
+		Return(&n2, nil).
+		AnyTimes()
+
+	client.EXPECT().
+		NetworkJoin(n1.NetworkID, n2.NetworkID).
+		Return(nil).
+		Times(1)
+
+	cmd := MakeCommand(map[string]interface{}{
+		"network_id_or_label":               fmt.Sprintf("%d", n1.NetworkID),
+		"network_to_be_deleted_id_or_label": n2.NetworkLabel,
+		"autoconfirm":                       true,
+	})
+
+	ret, err := networkJoinCmd(&cmd, client)
+	Expect(err).To(BeNil())
+	Expect(ret).To(BeEmpty())
+
+	//joining a network with itself should fail
+	cmd = MakeCommand(map[string]interface{}{
+		"network_id_or_label":               n1.NetworkID,
+		"network_to_be_deleted_id_or_label": n1.NetworkID,
+		"autoconfirm":                       true,
+	})
+
+	_, err = networkJoinCmd(&cmd, client)
+	Expect(err).NotTo(BeNil())
+}
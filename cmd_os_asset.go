@@ -22,12 +22,13 @@ var osAssetsCmds = []Command{
 		FlagSet:      flag.NewFlagSet("list secrets", flag.ExitOnError),
 		InitFunc: func(c *Command) {
 			c.Arguments = map[string]interface{}{
-				"format": c.FlagSet.String("format", _nilDefaultStr, "The output format. Supported values are 'json','csv'. The default format is human readable."),
-				"usage":  c.FlagSet.String("usage", _nilDefaultStr, "Asset's usage"),
+				"usage": c.FlagSet.String("usage", _nilDefaultStr, "Asset's usage"),
 			}
 		},
 		ExecuteFunc: assetsListCmd,
 		Endpoint:    ExtendedEndpoint,
+		Example:     "metalcloud-cli asset list -usage bootloader",
+		Formatted:   true,
 	},
 	{
 		Description:  "Create asset",
@@ -49,6 +50,7 @@ var osAssetsCmds = []Command{
 		},
 		ExecuteFunc: assetCreateCmd,
 		Endpoint:    ExtendedEndpoint,
+		Example:     "metalcloud-cli asset create -filename pxelinux.0 -usage bootloader -mime application/octet-stream -url https://repo.example.com/pxelinux.0",
 	},
 	{
 		Description:  "Delete asset",
@@ -413,6 +415,19 @@ func templateListAssociatedAssetsCmd(c *Command, client interfaces.MetalCloudCli
 		format = &f
 	}
 
+	TableSorter(schema).OrderBy(
+		schema[0].FieldName,
+		schema[1].FieldName).Sort(data)
+
+	data, schema, err = applyTableOptions(data, schema)
+	if err != nil {
+		return "", err
+	}
+
+	if ret, ok, err := getTableAsExpressionString(*format.(*string), data, schema); ok {
+		return ret, err
+	}
+
 	switch *format.(*string) {
 	case "json", "JSON":
 		ret, err := GetTableAsJSONString(data, schema)
@@ -426,19 +441,21 @@ func templateListAssociatedAssetsCmd(c *Command, client interfaces.MetalCloudCli
 			return "", err
 		}
 		sb.WriteString(ret)
+	case "yaml", "YAML":
+		ret, err := GetTableAsYAMLString(data, schema)
+		if err != nil {
+			return "", err
+		}
+		sb.WriteString(ret)
 
 	default:
 		sb.WriteString(fmt.Sprintf("Assets associated to template (%s #%d)\n", ret.VolumeTemplateLabel, ret.VolumeTemplateID))
 
-		TableSorter(schema).OrderBy(
-			schema[0].FieldName,
-			schema[1].FieldName).Sort(data)
-
 		AdjustFieldSizes(data, &schema)
 
 		sb.WriteString(GetTableAsString(data, schema))
 
-		sb.WriteString(fmt.Sprintf("Total: %d assets\n\n", len(*list)))
+		sb.WriteString(fmt.Sprintf("Total: %d assets\n\n", len(data)))
 	}
 
 	return sb.String(), nil
@@ -21,12 +21,13 @@ var osTemplatesCmds = []Command{
 		FlagSet:      flag.NewFlagSet("list templates", flag.ExitOnError),
 		InitFunc: func(c *Command) {
 			c.Arguments = map[string]interface{}{
-				"format": c.FlagSet.String("format", _nilDefaultStr, "The output format. Supported values are 'json','csv'. The default format is human readable."),
-				"usage":  c.FlagSet.String("usage", _nilDefaultStr, "Template's usage"),
+				"usage": c.FlagSet.String("usage", _nilDefaultStr, "Template's usage"),
 			}
 		},
 		ExecuteFunc: templatesListCmd,
 		Endpoint:    ExtendedEndpoint,
+		Example:     "metalcloud-cli os-template list",
+		Formatted:   true,
 	},
 	{
 		Description:  "Create template",
@@ -60,6 +61,7 @@ var osTemplatesCmds = []Command{
 		},
 		ExecuteFunc: templateCreateCmd,
 		Endpoint:    ExtendedEndpoint,
+		Example:     "metalcloud-cli os-template create -label centos7-9 -display-name \"CentOS 7.9\" -os-type CentOS -os-version 7.9 -os-architecture x86_64 -boot-type uefi_only -boot-methods-supported pxe_iscsi -initial-user root -initial-password p4ssw0rd -initial-ssh-port 22",
 	},
 	{
 		Description:  "Edit template",
@@ -103,12 +105,13 @@ var osTemplatesCmds = []Command{
 		InitFunc: func(c *Command) {
 			c.Arguments = map[string]interface{}{
 				"template_id_or_name": c.FlagSet.String("id", _nilDefaultStr, "Asset's id or name"),
-				"format":              c.FlagSet.String("format", _nilDefaultStr, "The output format. Supported values are 'json','csv'. The default format is human readable."),
 				"show_credentials":    c.FlagSet.Bool("show-credentials", false, "(Flag) If set returns the templates initial ssh credentials"),
 			}
 		},
 		ExecuteFunc: templateGetCmd,
 		Endpoint:    ExtendedEndpoint,
+		Example:     "metalcloud-cli os-template get -id centos7-9",
+		Formatted:   true,
 	},
 	{
 		Description:  "Delete template",
@@ -626,6 +629,19 @@ func templateGetCmd(c *Command, client interfaces.MetalCloudClient) (string, err
 		format = &f
 	}
 
+	TableSorter(schema).OrderBy(
+		schema[0].FieldName,
+		schema[1].FieldName).Sort(data)
+
+	data, schema, err = applyTableOptions(data, schema)
+	if err != nil {
+		return "", err
+	}
+
+	if ret, ok, err := getTableAsExpressionString(*format.(*string), data, schema); ok {
+		return ret, err
+	}
+
 	switch *format.(*string) {
 	case "json", "JSON":
 		ret, err := GetTableAsJSONString(data, schema)
@@ -639,14 +655,16 @@ func templateGetCmd(c *Command, client interfaces.MetalCloudClient) (string, err
 			return "", err
 		}
 		sb.WriteString(ret)
+	case "yaml", "YAML":
+		ret, err := GetTableAsYAMLString(data, schema)
+		if err != nil {
+			return "", err
+		}
+		sb.WriteString(ret)
 
 	default:
 		sb.WriteString(fmt.Sprintf("Template %s (%d)\n", template.VolumeTemplateLabel, template.VolumeTemplateID))
 
-		TableSorter(schema).OrderBy(
-			schema[0].FieldName,
-			schema[1].FieldName).Sort(data)
-
 		AdjustFieldSizes(data, &schema)
 
 		sb.WriteString(GetTableAsString(data, schema))
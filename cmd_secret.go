@@ -23,12 +23,13 @@ var secretsCmds = []Command{
 		FlagSet:      flag.NewFlagSet("list secrets", flag.ExitOnError),
 		InitFunc: func(c *Command) {
 			c.Arguments = map[string]interface{}{
-				"format": c.FlagSet.String("format", _nilDefaultStr, "The output format. Supported values are 'json','csv'. The default format is human readable."),
-				"usage":  c.FlagSet.String("usage", _nilDefaultStr, "Secret's usage"),
+				"usage": c.FlagSet.String("usage", _nilDefaultStr, "Secret's usage"),
 			}
 		},
 		ExecuteFunc: secretsListCmd,
 		Endpoint:    ExtendedEndpoint,
+		Example:     "metalcloud-cli secrets list",
+		Formatted:   true,
 	},
 	{
 		Description:  "Create secret",
@@ -47,6 +48,7 @@ var secretsCmds = []Command{
 		},
 		ExecuteFunc: secretCreateCmd,
 		Endpoint:    ExtendedEndpoint,
+		Example:     "cat password.txt | metalcloud-cli secret create -name db_password -pipe",
 	},
 	{
 		Description:  "Delete secret",
@@ -1,11 +1,16 @@
 package main
 
 import (
+	"encoding/json"
 	"flag"
 	"fmt"
+	"os"
+	"path/filepath"
 	"strings"
 
+	metalcloud "github.com/bigstepinc/metal-cloud-sdk-go"
 	interfaces "github.com/bigstepinc/metalcloud-cli/interfaces"
+	yaml "gopkg.in/yaml.v2"
 )
 
 var serversCmds = []Command{
@@ -19,13 +24,14 @@ var serversCmds = []Command{
 		FlagSet:      flag.NewFlagSet("list servers", flag.ExitOnError),
 		InitFunc: func(c *Command) {
 			c.Arguments = map[string]interface{}{
-				"format":           c.FlagSet.String("format", _nilDefaultStr, "The output format. Supported values are 'json','csv'. The default format is human readable."),
 				"filter":           c.FlagSet.String("filter", "*", "filter to use when searching for servers. Check the documentation for examples. Defaults to '*'"),
 				"show_credentials": c.FlagSet.Bool("show-credentials", false, "(Flag) If set returns the servers' IPMI credentials. (Slow for large queries)"),
 			}
 		},
 		ExecuteFunc: serversListCmd,
 		Endpoint:    DeveloperEndpoint,
+		Example:     "metalcloud-cli server list -format csv",
+		Formatted:   true,
 	},
 
 	{
@@ -38,12 +44,90 @@ var serversCmds = []Command{
 		InitFunc: func(c *Command) {
 			c.Arguments = map[string]interface{}{
 				"id":               c.FlagSet.Int("id", _nilDefaultInt, "Server's ID"),
-				"format":           c.FlagSet.String("format", _nilDefaultStr, "The output format. Supported values are 'json','csv'. The default format is human readable."),
 				"show_credentials": c.FlagSet.Bool("show-credentials", false, "(Flag) If set returns the servers' IPMI credentials"),
 			}
 		},
 		ExecuteFunc: serverGetCmd,
 		Endpoint:    DeveloperEndpoint,
+		Example:     "metalcloud-cli server get -id 100 -show-credentials",
+		Formatted:   true,
+	},
+
+	{
+		Description:  "Lists a server's components",
+		Subject:      "server",
+		AltSubject:   "srv",
+		Predicate:    "components",
+		AltPredicate: "comp",
+		FlagSet:      flag.NewFlagSet("list server components", flag.ExitOnError),
+		InitFunc: func(c *Command) {
+			c.Arguments = map[string]interface{}{
+				"server_id": c.FlagSet.Int("id", _nilDefaultInt, "(Required) Server's ID"),
+				"filter":    c.FlagSet.String("filter", "*", "filter to use when searching for components. Check the documentation for examples. Defaults to '*'"),
+			}
+		},
+		ExecuteFunc: serverComponentsListCmd,
+		Endpoint:    DeveloperEndpoint,
+		Formatted:   true,
+	},
+
+	{
+		Description:  "Sets the firmware target version of a server component",
+		Subject:      "server",
+		AltSubject:   "srv",
+		Predicate:    "firmware-set-target",
+		AltPredicate: "fw-target",
+		FlagSet:      flag.NewFlagSet("set server component firmware target version", flag.ExitOnError),
+		InitFunc: func(c *Command) {
+			c.Arguments = map[string]interface{}{
+				"server_id":           c.FlagSet.Int("id", _nilDefaultInt, "(Required) Server's ID"),
+				"server_component_id": c.FlagSet.Int("component-id", _nilDefaultInt, "(Required) Server component's ID"),
+				"version":             c.FlagSet.String("version", _nilDefaultStr, "(Required) The firmware version to which the component will be upgraded at the next upgrade session"),
+				"autoconfirm":         c.FlagSet.Bool("autoconfirm", false, "If true it does not ask for confirmation anymore"),
+			}
+		},
+		ExecuteFunc: serverFirmwareSetTargetCmd,
+		Endpoint:    DeveloperEndpoint,
+		Formatted:   true,
+	},
+
+	{
+		Description:  "Upgrades the firmware of a server or of a single server component",
+		Subject:      "server",
+		AltSubject:   "srv",
+		Predicate:    "firmware-upgrade",
+		AltPredicate: "fw-upgrade",
+		FlagSet:      flag.NewFlagSet("upgrade server firmware", flag.ExitOnError),
+		InitFunc: func(c *Command) {
+			c.Arguments = map[string]interface{}{
+				"server_id":           c.FlagSet.Int("id", _nilDefaultInt, "(Required) Server's ID"),
+				"server_component_id": c.FlagSet.Int("component-id", _nilDefaultInt, "Server component's ID. If set only this component is upgraded, otherwise an upgrade session upgrades all updateable components that have a target version set."),
+				"version":             c.FlagSet.String("version", _nilDefaultStr, "Firmware version to upgrade the component to. Only used together with -component-id. Defaults to the component's target version."),
+				"firmware_binary_url": c.FlagSet.String("url", _nilDefaultStr, "URL of the firmware binary. Only used together with -component-id. Defaults to the url registered for the version."),
+				"autoconfirm":         c.FlagSet.Bool("autoconfirm", false, "If true it does not ask for confirmation anymore"),
+			}
+		},
+		ExecuteFunc: serverFirmwareUpgradeCmd,
+		Endpoint:    DeveloperEndpoint,
+		Formatted:   true,
+	},
+
+	{
+		Description:  "Reports server components whose firmware does not match a baseline",
+		Subject:      "server",
+		AltSubject:   "srv",
+		Predicate:    "firmware-report",
+		AltPredicate: "fw-report",
+		FlagSet:      flag.NewFlagSet("server firmware compliance report", flag.ExitOnError),
+		InitFunc: func(c *Command) {
+			c.Arguments = map[string]interface{}{
+				"filter":   c.FlagSet.String("filter", "*", "filter to use when searching for servers. Check the documentation for examples. Defaults to '*'"),
+				"baseline": c.FlagSet.String("baseline", _nilDefaultStr, "(Required) Path to a YAML or JSON file containing a list of entries with the vendor, component and version keys."),
+			}
+		},
+		ExecuteFunc: serverFirmwareReportCmd,
+		Endpoint:    DeveloperEndpoint,
+		Formatted:   true,
 	},
 }
 
@@ -367,6 +451,15 @@ func serverGetCmd(c *Command, client interfaces.MetalCloudClient) (string, error
 		format = &f
 	}
 
+	data, schema, err = applyTableOptions(data, schema)
+	if err != nil {
+		return "", err
+	}
+
+	if ret, ok, err := getTableAsExpressionString(*format.(*string), data, schema); ok {
+		return ret, err
+	}
+
 	switch *format.(*string) {
 	case "json", "JSON":
 		ret, err := GetTableAsJSONString(data, schema)
@@ -380,6 +473,12 @@ func serverGetCmd(c *Command, client interfaces.MetalCloudClient) (string, error
 			return "", err
 		}
 		sb.WriteString(ret)
+	case "yaml", "YAML":
+		ret, err := GetTableAsYAMLString(data, schema)
+		if err != nil {
+			return "", err
+		}
+		sb.WriteString(ret)
 
 	default:
 		sb.WriteString("SERVER OVERVIEW\n")
@@ -414,3 +513,403 @@ func serverGetCmd(c *Command, client interfaces.MetalCloudClient) (string, error
 
 	return sb.String(), nil
 }
+
+var serverComponentSchema = []SchemaField{
+	{
+		FieldName: "ID",
+		FieldType: TypeInt,
+		FieldSize: 6,
+	},
+	{
+		FieldName: "NAME",
+		FieldType: TypeString,
+		FieldSize: 20,
+	},
+	{
+		FieldName: "TYPE",
+		FieldType: TypeString,
+		FieldSize: 10,
+	},
+	{
+		FieldName: "UPDATEABLE",
+		FieldType: TypeBool,
+		FieldSize: 5,
+	},
+	{
+		FieldName: "CURRENT_VERSION",
+		FieldType: TypeString,
+		FieldSize: 10,
+	},
+	{
+		FieldName: "AVAILABLE_VERSIONS",
+		FieldType: TypeString,
+		FieldSize: 10,
+	},
+	{
+		FieldName: "STATUS",
+		FieldType: TypeString,
+		FieldSize: 5,
+	},
+}
+
+func serverComponentsListCmd(c *Command, client interfaces.MetalCloudClient) (string, error) {
+
+	serverID, err := getParam(c, "server_id", "id")
+	if err != nil {
+		return "", err
+	}
+
+	list, err := client.ServerComponents(*serverID.(*int), getStringParam(c.Arguments["filter"]))
+	if err != nil {
+		return "", err
+	}
+
+	data := [][]interface{}{}
+	for _, sc := range *list {
+		data = append(data, serverComponentToRow(sc))
+	}
+
+	schema := make([]SchemaField, len(serverComponentSchema))
+	copy(schema, serverComponentSchema)
+
+	topLine := fmt.Sprintf("Components of server #%d:", *serverID.(*int))
+
+	return renderTable("Server components", topLine, getStringParam(c.Arguments["format"]), data, schema)
+}
+
+//getServerComponent returns a component of a server. The components of the server are searched as ServerComponentGet does not reliably return the server of a component.
+func getServerComponent(serverID int, serverComponentID int, client interfaces.MetalCloudClient) (*metalcloud.ServerComponent, error) {
+
+	list, err := client.ServerComponents(serverID, "*")
+	if err != nil {
+		return nil, err
+	}
+
+	for _, sc := range *list {
+		if sc.ServerComponentID == serverComponentID {
+			sc.ServerID = serverID
+			return &sc, nil
+		}
+	}
+
+	return nil, fmt.Errorf("Component #%d does not belong to server #%d", serverComponentID, serverID)
+}
+
+func serverFirmwareSetTargetCmd(c *Command, client interfaces.MetalCloudClient) (string, error) {
+
+	serverID, err := getParam(c, "server_id", "id")
+	if err != nil {
+		return "", err
+	}
+
+	componentID, err := getParam(c, "server_component_id", "component-id")
+	if err != nil {
+		return "", err
+	}
+
+	version, err := getParam(c, "version", "version")
+	if err != nil {
+		return "", err
+	}
+
+	sc, err := getServerComponent(*serverID.(*int), *componentID.(*int), client)
+	if err != nil {
+		return "", err
+	}
+
+	newVersion := *version.(*string)
+
+	confirm, err := confirmCommand(c, func() string {
+
+		confirmationMessage := fmt.Sprintf("Setting firmware target version of component %s (#%d) of server #%d from %s to %s. The upgrade will be applied at the next upgrade session. Are you sure? Type \"yes\" to continue:",
+			sc.ServerComponentName, sc.ServerComponentID,
+			sc.ServerID,
+			sc.ServerComponentFirmwareVersion,
+			newVersion)
+
+		//this is simply so that we don't output a text on the command line under go test
+		if strings.HasSuffix(os.Args[0], ".test") {
+			confirmationMessage = ""
+		}
+
+		return confirmationMessage
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if !confirm {
+		return "", fmt.Errorf("Operation not confirmed. Aborting")
+	}
+
+	err = client.ServerFirmwareComponentTargetVersionSet(sc.ServerComponentID, newVersion)
+	if err != nil {
+		return "", err
+	}
+
+	schema := make([]SchemaField, len(serverComponentSchema))
+	copy(schema, serverComponentSchema)
+
+	data := [][]interface{}{serverComponentToRow(*sc)}
+
+	topLine := fmt.Sprintf("Firmware target version of component %s (#%d) of server #%d set to %s:", sc.ServerComponentName, sc.ServerComponentID, sc.ServerID, newVersion)
+
+	return renderTable("Server components", topLine, getStringParam(c.Arguments["format"]), data, schema)
+}
+
+func serverFirmwareUpgradeCmd(c *Command, client interfaces.MetalCloudClient) (string, error) {
+
+	serverID, err := getParam(c, "server_id", "id")
+	if err != nil {
+		return "", err
+	}
+
+	components := []metalcloud.ServerComponent{}
+	version := ""
+	topLine := ""
+
+	componentID, singleComponent := getIntParamOk(c.Arguments["server_component_id"])
+	if singleComponent {
+
+		sc, err := getServerComponent(*serverID.(*int), componentID, client)
+		if err != nil {
+			return "", err
+		}
+
+		version = sc.ServerComponentFirmwareTargetVersion
+		if v, ok := getStringParamOk(c.Arguments["version"]); ok {
+			version = v
+		}
+
+		if version == "" {
+			return "", fmt.Errorf("Component #%d has no firmware target version. Use -version or set one with server firmware-set-target", componentID)
+		}
+
+		components = append(components, *sc)
+		topLine = fmt.Sprintf("Upgrading component %s (#%d) to version %s:", sc.ServerComponentName, sc.ServerComponentID, version)
+
+	} else {
+
+		if _, ok := getStringParamOk(c.Arguments["version"]); ok {
+			return "", fmt.Errorf("-version can only be used together with -component-id")
+		}
+
+		if _, ok := getStringParamOk(c.Arguments["firmware_binary_url"]); ok {
+			return "", fmt.Errorf("-url can only be used together with -component-id")
+		}
+
+		list, err := client.ServerComponents(*serverID.(*int), "*")
+		if err != nil {
+			return "", err
+		}
+
+		//the search does not return the target versions so the upgrade session decides which of these components are upgraded
+		for _, sc := range *list {
+			if sc.ServerComponentFirmwareUpdateable {
+				components = append(components, sc)
+			}
+		}
+
+		topLine = "Updateable components, the ones with a firmware target version will be upgraded:"
+	}
+
+	schema := make([]SchemaField, len(serverComponentSchema))
+	copy(schema, serverComponentSchema)
+
+	data := [][]interface{}{}
+	for _, sc := range components {
+		data = append(data, serverComponentToRow(sc))
+	}
+
+	confirm, err := confirmCommand(c, func() string {
+
+		table, _ := formatTable("Server components", topLine, "", data, schema)
+
+		confirmationMessage := fmt.Sprintf("%sUpgrading firmware of server #%d. This might reboot the server. Are you sure? Type \"yes\" to continue:",
+			table,
+			*serverID.(*int))
+
+		//this is simply so that we don't output a text on the command line under go test
+		if strings.HasSuffix(os.Args[0], ".test") {
+			confirmationMessage = ""
+		}
+
+		return confirmationMessage
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if !confirm {
+		return "", fmt.Errorf("Operation not confirmed. Aborting")
+	}
+
+	if singleComponent {
+		err = client.ServerFirmwareComponentUpgrade(
+			*serverID.(*int),
+			componentID,
+			version,
+			getStringParam(c.Arguments["firmware_binary_url"]))
+	} else {
+		err = client.ServerFirmwareUpgrade(*serverID.(*int))
+	}
+	if err != nil {
+		return "", err
+	}
+
+	return renderTable("Server components", fmt.Sprintf("Firmware upgrade session created for server #%d. %s", *serverID.(*int), topLine), getStringParam(c.Arguments["format"]), data, schema)
+}
+
+func serverComponentToRow(sc metalcloud.ServerComponent) []interface{} {
+	return []interface{}{
+		sc.ServerComponentID,
+		sc.ServerComponentName,
+		sc.ServerComponentType,
+		sc.ServerComponentFirmwareUpdateable,
+		sc.ServerComponentFirmwareVersion,
+		strings.Join(sc.ServerComponentFirmwareUpdateAvailableVersions, ","),
+		sc.ServerComponentFirmwareStatus,
+	}
+}
+
+//firmwareBaselineEntry is the required firmware version of a component model of a vendor
+type firmwareBaselineEntry struct {
+	Vendor    string `json:"vendor" yaml:"vendor"`
+	Component string `json:"component" yaml:"component"`
+	Version   string `json:"version" yaml:"version"`
+}
+
+func serverFirmwareReportCmd(c *Command, client interfaces.MetalCloudClient) (string, error) {
+
+	baselinePath, err := getParam(c, "baseline", "baseline")
+	if err != nil {
+		return "", err
+	}
+
+	baseline, err := loadFirmwareBaseline(*baselinePath.(*string))
+	if err != nil {
+		return "", err
+	}
+
+	servers, err := client.ServersSearch(getStringParam(c.Arguments["filter"]))
+	if err != nil {
+		return "", err
+	}
+
+	schema := []SchemaField{
+		{
+			FieldName: "SERVER_ID",
+			FieldType: TypeInt,
+			FieldSize: 6,
+		},
+		{
+			FieldName: "VENDOR",
+			FieldType: TypeString,
+			FieldSize: 5,
+		},
+		{
+			FieldName: "PRODUCT_NAME",
+			FieldType: TypeString,
+			FieldSize: 5,
+		},
+		{
+			FieldName: "COMPONENT_ID",
+			FieldType: TypeInt,
+			FieldSize: 6,
+		},
+		{
+			FieldName: "COMPONENT",
+			FieldType: TypeString,
+			FieldSize: 10,
+		},
+		{
+			FieldName: "CURRENT_VERSION",
+			FieldType: TypeString,
+			FieldSize: 10,
+		},
+		{
+			FieldName: "REQUIRED_VERSION",
+			FieldType: TypeString,
+			FieldSize: 10,
+		},
+	}
+
+	data := [][]interface{}{}
+	for _, s := range *servers {
+
+		components, err := client.ServerComponents(s.ServerID, "*")
+		if err != nil {
+			return "", err
+		}
+
+		for _, sc := range *components {
+
+			required, ok := getFirmwareBaselineVersion(baseline, s.ServerVendor, sc.ServerComponentName)
+			if !ok || sc.ServerComponentFirmwareVersion == required {
+				continue
+			}
+
+			data = append(data, []interface{}{
+				s.ServerID,
+				s.ServerVendor,
+				s.ServerProductName,
+				sc.ServerComponentID,
+				sc.ServerComponentName,
+				sc.ServerComponentFirmwareVersion,
+				required,
+			})
+		}
+	}
+
+	TableSorter(schema).OrderBy(schema[0].FieldName, schema[3].FieldName).Sort(data)
+
+	topLine := fmt.Sprintf("Components not matching the firmware baseline of %d servers:", len(*servers))
+
+	return renderTable("Non-compliant components", topLine, getStringParam(c.Arguments["format"]), data, schema)
+}
+
+//loadFirmwareBaseline reads a baseline file. Files with the .json extension are parsed as JSON, all others as YAML.
+func loadFirmwareBaseline(path string) ([]firmwareBaselineEntry, error) {
+
+	content, err := readInputFromFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	baseline := []firmwareBaselineEntry{}
+
+	if strings.ToLower(filepath.Ext(path)) == ".json" {
+		err = json.Unmarshal(content, &baseline)
+	} else {
+		err = yaml.Unmarshal(content, &baseline)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Could not parse baseline file %s: %v", path, err)
+	}
+
+	for i, e := range baseline {
+		if e.Component == "" || e.Version == "" {
+			return nil, fmt.Errorf("Baseline entry #%d must have both component and version set", i)
+		}
+	}
+
+	return baseline, nil
+}
+
+//getFirmwareBaselineVersion returns the required version of a component. Entries without a vendor apply to all vendors
+//unless an entry of the server's vendor exists for the component, wherever it is in the baseline.
+func getFirmwareBaselineVersion(baseline []firmwareBaselineEntry, vendor string, component string) (string, bool) {
+	version, found := "", false
+	for _, e := range baseline {
+		if !strings.EqualFold(e.Component, component) {
+			continue
+		}
+		if e.Vendor != "" && strings.EqualFold(e.Vendor, vendor) {
+			return e.Version, true
+		}
+		if e.Vendor == "" && !found {
+			version, found = e.Version, true
+		}
+	}
+	return version, found
+}
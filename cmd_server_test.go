@@ -4,6 +4,8 @@ import (
 	"encoding/csv"
 	"encoding/json"
 	"fmt"
+	"io/ioutil"
+	"os"
 	"strings"
 	"testing"
 
@@ -174,3 +176,374 @@ func TestServerGetCmd(t *testing.T) {
 	Expect(csv[1][5]).To(Equal("test"))
 
 }
+
+func TestServerComponentsListCmd(t *testing.T) {
+	RegisterTestingT(t)
+	ctrl := gomock.NewController(t)
+
+	sc := metalcloud.ServerComponent{
+		ServerComponentID:                              1001,
+		ServerID:                                       100,
+		ServerComponentName:                            "BIOS",
+		ServerComponentType:                            "bios",
+		ServerComponentFirmwareUpdateable:              true,
+		ServerComponentFirmwareVersion:                 "2.1",
+		ServerComponentFirmwareUpdateAvailableVersions: []string{"2.3", "2.4"},
+	}
+
+	list := []metalcloud.ServerComponent{sc}
+
+	client := mock_metalcloud.NewMockMetalCloudClient(ctrl)
+
+	client.EXPECT().
+		ServerComponents(sc.ServerID, "bios").
+		Return(&list, nil).
+		AnyTimes()
+
+	cmd := MakeCommand(map[string]interface{}{
+		"server_id": sc.ServerID,
+		"filter":    "bios",
+		"format":    "json",
+	})
+
+	ret, err := serverComponentsListCmd(&cmd, client)
+	Expect(err).To(BeNil())
+
+	var m []interface{}
+	err = json.Unmarshal([]byte(ret), &m)
+	Expect(err).To(BeNil())
+
+	r := m[0].(map[string]interface{})
+	Expect(int(r["ID"].(float64))).To(Equal(sc.ServerComponentID))
+	Expect(r["CURRENT_VERSION"].(string)).To(Equal("2.1"))
+	Expect(r).NotTo(HaveKey("TARGET_VERSION"))
+	Expect(r["AVAILABLE_VERSIONS"].(string)).To(Equal("2.3,2.4"))
+
+	cmd = MakeEmptyCommand()
+	_, err = serverComponentsListCmd(&cmd, client)
+	Expect(err).NotTo(BeNil())
+}
+
+func TestServerFirmwareSetTargetCmd(t *testing.T) {
+	RegisterTestingT(t)
+	ctrl := gomock.NewController(t)
+
+	//as with the API the search does not return the server of the component
+	sc := metalcloud.ServerComponent{
+		ServerComponentID:              1001,
+		ServerComponentName:            "BIOS",
+		ServerComponentFirmwareVersion: "2.1",
+	}
+
+	list := []metalcloud.ServerComponent{sc}
+
+	client := mock_metalcloud.NewMockMetalCloudClient(ctrl)
+
+	client.EXPECT().
+		ServerComponents(100, "*").
+		Return(&list, nil).
+		AnyTimes()
+
+	client.EXPECT().
+		ServerComponents(101, "*").
+		Return(&[]metalcloud.ServerComponent{}, nil).
+		AnyTimes()
+
+	client.EXPECT().
+		ServerFirmwareComponentTargetVersionSet(sc.ServerComponentID, "2.4").
+		Return(nil).
+		Times(1)
+
+	cmd := MakeCommand(map[string]interface{}{
+		"server_id":           100,
+		"server_component_id": sc.ServerComponentID,
+		"version":             "2.4",
+		"format":              "csv",
+	})
+
+	//test without autoconfirm
+	_, err := serverFirmwareSetTargetCmd(&cmd, client)
+	Expect(err.Error()).To(Equal("Operation not confirmed. Aborting"))
+
+	bTrue := true
+	cmd.Arguments["autoconfirm"] = &bTrue
+
+	ret, err := serverFirmwareSetTargetCmd(&cmd, client)
+	Expect(err).To(BeNil())
+
+	reader := csv.NewReader(strings.NewReader(ret))
+	rows, err := reader.ReadAll()
+	Expect(err).To(BeNil())
+	Expect(rows[1][1]).To(Equal("BIOS"))
+	Expect(rows[1][4]).To(Equal("2.1"))
+
+	//component of a different server
+	otherServerID := 101
+	cmd.Arguments["server_id"] = &otherServerID
+	_, err = serverFirmwareSetTargetCmd(&cmd, client)
+	Expect(err).NotTo(BeNil())
+	Expect(err.Error()).To(ContainSubstring("does not belong to server #101"))
+
+	//version is required
+	cmd = MakeCommand(map[string]interface{}{
+		"server_id":           100,
+		"server_component_id": sc.ServerComponentID,
+		"autoconfirm":         true,
+	})
+	_, err = serverFirmwareSetTargetCmd(&cmd, client)
+	Expect(err).NotTo(BeNil())
+}
+
+func TestServerFirmwareUpgradeCmd(t *testing.T) {
+	RegisterTestingT(t)
+	ctrl := gomock.NewController(t)
+
+	//as with the API the search does not return the target version nor the server of the components
+	sc1 := metalcloud.ServerComponent{
+		ServerComponentID:                 1001,
+		ServerComponentName:               "BIOS",
+		ServerComponentFirmwareUpdateable: true,
+		ServerComponentFirmwareVersion:    "2.1",
+	}
+
+	sc2 := metalcloud.ServerComponent{
+		ServerComponentID:              1002,
+		ServerComponentName:            "PSU",
+		ServerComponentFirmwareVersion: "1.0",
+	}
+
+	sc3 := metalcloud.ServerComponent{
+		ServerComponentID:                 1003,
+		ServerComponentName:               "NIC",
+		ServerComponentFirmwareUpdateable: true,
+		ServerComponentFirmwareVersion:    "3.0",
+	}
+
+	list := []metalcloud.ServerComponent{sc1, sc2, sc3}
+
+	client := mock_metalcloud.NewMockMetalCloudClient(ctrl)
+
+	client.EXPECT().
+		ServerComponents(100, "*").
+		Return(&list, nil).
+		AnyTimes()
+
+	client.EXPECT().
+		ServerComponents(101, "*").
+		Return(&[]metalcloud.ServerComponent{}, nil).
+		AnyTimes()
+
+	client.EXPECT().
+		ServerFirmwareUpgrade(100).
+		Return(nil).
+		Times(1)
+
+	client.EXPECT().
+		ServerFirmwareComponentUpgrade(100, sc1.ServerComponentID, "2.5", "").
+		Return(nil).
+		Times(1)
+
+	//whole server, the updateable components are reported and the upgrade session picks the ones with a target version
+	cmd := MakeCommand(map[string]interface{}{
+		"server_id":   100,
+		"format":      "json",
+		"autoconfirm": true,
+	})
+
+	ret, err := serverFirmwareUpgradeCmd(&cmd, client)
+	Expect(err).To(BeNil())
+
+	var m []interface{}
+	err = json.Unmarshal([]byte(ret), &m)
+	Expect(err).To(BeNil())
+	Expect(len(m)).To(Equal(2))
+
+	r := m[0].(map[string]interface{})
+	Expect(r["NAME"].(string)).To(Equal("BIOS"))
+	Expect(m[1].(map[string]interface{})["NAME"].(string)).To(Equal("NIC"))
+
+	//single component with explicit version
+	cmd = MakeCommand(map[string]interface{}{
+		"server_id":           100,
+		"server_component_id": sc1.ServerComponentID,
+		"version":             "2.5",
+		"format":              "json",
+		"autoconfirm":         true,
+	})
+
+	ret, err = serverFirmwareUpgradeCmd(&cmd, client)
+	Expect(err).To(BeNil())
+
+	err = json.Unmarshal([]byte(ret), &m)
+	Expect(err).To(BeNil())
+
+	r = m[0].(map[string]interface{})
+	Expect(r["CURRENT_VERSION"].(string)).To(Equal("2.1"))
+
+	//without -version the target version of the component is needed
+	cmd = MakeCommand(map[string]interface{}{
+		"server_id":           100,
+		"server_component_id": sc1.ServerComponentID,
+		"autoconfirm":         true,
+	})
+
+	_, err = serverFirmwareUpgradeCmd(&cmd, client)
+	Expect(err).NotTo(BeNil())
+	Expect(err.Error()).To(ContainSubstring("has no firmware target version"))
+
+	//component of a different server
+	cmd = MakeCommand(map[string]interface{}{
+		"server_id":           101,
+		"server_component_id": sc1.ServerComponentID,
+		"version":             "2.5",
+		"autoconfirm":         true,
+	})
+
+	_, err = serverFirmwareUpgradeCmd(&cmd, client)
+	Expect(err).NotTo(BeNil())
+
+	//without confirmation
+	cmd = MakeCommand(map[string]interface{}{
+		"server_id": 100,
+	})
+
+	_, err = serverFirmwareUpgradeCmd(&cmd, client)
+	Expect(err.Error()).To(Equal("Operation not confirmed. Aborting"))
+}
+
+func TestServerFirmwareReportCmd(t *testing.T) {
+	RegisterTestingT(t)
+	ctrl := gomock.NewController(t)
+
+	servers := []metalcloud.ServerSearchResult{
+		{
+			ServerID:     100,
+			ServerVendor: "Dell Inc.",
+		},
+		{
+			ServerID:     101,
+			ServerVendor: "HPE",
+		},
+	}
+
+	components100 := []metalcloud.ServerComponent{
+		{
+			ServerComponentID:              1001,
+			ServerComponentName:            "BIOS",
+			ServerComponentFirmwareVersion: "2.1",
+		},
+		{
+			ServerComponentID:              1002,
+			ServerComponentName:            "iDRAC",
+			ServerComponentFirmwareVersion: "4.0",
+		},
+	}
+
+	components101 := []metalcloud.ServerComponent{
+		{
+			ServerComponentID:              1011,
+			ServerComponentName:            "BIOS",
+			ServerComponentFirmwareVersion: "U30",
+		},
+	}
+
+	client := mock_metalcloud.NewMockMetalCloudClient(ctrl)
+
+	client.EXPECT().
+		ServersSearch("*").
+		Return(&servers, nil).
+		AnyTimes()
+
+	client.EXPECT().
+		ServerComponents(100, "*").
+		Return(&components100, nil).
+		AnyTimes()
+
+	client.EXPECT().
+		ServerComponents(101, "*").
+		Return(&components101, nil).
+		AnyTimes()
+
+	yamlBaseline := `
+- vendor: Dell Inc.
+  component: BIOS
+  version: "2.4"
+- vendor: dell inc.
+  component: idrac
+  version: "4.0"
+- component: BIOS
+  version: U30
+`
+
+	jsonBaseline := `[
+	{"vendor": "Dell Inc.", "component": "BIOS", "version": "2.4"},
+	{"vendor": "Dell Inc.", "component": "iDRAC", "version": "4.0"},
+	{"component": "BIOS", "version": "U30"}
+]`
+
+	for _, b := range []struct {
+		ext     string
+		content string
+	}{
+		{".yaml", yamlBaseline},
+		{".json", jsonBaseline},
+	} {
+		f, err := ioutil.TempFile("", "baseline-*"+b.ext)
+		Expect(err).To(BeNil())
+		defer os.Remove(f.Name())
+
+		_, err = f.WriteString(b.content)
+		Expect(err).To(BeNil())
+		f.Close()
+
+		cmd := MakeCommand(map[string]interface{}{
+			"filter":   "*",
+			"baseline": f.Name(),
+			"format":   "json",
+		})
+
+		ret, err := serverFirmwareReportCmd(&cmd, client)
+		Expect(err).To(BeNil())
+
+		var m []interface{}
+		err = json.Unmarshal([]byte(ret), &m)
+		Expect(err).To(BeNil())
+		Expect(len(m)).To(Equal(1))
+
+		r := m[0].(map[string]interface{})
+		Expect(int(r["SERVER_ID"].(float64))).To(Equal(100))
+		Expect(int(r["COMPONENT_ID"].(float64))).To(Equal(1001))
+		Expect(r["CURRENT_VERSION"].(string)).To(Equal("2.1"))
+		Expect(r["REQUIRED_VERSION"].(string)).To(Equal("2.4"))
+	}
+
+	//baseline is required
+	cmd := MakeCommand(map[string]interface{}{
+		"filter": "*",
+	})
+
+	_, err := serverFirmwareReportCmd(&cmd, client)
+	Expect(err).NotTo(BeNil())
+}
+
+func TestGetFirmwareBaselineVersion(t *testing.T) {
+	RegisterTestingT(t)
+
+	baseline := []firmwareBaselineEntry{
+		{Component: "BIOS", Version: "U30"},
+		{Vendor: "Dell Inc.", Component: "BIOS", Version: "2.4"},
+		{Vendor: "Dell Inc.", Component: "iDRAC", Version: "4.0"},
+	}
+
+	//the entry of the vendor is used even if an entry without vendor is listed first
+	v, ok := getFirmwareBaselineVersion(baseline, "Dell Inc.", "BIOS")
+	Expect(ok).To(BeTrue())
+	Expect(v).To(Equal("2.4"))
+
+	v, ok = getFirmwareBaselineVersion(baseline, "HPE", "bios")
+	Expect(ok).To(BeTrue())
+	Expect(v).To(Equal("U30"))
+
+	_, ok = getFirmwareBaselineVersion(baseline, "HPE", "iDRAC")
+	Expect(ok).To(BeFalse())
+}
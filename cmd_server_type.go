@@ -0,0 +1,284 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	metalcloud "github.com/bigstepinc/metal-cloud-sdk-go"
+	interfaces "github.com/bigstepinc/metalcloud-cli/interfaces"
+)
+
+//serverTypeCmds commands affecting server types
+var serverTypeCmds = []Command{
+
+	{
+		Description:  "Lists server types.",
+		Subject:      "server-type",
+		AltSubject:   "st",
+		Predicate:    "list",
+		AltPredicate: "ls",
+		FlagSet:      flag.NewFlagSet("list server types", flag.ExitOnError),
+		InitFunc: func(c *Command) {
+			c.Arguments = map[string]interface{}{
+				"datacenter":     c.FlagSet.String("datacenter", GetDatacenter(), "Server types' datacenter. Defaults to the default datacenter."),
+				"available_only": c.FlagSet.Bool("available-only", false, "(Flag) If set only server types with available servers are returned"),
+			}
+		},
+		ExecuteFunc: serverTypeListCmd,
+		Example:     "metalcloud-cli server-type list -available-only",
+		Formatted:   true,
+	},
+	{
+		Description:  "Get a server type.",
+		Subject:      "server-type",
+		AltSubject:   "st",
+		Predicate:    "get",
+		AltPredicate: "show",
+		FlagSet:      flag.NewFlagSet("get server type", flag.ExitOnError),
+		InitFunc: func(c *Command) {
+			c.Arguments = map[string]interface{}{
+				"server_type_id_or_label": c.FlagSet.String("id", _nilDefaultStr, "(Required) Server type's id or label."),
+			}
+		},
+		ExecuteFunc: serverTypeGetCmd,
+		Example:     "metalcloud-cli server-type get -id m-8-16-v2",
+		Formatted:   true,
+	},
+	{
+		Description:  "Lists server types matching a hardware configuration.",
+		Subject:      "server-type",
+		AltSubject:   "st",
+		Predicate:    "match",
+		AltPredicate: "find",
+		FlagSet:      flag.NewFlagSet("match server types", flag.ExitOnError),
+		InitFunc: func(c *Command) {
+			c.Arguments = map[string]interface{}{
+				"datacenter":                          c.FlagSet.String("datacenter", GetDatacenter(), "Server types' datacenter. Defaults to the default datacenter."),
+				"infrastructure_id_or_label":          c.FlagSet.String("infra", _nilDefaultStr, "Infrastructure's id or label. If set the number of available servers of each server type is also returned."),
+				"instance_array_instance_count":       c.FlagSet.Int("instance-count", _nilDefaultInt, "Desired instance count"),
+				"instance_array_ram_gbytes":           c.FlagSet.Int("ram", _nilDefaultInt, "Minimum RAM (GB)"),
+				"instance_array_processor_count":      c.FlagSet.Int("proc", _nilDefaultInt, "Minimum processor count"),
+				"instance_array_processor_core_mhz":   c.FlagSet.Int("proc-freq", _nilDefaultInt, "Minimum processor frequency (Mhz)"),
+				"instance_array_processor_core_count": c.FlagSet.Int("proc-core-count", _nilDefaultInt, "Minimum processor core count"),
+				"instance_array_disk_count":           c.FlagSet.Int("disks", _nilDefaultInt, "Minimum number of local drives"),
+				"instance_array_disk_size_mbytes":     c.FlagSet.Int("disk-size", _nilDefaultInt, "Minimum local disks' size in MB"),
+			}
+		},
+		ExecuteFunc: serverTypeMatchCmd,
+		Formatted:   true,
+	},
+}
+
+var serverTypeSchema = []SchemaField{
+	{
+		FieldName: "ID",
+		FieldType: TypeInt,
+		FieldSize: 6,
+	},
+	{
+		FieldName: "LABEL",
+		FieldType: TypeString,
+		FieldSize: 15,
+	},
+	{
+		FieldName: "NAME",
+		FieldType: TypeString,
+		FieldSize: 15,
+	},
+	{
+		FieldName: "RAM (GB)",
+		FieldType: TypeInt,
+		FieldSize: 6,
+	},
+	{
+		FieldName: "PROCESSOR",
+		FieldType: TypeString,
+		FieldSize: 20,
+	},
+	{
+		FieldName: "CORES",
+		FieldType: TypeInt,
+		FieldSize: 6,
+	},
+	{
+		FieldName: "DISKS",
+		FieldType: TypeString,
+		FieldSize: 10,
+	},
+	{
+		FieldName: "NETWORK (Mbps)",
+		FieldType: TypeInt,
+		FieldSize: 6,
+	},
+	{
+		FieldName: "CLASS",
+		FieldType: TypeString,
+		FieldSize: 6,
+	},
+}
+
+func serverTypeListCmd(c *Command, client interfaces.MetalCloudClient) (string, error) {
+
+	datacenter := getStringParam(c.Arguments["datacenter"])
+	if datacenter == "" {
+		datacenter = GetDatacenter()
+	}
+
+	list, err := client.ServerTypes(datacenter, getBoolParam(c.Arguments["available_only"]))
+	if err != nil {
+		return "", err
+	}
+
+	schema := make([]SchemaField, len(serverTypeSchema))
+	copy(schema, serverTypeSchema)
+
+	data := [][]interface{}{}
+	for _, st := range *list {
+		data = append(data, serverTypeToRow(st))
+	}
+
+	TableSorter(schema).OrderBy(schema[0].FieldName).Sort(data)
+
+	topLine := fmt.Sprintf("Server types in datacenter %s:", datacenter)
+
+	return renderTable("Server types", topLine, getStringParam(c.Arguments["format"]), data, schema)
+}
+
+func serverTypeGetCmd(c *Command, client interfaces.MetalCloudClient) (string, error) {
+
+	st, err := getServerTypeFromCommand("id", c, client)
+	if err != nil {
+		return "", err
+	}
+
+	schema := make([]SchemaField, len(serverTypeSchema))
+	copy(schema, serverTypeSchema)
+
+	schema = append(schema,
+		SchemaField{
+			FieldName: "EXPERIMENTAL",
+			FieldType: TypeBool,
+			FieldSize: 5,
+		})
+
+	row := serverTypeToRow(*st)
+	row = append(row, st.ServerTypeIsExperimental)
+
+	data := [][]interface{}{row}
+
+	topLine := fmt.Sprintf("Server type %s (#%d)", st.ServerTypeDisplayName, st.ServerTypeID)
+
+	return renderTransposedTable("server type", topLine, getStringParam(c.Arguments["format"]), data, schema)
+}
+
+func serverTypeMatchCmd(c *Command, client interfaces.MetalCloudClient) (string, error) {
+
+	datacenter := getStringParam(c.Arguments["datacenter"])
+	if datacenter == "" {
+		datacenter = GetDatacenter()
+	}
+
+	hc := argsToHardwareConfiguration(c.Arguments)
+
+	list, err := client.ServerTypesMatchHardwareConfiguration(datacenter, hc)
+	if err != nil {
+		return "", err
+	}
+
+	schema := make([]SchemaField, len(serverTypeSchema))
+	copy(schema, serverTypeSchema)
+
+	var available *map[string]metalcloud.ServerType
+	if _, ok := getStringParamOk(c.Arguments["infrastructure_id_or_label"]); ok {
+
+		infra, err := getInfrastructureFromCommand("infra", c, client)
+		if err != nil {
+			return "", err
+		}
+
+		available, err = client.ServerTypesMatches(infra.InfrastructureID, hc, nil, false)
+		if err != nil {
+			return "", err
+		}
+
+		schema = append(schema, SchemaField{
+			FieldName: "AVAILABLE",
+			FieldType: TypeInt,
+			FieldSize: 6,
+		})
+	}
+
+	data := [][]interface{}{}
+	for _, st := range *list {
+		row := serverTypeToRow(st)
+
+		if available != nil {
+			count := 0
+			if v, ok := (*available)[fmt.Sprintf("%d", st.ServerTypeID)]; ok {
+				count = v.ServerCount
+			}
+
+			if hc.InstanceArrayInstanceCount > 0 && count < hc.InstanceArrayInstanceCount {
+				continue
+			}
+
+			row = append(row, count)
+		}
+
+		data = append(data, row)
+	}
+
+	TableSorter(schema).OrderBy(schema[0].FieldName).Sort(data)
+
+	topLine := fmt.Sprintf("Server types in datacenter %s matching the requested configuration:", datacenter)
+
+	return renderTable("Server types", topLine, getStringParam(c.Arguments["format"]), data, schema)
+}
+
+func serverTypeToRow(st metalcloud.ServerType) []interface{} {
+
+	disks := ""
+	if st.ServerDiskCount > 0 {
+		disks = fmt.Sprintf("%d x %d GB %s", st.ServerDiskCount, st.ServerDiskSizeMBytes/1000, st.ServerDiskType)
+	}
+
+	return []interface{}{
+		st.ServerTypeID,
+		st.ServerTypeLabel,
+		st.ServerTypeDisplayName,
+		st.ServerRAMGbytes,
+		fmt.Sprintf("%d x %s", st.ServerProcessorCount, st.ServerProcessorName),
+		st.ServerProcessorCount * st.ServerProcessorCoreCount,
+		disks,
+		st.ServerNetworkTotalCapacityMBps,
+		st.ServerClass,
+	}
+}
+
+func argsToHardwareConfiguration(m map[string]interface{}) metalcloud.HardwareConfiguration {
+	return metalcloud.HardwareConfiguration{
+		InstanceArrayInstanceCount:      getIntParam(m["instance_array_instance_count"]),
+		InstanceArrayRAMGbytes:          getIntParam(m["instance_array_ram_gbytes"]),
+		InstanceArrayProcessorCount:     getIntParam(m["instance_array_processor_count"]),
+		InstanceArrayProcessorCoreMHZ:   getIntParam(m["instance_array_processor_core_mhz"]),
+		InstanceArrayProcessorCoreCount: getIntParam(m["instance_array_processor_core_count"]),
+		InstanceArrayDiskCount:          getIntParam(m["instance_array_disk_count"]),
+		InstanceArrayDiskSizeMBytes:     getIntParam(m["instance_array_disk_size_mbytes"]),
+	}
+}
+
+func getServerTypeFromCommand(paramName string, c *Command, client interfaces.MetalCloudClient) (*metalcloud.ServerType, error) {
+
+	m, err := getParam(c, "server_type_id_or_label", paramName)
+	if err != nil {
+		return nil, err
+	}
+
+	id, label, isID := idOrLabel(m)
+
+	if isID {
+		return client.ServerTypeGet(id)
+	}
+
+	return client.ServerTypeGetByLabel(label)
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	metalcloud "github.com/bigstepinc/metal-cloud-sdk-go"
+	mock_metalcloud "github.com/bigstepinc/metalcloud-cli/helpers"
+	gomock "github.com/golang/mock/gomock"
+	. "github.com/onsi/gomega"
+)
+
+func TestServerTypeListCmd(t *testing.T) {
+	RegisterTestingT(t)
+	ctrl := gomock.NewController(t)
+
+	st := metalcloud.ServerType{
+		ServerTypeID:             106,
+		ServerTypeLabel:          "M.40.256.12D",
+		ServerTypeDisplayName:    "M.40.256.12D",
+		ServerRAMGbytes:          256,
+		ServerProcessorCount:     2,
+		ServerProcessorCoreCount: 20,
+	}
+
+	list := map[int]metalcloud.ServerType{
+		st.ServerTypeID: st,
+	}
+
+	client := mock_metalcloud.NewMockMetalCloudClient(ctrl)
+
+	client.EXPECT().
+		ServerTypes("uk-reading", true).
+		Return(&list, nil).
+		AnyTimes()
+
+	cmd := MakeCommand(map[string]interface{}{
+		"datacenter":     "uk-reading",
+		"available_only": true,
+	})
+
+	expectedFirstRow := map[string]interface{}{
+		"ID":       st.ServerTypeID,
+		"LABEL":    st.ServerTypeLabel,
+		"RAM (GB)": st.ServerRAMGbytes,
+		"CORES":    40,
+	}
+
+	testListCommand(serverTypeListCmd, &cmd, client, expectedFirstRow, t)
+}
+
+func TestServerTypeGetCmd(t *testing.T) {
+	RegisterTestingT(t)
+	ctrl := gomock.NewController(t)
+
+	st := metalcloud.ServerType{
+		ServerTypeID:    106,
+		ServerTypeLabel: "M.40.256.12D",
+	}
+
+	client := mock_metalcloud.NewMockMetalCloudClient(ctrl)
+
+	client.EXPECT().
+		ServerTypeGet(st.ServerTypeID).
+		Return(&st, nil).
+		AnyTimes()
+
+	client.EXPECT().
+		ServerTypeGetByLabel(st.ServerTypeLabel).
+		Return(&st, nil).
+		AnyTimes()
+
+	cases := []CommandTestCase{
+		{
+			name: "st-get-id",
+			cmd: MakeCommand(map[string]interface{}{
+				"server_type_id_or_label": st.ServerTypeID,
+			}),
+			good: true,
+		},
+		{
+			name: "st-get-label",
+			cmd: MakeCommand(map[string]interface{}{
+				"server_type_id_or_label": st.ServerTypeLabel,
+			}),
+			good: true,
+		},
+		{
+			name: "st-get-missing-id",
+			cmd:  MakeEmptyCommand(),
+			good: false,
+		},
+	}
+
+	testGetCommand(serverTypeGetCmd, cases, client, nil, t)
+}
+
+func TestServerTypeMatchCmd(t *testing.T) {
+	RegisterTestingT(t)
+	ctrl := gomock.NewController(t)
+
+	infra := metalcloud.Infrastructure{
+		InfrastructureID: 10002,
+	}
+
+	st1 := metalcloud.ServerType{
+		ServerTypeID:    106,
+		ServerTypeLabel: "M.40.256.12D",
+		ServerRAMGbytes: 256,
+	}
+
+	st2 := metalcloud.ServerType{
+		ServerTypeID:    107,
+		ServerTypeLabel: "M.16.64.2",
+		ServerRAMGbytes: 64,
+	}
+
+	list := map[int]metalcloud.ServerType{
+		st1.ServerTypeID: st1,
+		st2.ServerTypeID: st2,
+	}
+
+	available := map[string]metalcloud.ServerType{
+		"106": {ServerTypeID: 106, ServerCount: 1},
+		"107": {ServerTypeID: 107, ServerCount: 5},
+	}
+
+	hc := metalcloud.HardwareConfiguration{
+		InstanceArrayRAMGbytes:          64,
+		InstanceArrayProcessorCoreCount: 16,
+		InstanceArrayDiskCount:          2,
+		InstanceArrayInstanceCount:      2,
+	}
+
+	client := mock_metalcloud.NewMockMetalCloudClient(ctrl)
+
+	client.EXPECT().
+		ServerTypesMatchHardwareConfiguration("uk-reading", hc).
+		Return(&list, nil).
+		AnyTimes()
+
+	client.EXPECT().
+		InfrastructureGet(infra.InfrastructureID).
+		Return(&infra, nil).
+		AnyTimes()
+
+	client.EXPECT().
+		ServerTypesMatches(infra.InfrastructureID, hc, nil, false).
+		Return(&available, nil).
+		AnyTimes()
+
+	cmd := MakeCommand(map[string]interface{}{
+		"datacenter":                          "uk-reading",
+		"instance_array_ram_gbytes":           64,
+		"instance_array_processor_core_count": 16,
+		"instance_array_disk_count":           2,
+		"instance_array_instance_count":       2,
+		"format":                              "json",
+	})
+
+	//without an infrastructure there is no availability information
+	ret, err := serverTypeMatchCmd(&cmd, client)
+	Expect(err).To(BeNil())
+
+	var m []interface{}
+	err = json.Unmarshal([]byte(ret), &m)
+	Expect(err).To(BeNil())
+	Expect(len(m)).To(Equal(2))
+
+	//with an infrastructure only server types with enough free servers are returned
+	infraID := fmt.Sprintf("%d", infra.InfrastructureID)
+	cmd.Arguments["infrastructure_id_or_label"] = &infraID
+
+	ret, err = serverTypeMatchCmd(&cmd, client)
+	Expect(err).To(BeNil())
+
+	err = json.Unmarshal([]byte(ret), &m)
+	Expect(err).To(BeNil())
+	Expect(len(m)).To(Equal(1))
+
+	r := m[0].(map[string]interface{})
+	Expect(r["LABEL"]).To(Equal(st2.ServerTypeLabel))
+	Expect(r["AVAILABLE"]).To(Equal(float64(5)))
+}
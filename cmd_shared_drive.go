@@ -0,0 +1,432 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	metalcloud "github.com/bigstepinc/metal-cloud-sdk-go"
+	interfaces "github.com/bigstepinc/metalcloud-cli/interfaces"
+)
+
+//sharedDriveCmds commands affecting shared drives
+var sharedDriveCmds = []Command{
+
+	{
+		Description:  "Creates a shared drive.",
+		Subject:      "shared-drive",
+		AltSubject:   "sd",
+		Predicate:    "create",
+		AltPredicate: "new",
+		FlagSet:      flag.NewFlagSet("create shared drive", flag.ExitOnError),
+		InitFunc: func(c *Command) {
+			c.Arguments = map[string]interface{}{
+				"infrastructure_id_or_label": c.FlagSet.String("infra", _nilDefaultStr, "(Required) Infrastructure's id or label. Note that the 'label' this be ambiguous in certain situations."),
+				"shared_drive_label":         c.FlagSet.String("label", _nilDefaultStr, "(Required) The label of the shared drive"),
+				"shared_drive_storage_type":  c.FlagSet.String("type", _nilDefaultStr, "Possible values: iscsi_ssd, iscsi_hdd"),
+				"shared_drive_size_mbytes":   c.FlagSet.Int("size", _nilDefaultInt, "(Optional, default = 40960) Shared drive's size in MBytes"),
+				"shared_drive_has_gfs":       c.FlagSet.Bool("gfs", false, "(Flag) If set the shared drive will be formatted with GFS"),
+				"instance_arrays":            c.FlagSet.String("ia", _nilDefaultStr, "Comma separated list of ids or labels of the instance arrays to attach the shared drive to"),
+				"return_id":                  c.FlagSet.Bool("return-id", false, "(Flag) If set will print the ID of the created shared drive. Useful for automating tasks."),
+			}
+		},
+		ExecuteFunc: sharedDriveCreateCmd,
+		Example:     "metalcloud-cli shared-drive create -infra complex-demo -label data -size 102400 -ia master",
+	},
+	{
+		Description:  "Get a shared drive.",
+		Subject:      "shared-drive",
+		AltSubject:   "sd",
+		Predicate:    "get",
+		AltPredicate: "show",
+		FlagSet:      flag.NewFlagSet("get shared drive", flag.ExitOnError),
+		InitFunc: func(c *Command) {
+			c.Arguments = map[string]interface{}{
+				"shared_drive_id_or_label": c.FlagSet.String("id", _nilDefaultStr, "(Required) Shared drive's id or label. Note that using the label can be ambiguous and is slower."),
+				"show_iscsi_credentials":   c.FlagSet.Bool("show-iscsi-credentials", false, "(Flag) If set returns the shared drive's iscsi credentials"),
+			}
+		},
+		ExecuteFunc: sharedDriveGetCmd,
+		Example:     "metalcloud-cli shared-drive get -id data",
+		Formatted:   true,
+	},
+	{
+		Description:  "Edit a shared drive.",
+		Subject:      "shared-drive",
+		AltSubject:   "sd",
+		Predicate:    "edit",
+		AltPredicate: "alter",
+		FlagSet:      flag.NewFlagSet("edit shared drive", flag.ExitOnError),
+		InitFunc: func(c *Command) {
+			c.Arguments = map[string]interface{}{
+				"shared_drive_id_or_label":  c.FlagSet.String("id", _nilDefaultStr, "(Required) Shared drive's id or label. Note that using the label can be ambiguous and is slower."),
+				"shared_drive_label":        c.FlagSet.String("label", _nilDefaultStr, "The new label of the shared drive"),
+				"shared_drive_storage_type": c.FlagSet.String("type", _nilDefaultStr, "Possible values: iscsi_ssd, iscsi_hdd"),
+				"shared_drive_size_mbytes":  c.FlagSet.Int("size", _nilDefaultInt, "Shared drive's size in MBytes"),
+				"instance_arrays":           c.FlagSet.String("ia", _nilDefaultStr, "Comma separated list of ids or labels of instance arrays. Replaces the current list of attached instance arrays."),
+			}
+		},
+		ExecuteFunc: sharedDriveEditCmd,
+	},
+	{
+		Description:  "Delete a shared drive.",
+		Subject:      "shared-drive",
+		AltSubject:   "sd",
+		Predicate:    "delete",
+		AltPredicate: "rm",
+		FlagSet:      flag.NewFlagSet("delete shared drive", flag.ExitOnError),
+		InitFunc: func(c *Command) {
+			c.Arguments = map[string]interface{}{
+				"shared_drive_id_or_label": c.FlagSet.String("id", _nilDefaultStr, "(Required) Shared drive's id or label. Note that using the label can be ambiguous and is slower."),
+				"autoconfirm":              c.FlagSet.Bool("autoconfirm", false, "If true it does not ask for confirmation anymore"),
+			}
+		},
+		ExecuteFunc: sharedDriveDeleteCmd,
+	},
+	{
+		Description:  "Attach a shared drive to an instance array.",
+		Subject:      "shared-drive",
+		AltSubject:   "sd",
+		Predicate:    "attach",
+		AltPredicate: "attach-ia",
+		FlagSet:      flag.NewFlagSet("attach shared drive", flag.ExitOnError),
+		InitFunc: func(c *Command) {
+			c.Arguments = map[string]interface{}{
+				"shared_drive_id_or_label":   c.FlagSet.String("id", _nilDefaultStr, "(Required) Shared drive's id or label. Note that using the label can be ambiguous and is slower."),
+				"instance_array_id_or_label": c.FlagSet.String("ia", _nilDefaultStr, "(Required) The id or label of the instance array to attach the shared drive to"),
+			}
+		},
+		ExecuteFunc: sharedDriveAttachCmd,
+	},
+	{
+		Description:  "Detach a shared drive from an instance array.",
+		Subject:      "shared-drive",
+		AltSubject:   "sd",
+		Predicate:    "detach",
+		AltPredicate: "detach-ia",
+		FlagSet:      flag.NewFlagSet("detach shared drive", flag.ExitOnError),
+		InitFunc: func(c *Command) {
+			c.Arguments = map[string]interface{}{
+				"shared_drive_id_or_label":   c.FlagSet.String("id", _nilDefaultStr, "(Required) Shared drive's id or label. Note that using the label can be ambiguous and is slower."),
+				"instance_array_id_or_label": c.FlagSet.String("ia", _nilDefaultStr, "(Required) The id or label of the instance array to detach the shared drive from"),
+			}
+		},
+		ExecuteFunc: sharedDriveDetachCmd,
+	},
+}
+
+func sharedDriveCreateCmd(c *Command, client interfaces.MetalCloudClient) (string, error) {
+
+	infra, err := getInfrastructureFromCommand("infra", c, client)
+	if err != nil {
+		return "", err
+	}
+
+	sd := metalcloud.SharedDrive{
+		SharedDriveLabel:       getStringParam(c.Arguments["shared_drive_label"]),
+		SharedDriveStorageType: getStringParam(c.Arguments["shared_drive_storage_type"]),
+		SharedDriveSizeMbytes:  getIntParam(c.Arguments["shared_drive_size_mbytes"]),
+		SharedDriveHasGFS:      getBoolParam(c.Arguments["shared_drive_has_gfs"]),
+	}
+
+	if sd.SharedDriveLabel == "" {
+		return "", fmt.Errorf("-label <shared_drive_label> is required")
+	}
+
+	if v, ok := getStringParamOk(c.Arguments["instance_arrays"]); ok {
+		iaIDs, err := getInstanceArrayIDsFromString(v, client)
+		if err != nil {
+			return "", err
+		}
+		sd.SharedDriveAttachedInstanceArrays = iaIDs
+	}
+
+	retSD, err := client.SharedDriveCreate(infra.InfrastructureID, sd)
+	if err != nil {
+		return "", err
+	}
+
+	if getBoolParam(c.Arguments["return_id"]) {
+		return fmt.Sprintf("%d", retSD.SharedDriveID), nil
+	}
+
+	return "", err
+}
+
+func sharedDriveGetCmd(c *Command, client interfaces.MetalCloudClient) (string, error) {
+
+	retSD, err := getSharedDriveFromCommand("id", c, client)
+	if err != nil {
+		return "", err
+	}
+
+	schema := []SchemaField{
+		{
+			FieldName: "ID",
+			FieldType: TypeInt,
+			FieldSize: 6,
+		},
+		{
+			FieldName: "LABEL",
+			FieldType: TypeString,
+			FieldSize: 30,
+		},
+		{
+			FieldName: "STATUS",
+			FieldType: TypeString,
+			FieldSize: 10,
+		},
+		{
+			FieldName: "SIZE (MB)",
+			FieldType: TypeInt,
+			FieldSize: 10,
+		},
+		{
+			FieldName: "TYPE",
+			FieldType: TypeString,
+			FieldSize: 10,
+		},
+		{
+			FieldName: "ATTACHED TO",
+			FieldType: TypeString,
+			FieldSize: 30,
+		},
+		{
+			FieldName: "SUBDOMAIN",
+			FieldType: TypeString,
+			FieldSize: 20,
+		},
+	}
+
+	attachedTo, err := sharedDriveAttachedInstanceArraysToString(retSD.SharedDriveOperation.SharedDriveAttachedInstanceArrays, client)
+	if err != nil {
+		return "", err
+	}
+
+	row := []interface{}{
+		retSD.SharedDriveID,
+		retSD.SharedDriveOperation.SharedDriveLabel,
+		sharedDriveStatus(*retSD),
+		retSD.SharedDriveOperation.SharedDriveSizeMbytes,
+		retSD.SharedDriveOperation.SharedDriveStorageType,
+		attachedTo,
+		retSD.SharedDriveSubdomain,
+	}
+
+	if getBoolParam(c.Arguments["show_iscsi_credentials"]) {
+		schema = append(schema, SchemaField{
+			FieldName: "CREDENTIALS",
+			FieldType: TypeString,
+			FieldSize: 5,
+		})
+
+		iscsi := retSD.SharedDriveCredentials.ISCSI
+		row = append(row, fmt.Sprintf("Target: %s Port:%d IQN:%s LUN ID:%d",
+			iscsi.StorageIPAddress,
+			iscsi.StoragePort,
+			iscsi.TargetIQN,
+			iscsi.LunID))
+	}
+
+	data := [][]interface{}{row}
+
+	topLine := fmt.Sprintf("Shared drive %s (#%d)", retSD.SharedDriveLabel, retSD.SharedDriveID)
+
+	return renderTransposedTable("shared drive", topLine, getStringParam(c.Arguments["format"]), data, schema)
+}
+
+func sharedDriveEditCmd(c *Command, client interfaces.MetalCloudClient) (string, error) {
+
+	retSD, err := getSharedDriveFromCommand("id", c, client)
+	if err != nil {
+		return "", err
+	}
+
+	sdo := retSD.SharedDriveOperation
+
+	updateIfStringParamSet(c.Arguments["shared_drive_label"], &sdo.SharedDriveLabel)
+	updateIfStringParamSet(c.Arguments["shared_drive_storage_type"], &sdo.SharedDriveStorageType)
+	updateIfIntParamSet(c.Arguments["shared_drive_size_mbytes"], &sdo.SharedDriveSizeMbytes)
+
+	if v, ok := getStringParamOk(c.Arguments["instance_arrays"]); ok {
+		iaIDs, err := getInstanceArrayIDsFromString(v, client)
+		if err != nil {
+			return "", err
+		}
+		sdo.SharedDriveAttachedInstanceArrays = iaIDs
+	}
+
+	_, err = client.SharedDriveEdit(retSD.SharedDriveID, sdo)
+
+	return "", err
+}
+
+func sharedDriveDeleteCmd(c *Command, client interfaces.MetalCloudClient) (string, error) {
+
+	retSD, err := getSharedDriveFromCommand("id", c, client)
+	if err != nil {
+		return "", err
+	}
+
+	retInfra, err := client.InfrastructureGet(retSD.InfrastructureID)
+	if err != nil {
+		return "", err
+	}
+
+	confirm, err := confirmCommand(c, func() string {
+
+		confirmationMessage := fmt.Sprintf("Deleting shared drive %s (%d) - from infrastructure %s (%d).  Are you sure? Type \"yes\" to continue:",
+			retSD.SharedDriveLabel, retSD.SharedDriveID,
+			retInfra.InfrastructureLabel, retInfra.InfrastructureID)
+
+		//this is simply so that we don't output a text on the command line under go test
+		if strings.HasSuffix(os.Args[0], ".test") {
+			confirmationMessage = ""
+		}
+
+		return confirmationMessage
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if !confirm {
+		return "", fmt.Errorf("Operation not confirmed. Aborting")
+	}
+
+	return "", client.SharedDriveDelete(retSD.SharedDriveID)
+}
+
+func sharedDriveAttachCmd(c *Command, client interfaces.MetalCloudClient) (string, error) {
+
+	retSD, err := getSharedDriveFromCommand("id", c, client)
+	if err != nil {
+		return "", err
+	}
+
+	retIA, err := getInstanceArrayFromCommand("ia", c, client)
+	if err != nil {
+		return "", err
+	}
+
+	sdo := retSD.SharedDriveOperation
+
+	for _, iaID := range sdo.SharedDriveAttachedInstanceArrays {
+		if iaID == retIA.InstanceArrayID {
+			return "", fmt.Errorf("shared drive %s (#%d) is already attached to instance array %s (#%d)",
+				retSD.SharedDriveLabel, retSD.SharedDriveID,
+				retIA.InstanceArrayLabel, retIA.InstanceArrayID)
+		}
+	}
+
+	sdo.SharedDriveAttachedInstanceArrays = append(sdo.SharedDriveAttachedInstanceArrays, retIA.InstanceArrayID)
+
+	_, err = client.SharedDriveEdit(retSD.SharedDriveID, sdo)
+
+	return "", err
+}
+
+func sharedDriveDetachCmd(c *Command, client interfaces.MetalCloudClient) (string, error) {
+
+	retSD, err := getSharedDriveFromCommand("id", c, client)
+	if err != nil {
+		return "", err
+	}
+
+	retIA, err := getInstanceArrayFromCommand("ia", c, client)
+	if err != nil {
+		return "", err
+	}
+
+	sdo := retSD.SharedDriveOperation
+
+	iaIDs := []int{}
+	for _, iaID := range sdo.SharedDriveAttachedInstanceArrays {
+		if iaID != retIA.InstanceArrayID {
+			iaIDs = append(iaIDs, iaID)
+		}
+	}
+
+	if len(iaIDs) == len(sdo.SharedDriveAttachedInstanceArrays) {
+		return "", fmt.Errorf("shared drive %s (#%d) is not attached to instance array %s (#%d)",
+			retSD.SharedDriveLabel, retSD.SharedDriveID,
+			retIA.InstanceArrayLabel, retIA.InstanceArrayID)
+	}
+
+	sdo.SharedDriveAttachedInstanceArrays = iaIDs
+
+	_, err = client.SharedDriveEdit(retSD.SharedDriveID, sdo)
+
+	return "", err
+}
+
+//sharedDriveStatus returns the service status, taking into account pending operations
+func sharedDriveStatus(sd metalcloud.SharedDrive) string {
+	status := sd.SharedDriveServiceStatus
+
+	if sd.SharedDriveServiceStatus != "ordered" && sd.SharedDriveOperation.SharedDriveDepoloyType == "edit" && sd.SharedDriveOperation.SharedDriveDeployStatus == "not_started" {
+		status = "edited"
+	}
+
+	if sd.SharedDriveServiceStatus != "ordered" && sd.SharedDriveOperation.SharedDriveDepoloyType == "delete" && sd.SharedDriveOperation.SharedDriveDeployStatus == "not_started" {
+		status = "marked for delete"
+	}
+
+	return status
+}
+
+func sharedDriveAttachedInstanceArraysToString(iaIDs []int, client interfaces.MetalCloudClient) (string, error) {
+	attached := []string{}
+	for _, iaID := range iaIDs {
+		ia, err := client.InstanceArrayGet(iaID)
+		if err != nil {
+			return "", err
+		}
+		attached = append(attached, fmt.Sprintf("%s (#%d)", ia.InstanceArrayLabel, ia.InstanceArrayID))
+	}
+	return strings.Join(attached, ", "), nil
+}
+
+//getInstanceArrayIDsFromString converts a comma separated list of instance array ids or labels into ids
+func getInstanceArrayIDsFromString(s string, client interfaces.MetalCloudClient) ([]int, error) {
+	iaIDs := []int{}
+
+	for _, v := range strings.Split(s, ",") {
+		v = strings.TrimSpace(v)
+		if v == "" {
+			continue
+		}
+
+		iaID, err := getIDOrDo(v, func(label string) (int, error) {
+			ia, err := client.InstanceArrayGetByLabel(label)
+			if err != nil {
+				return 0, err
+			}
+			return ia.InstanceArrayID, nil
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		iaIDs = append(iaIDs, iaID)
+	}
+
+	return iaIDs, nil
+}
+
+func getSharedDriveFromCommand(paramName string, c *Command, client interfaces.MetalCloudClient) (*metalcloud.SharedDrive, error) {
+
+	m, err := getParam(c, "shared_drive_id_or_label", paramName)
+	if err != nil {
+		return nil, err
+	}
+
+	id, label, isID := idOrLabel(m)
+
+	if isID {
+		return client.SharedDriveGet(id)
+	}
+
+	return client.SharedDriveGetByLabel(label)
+}
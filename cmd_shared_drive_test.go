@@ -0,0 +1,229 @@
+package main
+
+import (
+	"testing"
+
+	metalcloud "github.com/bigstepinc/metal-cloud-sdk-go"
+	mock_metalcloud "github.com/bigstepinc/metalcloud-cli/helpers"
+	gomock "github.com/golang/mock/gomock"
+	. "github.com/onsi/gomega"
+)
+
+func TestSharedDriveCreateCmd(t *testing.T) {
+	RegisterTestingT(t)
+	ctrl := gomock.NewController(t)
+
+	infra := metalcloud.Infrastructure{
+		InfrastructureID:    10002,
+		InfrastructureLabel: "testinfra",
+	}
+
+	ia := metalcloud.InstanceArray{
+		InstanceArrayID:    11,
+		InstanceArrayLabel: "testia",
+	}
+
+	sd := metalcloud.SharedDrive{
+		SharedDriveLabel:                  "testsd",
+		SharedDriveSizeMbytes:             2048,
+		SharedDriveAttachedInstanceArrays: []int{ia.InstanceArrayID, 12},
+	}
+
+	retSD := sd
+	retSD.SharedDriveID = 5033
+
+	client := mock_metalcloud.NewMockMetalCloudClient(ctrl)
+
+	client.EXPECT().
+		InfrastructureGet(infra.InfrastructureID).
+		Return(&infra, nil).
+		AnyTimes()
+
+	client.EXPECT().
+		InstanceArrayGetByLabel(ia.InstanceArrayLabel).
+		Return(&ia, nil).
+		AnyTimes()
+
+	client.EXPECT().
+		SharedDriveCreate(infra.InfrastructureID, sd).
+		Return(&retSD, nil).
+		AnyTimes()
+
+	cases := []CommandTestCase{
+		{
+			name: "sd-create-good1",
+			cmd: MakeCommand(map[string]interface{}{
+				"infrastructure_id_or_label": infra.InfrastructureID,
+				"shared_drive_label":         sd.SharedDriveLabel,
+				"shared_drive_size_mbytes":   sd.SharedDriveSizeMbytes,
+				"instance_arrays":            "testia, 12",
+			}),
+			good: true,
+			id:   retSD.SharedDriveID,
+		},
+		{
+			name: "sd-create-missing-label",
+			cmd: MakeCommand(map[string]interface{}{
+				"infrastructure_id_or_label": infra.InfrastructureID,
+			}),
+			good: false,
+		},
+	}
+
+	testCreateCommand(sharedDriveCreateCmd, cases, client, t)
+}
+
+func TestSharedDriveGetCmd(t *testing.T) {
+	RegisterTestingT(t)
+	ctrl := gomock.NewController(t)
+
+	ia := metalcloud.InstanceArray{
+		InstanceArrayID:    11,
+		InstanceArrayLabel: "testia",
+	}
+
+	sd := metalcloud.SharedDrive{
+		SharedDriveID:    5033,
+		SharedDriveLabel: "testsd",
+		SharedDriveOperation: metalcloud.SharedDriveOperation{
+			SharedDriveLabel:                  "testsd",
+			SharedDriveSizeMbytes:             2048,
+			SharedDriveAttachedInstanceArrays: []int{ia.InstanceArrayID},
+		},
+	}
+
+	client := mock_metalcloud.NewMockMetalCloudClient(ctrl)
+
+	client.EXPECT().
+		SharedDriveGet(sd.SharedDriveID).
+		Return(&sd, nil).
+		AnyTimes()
+
+	client.EXPECT().
+		InstanceArrayGet(ia.InstanceArrayID).
+		Return(&ia, nil).
+		AnyTimes()
+
+	cmd := MakeCommand(map[string]interface{}{
+		"shared_drive_id_or_label": sd.SharedDriveID,
+	})
+
+	ret, err := sharedDriveGetCmd(&cmd, client)
+	Expect(err).To(BeNil())
+	Expect(ret).To(ContainSubstring("testia (#11)"))
+
+	cmd = MakeCommand(map[string]interface{}{
+		"shared_drive_id_or_label": sd.SharedDriveID,
+		"format":                   "json",
+	})
+
+	ret, err = sharedDriveGetCmd(&cmd, client)
+	Expect(err).To(BeNil())
+	Expect(JSONFirstRowEquals(ret, map[string]interface{}{
+		"ID":          sd.SharedDriveID,
+		"LABEL":       sd.SharedDriveLabel,
+		"ATTACHED TO": "testia (#11)",
+	})).To(BeNil())
+}
+
+func TestSharedDriveAttachDetachCmd(t *testing.T) {
+	RegisterTestingT(t)
+	ctrl := gomock.NewController(t)
+
+	ia := metalcloud.InstanceArray{
+		InstanceArrayID:    11,
+		InstanceArrayLabel: "testia",
+	}
+
+	sdo := metalcloud.SharedDriveOperation{
+		SharedDriveID:                     5033,
+		SharedDriveLabel:                  "testsd",
+		SharedDriveAttachedInstanceArrays: []int{12},
+	}
+
+	sd := metalcloud.SharedDrive{
+		SharedDriveID:        5033,
+		SharedDriveLabel:     "testsd",
+		SharedDriveOperation: sdo,
+	}
+
+	client := mock_metalcloud.NewMockMetalCloudClient(ctrl)
+
+	client.EXPECT().
+		SharedDriveGetByLabel(sd.SharedDriveLabel).
+		Return(&sd, nil).
+		AnyTimes()
+
+	client.EXPECT().
+		InstanceArrayGet(ia.InstanceArrayID).
+		Return(&ia, nil).
+		AnyTimes()
+
+	expectedOperation := sdo
+	expectedOperation.SharedDriveAttachedInstanceArrays = []int{12, ia.InstanceArrayID}
+
+	client.EXPECT().
+		SharedDriveEdit(sd.SharedDriveID, expectedOperation).
+		Return(&sd, nil).
+		Times(1)
+
+	cmd := MakeCommand(map[string]interface{}{
+		"shared_drive_id_or_label":   sd.SharedDriveLabel,
+		"instance_array_id_or_label": ia.InstanceArrayID,
+	})
+
+	_, err := sharedDriveAttachCmd(&cmd, client)
+	Expect(err).To(BeNil())
+
+	//not attached, detach should fail
+	_, err = sharedDriveDetachCmd(&cmd, client)
+	Expect(err).NotTo(BeNil())
+}
+
+func TestSharedDriveDeleteCmd(t *testing.T) {
+	RegisterTestingT(t)
+	ctrl := gomock.NewController(t)
+
+	infra := metalcloud.Infrastructure{
+		InfrastructureID:    10002,
+		InfrastructureLabel: "testinfra",
+	}
+
+	sd := metalcloud.SharedDrive{
+		SharedDriveID:    5033,
+		SharedDriveLabel: "testsd",
+		InfrastructureID: infra.InfrastructureID,
+	}
+
+	client := mock_metalcloud.NewMockMetalCloudClient(ctrl)
+
+	client.EXPECT().
+		InfrastructureGet(infra.InfrastructureID).
+		Return(&infra, nil).
+		AnyTimes()
+
+	client.EXPECT().
+		SharedDriveGet(sd.SharedDriveID).
+		Return(&sd, nil).
+		AnyTimes()
+
+	client.EXPECT().
+		SharedDriveDelete(sd.SharedDriveID).
+		Return(nil).
+		Times(1)
+
+	cmd := MakeCommand(map[string]interface{}{
+		"shared_drive_id_or_label": sd.SharedDriveID,
+	})
+
+	//test without autoconfirm
+	_, err := sharedDriveDeleteCmd(&cmd, client)
+	Expect(err.Error()).To(Equal("Operation not confirmed. Aborting"))
+
+	bTrue := true
+	cmd.Arguments["autoconfirm"] = &bTrue
+
+	ret, err := sharedDriveDeleteCmd(&cmd, client)
+	Expect(err).To(BeNil())
+	Expect(ret).To(BeEmpty())
+}
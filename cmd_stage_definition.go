@@ -21,12 +21,12 @@ var stageDefinitionsCmds = []Command{
 		AltPredicate: "ls",
 		FlagSet:      flag.NewFlagSet("list stage definitions", flag.ExitOnError),
 		InitFunc: func(c *Command) {
-			c.Arguments = map[string]interface{}{
-				"format": c.FlagSet.String("format", _nilDefaultStr, "The output format. Supported values are 'json','csv'. The default format is human readable."),
-			}
+			c.Arguments = map[string]interface{}{}
 		},
 		ExecuteFunc: stageDefinitionsListCmd,
 		Endpoint:    ExtendedEndpoint,
+		Example:     "metalcloud-cli stage-definition list",
+		Formatted:   true,
 	},
 	{
 		Description:  "Create stage definition",
@@ -67,6 +67,7 @@ var stageDefinitionsCmds = []Command{
 		},
 		ExecuteFunc: stageDefinitionCreateCmd,
 		Endpoint:    ExtendedEndpoint,
+		Example:     "metalcloud-cli stage-definition create -label notify -title Notify -type HTTPRequest -http-request-method POST -http-request-url https://hooks.example.com/deployed",
 	},
 	{
 		Description:  "Delete stage definition",
@@ -465,6 +466,12 @@ func getStageDefinitionFromCommand(paramName string, c *Command, client interfac
 		return nil, err
 	}
 
+	return getStageDefinitionByIDOrLabel(v, client)
+}
+
+//getStageDefinitionByIDOrLabel returns a stage definition given a pointer to its id or label
+func getStageDefinitionByIDOrLabel(v interface{}, client interfaces.MetalCloudClient) (*metalcloud.StageDefinition, error) {
+
 	id, label, isID := idOrLabel(v)
 
 	if isID {
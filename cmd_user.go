@@ -0,0 +1,84 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	metalcloud "github.com/bigstepinc/metal-cloud-sdk-go"
+	interfaces "github.com/bigstepinc/metalcloud-cli/interfaces"
+)
+
+//userCmds commands affecting users
+var userCmds = []Command{
+
+	{
+		Description:  "Get user details.",
+		Subject:      "user",
+		AltSubject:   "usr",
+		Predicate:    "get",
+		AltPredicate: "show",
+		FlagSet:      flag.NewFlagSet("get user", flag.ExitOnError),
+		InitFunc: func(c *Command) {
+			c.Arguments = map[string]interface{}{
+				"user_id_or_email": c.FlagSet.String("id", _nilDefaultStr, "(Required) User's id or email."),
+			}
+		},
+		ExecuteFunc: userGetCmd,
+		Example:     "metalcloud-cli user get -id user@example.com",
+		Formatted:   true,
+	},
+}
+
+func userGetCmd(c *Command, client interfaces.MetalCloudClient) (string, error) {
+
+	user, err := getUserFromCommand("id", c, client)
+	if err != nil {
+		return "", err
+	}
+
+	schema := []SchemaField{
+		{
+			FieldName: "ID",
+			FieldType: TypeInt,
+			FieldSize: 6,
+		},
+		{
+			FieldName: "EMAIL",
+			FieldType: TypeString,
+			FieldSize: 20,
+		},
+		{
+			FieldName: "DISPLAY_NAME",
+			FieldType: TypeString,
+			FieldSize: 20,
+		},
+	}
+
+	data := [][]interface{}{
+		{
+			user.UserID,
+			user.UserEmail,
+			user.UserDisplayName,
+		},
+	}
+
+	topLine := fmt.Sprintf("User %s (#%d)", user.UserEmail, user.UserID)
+
+	return renderTransposedTable("user", topLine, getStringParam(c.Arguments["format"]), data, schema)
+}
+
+func getUserFromCommand(paramName string, c *Command, client interfaces.MetalCloudClient) (*metalcloud.User, error) {
+
+	m, err := getParam(c, "user_id_or_email", paramName)
+	if err != nil {
+		return nil, err
+	}
+
+	id, email, isID := idOrLabel(m)
+
+	if isID {
+		return client.UserGet(id)
+	}
+
+	return client.UserGetByEmail(email)
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"testing"
+
+	metalcloud "github.com/bigstepinc/metal-cloud-sdk-go"
+	mock_metalcloud "github.com/bigstepinc/metalcloud-cli/helpers"
+	gomock "github.com/golang/mock/gomock"
+	. "github.com/onsi/gomega"
+)
+
+func TestUserGetCmd(t *testing.T) {
+	RegisterTestingT(t)
+	ctrl := gomock.NewController(t)
+
+	user := metalcloud.User{
+		UserID:          1,
+		UserEmail:       "user@test.com",
+		UserDisplayName: "Test User",
+	}
+
+	client := mock_metalcloud.NewMockMetalCloudClient(ctrl)
+
+	client.EXPECT().
+		UserGet(user.UserID).
+		Return(&user, nil).
+		AnyTimes()
+
+	client.EXPECT().
+		UserGetByEmail(user.UserEmail).
+		Return(&user, nil).
+		AnyTimes()
+
+	cases := []CommandTestCase{
+		{
+			name: "user-get-id",
+			cmd: MakeCommand(map[string]interface{}{
+				"user_id_or_email": user.UserID,
+			}),
+			good: true,
+		},
+		{
+			name: "user-get-email",
+			cmd: MakeCommand(map[string]interface{}{
+				"user_id_or_email": user.UserEmail,
+			}),
+			good: true,
+		},
+		{
+			name: "user-get-missing-id",
+			cmd:  MakeEmptyCommand(),
+			good: false,
+		},
+	}
+
+	testGetCommand(userGetCmd, cases, client, map[string]interface{}{
+		"ID":    user.UserID,
+		"EMAIL": user.UserEmail,
+	}, t)
+}
@@ -22,12 +22,13 @@ var variablesCmds = []Command{
 		FlagSet:      flag.NewFlagSet("list variables", flag.ExitOnError),
 		InitFunc: func(c *Command) {
 			c.Arguments = map[string]interface{}{
-				"format": c.FlagSet.String("format", _nilDefaultStr, "The output format. Supported values are 'json','csv'. The default format is human readable."),
-				"usage":  c.FlagSet.String("usage", _nilDefaultStr, "Variable's usage"),
+				"usage": c.FlagSet.String("usage", _nilDefaultStr, "Variable's usage"),
 			}
 		},
 		ExecuteFunc: variablesListCmd,
 		Endpoint:    ExtendedEndpoint,
+		Example:     "metalcloud-cli variable list",
+		Formatted:   true,
 	},
 	{
 		Description:  "Create variable",
@@ -46,6 +47,7 @@ var variablesCmds = []Command{
 		},
 		ExecuteFunc: variableCreateCmd,
 		Endpoint:    ExtendedEndpoint,
+		Example:     "echo -n uk-reading | metalcloud-cli variable create -name region -pipe",
 	},
 	{
 		Description:  "Delete variable",
@@ -18,12 +18,12 @@ var versionCmds = []Command{
 		FlagSet:      flag.NewFlagSet("list variables", flag.ExitOnError),
 		InitFunc: func(c *Command) {
 			c.Arguments = map[string]interface{}{
-				"format": c.FlagSet.String("format", _nilDefaultStr, "The output format. Supported values are 'json','csv'. The default format is human readable."),
-				"usage":  c.FlagSet.String("usage", _nilDefaultStr, "Variable's usage"),
+				"usage": c.FlagSet.String("usage", _nilDefaultStr, "Variable's usage"),
 			}
 		},
 		ExecuteFunc: versionShowCmd,
 		Endpoint:    UserEndpoint,
+		Formatted:   true,
 	},
 }
 
@@ -19,13 +19,14 @@ var volumeTemplateyCmds = []Command{
 		FlagSet:      flag.NewFlagSet("list volume templates", flag.ExitOnError),
 		InitFunc: func(c *Command) {
 			c.Arguments = map[string]interface{}{
-				"format":     c.FlagSet.String("format", _nilDefaultStr, "The output format. Supported values are 'json','csv'. The default format is human readable."),
 				"local_only": c.FlagSet.Bool("local-only", false, "Show only templates that support local install"),
 				"pxe_only":   c.FlagSet.Bool("pxe-only", false, "Show only templates that support pxe booting"),
 			}
 		},
 		ExecuteFunc: volumeTemplatesListCmd,
 		Endpoint:    ExtendedEndpoint,
+		Example:     "metalcloud-cli volume-template list -local-only",
+		Formatted:   true,
 	},
 	{
 		Description:  "Create volume templates",
@@ -49,6 +50,7 @@ var volumeTemplateyCmds = []Command{
 		},
 		ExecuteFunc: volumeTemplateCreateCmd,
 		Endpoint:    ExtendedEndpoint,
+		Example:     "metalcloud-cli volume-template create -id 1234 -label centos7-6-custom -name \"CentOS 7.6 custom\" -description \"CentOS 7.6 with our packages\" -boot-type hybrid",
 	},
 }
 
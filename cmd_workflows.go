@@ -21,12 +21,13 @@ var workflowCmds = []Command{
 		FlagSet:      flag.NewFlagSet("list workflows", flag.ExitOnError),
 		InitFunc: func(c *Command) {
 			c.Arguments = map[string]interface{}{
-				"usage":  c.FlagSet.String("usage", _nilDefaultStr, "Workflow usage. One of infrastructure, network_equipment, server, free_standing, storage_pool, user, os_template"),
-				"format": c.FlagSet.String("format", _nilDefaultStr, "The output format. Supported values are 'json','csv'. The default format is human readable."),
+				"usage": c.FlagSet.String("usage", _nilDefaultStr, "Workflow usage. One of infrastructure, network_equipment, server, free_standing, storage_pool, user, os_template"),
 			}
 		},
 		ExecuteFunc: workflowsListCmd,
 		Endpoint:    ExtendedEndpoint,
+		Example:     "metalcloud-cli workflow list -usage infrastructure",
+		Formatted:   true,
 	},
 	{
 		Description:  "Get workflow details",
@@ -38,11 +39,12 @@ var workflowCmds = []Command{
 		InitFunc: func(c *Command) {
 			c.Arguments = map[string]interface{}{
 				"workflow_id_or_label": c.FlagSet.String("id", _nilDefaultStr, "Workflow's id or label."),
-				"format":               c.FlagSet.String("format", _nilDefaultStr, "The output format. Supported values are 'json','csv'. The default format is human readable."),
 			}
 		},
 		ExecuteFunc: workflowGetCmd,
 		Endpoint:    ExtendedEndpoint,
+		Example:     "metalcloud-cli workflow get -id deploy-web",
+		Formatted:   true,
 	},
 	{
 		Description:  "Create workflow ",
@@ -64,6 +66,7 @@ var workflowCmds = []Command{
 		},
 		ExecuteFunc: workflowCreateCmd,
 		Endpoint:    ExtendedEndpoint,
+		Example:     "metalcloud-cli workflow create -label deploy-web -title \"Deploy web\" -usage infrastructure",
 	},
 	{
 		Description:  "Delete a stage from a workflow",
@@ -26,6 +26,9 @@ type Command struct {
 	InitFunc     CommandInitFunc
 	ExecuteFunc  CommandExecuteFunc
 	Endpoint     string
+	Example      string
+	Hidden       bool
+	Formatted    bool
 }
 
 func sameCommand(a *Command, b *Command) bool {
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+//ConfigProfile holds the connection settings of a named profile
+type ConfigProfile struct {
+	Endpoint   string `yaml:"endpoint,omitempty"`
+	User       string `yaml:"user,omitempty"`
+	APIKey     string `yaml:"api_key,omitempty"`
+	Datacenter string `yaml:"datacenter,omitempty"`
+	Admin      bool   `yaml:"admin,omitempty"`
+}
+
+//Config is the content of the configuration file
+type Config struct {
+	CurrentProfile string                   `yaml:"current_profile,omitempty"`
+	Profiles       map[string]ConfigProfile `yaml:"profiles,omitempty"`
+}
+
+//GetConfigFilePath returns the path of the configuration file. Defaults to ~/.metalcloud/config.yaml
+func GetConfigFilePath() string {
+	if v := os.Getenv("METALCLOUD_CONFIG_FILE"); v != "" {
+		return v
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		home = "."
+	}
+
+	return filepath.Join(home, ".metalcloud", "config.yaml")
+}
+
+//loadConfig reads the configuration file. A missing file results in an empty configuration.
+func loadConfig(path string) (*Config, error) {
+
+	config := Config{
+		Profiles: map[string]ConfigProfile{},
+	}
+
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return &config, nil
+		}
+		return nil, err
+	}
+
+	err = yaml.Unmarshal(content, &config)
+	if err != nil {
+		return nil, fmt.Errorf("Could not parse configuration file %s: %v", path, err)
+	}
+
+	if config.Profiles == nil {
+		config.Profiles = map[string]ConfigProfile{}
+	}
+
+	return &config, nil
+}
+
+//saveConfig writes the configuration file. The file holds API keys so it is only readable by the owner.
+func saveConfig(path string, config *Config) error {
+
+	content, err := yaml.Marshal(config)
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(filepath.Dir(path), 0700)
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(path, content, 0600)
+}
+
+//loadProfile sets the environment variables that are not already set from the selected profile.
+//The profile is the one given as argument, the one in METALCLOUD_PROFILE or the configuration file's current profile, in this order.
+func loadProfile(profileName string) error {
+
+	config, err := loadConfig(GetConfigFilePath())
+	if err != nil {
+		return err
+	}
+
+	if profileName == "" {
+		profileName = os.Getenv("METALCLOUD_PROFILE")
+	}
+
+	if profileName == "" {
+		profileName = config.CurrentProfile
+	}
+
+	if profileName == "" {
+		return nil
+	}
+
+	profile, ok := config.Profiles[profileName]
+	if !ok {
+		return fmt.Errorf("Profile %s not found in %s", profileName, GetConfigFilePath())
+	}
+
+	setEnvIfNotSet("METALCLOUD_ENDPOINT", profile.Endpoint)
+	setEnvIfNotSet("METALCLOUD_USER_EMAIL", profile.User)
+	setEnvIfNotSet("METALCLOUD_API_KEY", profile.APIKey)
+	setEnvIfNotSet("METALCLOUD_DATACENTER", profile.Datacenter)
+	if profile.Admin {
+		setEnvIfNotSet("METALCLOUD_ADMIN", "true")
+	}
+
+	return nil
+}
+
+func setEnvIfNotSet(key string, value string) {
+	if _, ok := os.LookupEnv(key); !ok && value != "" {
+		os.Setenv(key, value)
+	}
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestLoadProfile(t *testing.T) {
+	RegisterTestingT(t)
+
+	envs := []string{
+		"METALCLOUD_USER_EMAIL",
+		"METALCLOUD_API_KEY",
+		"METALCLOUD_ENDPOINT",
+		"METALCLOUD_ADMIN",
+		"METALCLOUD_DATACENTER",
+		"METALCLOUD_PROFILE",
+		"METALCLOUD_CONFIG_FILE",
+	}
+
+	//remember the current env values, clear them during the test
+	currentEnvVals := map[string]string{}
+	for _, e := range envs {
+		if v, ok := os.LookupEnv(e); ok {
+			currentEnvVals[e] = v
+		}
+		os.Unsetenv(e)
+	}
+
+	dir, err := ioutil.TempDir("", "metalcloud-config")
+	Expect(err).To(BeNil())
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "config.yaml")
+	os.Setenv("METALCLOUD_CONFIG_FILE", path)
+
+	//a missing file is not an error
+	Expect(loadProfile("")).To(BeNil())
+
+	content := `
+current_profile: staging
+profiles:
+  staging:
+    endpoint: https://staging.example.com
+    user: staging@example.com
+    api_key: "1:staging"
+    datacenter: dc-staging
+  production:
+    endpoint: https://production.example.com
+    user: production@example.com
+    api_key: "1:production"
+    datacenter: dc-production
+    admin: true
+`
+	err = ioutil.WriteFile(path, []byte(content), 0600)
+	Expect(err).To(BeNil())
+
+	//the current profile is used by default
+	Expect(loadProfile("")).To(BeNil())
+	Expect(os.Getenv("METALCLOUD_ENDPOINT")).To(Equal("https://staging.example.com"))
+	Expect(os.Getenv("METALCLOUD_USER_EMAIL")).To(Equal("staging@example.com"))
+	Expect(os.Getenv("METALCLOUD_ADMIN")).To(Equal(""))
+
+	for _, e := range envs[:5] {
+		os.Unsetenv(e)
+	}
+
+	//METALCLOUD_PROFILE overrides the current profile and environment variables override the profile
+	os.Setenv("METALCLOUD_PROFILE", "production")
+	os.Setenv("METALCLOUD_DATACENTER", "dc-override")
+
+	Expect(loadProfile("")).To(BeNil())
+	Expect(os.Getenv("METALCLOUD_ENDPOINT")).To(Equal("https://production.example.com"))
+	Expect(os.Getenv("METALCLOUD_API_KEY")).To(Equal("1:production"))
+	Expect(os.Getenv("METALCLOUD_DATACENTER")).To(Equal("dc-override"))
+	Expect(os.Getenv("METALCLOUD_ADMIN")).To(Equal("true"))
+
+	//the -profile argument overrides METALCLOUD_PROFILE
+	for _, e := range envs[:5] {
+		os.Unsetenv(e)
+	}
+
+	Expect(loadProfile("staging")).To(BeNil())
+	Expect(os.Getenv("METALCLOUD_ENDPOINT")).To(Equal("https://staging.example.com"))
+
+	Expect(loadProfile("missing")).NotTo(BeNil())
+
+	//put back the env values
+	for _, e := range envs {
+		os.Unsetenv(e)
+	}
+	for k, v := range currentEnvVals {
+		os.Setenv(k, v)
+	}
+}
@@ -0,0 +1,419 @@
+//Package fake provides an in-memory implementation of the MetalCloudClient interface.
+//It keeps infrastructures, instance arrays, drive arrays and the other objects of the API in memory,
+//allocating IDs and resolving labels the way the API does, so that commands can be chained in tests
+//or run without a network connection. The client is not safe for concurrent use.
+package fake
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	metalcloud "github.com/bigstepinc/metal-cloud-sdk-go"
+	interfaces "github.com/bigstepinc/metalcloud-cli/interfaces"
+)
+
+//EndpointScheme is the scheme of the METALCLOUD_ENDPOINT values served by the fake client
+const EndpointScheme = "fake://"
+
+//DefaultLimits are the user limits returned for every infrastructure, named as the limits compared with the usage by infrastructure limits
+var DefaultLimits = map[string]interface{}{
+	"instance_arrays_max": 20,
+	"instances_max":       50,
+	"drive_arrays_max":    40,
+	"drives_max":          100,
+	"drive_gbytes_max":    10240,
+}
+
+//state holds all the objects of the fake client. It is saved as json when the client is backed by a file.
+type state struct {
+	NextID int `json:"next_id"`
+
+	Users                    map[int]metalcloud.User                             `json:"users"`
+	Datacenters              map[string]metalcloud.Datacenter                    `json:"datacenters"`
+	DatacenterConfigs        map[string]metalcloud.DatacenterConfig              `json:"datacenter_configs"`
+	ServerTypes              map[int]metalcloud.ServerType                       `json:"server_types"`
+	Servers                  map[int]metalcloud.Server                           `json:"servers"`
+	ServerComponents         map[int]metalcloud.ServerComponent                  `json:"server_components"`
+	VolumeTemplates          map[int]metalcloud.VolumeTemplate                   `json:"volume_templates"`
+	OSTemplates              map[int]metalcloud.OSTemplate                       `json:"os_templates"`
+	OSTemplateAssets         map[int]map[int]metalcloud.OSTemplateOSAssetData    `json:"os_template_assets"`
+	OSAssets                 map[int]metalcloud.OSAsset                          `json:"os_assets"`
+	Secrets                  map[int]metalcloud.Secret                           `json:"secrets"`
+	Variables                map[int]metalcloud.Variable                         `json:"variables"`
+	StageDefinitions         map[int]metalcloud.StageDefinition                  `json:"stage_definitions"`
+	Workflows                map[int]metalcloud.Workflow                         `json:"workflows"`
+	WorkflowStages           map[int]metalcloud.WorkflowStageDefinitionReference `json:"workflow_stages"`
+	Infrastructures          map[int]metalcloud.Infrastructure                   `json:"infrastructures"`
+	CustomStages             map[int]metalcloud.WorkflowStageAssociation         `json:"custom_stages"`
+	InstanceArrays           map[int]metalcloud.InstanceArray                    `json:"instance_arrays"`
+	InstanceArrayServerTypes map[int]int                                         `json:"instance_array_server_types"`
+	Instances                instances                                           `json:"instances"`
+	PowerStatus              map[int]string                                      `json:"power_status"`
+	DriveArrays              map[int]metalcloud.DriveArray                       `json:"drive_arrays"`
+	Drives                   map[int]metalcloud.Drive                            `json:"drives"`
+	Snapshots                map[int]metalcloud.Snapshot                         `json:"snapshots"`
+	Networks                 map[int]metalcloud.Network                          `json:"networks"`
+	SharedDrives             map[int]metalcloud.SharedDrive                      `json:"shared_drives"`
+	Limits                   map[string]interface{}                              `json:"limits"`
+}
+
+//instances are saved without their credentials as the SDK cannot read back the json it produces for them
+type instances map[int]metalcloud.Instance
+
+//UnmarshalJSON reads the instances ignoring their credentials
+func (m *instances) UnmarshalJSON(b []byte) error {
+
+	raw := map[int]map[string]json.RawMessage{}
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return err
+	}
+
+	*m = instances{}
+	for id, fields := range raw {
+		delete(fields, "instance_credentials")
+
+		content, err := json.Marshal(fields)
+		if err != nil {
+			return err
+		}
+
+		i := metalcloud.Instance{}
+		if err := json.Unmarshal(content, &i); err != nil {
+			return err
+		}
+		(*m)[id] = i
+	}
+
+	return nil
+}
+
+//Client is an in-memory MetalCloudClient
+type Client struct {
+	userID    int
+	userEmail string
+	endpoint  string
+	path      string
+	state     state
+}
+
+var _ interfaces.MetalCloudClient = (*Client)(nil)
+
+//NewClient returns an empty client owned by the given user. The datacenter, two server types and
+//two volume templates are created so that infrastructures can be designed and deployed straight away.
+func NewClient(userEmail string, datacenter string) *Client {
+
+	c := &Client{
+		userEmail: userEmail,
+		endpoint:  EndpointScheme,
+		state:     newState(),
+	}
+
+	c.seed(datacenter)
+
+	return c
+}
+
+//NewClientFromFile returns a client whose objects are loaded from and saved to the given json file
+//after every change, so that consecutive runs of the CLI share them. The file is created if missing.
+func NewClientFromFile(path string, userEmail string, datacenter string) (*Client, error) {
+
+	c := NewClient(userEmail, datacenter)
+	c.path = path
+	c.endpoint = EndpointScheme + path
+
+	content, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return c, c.save()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	s := newState()
+	err = json.Unmarshal(content, &s)
+	if err != nil {
+		return nil, fmt.Errorf("Could not read the state of the fake client from %s: %v", path, err)
+	}
+	c.state = s
+
+	//the user is looked up again as the email might differ from the one used to create the file
+	c.userID = 0
+	for _, u := range c.state.Users {
+		if u.UserEmail == userEmail {
+			c.userID = u.UserID
+		}
+	}
+	if c.userID == 0 {
+		c.userID = c.addUser(userEmail)
+		return c, c.save()
+	}
+
+	return c, nil
+}
+
+//NewClientFromEndpoint returns the client for an endpoint such as fake:// (in memory only) or fake:///tmp/state.json
+func NewClientFromEndpoint(endpoint string, userEmail string, datacenter string) (interfaces.MetalCloudClient, error) {
+
+	if !IsFakeEndpoint(endpoint) {
+		return nil, fmt.Errorf("%s is not a fake endpoint", endpoint)
+	}
+
+	path := strings.TrimPrefix(endpoint, EndpointScheme)
+	if path == "" {
+		return NewClient(userEmail, datacenter), nil
+	}
+
+	return NewClientFromFile(path, userEmail, datacenter)
+}
+
+//IsFakeEndpoint returns true if the endpoint should be served by the fake client
+func IsFakeEndpoint(endpoint string) bool {
+	return strings.HasPrefix(endpoint, EndpointScheme)
+}
+
+func newState() state {
+	return state{
+		NextID:                   1,
+		Users:                    map[int]metalcloud.User{},
+		Datacenters:              map[string]metalcloud.Datacenter{},
+		DatacenterConfigs:        map[string]metalcloud.DatacenterConfig{},
+		ServerTypes:              map[int]metalcloud.ServerType{},
+		Servers:                  map[int]metalcloud.Server{},
+		ServerComponents:         map[int]metalcloud.ServerComponent{},
+		VolumeTemplates:          map[int]metalcloud.VolumeTemplate{},
+		OSTemplates:              map[int]metalcloud.OSTemplate{},
+		OSTemplateAssets:         map[int]map[int]metalcloud.OSTemplateOSAssetData{},
+		OSAssets:                 map[int]metalcloud.OSAsset{},
+		Secrets:                  map[int]metalcloud.Secret{},
+		Variables:                map[int]metalcloud.Variable{},
+		StageDefinitions:         map[int]metalcloud.StageDefinition{},
+		Workflows:                map[int]metalcloud.Workflow{},
+		WorkflowStages:           map[int]metalcloud.WorkflowStageDefinitionReference{},
+		Infrastructures:          map[int]metalcloud.Infrastructure{},
+		CustomStages:             map[int]metalcloud.WorkflowStageAssociation{},
+		InstanceArrays:           map[int]metalcloud.InstanceArray{},
+		InstanceArrayServerTypes: map[int]int{},
+		Instances:                instances{},
+		PowerStatus:              map[int]string{},
+		DriveArrays:              map[int]metalcloud.DriveArray{},
+		Drives:                   map[int]metalcloud.Drive{},
+		Snapshots:                map[int]metalcloud.Snapshot{},
+		Networks:                 map[int]metalcloud.Network{},
+		SharedDrives:             map[int]metalcloud.SharedDrive{},
+		Limits:                   map[string]interface{}{},
+	}
+}
+
+func (c *Client) seed(datacenter string) {
+
+	c.userID = c.addUser(c.userEmail)
+
+	for k, v := range DefaultLimits {
+		c.state.Limits[k] = v
+	}
+
+	if datacenter != "" {
+		c.state.Datacenters[datacenter] = metalcloud.Datacenter{
+			DatacenterName:        datacenter,
+			DatacenterDisplayName: datacenter,
+			DatacenterType:        "metal_cloud",
+		}
+	}
+
+	for _, st := range []metalcloud.ServerType{
+		{
+			ServerTypeName:           "M.8.16.v2",
+			ServerTypeDisplayName:    "M.8.16.v2",
+			ServerProcessorCount:     1,
+			ServerProcessorCoreCount: 8,
+			ServerProcessorCoreMHz:   2400,
+			ServerRAMGbytes:          16,
+			ServerDiskCount:          0,
+			ServerClass:              "bigdata",
+			ServerCount:              100,
+		},
+		{
+			ServerTypeName:           "M.40.256.v1",
+			ServerTypeDisplayName:    "M.40.256.v1",
+			ServerProcessorCount:     2,
+			ServerProcessorCoreCount: 20,
+			ServerProcessorCoreMHz:   2200,
+			ServerRAMGbytes:          256,
+			ServerDiskCount:          2,
+			ServerDiskSizeMBytes:     1920000,
+			ServerDiskType:           "ssd",
+			ServerClass:              "bigdata",
+			ServerCount:              20,
+		},
+	} {
+		st.ServerTypeID = c.nextID()
+		st.ServerTypeLabel = strings.ToLower(strings.Replace(st.ServerTypeName, ".", "-", -1))
+		c.state.ServerTypes[st.ServerTypeID] = st
+	}
+
+	for _, vt := range []metalcloud.VolumeTemplate{
+		{
+			VolumeTemplateLabel:                "centos7-6",
+			VolumeTemplateDisplayName:          "CentOS 7.6",
+			VolumeTemplateSizeMBytes:           40960,
+			VolumeTemplateLocalDiskSupported:   true,
+			VolumeTemplateBootMethodsSupported: "pxe_iscsi,local_drives",
+			VolumeTemplateDeprecationStatus:    "not_deprecated",
+			VolumeTemplateOperatingSystem:      metalcloud.OperatingSystem{OperatingSystemType: "CentOS", OperatingSystemVersion: "7.6", OperatingSystemArchitecture: "x86_64"},
+		},
+		{
+			VolumeTemplateLabel:                "ubuntu-18-04",
+			VolumeTemplateDisplayName:          "Ubuntu 18.04",
+			VolumeTemplateSizeMBytes:           40960,
+			VolumeTemplateLocalDiskSupported:   true,
+			VolumeTemplateBootMethodsSupported: "pxe_iscsi,local_drives",
+			VolumeTemplateDeprecationStatus:    "not_deprecated",
+			VolumeTemplateOperatingSystem:      metalcloud.OperatingSystem{OperatingSystemType: "Ubuntu", OperatingSystemVersion: "18.04", OperatingSystemArchitecture: "x86_64"},
+		},
+	} {
+		vt.VolumeTemplateID = c.nextID()
+		c.state.VolumeTemplates[vt.VolumeTemplateID] = vt
+	}
+}
+
+func (c *Client) addUser(email string) int {
+	id := c.nextID()
+	c.state.Users[id] = metalcloud.User{
+		UserID:          id,
+		UserEmail:       email,
+		UserDisplayName: strings.Split(email, "@")[0],
+	}
+	return id
+}
+
+//nextID allocates an ID. All objects share the same sequence which makes mixing up IDs in tests obvious.
+func (c *Client) nextID() int {
+	id := c.state.NextID
+	c.state.NextID++
+	return id
+}
+
+//save writes the state to the backing file, if any. It is called after every change.
+func (c *Client) save() error {
+
+	if c.path == "" {
+		return nil
+	}
+
+	content, err := json.MarshalIndent(c.state, "", "\t")
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(c.path, content, 0600)
+}
+
+//AddServerType adds a server type to the catalog and returns its ID
+func (c *Client) AddServerType(serverType metalcloud.ServerType) (int, error) {
+	serverType.ServerTypeID = c.nextID()
+	c.state.ServerTypes[serverType.ServerTypeID] = serverType
+	return serverType.ServerTypeID, c.save()
+}
+
+//AddServer adds a server to the inventory and returns its ID
+func (c *Client) AddServer(server metalcloud.Server) (int, error) {
+	server.ServerID = c.nextID()
+	c.state.Servers[server.ServerID] = server
+	return server.ServerID, c.save()
+}
+
+//AddVolumeTemplate adds a volume template to the catalog and returns its ID
+func (c *Client) AddVolumeTemplate(volumeTemplate metalcloud.VolumeTemplate) (int, error) {
+	volumeTemplate.VolumeTemplateID = c.nextID()
+	c.state.VolumeTemplates[volumeTemplate.VolumeTemplateID] = volumeTemplate
+	return volumeTemplate.VolumeTemplateID, c.save()
+}
+
+//SetLimit changes a user limit returned by InfrastructureUserLimits
+func (c *Client) SetLimit(name string, value interface{}) error {
+	c.state.Limits[name] = value
+	return c.save()
+}
+
+//GetUserEmail returns the email of the user the client acts as
+func (c *Client) GetUserEmail() string {
+	return c.userEmail
+}
+
+//GetEndpoint returns the fake endpoint
+func (c *Client) GetEndpoint() string {
+	return c.endpoint
+}
+
+//GetUserID returns the ID of the user the client acts as
+func (c *Client) GetUserID() int {
+	return c.userID
+}
+
+func notFound(objectType string, id interface{}) error {
+	return fmt.Errorf("%s %v not found", objectType, id)
+}
+
+func now() string {
+	return time.Now().UTC().Format(time.RFC3339)
+}
+
+//sortedIDs returns the keys of a map with int keys in ascending order so that lookups by label are deterministic
+func sortedIDs(m interface{}) []int {
+	ids := []int{}
+	for _, k := range reflect.ValueOf(m).MapKeys() {
+		ids = append(ids, int(k.Int()))
+	}
+	sort.Ints(ids)
+	return ids
+}
+
+//copyFields sets the fields of dst to the values of the fields of src having the same json name.
+//Fields of different types, such as interfaces and their operations, are converted through json.
+//The live objects and their operations share field names so this moves values between them.
+func copyFields(dst interface{}, src interface{}) {
+
+	dv := reflect.ValueOf(dst).Elem()
+	sv := reflect.Indirect(reflect.ValueOf(src))
+
+	dstFields := map[string]reflect.Value{}
+	for i := 0; i < dv.NumField(); i++ {
+		dstFields[jsonName(dv.Type().Field(i))] = dv.Field(i)
+	}
+
+	for i := 0; i < sv.NumField(); i++ {
+		df, ok := dstFields[jsonName(sv.Type().Field(i))]
+		if !ok || !df.CanSet() {
+			continue
+		}
+
+		sf := sv.Field(i)
+		if sf.Type() == df.Type() {
+			df.Set(sf)
+			continue
+		}
+
+		b, err := json.Marshal(sf.Interface())
+		if err != nil {
+			continue
+		}
+		v := reflect.New(df.Type())
+		if json.Unmarshal(b, v.Interface()) == nil {
+			df.Set(v.Elem())
+		}
+	}
+}
+
+func jsonName(f reflect.StructField) string {
+	name := strings.Split(f.Tag.Get("json"), ",")[0]
+	if name == "" {
+		return f.Name
+	}
+	return name
+}
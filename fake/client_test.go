@@ -0,0 +1,314 @@
+package fake
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	metalcloud "github.com/bigstepinc/metal-cloud-sdk-go"
+	. "github.com/onsi/gomega"
+)
+
+func TestInfrastructureLifecycle(t *testing.T) {
+	RegisterTestingT(t)
+
+	c := NewClient("user@user.com", "test")
+
+	infra, err := c.InfrastructureCreate(metalcloud.Infrastructure{InfrastructureLabel: "test", DatacenterName: "test"})
+	Expect(err).To(BeNil())
+	Expect(infra.InfrastructureServiceStatus).To(Equal("ordered"))
+	Expect(infra.UserEmailOwner).To(Equal("user@user.com"))
+
+	_, err = c.InfrastructureCreate(metalcloud.Infrastructure{InfrastructureLabel: "test", DatacenterName: "test"})
+	Expect(err).NotTo(BeNil())
+
+	_, err = c.InfrastructureCreate(metalcloud.Infrastructure{InfrastructureLabel: "other", DatacenterName: "missing"})
+	Expect(err).NotTo(BeNil())
+
+	networks, err := c.Networks(infra.InfrastructureID)
+	Expect(err).To(BeNil())
+	Expect(*networks).To(HaveKey("wan"))
+	Expect(*networks).To(HaveKey("san"))
+
+	ia, err := c.InstanceArrayCreate(infra.InfrastructureID, metalcloud.InstanceArray{
+		InstanceArrayLabel:         "master",
+		InstanceArrayInstanceCount: 2,
+		InstanceArrayRAMGbytes:     100,
+	})
+	Expect(err).To(BeNil())
+	Expect(ia.InstanceArrayServiceStatus).To(Equal("ordered"))
+	Expect(ia.InstanceArrayOperation.InstanceArrayDeployType).To(Equal("create"))
+	Expect(ia.InstanceArrayOperation.InstanceArrayInterfaces).To(HaveLen(4))
+
+	da, err := c.DriveArrayCreate(infra.InfrastructureID, metalcloud.DriveArray{
+		DriveArrayLabel:                   "master-da",
+		InstanceArrayID:                   ia.InstanceArrayID,
+		DriveArrayExpandWithInstanceArray: true,
+	})
+	Expect(err).To(BeNil())
+	Expect(da.DriveArrayStorageType).To(Equal("iscsi_ssd"))
+
+	ret, err := c.InstanceArrayGetByLabel("master")
+	Expect(err).To(BeNil())
+	Expect(ret.InstanceArrayID).To(Equal(ia.InstanceArrayID))
+
+	err = c.InfrastructureDeploy(infra.InfrastructureID, metalcloud.ShutdownOptions{}, false, false)
+	Expect(err).To(BeNil())
+
+	infra, err = c.InfrastructureGet(infra.InfrastructureID)
+	Expect(err).To(BeNil())
+	Expect(infra.InfrastructureServiceStatus).To(Equal("active"))
+	Expect(infra.InfrastructureOperation.InfrastructureDeployStatus).To(Equal("finished"))
+
+	instances, err := c.InstanceArrayInstances(ia.InstanceArrayID)
+	Expect(err).To(BeNil())
+	Expect(*instances).To(HaveLen(2))
+	for _, i := range *instances {
+		//the smallest server type with enough RAM is used
+		st, err := c.ServerTypeGet(i.ServerTypeID)
+		Expect(err).To(BeNil())
+		Expect(st.ServerTypeName).To(Equal("M.40.256.v1"))
+
+		power, err := c.InstanceServerPowerGet(i.InstanceID)
+		Expect(err).To(BeNil())
+		Expect(*power).To(Equal("on"))
+	}
+
+	drives, err := c.DriveArrayDrives(da.DriveArrayID)
+	Expect(err).To(BeNil())
+	Expect(*drives).To(HaveLen(2))
+
+	iao := *ia.InstanceArrayOperation
+	iao.InstanceArrayInstanceCount = 3
+	ia, err = c.InstanceArrayEdit(ia.InstanceArrayID, iao, nil, nil, nil, nil)
+	Expect(err).To(BeNil())
+	Expect(ia.InstanceArrayInstanceCount).To(Equal(2))
+	Expect(ia.InstanceArrayOperation.InstanceArrayDeployType).To(Equal("edit"))
+
+	infra, err = c.InfrastructureGet(infra.InfrastructureID)
+	Expect(err).To(BeNil())
+	Expect(infra.InfrastructureOperation.InfrastructureDeployStatus).To(Equal("not_started"))
+
+	err = c.InfrastructureDeployByLabel("test", metalcloud.ShutdownOptions{}, false, false)
+	Expect(err).To(BeNil())
+
+	instances, err = c.InstanceArrayInstances(ia.InstanceArrayID)
+	Expect(err).To(BeNil())
+	Expect(*instances).To(HaveLen(3))
+
+	drives, err = c.DriveArrayDrives(da.DriveArrayID)
+	Expect(err).To(BeNil())
+	Expect(*drives).To(HaveLen(3))
+
+	//deployed objects are deleted on the next deploy
+	err = c.InstanceArrayDelete(ia.InstanceArrayID)
+	Expect(err).To(BeNil())
+
+	ia, err = c.InstanceArrayGet(ia.InstanceArrayID)
+	Expect(err).To(BeNil())
+	Expect(ia.InstanceArrayOperation.InstanceArrayDeployType).To(Equal("delete"))
+
+	err = c.InfrastructureDeploy(infra.InfrastructureID, metalcloud.ShutdownOptions{}, false, false)
+	Expect(err).To(BeNil())
+
+	_, err = c.InstanceArrayGet(ia.InstanceArrayID)
+	Expect(err).NotTo(BeNil())
+
+	da, err = c.DriveArrayGet(da.DriveArrayID)
+	Expect(err).To(BeNil())
+	Expect(da.InstanceArrayID).To(Equal(0))
+
+	err = c.InfrastructureDelete(infra.InfrastructureID)
+	Expect(err).To(BeNil())
+
+	_, err = c.InfrastructureGet(infra.InfrastructureID)
+	Expect(err).NotTo(BeNil())
+
+	_, err = c.DriveArrayGet(da.DriveArrayID)
+	Expect(err).NotTo(BeNil())
+	Expect(c.state.Drives).To(BeEmpty())
+	Expect(c.state.Networks).To(BeEmpty())
+}
+
+func TestInfrastructureOperationCancel(t *testing.T) {
+	RegisterTestingT(t)
+
+	c := NewClient("user@user.com", "test")
+
+	infra, err := c.InfrastructureCreate(metalcloud.Infrastructure{InfrastructureLabel: "test", DatacenterName: "test"})
+	Expect(err).To(BeNil())
+
+	ia, err := c.InstanceArrayCreate(infra.InfrastructureID, metalcloud.InstanceArray{InstanceArrayLabel: "deployed"})
+	Expect(err).To(BeNil())
+
+	err = c.InfrastructureDeploy(infra.InfrastructureID, metalcloud.ShutdownOptions{}, false, false)
+	Expect(err).To(BeNil())
+
+	iao := *ia.InstanceArrayOperation
+	iao.InstanceArrayInstanceCount = 5
+	_, err = c.InstanceArrayEdit(ia.InstanceArrayID, iao, nil, nil, nil, nil)
+	Expect(err).To(BeNil())
+
+	ordered, err := c.InstanceArrayCreate(infra.InfrastructureID, metalcloud.InstanceArray{InstanceArrayLabel: "ordered"})
+	Expect(err).To(BeNil())
+
+	lan, err := c.NetworkCreate(infra.InfrastructureID, metalcloud.Network{NetworkType: "lan"})
+	Expect(err).To(BeNil())
+
+	err = c.InfrastructureOperationCancelByLabel("test")
+	Expect(err).To(BeNil())
+
+	ia, err = c.InstanceArrayGet(ia.InstanceArrayID)
+	Expect(err).To(BeNil())
+	Expect(ia.InstanceArrayOperation.InstanceArrayInstanceCount).To(Equal(1))
+	Expect(ia.InstanceArrayOperation.InstanceArrayDeployStatus).To(Equal("finished"))
+
+	_, err = c.InstanceArrayGet(ordered.InstanceArrayID)
+	Expect(err).NotTo(BeNil())
+
+	_, err = c.NetworkGet(lan.NetworkID)
+	Expect(err).NotTo(BeNil())
+
+	infra, err = c.InfrastructureGet(infra.InfrastructureID)
+	Expect(err).To(BeNil())
+	Expect(infra.InfrastructureOperation.InfrastructureDeployStatus).To(Equal("finished"))
+}
+
+func TestInstanceArrayInterfaces(t *testing.T) {
+	RegisterTestingT(t)
+
+	c := NewClient("user@user.com", "test")
+
+	infra, err := c.InfrastructureCreate(metalcloud.Infrastructure{InfrastructureLabel: "test", DatacenterName: "test"})
+	Expect(err).To(BeNil())
+
+	ia, err := c.InstanceArrayCreate(infra.InfrastructureID, metalcloud.InstanceArray{})
+	Expect(err).To(BeNil())
+	Expect(ia.InstanceArrayLabel).To(Equal(fmt.Sprintf("instance-array-%d", ia.InstanceArrayID)))
+
+	lan1, err := c.NetworkCreate(infra.InfrastructureID, metalcloud.Network{NetworkType: "lan", NetworkLabel: "lan1"})
+	Expect(err).To(BeNil())
+
+	lan2, err := c.NetworkCreate(infra.InfrastructureID, metalcloud.Network{NetworkType: "lan", NetworkLabel: "lan2"})
+	Expect(err).To(BeNil())
+
+	_, err = c.NetworkCreate(infra.InfrastructureID, metalcloud.Network{NetworkType: "wan"})
+	Expect(err).NotTo(BeNil())
+
+	ia, err = c.InstanceArrayInterfaceAttachNetwork(ia.InstanceArrayID, 2, lan2.NetworkID)
+	Expect(err).To(BeNil())
+	Expect(ia.InstanceArrayOperation.InstanceArrayInterfaces[2].NetworkID).To(Equal(lan2.NetworkID))
+
+	_, err = c.InstanceArrayInterfaceAttachNetwork(ia.InstanceArrayID, 7, lan2.NetworkID)
+	Expect(err).NotTo(BeNil())
+
+	err = c.NetworkJoin(lan1.NetworkID, lan2.NetworkID)
+	Expect(err).To(BeNil())
+
+	ia, err = c.InstanceArrayGet(ia.InstanceArrayID)
+	Expect(err).To(BeNil())
+	Expect(ia.InstanceArrayOperation.InstanceArrayInterfaces[2].NetworkID).To(Equal(lan1.NetworkID))
+
+	ia, err = c.InstanceArrayInterfaceDetach(ia.InstanceArrayID, 2)
+	Expect(err).To(BeNil())
+	Expect(ia.InstanceArrayOperation.InstanceArrayInterfaces[2].NetworkID).To(Equal(0))
+}
+
+func TestWorkflowStages(t *testing.T) {
+	RegisterTestingT(t)
+
+	c := NewClient("user@user.com", "test")
+
+	w, err := c.WorkflowCreate(metalcloud.Workflow{WorkflowLabel: "wf"})
+	Expect(err).To(BeNil())
+
+	s1, err := c.StageDefinitionCreate(metalcloud.StageDefinition{StageDefinitionLabel: "s1"})
+	Expect(err).To(BeNil())
+
+	s2, err := c.StageDefinitionCreate(metalcloud.StageDefinition{StageDefinitionLabel: "s2"})
+	Expect(err).To(BeNil())
+
+	Expect(c.WorkflowStageAddAsNewRunLevel(w.WorkflowID, s1.StageDefinitionID, 0)).To(BeNil())
+	Expect(c.WorkflowStageAddAsNewRunLevel(w.WorkflowID, s2.StageDefinitionID, 0)).To(BeNil())
+
+	stages, err := c.WorkflowStages(w.WorkflowID)
+	Expect(err).To(BeNil())
+	Expect(*stages).To(HaveLen(2))
+	Expect((*stages)[0].StageDefinitionID).To(Equal(s2.StageDefinitionID))
+	Expect((*stages)[1].StageDefinitionID).To(Equal(s1.StageDefinitionID))
+	Expect((*stages)[1].WorkflowStageRunLevel).To(Equal(1))
+
+	Expect(c.WorkflowMoveIntoRunLevel(w.WorkflowID, s1.StageDefinitionID, 1, 0)).To(BeNil())
+
+	stages, err = c.WorkflowStages(w.WorkflowID)
+	Expect(err).To(BeNil())
+	Expect((*stages)[1].WorkflowStageRunLevel).To(Equal(0))
+
+	Expect(c.StageDefinitionDelete(s1.StageDefinitionID)).To(BeNil())
+
+	stages, err = c.WorkflowStages(w.WorkflowID)
+	Expect(err).To(BeNil())
+	Expect(*stages).To(HaveLen(1))
+}
+
+func TestServerTypeGetByLabel(t *testing.T) {
+	RegisterTestingT(t)
+
+	c := NewClient("user@user.com", "test")
+
+	st, err := c.ServerTypeGetByLabel("m-8-16-v2")
+	Expect(err).To(BeNil())
+	Expect(st.ServerTypeName).To(Equal("M.8.16.v2"))
+
+	//as with the API names are not labels
+	_, err = c.ServerTypeGetByLabel("M.8.16.v2")
+	Expect(err).NotTo(BeNil())
+}
+
+func TestNewClientFromFile(t *testing.T) {
+	RegisterTestingT(t)
+
+	dir, err := ioutil.TempDir("", "metalcloud-fake")
+	Expect(err).To(BeNil())
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "state.json")
+
+	c, err := NewClientFromEndpoint(EndpointScheme+path, "user@user.com", "test")
+	Expect(err).To(BeNil())
+	Expect(c.GetEndpoint()).To(Equal(EndpointScheme + path))
+
+	infra, err := c.InfrastructureCreate(metalcloud.Infrastructure{InfrastructureLabel: "test", DatacenterName: "test"})
+	Expect(err).To(BeNil())
+
+	_, err = c.InstanceArrayCreate(infra.InfrastructureID, metalcloud.InstanceArray{InstanceArrayLabel: "master"})
+	Expect(err).To(BeNil())
+
+	Expect(c.InfrastructureDeploy(infra.InfrastructureID, metalcloud.ShutdownOptions{}, false, false)).To(BeNil())
+
+	//a second client sees the objects created by the first one
+	c2, err := NewClientFromFile(path, "user@user.com", "test")
+	Expect(err).To(BeNil())
+	Expect(c2.GetUserID()).To(Equal(c.GetUserID()))
+
+	ia, err := c2.InstanceArrayGetByLabel("master")
+	Expect(err).To(BeNil())
+	Expect(ia.InstanceArrayServiceStatus).To(Equal("active"))
+
+	instances, err := c2.InstanceArrayInstances(ia.InstanceArrayID)
+	Expect(err).To(BeNil())
+	Expect(*instances).To(HaveLen(1))
+
+	c3, err := NewClientFromFile(path, "other@user.com", "test")
+	Expect(err).To(BeNil())
+	Expect(c3.GetUserID()).NotTo(Equal(c.GetUserID()))
+
+	_, err = NewClientFromEndpoint("https://api.bigstep.com", "user@user.com", "test")
+	Expect(err).NotTo(BeNil())
+
+	m, err := NewClientFromEndpoint("fake://", "user@user.com", "test")
+	Expect(err).To(BeNil())
+	Expect(m.GetEndpoint()).To(Equal("fake://"))
+}
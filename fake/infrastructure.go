@@ -0,0 +1,1019 @@
+package fake
+
+import (
+	"fmt"
+	"strconv"
+
+	metalcloud "github.com/bigstepinc/metal-cloud-sdk-go"
+)
+
+const (
+	serviceStatusOrdered = "ordered"
+	serviceStatusActive  = "active"
+
+	deployTypeCreate = "create"
+	deployTypeEdit   = "edit"
+	deployTypeDelete = "delete"
+
+	deployStatusNotStarted = "not_started"
+	deployStatusFinished   = "finished"
+
+	//instanceArrayInterfaceCount is the number of interfaces each instance array is created with
+	instanceArrayInterfaceCount = 4
+)
+
+//deployTypeFor returns the deploy type of a change to an object. Objects that were never deployed stay created.
+func deployTypeFor(serviceStatus string) string {
+	if serviceStatus == serviceStatusOrdered {
+		return deployTypeCreate
+	}
+	return deployTypeEdit
+}
+
+func (c *Client) infrastructure(infrastructureID int) (*metalcloud.Infrastructure, error) {
+	infra, ok := c.state.Infrastructures[infrastructureID]
+	if !ok {
+		return nil, notFound("Infrastructure", infrastructureID)
+	}
+	return &infra, nil
+}
+
+func (c *Client) infrastructureIDByLabel(infrastructureLabel string) (int, error) {
+	for _, id := range sortedIDs(c.state.Infrastructures) {
+		if c.state.Infrastructures[id].InfrastructureLabel == infrastructureLabel {
+			return id, nil
+		}
+	}
+	return 0, notFound("Infrastructure", infrastructureLabel)
+}
+
+//markInfrastructureChanged records that the infrastructure has changes waiting for a deploy
+func (c *Client) markInfrastructureChanged(infrastructureID int) {
+	infra, ok := c.state.Infrastructures[infrastructureID]
+	if !ok {
+		return
+	}
+	infra.InfrastructureOperation.InfrastructureDeployType = deployTypeFor(infra.InfrastructureServiceStatus)
+	infra.InfrastructureOperation.InfrastructureDeployStatus = deployStatusNotStarted
+	infra.InfrastructureOperation.InfrastructureUpdatedTimestamp = now()
+	c.state.Infrastructures[infrastructureID] = infra
+}
+
+//InfrastructureCreate creates an infrastructure together with its wan and san networks
+func (c *Client) InfrastructureCreate(infrastructure metalcloud.Infrastructure) (*metalcloud.Infrastructure, error) {
+
+	if infrastructure.InfrastructureLabel == "" {
+		return nil, fmt.Errorf("Infrastructure label is required")
+	}
+
+	if _, ok := c.state.Datacenters[infrastructure.DatacenterName]; !ok {
+		return nil, notFound("Datacenter", infrastructure.DatacenterName)
+	}
+
+	for _, i := range c.state.Infrastructures {
+		if i.InfrastructureLabel == infrastructure.InfrastructureLabel && i.UserIDowner == c.userID {
+			return nil, fmt.Errorf("Infrastructure label %s is already in use", infrastructure.InfrastructureLabel)
+		}
+	}
+
+	infrastructure.InfrastructureID = c.nextID()
+	infrastructure.UserIDowner = c.userID
+	infrastructure.UserEmailOwner = c.userEmail
+	infrastructure.InfrastructureServiceStatus = serviceStatusOrdered
+	infrastructure.InfrastructureCreatedTimestamp = now()
+	infrastructure.InfrastructureUpdatedTimestamp = infrastructure.InfrastructureCreatedTimestamp
+	if infrastructure.InfrastructureSubdomain == "" {
+		infrastructure.InfrastructureSubdomain = fmt.Sprintf("%s.%s", infrastructure.InfrastructureLabel, infrastructure.DatacenterName)
+	}
+
+	infrastructure.InfrastructureOperation = metalcloud.InfrastructureOperation{}
+	copyFields(&infrastructure.InfrastructureOperation, &infrastructure)
+	infrastructure.InfrastructureOperation.InfrastructureDeployType = deployTypeCreate
+	infrastructure.InfrastructureOperation.InfrastructureDeployStatus = deployStatusNotStarted
+
+	c.state.Infrastructures[infrastructure.InfrastructureID] = infrastructure
+
+	for _, networkType := range []string{"wan", "san"} {
+		_, err := c.networkCreate(infrastructure.InfrastructureID, metalcloud.Network{
+			NetworkLabel: networkType,
+			NetworkType:  networkType,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &infrastructure, c.save()
+}
+
+//Infrastructures returns the infrastructures of the user
+func (c *Client) Infrastructures() (*map[string]metalcloud.Infrastructure, error) {
+	res := map[string]metalcloud.Infrastructure{}
+	for _, i := range c.state.Infrastructures {
+		res[i.InfrastructureLabel] = i
+	}
+	return &res, nil
+}
+
+//InfrastructureEdit alters an infrastructure
+func (c *Client) InfrastructureEdit(infrastructureID int, infrastructureOperation metalcloud.InfrastructureOperation) (*metalcloud.Infrastructure, error) {
+
+	infra, err := c.infrastructure(infrastructureID)
+	if err != nil {
+		return nil, err
+	}
+
+	if infrastructureOperation.InfrastructureLabel == "" {
+		return nil, fmt.Errorf("Infrastructure label is required")
+	}
+
+	if infrastructureOperation.DatacenterName != infra.DatacenterName {
+		if _, ok := c.state.Datacenters[infrastructureOperation.DatacenterName]; !ok {
+			return nil, notFound("Datacenter", infrastructureOperation.DatacenterName)
+		}
+	}
+
+	infrastructureOperation.InfrastructureID = infrastructureID
+	infra.InfrastructureOperation = infrastructureOperation
+	c.state.Infrastructures[infrastructureID] = *infra
+	c.markInfrastructureChanged(infrastructureID)
+
+	return c.InfrastructureGet(infrastructureID)
+}
+
+//InfrastructureEditByLabel alters an infrastructure
+func (c *Client) InfrastructureEditByLabel(infrastructureLabel string, infrastructureOperation metalcloud.InfrastructureOperation) (*metalcloud.Infrastructure, error) {
+	id, err := c.infrastructureIDByLabel(infrastructureLabel)
+	if err != nil {
+		return nil, err
+	}
+	return c.InfrastructureEdit(id, infrastructureOperation)
+}
+
+//InfrastructureDelete deletes an infrastructure and all the objects in it
+func (c *Client) InfrastructureDelete(infrastructureID int) error {
+
+	if _, err := c.infrastructure(infrastructureID); err != nil {
+		return err
+	}
+
+	for id, ia := range c.state.InstanceArrays {
+		if ia.InfrastructureID == infrastructureID {
+			c.removeInstanceArray(id)
+		}
+	}
+
+	for id, da := range c.state.DriveArrays {
+		if da.InfrastructureID == infrastructureID {
+			c.removeDriveArray(id)
+		}
+	}
+
+	for id, n := range c.state.Networks {
+		if n.InfrastructureID == infrastructureID {
+			delete(c.state.Networks, id)
+		}
+	}
+
+	for id, sd := range c.state.SharedDrives {
+		if sd.InfrastructureID == infrastructureID {
+			delete(c.state.SharedDrives, id)
+		}
+	}
+
+	for id, s := range c.state.CustomStages {
+		if s.InfrastructureID == infrastructureID {
+			delete(c.state.CustomStages, id)
+		}
+	}
+
+	delete(c.state.Infrastructures, infrastructureID)
+
+	return c.save()
+}
+
+//InfrastructureDeleteByLabel deletes an infrastructure and all the objects in it
+func (c *Client) InfrastructureDeleteByLabel(infrastructureLabel string) error {
+	id, err := c.infrastructureIDByLabel(infrastructureLabel)
+	if err != nil {
+		return err
+	}
+	return c.InfrastructureDelete(id)
+}
+
+//InfrastructureOperationCancel reverts the changes that were not deployed.
+//Objects that were never deployed are removed, the others get their operation reset to their current state.
+func (c *Client) InfrastructureOperationCancel(infrastructureID int) error {
+
+	infra, err := c.infrastructure(infrastructureID)
+	if err != nil {
+		return err
+	}
+
+	for id, ia := range c.state.InstanceArrays {
+		if ia.InfrastructureID != infrastructureID || ia.InstanceArrayOperation == nil || ia.InstanceArrayOperation.InstanceArrayDeployStatus != deployStatusNotStarted {
+			continue
+		}
+		if ia.InstanceArrayServiceStatus == serviceStatusOrdered {
+			c.removeInstanceArray(id)
+			continue
+		}
+		iao := metalcloud.InstanceArrayOperation{}
+		copyFields(&iao, &ia)
+		iao.InstanceArrayDeployType = deployTypeEdit
+		iao.InstanceArrayDeployStatus = deployStatusFinished
+		ia.InstanceArrayOperation = &iao
+		c.state.InstanceArrays[id] = ia
+	}
+
+	for id, da := range c.state.DriveArrays {
+		if da.InfrastructureID != infrastructureID || da.DriveArrayOperation == nil || da.DriveArrayOperation.DriveArrayDeployStatus != deployStatusNotStarted {
+			continue
+		}
+		if da.DriveArrayServiceStatus == serviceStatusOrdered {
+			c.removeDriveArray(id)
+			continue
+		}
+		dao := metalcloud.DriveArrayOperation{}
+		copyFields(&dao, &da)
+		dao.DriveArrayDeployType = deployTypeEdit
+		dao.DriveArrayDeployStatus = deployStatusFinished
+		da.DriveArrayOperation = &dao
+		c.state.DriveArrays[id] = da
+	}
+
+	for id, sd := range c.state.SharedDrives {
+		if sd.InfrastructureID != infrastructureID || sd.SharedDriveOperation.SharedDriveDeployStatus != deployStatusNotStarted {
+			continue
+		}
+		if sd.SharedDriveServiceStatus == serviceStatusOrdered {
+			delete(c.state.SharedDrives, id)
+			continue
+		}
+		sdo := metalcloud.SharedDriveOperation{}
+		copyFields(&sdo, &sd)
+		sdo.SharedDriveDepoloyType = deployTypeEdit
+		sdo.SharedDriveDeployStatus = deployStatusFinished
+		sd.SharedDriveOperation = sdo
+		c.state.SharedDrives[id] = sd
+	}
+
+	//networks have no deploy status, created networks are recognized by their deploy type
+	for id, n := range c.state.Networks {
+		if n.InfrastructureID != infrastructureID || n.NetworkOperation == nil {
+			continue
+		}
+		if n.NetworkOperation.NetworkDeployType == deployTypeCreate {
+			c.removeNetwork(id)
+			continue
+		}
+		no := metalcloud.NetworkOperation{}
+		copyFields(&no, &n)
+		no.NetworkDeployType = deployTypeEdit
+		n.NetworkOperation = &no
+		c.state.Networks[id] = n
+	}
+
+	io := metalcloud.InfrastructureOperation{}
+	copyFields(&io, infra)
+	io.InfrastructureDeployType = deployTypeFor(infra.InfrastructureServiceStatus)
+	io.InfrastructureDeployStatus = deployStatusFinished
+	if infra.InfrastructureServiceStatus == serviceStatusOrdered {
+		io.InfrastructureDeployStatus = deployStatusNotStarted
+	}
+	infra.InfrastructureOperation = io
+	c.state.Infrastructures[infrastructureID] = *infra
+
+	return c.save()
+}
+
+//InfrastructureOperationCancelByLabel reverts the changes that were not deployed
+func (c *Client) InfrastructureOperationCancelByLabel(infrastructureLabel string) error {
+	id, err := c.infrastructureIDByLabel(infrastructureLabel)
+	if err != nil {
+		return err
+	}
+	return c.InfrastructureOperationCancel(id)
+}
+
+//InfrastructureDeploy applies all pending changes. The deploy finishes immediately.
+func (c *Client) InfrastructureDeploy(infrastructureID int, shutdownOptions metalcloud.ShutdownOptions, allowDataLoss bool, skipAnsible bool) error {
+
+	infra, err := c.infrastructure(infrastructureID)
+	if err != nil {
+		return err
+	}
+
+	for _, id := range sortedIDs(c.state.Networks) {
+		n := c.state.Networks[id]
+		if n.InfrastructureID != infrastructureID || n.NetworkOperation == nil {
+			continue
+		}
+		if n.NetworkOperation.NetworkDeployType == deployTypeDelete {
+			c.removeNetwork(id)
+			continue
+		}
+		no := *n.NetworkOperation
+		copyFields(&n, &no)
+		no.NetworkDeployType = deployTypeEdit
+		n.NetworkOperation = &no
+		n.NetworkUpdatedTimestamp = now()
+		c.state.Networks[id] = n
+	}
+
+	for _, id := range sortedIDs(c.state.InstanceArrays) {
+		ia := c.state.InstanceArrays[id]
+		if ia.InfrastructureID != infrastructureID || ia.InstanceArrayOperation == nil || ia.InstanceArrayOperation.InstanceArrayDeployStatus != deployStatusNotStarted {
+			continue
+		}
+		if ia.InstanceArrayOperation.InstanceArrayDeployType == deployTypeDelete {
+			c.removeInstanceArray(id)
+			continue
+		}
+		iao := *ia.InstanceArrayOperation
+		copyFields(&ia, &iao)
+		ia.InstanceArrayServiceStatus = serviceStatusActive
+		iao.InstanceArrayServiceStatus = serviceStatusActive
+		iao.InstanceArrayDeployStatus = deployStatusFinished
+		ia.InstanceArrayOperation = &iao
+		c.state.InstanceArrays[id] = ia
+		c.deployInstances(ia)
+	}
+
+	for _, id := range sortedIDs(c.state.DriveArrays) {
+		da := c.state.DriveArrays[id]
+		if da.InfrastructureID != infrastructureID || da.DriveArrayOperation == nil {
+			continue
+		}
+		if da.DriveArrayOperation.DriveArrayDeployStatus == deployStatusNotStarted {
+			if da.DriveArrayOperation.DriveArrayDeployType == deployTypeDelete {
+				c.removeDriveArray(id)
+				continue
+			}
+			dao := *da.DriveArrayOperation
+			copyFields(&da, &dao)
+			da.DriveArrayServiceStatus = serviceStatusActive
+			dao.DriveArrayDeployStatus = deployStatusFinished
+			da.DriveArrayOperation = &dao
+			c.state.DriveArrays[id] = da
+		}
+		//drives follow the instances so they are checked even if the drive array did not change
+		if da.DriveArrayServiceStatus == serviceStatusActive {
+			c.deployDrives(da)
+		}
+	}
+
+	for _, id := range sortedIDs(c.state.SharedDrives) {
+		sd := c.state.SharedDrives[id]
+		if sd.InfrastructureID != infrastructureID || sd.SharedDriveOperation.SharedDriveDeployStatus != deployStatusNotStarted {
+			continue
+		}
+		if sd.SharedDriveOperation.SharedDriveDepoloyType == deployTypeDelete {
+			delete(c.state.SharedDrives, id)
+			continue
+		}
+		sdo := sd.SharedDriveOperation
+		copyFields(&sd, &sdo)
+		sd.SharedDriveServiceStatus = serviceStatusActive
+		sdo.SharedDriveServiceStatus = serviceStatusActive
+		sdo.SharedDriveDeployStatus = deployStatusFinished
+		sd.SharedDriveOperation = sdo
+		sd.SharedDriveUpdatedTimestamp = now()
+		c.state.SharedDrives[id] = sd
+	}
+
+	io := infra.InfrastructureOperation
+	copyFields(infra, &io)
+	infra.InfrastructureServiceStatus = serviceStatusActive
+	infra.InfrastructureDeployID = c.nextID()
+	infra.InfrastructureUpdatedTimestamp = now()
+	io.InfrastructureDeployType = deployTypeEdit
+	io.InfrastructureDeployStatus = deployStatusFinished
+	infra.InfrastructureOperation = io
+	c.state.Infrastructures[infrastructureID] = *infra
+
+	return c.save()
+}
+
+//InfrastructureDeployByLabel applies all pending changes
+func (c *Client) InfrastructureDeployByLabel(infrastructureLabel string, shutdownOptions metalcloud.ShutdownOptions, allowDataLoss bool, skipAnsible bool) error {
+	id, err := c.infrastructureIDByLabel(infrastructureLabel)
+	if err != nil {
+		return err
+	}
+	return c.InfrastructureDeploy(id, shutdownOptions, allowDataLoss, skipAnsible)
+}
+
+//InfrastructureGet returns an infrastructure
+func (c *Client) InfrastructureGet(infrastructureID int) (*metalcloud.Infrastructure, error) {
+	return c.infrastructure(infrastructureID)
+}
+
+//InfrastructureGetByLabel returns an infrastructure
+func (c *Client) InfrastructureGetByLabel(infrastructureLabel string) (*metalcloud.Infrastructure, error) {
+	id, err := c.infrastructureIDByLabel(infrastructureLabel)
+	if err != nil {
+		return nil, err
+	}
+	return c.InfrastructureGet(id)
+}
+
+//InfrastructureUserLimits returns the limits of the user, the same for all infrastructures
+func (c *Client) InfrastructureUserLimits(infrastructureID int) (*map[string]interface{}, error) {
+
+	if _, err := c.infrastructure(infrastructureID); err != nil {
+		return nil, err
+	}
+
+	limits := map[string]interface{}{}
+	for k, v := range c.state.Limits {
+		limits[k] = v
+	}
+
+	return &limits, nil
+}
+
+//InfrastructureUserLimitsByLabel returns the limits of the user, the same for all infrastructures
+func (c *Client) InfrastructureUserLimitsByLabel(infrastructureLabel string) (*map[string]interface{}, error) {
+	id, err := c.infrastructureIDByLabel(infrastructureLabel)
+	if err != nil {
+		return nil, err
+	}
+	return c.InfrastructureUserLimits(id)
+}
+
+//InfrastructureDeployCustomStageAddIntoRunlevel runs a stage definition in the given runlevel of the deploy
+func (c *Client) InfrastructureDeployCustomStageAddIntoRunlevel(infraID int, stageID int, runLevel int, stageRunMoment string) error {
+
+	if _, err := c.infrastructure(infraID); err != nil {
+		return err
+	}
+
+	if _, ok := c.state.StageDefinitions[stageID]; !ok {
+		return notFound("Stage definition", stageID)
+	}
+
+	id := c.nextID()
+	c.state.CustomStages[id] = metalcloud.WorkflowStageAssociation{
+		InfrastructureDeployCustomStageID:       id,
+		InfrastructureID:                        infraID,
+		StageDefinitionID:                       stageID,
+		InfrastructureDeployCustomStageType:     stageRunMoment,
+		InfrastructureDeployCustomStageRunLevel: runLevel,
+	}
+
+	return c.save()
+}
+
+//InfrastructureDeployCustomStageDeleteIntoRunlevel removes a stage definition from the deploy
+func (c *Client) InfrastructureDeployCustomStageDeleteIntoRunlevel(infraID int, stageID int, runLevel int, stageRunMoment string) error {
+
+	for id, s := range c.state.CustomStages {
+		if s.InfrastructureID == infraID &&
+			s.StageDefinitionID == stageID &&
+			s.InfrastructureDeployCustomStageRunLevel == runLevel &&
+			s.InfrastructureDeployCustomStageType == stageRunMoment {
+			delete(c.state.CustomStages, id)
+			return c.save()
+		}
+	}
+
+	return notFound("Custom stage", stageID)
+}
+
+//InfrastructureDeployCustomStages returns the stage definitions run at the given moment of the deploy
+func (c *Client) InfrastructureDeployCustomStages(infraID int, stageDefinitionType string) (*[]metalcloud.WorkflowStageAssociation, error) {
+
+	if _, err := c.infrastructure(infraID); err != nil {
+		return nil, err
+	}
+
+	stages := []metalcloud.WorkflowStageAssociation{}
+	for _, id := range sortedIDs(c.state.CustomStages) {
+		s := c.state.CustomStages[id]
+		if s.InfrastructureID == infraID && s.InfrastructureDeployCustomStageType == stageDefinitionType {
+			stages = append(stages, s)
+		}
+	}
+
+	return &stages, nil
+}
+
+func (c *Client) instanceArray(instanceArrayID int) (*metalcloud.InstanceArray, error) {
+	ia, ok := c.state.InstanceArrays[instanceArrayID]
+	if !ok {
+		return nil, notFound("Instance array", instanceArrayID)
+	}
+	return &ia, nil
+}
+
+func (c *Client) instanceArrayIDByLabel(instanceArrayLabel string) (int, error) {
+	for _, id := range sortedIDs(c.state.InstanceArrays) {
+		if c.state.InstanceArrays[id].InstanceArrayLabel == instanceArrayLabel {
+			return id, nil
+		}
+	}
+	return 0, notFound("Instance array", instanceArrayLabel)
+}
+
+//removeInstanceArray deletes an instance array and its instances and detaches its drive arrays
+func (c *Client) removeInstanceArray(instanceArrayID int) {
+
+	for id, i := range c.state.Instances {
+		if i.InstanceArrayID == instanceArrayID {
+			c.removeInstance(id)
+		}
+	}
+
+	for id, da := range c.state.DriveArrays {
+		if da.InstanceArrayID == instanceArrayID {
+			da.InstanceArrayID = 0
+			if da.DriveArrayOperation != nil {
+				da.DriveArrayOperation.InstanceArrayID = 0
+			}
+			c.state.DriveArrays[id] = da
+		}
+	}
+
+	delete(c.state.InstanceArrayServerTypes, instanceArrayID)
+	delete(c.state.InstanceArrays, instanceArrayID)
+}
+
+func (c *Client) removeInstance(instanceID int) {
+
+	for id, d := range c.state.Drives {
+		if d.InstanceID == instanceID {
+			d.InstanceID = 0
+			c.state.Drives[id] = d
+		}
+	}
+
+	delete(c.state.PowerStatus, instanceID)
+	delete(c.state.Instances, instanceID)
+}
+
+//instanceArrayInstanceIDs returns the IDs of the instances of an instance array in ascending order
+func (c *Client) instanceArrayInstanceIDs(instanceArrayID int) []int {
+	ids := []int{}
+	for _, id := range sortedIDs(c.state.Instances) {
+		if c.state.Instances[id].InstanceArrayID == instanceArrayID {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+//deployInstances creates or removes instances so that their number matches the instance count
+func (c *Client) deployInstances(ia metalcloud.InstanceArray) {
+
+	ids := c.instanceArrayInstanceIDs(ia.InstanceArrayID)
+
+	for len(ids) > ia.InstanceArrayInstanceCount {
+		c.removeInstance(ids[len(ids)-1])
+		ids = ids[:len(ids)-1]
+	}
+
+	serverTypeID, ok := c.state.InstanceArrayServerTypes[ia.InstanceArrayID]
+	if !ok {
+		matches := c.matchServerTypes(metalcloud.HardwareConfiguration{
+			InstanceArrayRAMGbytes:          ia.InstanceArrayRAMGbytes,
+			InstanceArrayProcessorCount:     ia.InstanceArrayProcessorCount,
+			InstanceArrayProcessorCoreCount: ia.InstanceArrayProcessorCoreCount,
+			InstanceArrayDiskCount:          ia.InstanceArrayDiskCount,
+		})
+		if len(matches) > 0 {
+			serverTypeID = matches[0]
+		}
+	}
+
+	for i := len(ids); i < ia.InstanceArrayInstanceCount; i++ {
+		id := c.nextID()
+		c.state.Instances[id] = metalcloud.Instance{
+			InstanceID:               id,
+			InstanceLabel:            fmt.Sprintf("instance-%d", id),
+			InstanceSubdomain:        fmt.Sprintf("instance-%d.%s", id, ia.InstanceArraySubdomain),
+			InstanceArrayID:          ia.InstanceArrayID,
+			ServerTypeID:             serverTypeID,
+			InstanceServiceStatus:    serviceStatusActive,
+			InstanceCreatedTimestamp: now(),
+			TemplateIDOrigin:         ia.VolumeTemplateID,
+			InstanceOperation: metalcloud.InstanceOperation{
+				InstanceID:           id,
+				InstanceLabel:        fmt.Sprintf("instance-%d", id),
+				InstanceArrayID:      ia.InstanceArrayID,
+				ServerTypeID:         serverTypeID,
+				InstanceDeployType:   deployTypeCreate,
+				InstanceDeployStatus: deployStatusFinished,
+			},
+		}
+		c.state.PowerStatus[id] = "on"
+	}
+
+	//instances are moved to the requested server type on edits
+	for _, id := range ids {
+		i := c.state.Instances[id]
+		if serverTypeID != 0 {
+			i.ServerTypeID = serverTypeID
+			i.InstanceOperation.ServerTypeID = serverTypeID
+		}
+		c.state.Instances[id] = i
+	}
+}
+
+//InstanceArrayCreate creates an instance array with its interfaces. The instances are created on deploy.
+func (c *Client) InstanceArrayCreate(infrastructureID int, instanceArray metalcloud.InstanceArray) (*metalcloud.InstanceArray, error) {
+
+	infra, err := c.infrastructure(infrastructureID)
+	if err != nil {
+		return nil, err
+	}
+
+	instanceArray.InstanceArrayID = c.nextID()
+	instanceArray.InfrastructureID = infrastructureID
+	instanceArray.InstanceArrayServiceStatus = serviceStatusOrdered
+
+	if instanceArray.InstanceArrayLabel == "" {
+		instanceArray.InstanceArrayLabel = fmt.Sprintf("instance-array-%d", instanceArray.InstanceArrayID)
+	}
+
+	for _, ia := range c.state.InstanceArrays {
+		if ia.InfrastructureID == infrastructureID && ia.InstanceArrayLabel == instanceArray.InstanceArrayLabel {
+			return nil, fmt.Errorf("Instance array label %s is already in use", instanceArray.InstanceArrayLabel)
+		}
+	}
+
+	if instanceArray.InstanceArrayInstanceCount == 0 {
+		instanceArray.InstanceArrayInstanceCount = 1
+	}
+
+	if instanceArray.VolumeTemplateID != 0 {
+		if _, ok := c.state.VolumeTemplates[instanceArray.VolumeTemplateID]; !ok {
+			return nil, notFound("Volume template", instanceArray.VolumeTemplateID)
+		}
+	}
+
+	instanceArray.InstanceArraySubdomain = fmt.Sprintf("%s.%s", instanceArray.InstanceArrayLabel, infra.InfrastructureSubdomain)
+
+	instanceArray.InstanceArrayInterfaces = []metalcloud.InstanceArrayInterface{}
+	for i := 0; i < instanceArrayInterfaceCount; i++ {
+		instanceArray.InstanceArrayInterfaces = append(instanceArray.InstanceArrayInterfaces, metalcloud.InstanceArrayInterface{
+			InstanceArrayInterfaceID:            c.nextID(),
+			InstanceArrayInterfaceIndex:         i,
+			InstanceArrayID:                     instanceArray.InstanceArrayID,
+			InstanceArrayInterfaceServiceStatus: serviceStatusOrdered,
+		})
+	}
+
+	iao := metalcloud.InstanceArrayOperation{}
+	copyFields(&iao, &instanceArray)
+	iao.InstanceArrayDeployType = deployTypeCreate
+	iao.InstanceArrayDeployStatus = deployStatusNotStarted
+	instanceArray.InstanceArrayOperation = &iao
+
+	c.state.InstanceArrays[instanceArray.InstanceArrayID] = instanceArray
+	c.markInfrastructureChanged(infrastructureID)
+
+	return &instanceArray, c.save()
+}
+
+//InstanceArrayCreateByLabel creates an instance array
+func (c *Client) InstanceArrayCreateByLabel(infrastructureLabel string, instanceArray metalcloud.InstanceArray) (*metalcloud.InstanceArray, error) {
+	id, err := c.infrastructureIDByLabel(infrastructureLabel)
+	if err != nil {
+		return nil, err
+	}
+	return c.InstanceArrayCreate(id, instanceArray)
+}
+
+//InstanceArrayEdit replaces the operation of an instance array. The server type of the first match is used on deploy.
+func (c *Client) InstanceArrayEdit(instanceArrayID int, instanceArrayOperation metalcloud.InstanceArrayOperation, bSwapExistingInstancesHardware *bool, bKeepDetachingDrives *bool, objServerTypeMatches *metalcloud.ServerTypeMatches, arrInstancesToBeDeleted *[]int) (*metalcloud.InstanceArray, error) {
+
+	ia, err := c.instanceArray(instanceArrayID)
+	if err != nil {
+		return nil, err
+	}
+
+	if objServerTypeMatches != nil && len(objServerTypeMatches.ServerTypes) > 0 {
+		ids := sortedIDs(objServerTypeMatches.ServerTypes)
+		if _, ok := c.state.ServerTypes[ids[0]]; !ok {
+			return nil, notFound("Server type", ids[0])
+		}
+		c.state.InstanceArrayServerTypes[instanceArrayID] = ids[0]
+	}
+
+	//the interfaces are changed with the attach and detach calls only
+	if ia.InstanceArrayOperation != nil {
+		instanceArrayOperation.InstanceArrayInterfaces = ia.InstanceArrayOperation.InstanceArrayInterfaces
+	}
+
+	instanceArrayOperation.InstanceArrayID = instanceArrayID
+	instanceArrayOperation.InstanceArrayServiceStatus = ia.InstanceArrayServiceStatus
+	instanceArrayOperation.InstanceArrayDeployType = deployTypeFor(ia.InstanceArrayServiceStatus)
+	instanceArrayOperation.InstanceArrayDeployStatus = deployStatusNotStarted
+	ia.InstanceArrayOperation = &instanceArrayOperation
+
+	c.state.InstanceArrays[instanceArrayID] = *ia
+	c.markInfrastructureChanged(ia.InfrastructureID)
+
+	return ia, c.save()
+}
+
+//InstanceArrayEditByLabel replaces the operation of an instance array
+func (c *Client) InstanceArrayEditByLabel(instanceArrayLabel string, instanceArrayOperation metalcloud.InstanceArrayOperation, bSwapExistingInstancesHardware *bool, bKeepDetachingDrives *bool, objServerTypeMatches *metalcloud.ServerTypeMatches, arrInstancesToBeDeleted *[]int) (*metalcloud.InstanceArray, error) {
+	id, err := c.instanceArrayIDByLabel(instanceArrayLabel)
+	if err != nil {
+		return nil, err
+	}
+	return c.InstanceArrayEdit(id, instanceArrayOperation, bSwapExistingInstancesHardware, bKeepDetachingDrives, objServerTypeMatches, arrInstancesToBeDeleted)
+}
+
+//InstanceArrayDelete deletes an instance array on the next deploy, or immediately if it was never deployed
+func (c *Client) InstanceArrayDelete(instanceArrayID int) error {
+
+	ia, err := c.instanceArray(instanceArrayID)
+	if err != nil {
+		return err
+	}
+
+	if ia.InstanceArrayServiceStatus == serviceStatusOrdered {
+		c.removeInstanceArray(instanceArrayID)
+	} else {
+		ia.InstanceArrayOperation.InstanceArrayDeployType = deployTypeDelete
+		ia.InstanceArrayOperation.InstanceArrayDeployStatus = deployStatusNotStarted
+		c.state.InstanceArrays[instanceArrayID] = *ia
+	}
+
+	c.markInfrastructureChanged(ia.InfrastructureID)
+
+	return c.save()
+}
+
+//InstanceArrayDeleteByLabel deletes an instance array
+func (c *Client) InstanceArrayDeleteByLabel(instanceArrayLabel string) error {
+	id, err := c.instanceArrayIDByLabel(instanceArrayLabel)
+	if err != nil {
+		return err
+	}
+	return c.InstanceArrayDelete(id)
+}
+
+func (c *Client) setInstanceArrayPower(instanceArrayID int, power string) (*metalcloud.InstanceArray, error) {
+
+	ia, err := c.instanceArray(instanceArrayID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, id := range c.instanceArrayInstanceIDs(instanceArrayID) {
+		c.state.PowerStatus[id] = power
+	}
+
+	return ia, c.save()
+}
+
+//InstanceArrayStop powers off the instances of an instance array
+func (c *Client) InstanceArrayStop(instanceArrayID int) (*metalcloud.InstanceArray, error) {
+	return c.setInstanceArrayPower(instanceArrayID, "off")
+}
+
+//InstanceArrayStopByLabel powers off the instances of an instance array
+func (c *Client) InstanceArrayStopByLabel(instanceArrayLabel string) (*metalcloud.InstanceArray, error) {
+	id, err := c.instanceArrayIDByLabel(instanceArrayLabel)
+	if err != nil {
+		return nil, err
+	}
+	return c.InstanceArrayStop(id)
+}
+
+//InstanceArrayStart powers on the instances of an instance array
+func (c *Client) InstanceArrayStart(instanceArrayID int) (*metalcloud.InstanceArray, error) {
+	return c.setInstanceArrayPower(instanceArrayID, "on")
+}
+
+//InstanceArrayStartByLabel powers on the instances of an instance array
+func (c *Client) InstanceArrayStartByLabel(instanceArrayLabel string) (*metalcloud.InstanceArray, error) {
+	id, err := c.instanceArrayIDByLabel(instanceArrayLabel)
+	if err != nil {
+		return nil, err
+	}
+	return c.InstanceArrayStart(id)
+}
+
+//InstanceArrayGet returns an instance array
+func (c *Client) InstanceArrayGet(instanceArrayID int) (*metalcloud.InstanceArray, error) {
+	return c.instanceArray(instanceArrayID)
+}
+
+//InstanceArrayGetByLabel returns an instance array
+func (c *Client) InstanceArrayGetByLabel(instanceArrayLabel string) (*metalcloud.InstanceArray, error) {
+	id, err := c.instanceArrayIDByLabel(instanceArrayLabel)
+	if err != nil {
+		return nil, err
+	}
+	return c.InstanceArrayGet(id)
+}
+
+//InstanceArrays returns the instance arrays of an infrastructure
+func (c *Client) InstanceArrays(infrastructureID int) (*map[string]metalcloud.InstanceArray, error) {
+
+	if _, err := c.infrastructure(infrastructureID); err != nil {
+		return nil, err
+	}
+
+	res := map[string]metalcloud.InstanceArray{}
+	for _, ia := range c.state.InstanceArrays {
+		if ia.InfrastructureID == infrastructureID {
+			res[ia.InstanceArrayLabel] = ia
+		}
+	}
+
+	return &res, nil
+}
+
+//InstanceArraysByLabel returns the instance arrays of an infrastructure
+func (c *Client) InstanceArraysByLabel(infrastructureLabel string) (*map[string]metalcloud.InstanceArray, error) {
+	id, err := c.infrastructureIDByLabel(infrastructureLabel)
+	if err != nil {
+		return nil, err
+	}
+	return c.InstanceArrays(id)
+}
+
+//InstanceArrayInstances returns the instances of an instance array
+func (c *Client) InstanceArrayInstances(instanceArrayID int) (*map[string]metalcloud.Instance, error) {
+
+	if _, err := c.instanceArray(instanceArrayID); err != nil {
+		return nil, err
+	}
+
+	res := map[string]metalcloud.Instance{}
+	for _, id := range c.instanceArrayInstanceIDs(instanceArrayID) {
+		i := c.state.Instances[id]
+		res[i.InstanceLabel] = i
+	}
+
+	return &res, nil
+}
+
+//InstanceArrayInstancesByLabel returns the instances of an instance array
+func (c *Client) InstanceArrayInstancesByLabel(instanceArrayLabel string) (*map[string]metalcloud.Instance, error) {
+	id, err := c.instanceArrayIDByLabel(instanceArrayLabel)
+	if err != nil {
+		return nil, err
+	}
+	return c.InstanceArrayInstances(id)
+}
+
+func (c *Client) setInstanceArrayInterfaceNetwork(instanceArrayID int, instanceArrayInterfaceIndex int, networkID int) (*metalcloud.InstanceArray, error) {
+
+	ia, err := c.instanceArray(instanceArrayID)
+	if err != nil {
+		return nil, err
+	}
+
+	if networkID != 0 {
+		n, ok := c.state.Networks[networkID]
+		if !ok {
+			return nil, notFound("Network", networkID)
+		}
+		if n.InfrastructureID != ia.InfrastructureID {
+			return nil, fmt.Errorf("Network %d is not in the infrastructure of instance array %d", networkID, instanceArrayID)
+		}
+	}
+
+	iao := ia.InstanceArrayOperation
+	found := false
+	for i := range iao.InstanceArrayInterfaces {
+		if iao.InstanceArrayInterfaces[i].InstanceArrayInterfaceIndex == instanceArrayInterfaceIndex {
+			iao.InstanceArrayInterfaces[i].NetworkID = networkID
+			found = true
+		}
+	}
+	if !found {
+		return nil, notFound("Instance array interface", instanceArrayInterfaceIndex)
+	}
+
+	if iao.InstanceArrayDeployStatus != deployStatusNotStarted {
+		iao.InstanceArrayDeployType = deployTypeFor(ia.InstanceArrayServiceStatus)
+		iao.InstanceArrayDeployStatus = deployStatusNotStarted
+	}
+
+	c.state.InstanceArrays[instanceArrayID] = *ia
+	c.markInfrastructureChanged(ia.InfrastructureID)
+
+	return ia, c.save()
+}
+
+//InstanceArrayInterfaceAttachNetwork attaches an interface of an instance array to a network
+func (c *Client) InstanceArrayInterfaceAttachNetwork(instanceArrayID int, instanceArrayInterfaceIndex int, networkID int) (*metalcloud.InstanceArray, error) {
+	return c.setInstanceArrayInterfaceNetwork(instanceArrayID, instanceArrayInterfaceIndex, networkID)
+}
+
+//InstanceArrayInterfaceDetach detaches an interface of an instance array from its network
+func (c *Client) InstanceArrayInterfaceDetach(instanceArrayID int, instanceArrayInterfaceIndex int) (*metalcloud.InstanceArray, error) {
+	return c.setInstanceArrayInterfaceNetwork(instanceArrayID, instanceArrayInterfaceIndex, 0)
+}
+
+func (c *Client) instanceIDByLabel(instanceLabel string) (int, error) {
+	for _, id := range sortedIDs(c.state.Instances) {
+		if c.state.Instances[id].InstanceLabel == instanceLabel {
+			return id, nil
+		}
+	}
+	return 0, notFound("Instance", instanceLabel)
+}
+
+//InstanceGet returns an instance
+func (c *Client) InstanceGet(instanceID int) (*metalcloud.Instance, error) {
+	i, ok := c.state.Instances[instanceID]
+	if !ok {
+		return nil, notFound("Instance", instanceID)
+	}
+	return &i, nil
+}
+
+//InstanceGetByLabel returns an instance
+func (c *Client) InstanceGetByLabel(instanceLabel string) (*metalcloud.Instance, error) {
+	id, err := c.instanceIDByLabel(instanceLabel)
+	if err != nil {
+		return nil, err
+	}
+	return c.InstanceGet(id)
+}
+
+//InstanceServerPowerSet powers an instance on or off. Supported operations are 'on', 'off', 'reset' and 'soft'.
+func (c *Client) InstanceServerPowerSet(instanceID int, operation string) error {
+
+	if _, ok := c.state.Instances[instanceID]; !ok {
+		return notFound("Instance", instanceID)
+	}
+
+	switch operation {
+	case "on", "reset":
+		c.state.PowerStatus[instanceID] = "on"
+	case "off", "soft":
+		c.state.PowerStatus[instanceID] = "off"
+	default:
+		return fmt.Errorf("Invalid power operation %s", operation)
+	}
+
+	return c.save()
+}
+
+//InstanceServerPowerSetByLabel powers an instance on or off
+func (c *Client) InstanceServerPowerSetByLabel(instanceLabel string, operation string) error {
+	id, err := c.instanceIDByLabel(instanceLabel)
+	if err != nil {
+		return err
+	}
+	return c.InstanceServerPowerSet(id, operation)
+}
+
+//InstanceServerPowerGet returns the power status of an instance
+func (c *Client) InstanceServerPowerGet(instanceID int) (*string, error) {
+
+	if _, ok := c.state.Instances[instanceID]; !ok {
+		return nil, notFound("Instance", instanceID)
+	}
+
+	power := c.state.PowerStatus[instanceID]
+
+	return &power, nil
+}
+
+//InstanceServerPowerGetByLabel returns the power status of an instance
+func (c *Client) InstanceServerPowerGetByLabel(instanceLabel string) (*string, error) {
+	id, err := c.instanceIDByLabel(instanceLabel)
+	if err != nil {
+		return nil, err
+	}
+	return c.InstanceServerPowerGet(id)
+}
+
+//InstanceServerPowerGetBatch returns the power status of the given instances of an infrastructure keyed by instance ID
+func (c *Client) InstanceServerPowerGetBatch(infrastructureID int, instanceIDs []int) (*map[string]string, error) {
+
+	if _, err := c.infrastructure(infrastructureID); err != nil {
+		return nil, err
+	}
+
+	res := map[string]string{}
+	for _, id := range instanceIDs {
+		power, err := c.InstanceServerPowerGet(id)
+		if err != nil {
+			return nil, err
+		}
+		res[strconv.Itoa(id)] = *power
+	}
+
+	return &res, nil
+}
+
+//InstanceServerPowerGetBatchByLabel returns the power status of the given instances of an infrastructure
+func (c *Client) InstanceServerPowerGetBatchByLabel(infrastructureLabel string, instanceIDs []int) (*map[string]string, error) {
+	id, err := c.infrastructureIDByLabel(infrastructureLabel)
+	if err != nil {
+		return nil, err
+	}
+	return c.InstanceServerPowerGetBatch(id, instanceIDs)
+}
@@ -0,0 +1,1227 @@
+package fake
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	metalcloud "github.com/bigstepinc/metal-cloud-sdk-go"
+)
+
+//AddServerComponent adds a component to a server and returns its ID
+func (c *Client) AddServerComponent(serverComponent metalcloud.ServerComponent) (int, error) {
+
+	if _, ok := c.state.Servers[serverComponent.ServerID]; !ok {
+		return 0, notFound("Server", serverComponent.ServerID)
+	}
+
+	serverComponent.ServerComponentID = c.nextID()
+	c.state.ServerComponents[serverComponent.ServerComponentID] = serverComponent
+
+	return serverComponent.ServerComponentID, c.save()
+}
+
+//UserGet returns a user
+func (c *Client) UserGet(userID int) (*metalcloud.User, error) {
+	u, ok := c.state.Users[userID]
+	if !ok {
+		return nil, notFound("User", userID)
+	}
+	return &u, nil
+}
+
+//UserGetByEmail returns a user
+func (c *Client) UserGetByEmail(userLabel string) (*metalcloud.User, error) {
+	id, err := c.UserEmailToUserID(userLabel)
+	if err != nil {
+		return nil, err
+	}
+	return c.UserGet(*id)
+}
+
+//UserEmailToUserID returns the ID of the user with the given email
+func (c *Client) UserEmailToUserID(userEmail string) (*int, error) {
+	for _, id := range sortedIDs(c.state.Users) {
+		if c.state.Users[id].UserEmail == userEmail {
+			return &id, nil
+		}
+	}
+	return nil, notFound("User", userEmail)
+}
+
+//Datacenters returns the datacenters. Datacenters in maintenance are skipped if onlyActive is set.
+func (c *Client) Datacenters(onlyActive bool) (*map[string]metalcloud.Datacenter, error) {
+	return c.DatacentersByUserID(0, onlyActive)
+}
+
+//DatacentersByUserID returns the public datacenters and the ones of the given user
+func (c *Client) DatacentersByUserID(userID int, onlyActive bool) (*map[string]metalcloud.Datacenter, error) {
+
+	res := map[string]metalcloud.Datacenter{}
+	for name, dc := range c.state.Datacenters {
+		if onlyActive && dc.DatacenterIsMaintenance {
+			continue
+		}
+		if dc.UserID != 0 && dc.UserID != userID {
+			continue
+		}
+		res[name] = dc
+	}
+
+	return &res, nil
+}
+
+//DatacentersByUserEmail returns the public datacenters and the ones of the given user
+func (c *Client) DatacentersByUserEmail(userEmail string, onlyActive bool) (*map[string]metalcloud.Datacenter, error) {
+	id, err := c.UserEmailToUserID(userEmail)
+	if err != nil {
+		return nil, err
+	}
+	return c.DatacentersByUserID(*id, onlyActive)
+}
+
+//DatacenterGet returns a datacenter
+func (c *Client) DatacenterGet(datacenterName string) (*metalcloud.Datacenter, error) {
+	dc, ok := c.state.Datacenters[datacenterName]
+	if !ok {
+		return nil, notFound("Datacenter", datacenterName)
+	}
+	return &dc, nil
+}
+
+//DatacenterGetForUserByEmail returns a datacenter
+func (c *Client) DatacenterGetForUserByEmail(datacenterName string, userID string) (*metalcloud.Datacenter, error) {
+	return c.DatacenterGet(datacenterName)
+}
+
+//DatacenterGetForUserByID returns a datacenter
+func (c *Client) DatacenterGetForUserByID(datacenterName string, userID int) (*metalcloud.Datacenter, error) {
+	return c.DatacenterGet(datacenterName)
+}
+
+//DatacenterConfigGet returns the configuration of a datacenter
+func (c *Client) DatacenterConfigGet(datacenterName string) (*metalcloud.DatacenterConfig, error) {
+
+	if _, err := c.DatacenterGet(datacenterName); err != nil {
+		return nil, err
+	}
+
+	config := c.state.DatacenterConfigs[datacenterName]
+
+	return &config, nil
+}
+
+//DatacenterConfigUpdate replaces the configuration of a datacenter
+func (c *Client) DatacenterConfigUpdate(datacenterName string, datacenterConfig metalcloud.DatacenterConfig) error {
+
+	if _, err := c.DatacenterGet(datacenterName); err != nil {
+		return err
+	}
+
+	c.state.DatacenterConfigs[datacenterName] = datacenterConfig
+
+	return c.save()
+}
+
+//DatacenterCreate creates a datacenter
+func (c *Client) DatacenterCreate(datacenter metalcloud.Datacenter, datacenterConfig metalcloud.DatacenterConfig) (*metalcloud.Datacenter, error) {
+
+	if datacenter.DatacenterName == "" {
+		return nil, fmt.Errorf("Datacenter name is required")
+	}
+
+	if _, ok := c.state.Datacenters[datacenter.DatacenterName]; ok {
+		return nil, fmt.Errorf("Datacenter %s already exists", datacenter.DatacenterName)
+	}
+
+	if datacenter.DatacenterNameParent != "" {
+		if _, ok := c.state.Datacenters[datacenter.DatacenterNameParent]; !ok {
+			return nil, notFound("Datacenter", datacenter.DatacenterNameParent)
+		}
+	}
+
+	datacenter.DatacenterCreatedTimestamp = now()
+	datacenter.DatacenterUpdatedTimestamp = datacenter.DatacenterCreatedTimestamp
+
+	c.state.Datacenters[datacenter.DatacenterName] = datacenter
+	c.state.DatacenterConfigs[datacenter.DatacenterName] = datacenterConfig
+
+	return &datacenter, c.save()
+}
+
+//DatacenterAgentsConfigJSONDownloadURL returns the url of the configuration of the agents of a datacenter
+func (c *Client) DatacenterAgentsConfigJSONDownloadURL(datacenterName string, decrypt bool) (string, error) {
+
+	if _, err := c.DatacenterGet(datacenterName); err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%s/datacenter/%s/agents-config.json", strings.TrimSuffix(c.endpoint, "/"), datacenterName), nil
+}
+
+//matchServerTypes returns the IDs of the server types that satisfy a hardware configuration, smallest first
+func (c *Client) matchServerTypes(hardwareConfiguration metalcloud.HardwareConfiguration) []int {
+
+	ids := []int{}
+	for _, id := range sortedIDs(c.state.ServerTypes) {
+		st := c.state.ServerTypes[id]
+		if st.ServerRAMGbytes >= hardwareConfiguration.InstanceArrayRAMGbytes &&
+			st.ServerProcessorCount >= hardwareConfiguration.InstanceArrayProcessorCount &&
+			st.ServerProcessorCoreCount >= hardwareConfiguration.InstanceArrayProcessorCoreCount &&
+			st.ServerProcessorCoreMHz >= hardwareConfiguration.InstanceArrayProcessorCoreMHZ &&
+			st.ServerDiskCount >= hardwareConfiguration.InstanceArrayDiskCount &&
+			st.ServerDiskSizeMBytes >= hardwareConfiguration.InstanceArrayDiskSizeMBytes {
+			ids = append(ids, id)
+		}
+	}
+
+	sort.SliceStable(ids, func(i, j int) bool {
+		return c.state.ServerTypes[ids[i]].ServerRAMGbytes < c.state.ServerTypes[ids[j]].ServerRAMGbytes
+	})
+
+	return ids
+}
+
+//ServerTypes returns the server types of a datacenter. All datacenters share the same server types.
+func (c *Client) ServerTypes(datacenterName string, bOnlyAvailable bool) (*map[int]metalcloud.ServerType, error) {
+
+	if _, err := c.DatacenterGet(datacenterName); err != nil {
+		return nil, err
+	}
+
+	res := map[int]metalcloud.ServerType{}
+	for id, st := range c.state.ServerTypes {
+		if bOnlyAvailable && st.ServerCount == 0 {
+			continue
+		}
+		res[id] = st
+	}
+
+	return &res, nil
+}
+
+//ServerTypeDatacenter returns the IDs of the server types of a datacenter
+func (c *Client) ServerTypeDatacenter(datacenterName string) (*[]int, error) {
+
+	if _, err := c.DatacenterGet(datacenterName); err != nil {
+		return nil, err
+	}
+
+	ids := sortedIDs(c.state.ServerTypes)
+
+	return &ids, nil
+}
+
+//ServerTypeGet returns a server type
+func (c *Client) ServerTypeGet(serverTypeID int) (*metalcloud.ServerType, error) {
+	st, ok := c.state.ServerTypes[serverTypeID]
+	if !ok {
+		return nil, notFound("Server type", serverTypeID)
+	}
+	return &st, nil
+}
+
+//ServerTypeGetByLabel returns a server type by label. As with the API the name is not matched.
+func (c *Client) ServerTypeGetByLabel(serverTypeLabel string) (*metalcloud.ServerType, error) {
+	for _, id := range sortedIDs(c.state.ServerTypes) {
+		st := c.state.ServerTypes[id]
+		if st.ServerTypeLabel == serverTypeLabel {
+			return &st, nil
+		}
+	}
+	return nil, notFound("Server type", serverTypeLabel)
+}
+
+//ServerTypesMatchHardwareConfiguration returns the server types that satisfy a hardware configuration
+func (c *Client) ServerTypesMatchHardwareConfiguration(datacenterName string, hardwareConfiguration metalcloud.HardwareConfiguration) (*map[int]metalcloud.ServerType, error) {
+
+	if _, err := c.DatacenterGet(datacenterName); err != nil {
+		return nil, err
+	}
+
+	res := map[int]metalcloud.ServerType{}
+	for _, id := range c.matchServerTypes(hardwareConfiguration) {
+		res[id] = c.state.ServerTypes[id]
+	}
+
+	return &res, nil
+}
+
+//ServerTypesMatches returns the server types that satisfy a hardware configuration keyed by ID.
+//The server count is the number of servers not used by deployed instances.
+func (c *Client) ServerTypesMatches(infrastructureID int, hardwareConfiguration metalcloud.HardwareConfiguration, instanceArrayID *int, bAllowServerSwap bool) (*map[string]metalcloud.ServerType, error) {
+
+	if _, err := c.infrastructure(infrastructureID); err != nil {
+		return nil, err
+	}
+
+	used := map[int]int{}
+	for _, i := range c.state.Instances {
+		used[i.ServerTypeID]++
+	}
+
+	res := map[string]metalcloud.ServerType{}
+	for _, id := range c.matchServerTypes(hardwareConfiguration) {
+		st := c.state.ServerTypes[id]
+		st.ServerCount -= used[id]
+		if st.ServerCount < 0 {
+			st.ServerCount = 0
+		}
+		res[strconv.Itoa(id)] = st
+	}
+
+	return &res, nil
+}
+
+//ServerTypesMatchesByLabel returns the server types that satisfy a hardware configuration keyed by ID
+func (c *Client) ServerTypesMatchesByLabel(infrastructureLabel string, hardwareConfiguration metalcloud.HardwareConfiguration, instanceArrayID *int, bAllowServerSwap bool) (*map[string]metalcloud.ServerType, error) {
+	id, err := c.infrastructureIDByLabel(infrastructureLabel)
+	if err != nil {
+		return nil, err
+	}
+	return c.ServerTypesMatches(id, hardwareConfiguration, instanceArrayID, bAllowServerSwap)
+}
+
+//matchesFilter returns true if the json fields of an object match all the space separated field:value terms of a filter.
+//An empty filter or * matches everything.
+func matchesFilter(object interface{}, filter string) bool {
+
+	b, err := json.Marshal(object)
+	if err != nil {
+		return false
+	}
+
+	fields := map[string]interface{}{}
+	if json.Unmarshal(b, &fields) != nil {
+		return false
+	}
+
+	for _, term := range strings.Fields(filter) {
+		if term == "*" {
+			continue
+		}
+
+		parts := strings.SplitN(strings.TrimPrefix(term, "+"), ":", 2)
+		if len(parts) != 2 {
+			return false
+		}
+
+		value, ok := fields[parts[0]]
+		if !ok || fmt.Sprintf("%v", value) != parts[1] {
+			return false
+		}
+	}
+
+	return true
+}
+
+//ServersSearch returns the servers matching a filter made of field:value terms such as '+server_id:10 server_status:available'
+func (c *Client) ServersSearch(filter string) (*[]metalcloud.ServerSearchResult, error) {
+
+	res := []metalcloud.ServerSearchResult{}
+	for _, id := range sortedIDs(c.state.Servers) {
+		s := c.state.Servers[id]
+
+		r := metalcloud.ServerSearchResult{}
+		copyFields(&r, &s)
+		if st, ok := c.state.ServerTypes[s.ServerTypeID]; ok {
+			r.ServerTypeName = st.ServerTypeName
+		}
+
+		if matchesFilter(r, filter) {
+			res = append(res, r)
+		}
+	}
+
+	return &res, nil
+}
+
+//ServerGet returns a server
+func (c *Client) ServerGet(serverID int, decryptPasswd bool) (*metalcloud.Server, error) {
+
+	s, ok := c.state.Servers[serverID]
+	if !ok {
+		return nil, notFound("Server", serverID)
+	}
+
+	if !decryptPasswd {
+		s.ServerIPMInternalPassword = ""
+	}
+
+	return &s, nil
+}
+
+//ServerComponents returns the components of a server. The filter matches the type or the name of the component.
+//As with the search of the API the target version is not returned.
+func (c *Client) ServerComponents(serverID int, filter string) (*[]metalcloud.ServerComponent, error) {
+
+	if _, ok := c.state.Servers[serverID]; !ok {
+		return nil, notFound("Server", serverID)
+	}
+
+	res := []metalcloud.ServerComponent{}
+	for _, id := range sortedIDs(c.state.ServerComponents) {
+		sc := c.state.ServerComponents[id]
+		if sc.ServerID != serverID {
+			continue
+		}
+		if filter != "" && filter != "*" && sc.ServerComponentType != filter && !strings.Contains(sc.ServerComponentName, filter) {
+			continue
+		}
+		sc.ServerComponentFirmwareTargetVersion = ""
+		res = append(res, sc)
+	}
+
+	return &res, nil
+}
+
+//ServerComponentGet returns a server component
+func (c *Client) ServerComponentGet(serverComponentID int) (*metalcloud.ServerComponent, error) {
+	sc, ok := c.state.ServerComponents[serverComponentID]
+	if !ok {
+		return nil, notFound("Server component", serverComponentID)
+	}
+	return &sc, nil
+}
+
+//upgradeServerComponent sets the firmware version of a component. Upgrades finish immediately.
+func (c *Client) upgradeServerComponent(sc metalcloud.ServerComponent, version string) {
+	sc.ServerComponentFirmwareVersion = version
+	sc.ServerComponentFirmwareTargetVersion = ""
+	sc.ServerComponentFirmwareStatus = "upgraded"
+	sc.ServerComponentFirmwareUpdateTimestamp = now()
+	c.state.ServerComponents[sc.ServerComponentID] = sc
+}
+
+//ServerFirmwareComponentUpgrade upgrades the firmware of a server component
+func (c *Client) ServerFirmwareComponentUpgrade(serverID int, serverComponentID int, serverComponentFirmwareNewVersion string, firmwareBinaryURL string) error {
+
+	sc, err := c.ServerComponentGet(serverComponentID)
+	if err != nil {
+		return err
+	}
+
+	if sc.ServerID != serverID {
+		return fmt.Errorf("Server component %d does not belong to server %d", serverComponentID, serverID)
+	}
+
+	if !sc.ServerComponentFirmwareUpdateable {
+		return fmt.Errorf("The firmware of server component %d cannot be upgraded", serverComponentID)
+	}
+
+	c.upgradeServerComponent(*sc, serverComponentFirmwareNewVersion)
+
+	return c.save()
+}
+
+//ServerFirmwareUpgrade upgrades the components of a server that have a target version set
+func (c *Client) ServerFirmwareUpgrade(serverID int) error {
+
+	components, err := c.ServerComponents(serverID, "")
+	if err != nil {
+		return err
+	}
+
+	for _, sc := range *components {
+		if sc.ServerComponentFirmwareUpdateable && sc.ServerComponentFirmwareTargetVersion != "" {
+			c.upgradeServerComponent(sc, sc.ServerComponentFirmwareTargetVersion)
+		}
+	}
+
+	return c.save()
+}
+
+//ServerFirmwareComponentTargetVersionSet sets the version a component is upgraded to by ServerFirmwareUpgrade
+func (c *Client) ServerFirmwareComponentTargetVersionSet(serverComponentID int, serverComponentFirmwareNewVersion string) error {
+
+	sc, err := c.ServerComponentGet(serverComponentID)
+	if err != nil {
+		return err
+	}
+
+	sc.ServerComponentFirmwareTargetVersion = serverComponentFirmwareNewVersion
+	c.state.ServerComponents[serverComponentID] = *sc
+
+	return c.save()
+}
+
+//ServerFirmwareComponentTargetVersionUpdate refreshes the target version of a component. Nothing changes as there is no firmware catalog.
+func (c *Client) ServerFirmwareComponentTargetVersionUpdate(serverComponentID int) error {
+	_, err := c.ServerComponentGet(serverComponentID)
+	return err
+}
+
+//ServerFirmwareComponentTargetVersionAdd makes a new firmware version available for a component
+func (c *Client) ServerFirmwareComponentTargetVersionAdd(serverComponentID int, version string, firmareBinaryURL string) error {
+
+	sc, err := c.ServerComponentGet(serverComponentID)
+	if err != nil {
+		return err
+	}
+
+	sc.ServerComponentFirmwareUpdateAvailableVersions = append(sc.ServerComponentFirmwareUpdateAvailableVersions, version)
+	c.state.ServerComponents[serverComponentID] = *sc
+
+	return c.save()
+}
+
+//VolumeTemplates returns the volume templates
+func (c *Client) VolumeTemplates() (*map[string]metalcloud.VolumeTemplate, error) {
+	res := map[string]metalcloud.VolumeTemplate{}
+	for _, vt := range c.state.VolumeTemplates {
+		res[vt.VolumeTemplateLabel] = vt
+	}
+	return &res, nil
+}
+
+//VolumeTemplateGet returns a volume template
+func (c *Client) VolumeTemplateGet(volumeTemplateID int) (*metalcloud.VolumeTemplate, error) {
+	vt, ok := c.state.VolumeTemplates[volumeTemplateID]
+	if !ok {
+		return nil, notFound("Volume template", volumeTemplateID)
+	}
+	return &vt, nil
+}
+
+//VolumeTemplateGetByLabel returns a volume template
+func (c *Client) VolumeTemplateGetByLabel(volumeTemplateLabel string) (*metalcloud.VolumeTemplate, error) {
+	for _, id := range sortedIDs(c.state.VolumeTemplates) {
+		if c.state.VolumeTemplates[id].VolumeTemplateLabel == volumeTemplateLabel {
+			return c.VolumeTemplateGet(id)
+		}
+	}
+	return nil, notFound("Volume template", volumeTemplateLabel)
+}
+
+//VolumeTemplateCreate creates a volume template from a drive
+func (c *Client) VolumeTemplateCreate(driveID int, label string, description string, displayName string, bootType string, deprecationStatus string, bootMethodsSupported string, volumeTemplateTags []string) (*metalcloud.VolumeTemplate, error) {
+
+	d, ok := c.state.Drives[driveID]
+	if !ok {
+		return nil, notFound("Drive", driveID)
+	}
+
+	for _, vt := range c.state.VolumeTemplates {
+		if vt.VolumeTemplateLabel == label {
+			return nil, fmt.Errorf("Volume template label %s is already in use", label)
+		}
+	}
+
+	vt := metalcloud.VolumeTemplate{
+		VolumeTemplateID:                   c.nextID(),
+		VolumeTemplateLabel:                label,
+		VolumeTemplateDisplayName:          displayName,
+		VolumeTemplateDescription:          description,
+		VolumeTemplateSizeMBytes:           d.DriveSizeMBytes,
+		VolumeTemplateBootMethodsSupported: bootMethodsSupported,
+		VolumeTemplateDeprecationStatus:    deprecationStatus,
+	}
+
+	if origin, ok := c.state.VolumeTemplates[d.TemplateIDOrigin]; ok {
+		vt.VolumeTemplateOperatingSystem = origin.VolumeTemplateOperatingSystem
+		vt.VolumeTemplateLocalDiskSupported = origin.VolumeTemplateLocalDiskSupported
+	}
+
+	c.state.VolumeTemplates[vt.VolumeTemplateID] = vt
+
+	return &vt, c.save()
+}
+
+//VolumeTemplateCreateByLabel creates a volume template from a drive
+func (c *Client) VolumeTemplateCreateByLabel(driveLabel string, label string, description string, displayName string, bootType string, deprecationStatus string, bootMethodsSupported string, volumeTemplateTags []string) (*metalcloud.VolumeTemplate, error) {
+	for _, id := range sortedIDs(c.state.Drives) {
+		if c.state.Drives[id].DriveLabel == driveLabel {
+			return c.VolumeTemplateCreate(id, label, description, displayName, bootType, deprecationStatus, bootMethodsSupported, volumeTemplateTags)
+		}
+	}
+	return nil, notFound("Drive", driveLabel)
+}
+
+//OSTemplateCreate creates an OS template
+func (c *Client) OSTemplateCreate(osTemplate metalcloud.OSTemplate) (*metalcloud.OSTemplate, error) {
+
+	if osTemplate.VolumeTemplateLabel == "" {
+		return nil, fmt.Errorf("OS template label is required")
+	}
+
+	for _, t := range c.state.OSTemplates {
+		if t.VolumeTemplateLabel == osTemplate.VolumeTemplateLabel {
+			return nil, fmt.Errorf("OS template label %s is already in use", osTemplate.VolumeTemplateLabel)
+		}
+	}
+
+	osTemplate.VolumeTemplateID = c.nextID()
+	osTemplate.UserID = c.userID
+	osTemplate.VolumeTemplateIsOSTemplate = true
+	osTemplate.VolumeTemplateCreatedTimestamp = now()
+	osTemplate.VolumeTemplateUpdatedTimestamp = osTemplate.VolumeTemplateCreatedTimestamp
+
+	c.state.OSTemplates[osTemplate.VolumeTemplateID] = osTemplate
+
+	return &osTemplate, c.save()
+}
+
+//OSTemplateDelete deletes an OS template
+func (c *Client) OSTemplateDelete(osTemplateID int) error {
+
+	if _, ok := c.state.OSTemplates[osTemplateID]; !ok {
+		return notFound("OS template", osTemplateID)
+	}
+
+	delete(c.state.OSTemplates, osTemplateID)
+	delete(c.state.OSTemplateAssets, osTemplateID)
+
+	return c.save()
+}
+
+//OSTemplateUpdate replaces an OS template
+func (c *Client) OSTemplateUpdate(osTemplateID int, osTemplate metalcloud.OSTemplate) (*metalcloud.OSTemplate, error) {
+
+	t, ok := c.state.OSTemplates[osTemplateID]
+	if !ok {
+		return nil, notFound("OS template", osTemplateID)
+	}
+
+	osTemplate.VolumeTemplateID = osTemplateID
+	osTemplate.UserID = t.UserID
+	osTemplate.VolumeTemplateIsOSTemplate = true
+	osTemplate.VolumeTemplateCreatedTimestamp = t.VolumeTemplateCreatedTimestamp
+	osTemplate.VolumeTemplateUpdatedTimestamp = now()
+
+	c.state.OSTemplates[osTemplateID] = osTemplate
+
+	return &osTemplate, c.save()
+}
+
+//OSTemplateGet returns an OS template
+func (c *Client) OSTemplateGet(osTemplateID int, decryptPasswd bool) (*metalcloud.OSTemplate, error) {
+
+	t, ok := c.state.OSTemplates[osTemplateID]
+	if !ok {
+		return nil, notFound("OS template", osTemplateID)
+	}
+
+	if t.OSTemplateCredentials != nil && !decryptPasswd {
+		credentials := *t.OSTemplateCredentials
+		credentials.OSTemplateInitialPassword = ""
+		t.OSTemplateCredentials = &credentials
+	}
+
+	return &t, nil
+}
+
+//OSTemplates returns the OS templates
+func (c *Client) OSTemplates() (*map[string]metalcloud.OSTemplate, error) {
+	res := map[string]metalcloud.OSTemplate{}
+	for _, t := range c.state.OSTemplates {
+		res[t.VolumeTemplateLabel] = t
+	}
+	return &res, nil
+}
+
+//OSTemplateOSAssets returns the assets of an OS template keyed by asset ID
+func (c *Client) OSTemplateOSAssets(osTemplateID int) (*map[string]metalcloud.OSTemplateOSAssetData, error) {
+
+	if _, ok := c.state.OSTemplates[osTemplateID]; !ok {
+		return nil, notFound("OS template", osTemplateID)
+	}
+
+	res := map[string]metalcloud.OSTemplateOSAssetData{}
+	for id, data := range c.state.OSTemplateAssets[osTemplateID] {
+		if a, ok := c.state.OSAssets[id]; ok {
+			data.OSAsset = &a
+		}
+		res[strconv.Itoa(id)] = data
+	}
+
+	return &res, nil
+}
+
+//osTemplateAsset returns the association of an asset with an OS template
+func (c *Client) osTemplateAsset(osTemplateID int, osAssetID int) (*metalcloud.OSTemplateOSAssetData, error) {
+
+	if _, ok := c.state.OSTemplates[osTemplateID]; !ok {
+		return nil, notFound("OS template", osTemplateID)
+	}
+
+	data, ok := c.state.OSTemplateAssets[osTemplateID][osAssetID]
+	if !ok {
+		return nil, fmt.Errorf("OS asset %d is not associated with OS template %d", osAssetID, osTemplateID)
+	}
+
+	return &data, nil
+}
+
+//OSTemplateAddOSAsset associates an asset with an OS template
+func (c *Client) OSTemplateAddOSAsset(osTemplateID int, osAssetID int, path string, variablesJSON string) error {
+
+	if _, ok := c.state.OSTemplates[osTemplateID]; !ok {
+		return notFound("OS template", osTemplateID)
+	}
+
+	if _, ok := c.state.OSAssets[osAssetID]; !ok {
+		return notFound("OS asset", osAssetID)
+	}
+
+	if _, ok := c.state.OSTemplateAssets[osTemplateID]; !ok {
+		c.state.OSTemplateAssets[osTemplateID] = map[int]metalcloud.OSTemplateOSAssetData{}
+	}
+
+	c.state.OSTemplateAssets[osTemplateID][osAssetID] = metalcloud.OSTemplateOSAssetData{
+		OSAssetFilePath:                   path,
+		OSTemplateOSAssetVariablesJSON:    variablesJSON,
+		OSTemplateOSAssetUpdatedTimestamp: now(),
+	}
+
+	return c.save()
+}
+
+//OSTemplateRemoveOSAsset removes an asset from an OS template
+func (c *Client) OSTemplateRemoveOSAsset(osTemplateID int, osAssetID int) error {
+
+	if _, err := c.osTemplateAsset(osTemplateID, osAssetID); err != nil {
+		return err
+	}
+
+	delete(c.state.OSTemplateAssets[osTemplateID], osAssetID)
+
+	return c.save()
+}
+
+//OSTemplateUpdateOSAssetPath changes the path of an asset of an OS template
+func (c *Client) OSTemplateUpdateOSAssetPath(osTemplateID int, osAssetID int, path string) error {
+
+	data, err := c.osTemplateAsset(osTemplateID, osAssetID)
+	if err != nil {
+		return err
+	}
+
+	data.OSAssetFilePath = path
+	data.OSTemplateOSAssetUpdatedTimestamp = now()
+	c.state.OSTemplateAssets[osTemplateID][osAssetID] = *data
+
+	return c.save()
+}
+
+//OSTemplateUpdateOSAssetVariables changes the variables of an asset of an OS template
+func (c *Client) OSTemplateUpdateOSAssetVariables(osTemplateID int, osAssetID int, variablesJSON string) error {
+
+	data, err := c.osTemplateAsset(osTemplateID, osAssetID)
+	if err != nil {
+		return err
+	}
+
+	data.OSTemplateOSAssetVariablesJSON = variablesJSON
+	data.OSTemplateOSAssetUpdatedTimestamp = now()
+	c.state.OSTemplateAssets[osTemplateID][osAssetID] = *data
+
+	return c.save()
+}
+
+//OSAssetCreate creates an OS asset
+func (c *Client) OSAssetCreate(osAsset metalcloud.OSAsset) (*metalcloud.OSAsset, error) {
+
+	if osAsset.OSAssetFileName == "" {
+		return nil, fmt.Errorf("OS asset file name is required")
+	}
+
+	osAsset.OSAssetID = c.nextID()
+	osAsset.UserIDOwner = c.userID
+	osAsset.OSAssetFileSizeBytes = len(osAsset.OSAssetContentsBase64) * 3 / 4
+	osAsset.OSAssetCreatedTimestamp = now()
+	osAsset.OSAssetUpdatedTimestamp = osAsset.OSAssetCreatedTimestamp
+
+	c.state.OSAssets[osAsset.OSAssetID] = osAsset
+
+	return &osAsset, c.save()
+}
+
+//OSAssetDelete deletes an OS asset and removes it from the OS templates using it
+func (c *Client) OSAssetDelete(osAssetID int) error {
+
+	if _, ok := c.state.OSAssets[osAssetID]; !ok {
+		return notFound("OS asset", osAssetID)
+	}
+
+	for _, assets := range c.state.OSTemplateAssets {
+		delete(assets, osAssetID)
+	}
+	delete(c.state.OSAssets, osAssetID)
+
+	return c.save()
+}
+
+//OSAssetUpdate replaces an OS asset
+func (c *Client) OSAssetUpdate(osAssetID int, osAsset metalcloud.OSAsset) (*metalcloud.OSAsset, error) {
+
+	a, ok := c.state.OSAssets[osAssetID]
+	if !ok {
+		return nil, notFound("OS asset", osAssetID)
+	}
+
+	osAsset.OSAssetID = osAssetID
+	osAsset.UserIDOwner = a.UserIDOwner
+	osAsset.OSAssetFileSizeBytes = len(osAsset.OSAssetContentsBase64) * 3 / 4
+	osAsset.OSAssetCreatedTimestamp = a.OSAssetCreatedTimestamp
+	osAsset.OSAssetUpdatedTimestamp = now()
+
+	c.state.OSAssets[osAssetID] = osAsset
+
+	return &osAsset, c.save()
+}
+
+//OSAssetGet returns an OS asset
+func (c *Client) OSAssetGet(osAssetID int) (*metalcloud.OSAsset, error) {
+	a, ok := c.state.OSAssets[osAssetID]
+	if !ok {
+		return nil, notFound("OS asset", osAssetID)
+	}
+	return &a, nil
+}
+
+//OSAssets returns the OS assets keyed by file name
+func (c *Client) OSAssets() (*map[string]metalcloud.OSAsset, error) {
+	res := map[string]metalcloud.OSAsset{}
+	for _, a := range c.state.OSAssets {
+		res[a.OSAssetFileName] = a
+	}
+	return &res, nil
+}
+
+//SecretCreate creates a secret
+func (c *Client) SecretCreate(secret metalcloud.Secret) (*metalcloud.Secret, error) {
+
+	if secret.SecretName == "" {
+		return nil, fmt.Errorf("Secret name is required")
+	}
+
+	for _, s := range c.state.Secrets {
+		if s.SecretName == secret.SecretName {
+			return nil, fmt.Errorf("Secret name %s is already in use", secret.SecretName)
+		}
+	}
+
+	secret.SecretID = c.nextID()
+	secret.UserIDOwner = c.userID
+	secret.SecretCreatedTimestamp = now()
+	secret.SecretUpdatedTimestamp = secret.SecretCreatedTimestamp
+
+	c.state.Secrets[secret.SecretID] = secret
+
+	return &secret, c.save()
+}
+
+//SecretDelete deletes a secret
+func (c *Client) SecretDelete(secretID int) error {
+
+	if _, ok := c.state.Secrets[secretID]; !ok {
+		return notFound("Secret", secretID)
+	}
+
+	delete(c.state.Secrets, secretID)
+
+	return c.save()
+}
+
+//SecretUpdate replaces a secret
+func (c *Client) SecretUpdate(secretID int, secret metalcloud.Secret) (*metalcloud.Secret, error) {
+
+	s, ok := c.state.Secrets[secretID]
+	if !ok {
+		return nil, notFound("Secret", secretID)
+	}
+
+	secret.SecretID = secretID
+	secret.UserIDOwner = s.UserIDOwner
+	secret.SecretCreatedTimestamp = s.SecretCreatedTimestamp
+	secret.SecretUpdatedTimestamp = now()
+
+	c.state.Secrets[secretID] = secret
+
+	return &secret, c.save()
+}
+
+//SecretGet returns a secret
+func (c *Client) SecretGet(secretID int) (*metalcloud.Secret, error) {
+	s, ok := c.state.Secrets[secretID]
+	if !ok {
+		return nil, notFound("Secret", secretID)
+	}
+	return &s, nil
+}
+
+//Secrets returns the secrets with the given usage, or all of them if usage is empty, keyed by name
+func (c *Client) Secrets(usage string) (*map[string]metalcloud.Secret, error) {
+	res := map[string]metalcloud.Secret{}
+	for _, s := range c.state.Secrets {
+		if usage == "" || s.SecretUsage == usage {
+			res[s.SecretName] = s
+		}
+	}
+	return &res, nil
+}
+
+//VariableCreate creates a variable
+func (c *Client) VariableCreate(variable metalcloud.Variable) (*metalcloud.Variable, error) {
+
+	if variable.VariableName == "" {
+		return nil, fmt.Errorf("Variable name is required")
+	}
+
+	for _, v := range c.state.Variables {
+		if v.VariableName == variable.VariableName {
+			return nil, fmt.Errorf("Variable name %s is already in use", variable.VariableName)
+		}
+	}
+
+	variable.VariableID = c.nextID()
+	variable.UserIDOwner = c.userID
+	variable.VariableCreatedTimestamp = now()
+	variable.VariableUpdatedTimestamp = variable.VariableCreatedTimestamp
+
+	c.state.Variables[variable.VariableID] = variable
+
+	return &variable, c.save()
+}
+
+//VariableDelete deletes a variable
+func (c *Client) VariableDelete(variableID int) error {
+
+	if _, ok := c.state.Variables[variableID]; !ok {
+		return notFound("Variable", variableID)
+	}
+
+	delete(c.state.Variables, variableID)
+
+	return c.save()
+}
+
+//VariableUpdate replaces a variable
+func (c *Client) VariableUpdate(variableID int, variable metalcloud.Variable) (*metalcloud.Variable, error) {
+
+	v, ok := c.state.Variables[variableID]
+	if !ok {
+		return nil, notFound("Variable", variableID)
+	}
+
+	variable.VariableID = variableID
+	variable.UserIDOwner = v.UserIDOwner
+	variable.VariableCreatedTimestamp = v.VariableCreatedTimestamp
+	variable.VariableUpdatedTimestamp = now()
+
+	c.state.Variables[variableID] = variable
+
+	return &variable, c.save()
+}
+
+//VariableGet returns a variable
+func (c *Client) VariableGet(variableID int) (*metalcloud.Variable, error) {
+	v, ok := c.state.Variables[variableID]
+	if !ok {
+		return nil, notFound("Variable", variableID)
+	}
+	return &v, nil
+}
+
+//Variables returns the variables with the given usage, or all of them if usage is empty, keyed by name
+func (c *Client) Variables(usage string) (*map[string]metalcloud.Variable, error) {
+	res := map[string]metalcloud.Variable{}
+	for _, v := range c.state.Variables {
+		if usage == "" || v.VariableUsage == usage {
+			res[v.VariableName] = v
+		}
+	}
+	return &res, nil
+}
+
+//StageDefinitionCreate creates a stage definition
+func (c *Client) StageDefinitionCreate(stageDefinition metalcloud.StageDefinition) (*metalcloud.StageDefinition, error) {
+
+	if stageDefinition.StageDefinitionLabel == "" {
+		return nil, fmt.Errorf("Stage definition label is required")
+	}
+
+	for _, s := range c.state.StageDefinitions {
+		if s.StageDefinitionLabel == stageDefinition.StageDefinitionLabel {
+			return nil, fmt.Errorf("Stage definition label %s is already in use", stageDefinition.StageDefinitionLabel)
+		}
+	}
+
+	stageDefinition.StageDefinitionID = c.nextID()
+	stageDefinition.UserIDOwner = c.userID
+	stageDefinition.StageDefinitionCreatedTimestamp = now()
+	stageDefinition.StageDefinitionUpdatedTimestamp = stageDefinition.StageDefinitionCreatedTimestamp
+
+	c.state.StageDefinitions[stageDefinition.StageDefinitionID] = stageDefinition
+
+	return &stageDefinition, c.save()
+}
+
+//StageDefinitionDelete deletes a stage definition and removes it from workflows and infrastructure deploys
+func (c *Client) StageDefinitionDelete(stageDefinitionID int) error {
+
+	if _, ok := c.state.StageDefinitions[stageDefinitionID]; !ok {
+		return notFound("Stage definition", stageDefinitionID)
+	}
+
+	for id, s := range c.state.WorkflowStages {
+		if s.StageDefinitionID == stageDefinitionID {
+			delete(c.state.WorkflowStages, id)
+		}
+	}
+
+	for id, s := range c.state.CustomStages {
+		if s.StageDefinitionID == stageDefinitionID {
+			delete(c.state.CustomStages, id)
+		}
+	}
+
+	delete(c.state.StageDefinitions, stageDefinitionID)
+
+	return c.save()
+}
+
+//StageDefinitionUpdate replaces a stage definition
+func (c *Client) StageDefinitionUpdate(stageDefinitionID int, stageDefinition metalcloud.StageDefinition) (*metalcloud.StageDefinition, error) {
+
+	s, ok := c.state.StageDefinitions[stageDefinitionID]
+	if !ok {
+		return nil, notFound("Stage definition", stageDefinitionID)
+	}
+
+	stageDefinition.StageDefinitionID = stageDefinitionID
+	stageDefinition.UserIDOwner = s.UserIDOwner
+	stageDefinition.StageDefinitionCreatedTimestamp = s.StageDefinitionCreatedTimestamp
+	stageDefinition.StageDefinitionUpdatedTimestamp = now()
+
+	c.state.StageDefinitions[stageDefinitionID] = stageDefinition
+
+	return &stageDefinition, c.save()
+}
+
+//StageDefinitionGet returns a stage definition
+func (c *Client) StageDefinitionGet(stageDefinitionID int) (*metalcloud.StageDefinition, error) {
+	s, ok := c.state.StageDefinitions[stageDefinitionID]
+	if !ok {
+		return nil, notFound("Stage definition", stageDefinitionID)
+	}
+	return &s, nil
+}
+
+//StageDefinitions returns the stage definitions keyed by label
+func (c *Client) StageDefinitions() (*map[string]metalcloud.StageDefinition, error) {
+	res := map[string]metalcloud.StageDefinition{}
+	for _, s := range c.state.StageDefinitions {
+		res[s.StageDefinitionLabel] = s
+	}
+	return &res, nil
+}
+
+//WorkflowCreate creates a workflow
+func (c *Client) WorkflowCreate(workflow metalcloud.Workflow) (*metalcloud.Workflow, error) {
+
+	if workflow.WorkflowLabel == "" {
+		return nil, fmt.Errorf("Workflow label is required")
+	}
+
+	for _, w := range c.state.Workflows {
+		if w.WorkflowLabel == workflow.WorkflowLabel {
+			return nil, fmt.Errorf("Workflow label %s is already in use", workflow.WorkflowLabel)
+		}
+	}
+
+	workflow.WorkflowID = c.nextID()
+	workflow.UserIDOwner = c.userID
+	workflow.WorkflowCreatedTimestamp = now()
+	workflow.WorkflowUpdatedTimestamp = workflow.WorkflowCreatedTimestamp
+
+	c.state.Workflows[workflow.WorkflowID] = workflow
+
+	return &workflow, c.save()
+}
+
+//WorkflowDelete deletes a workflow and its stages
+func (c *Client) WorkflowDelete(workflowID int) error {
+
+	if _, ok := c.state.Workflows[workflowID]; !ok {
+		return notFound("Workflow", workflowID)
+	}
+
+	for id, s := range c.state.WorkflowStages {
+		if s.WorkflowID == workflowID {
+			delete(c.state.WorkflowStages, id)
+		}
+	}
+
+	delete(c.state.Workflows, workflowID)
+
+	return c.save()
+}
+
+//WorkflowUpdate replaces a workflow
+func (c *Client) WorkflowUpdate(workflowID int, workflow metalcloud.Workflow) (*metalcloud.Workflow, error) {
+
+	w, ok := c.state.Workflows[workflowID]
+	if !ok {
+		return nil, notFound("Workflow", workflowID)
+	}
+
+	workflow.WorkflowID = workflowID
+	workflow.UserIDOwner = w.UserIDOwner
+	workflow.WorkflowCreatedTimestamp = w.WorkflowCreatedTimestamp
+	workflow.WorkflowUpdatedTimestamp = now()
+
+	c.state.Workflows[workflowID] = workflow
+
+	return &workflow, c.save()
+}
+
+//WorkflowGet returns a workflow
+func (c *Client) WorkflowGet(workflowID int) (*metalcloud.Workflow, error) {
+	w, ok := c.state.Workflows[workflowID]
+	if !ok {
+		return nil, notFound("Workflow", workflowID)
+	}
+	return &w, nil
+}
+
+//Workflows returns the workflows keyed by label
+func (c *Client) Workflows() (*map[string]metalcloud.Workflow, error) {
+	return c.WorkflowsWithUsage("")
+}
+
+//WorkflowsWithUsage returns the workflows with the given usage, or all of them if usage is empty, keyed by label
+func (c *Client) WorkflowsWithUsage(usage string) (*map[string]metalcloud.Workflow, error) {
+	res := map[string]metalcloud.Workflow{}
+	for _, w := range c.state.Workflows {
+		if usage == "" || w.WorkflowUsage == usage {
+			res[w.WorkflowLabel] = w
+		}
+	}
+	return &res, nil
+}
+
+//WorkflowStages returns the stages of a workflow ordered by run level
+func (c *Client) WorkflowStages(workflowID int) (*[]metalcloud.WorkflowStageDefinitionReference, error) {
+
+	if _, ok := c.state.Workflows[workflowID]; !ok {
+		return nil, notFound("Workflow", workflowID)
+	}
+
+	res := []metalcloud.WorkflowStageDefinitionReference{}
+	for _, id := range sortedIDs(c.state.WorkflowStages) {
+		if s := c.state.WorkflowStages[id]; s.WorkflowID == workflowID {
+			res = append(res, s)
+		}
+	}
+
+	sort.SliceStable(res, func(i, j int) bool {
+		return res[i].WorkflowStageRunLevel < res[j].WorkflowStageRunLevel
+	})
+
+	return &res, nil
+}
+
+//WorkflowStageGet returns a stage of a workflow
+func (c *Client) WorkflowStageGet(workflowStageID int) (*metalcloud.WorkflowStageDefinitionReference, error) {
+	s, ok := c.state.WorkflowStages[workflowStageID]
+	if !ok {
+		return nil, notFound("Workflow stage", workflowStageID)
+	}
+	return &s, nil
+}
+
+//shiftRunLevels moves the stages of a workflow at or after a run level one run level later, making room for a new run level
+func (c *Client) shiftRunLevels(workflowID int, runLevel int) {
+	for id, s := range c.state.WorkflowStages {
+		if s.WorkflowID == workflowID && s.WorkflowStageRunLevel >= runLevel {
+			s.WorkflowStageRunLevel++
+			c.state.WorkflowStages[id] = s
+		}
+	}
+}
+
+func (c *Client) workflowStageAdd(workflowID int, stageDefinitionID int, destinationRunLevel int, asNewRunLevel bool) error {
+
+	if _, ok := c.state.Workflows[workflowID]; !ok {
+		return notFound("Workflow", workflowID)
+	}
+
+	if _, ok := c.state.StageDefinitions[stageDefinitionID]; !ok {
+		return notFound("Stage definition", stageDefinitionID)
+	}
+
+	if asNewRunLevel {
+		c.shiftRunLevels(workflowID, destinationRunLevel)
+	}
+
+	id := c.nextID()
+	c.state.WorkflowStages[id] = metalcloud.WorkflowStageDefinitionReference{
+		WorkflowStageID:       id,
+		WorkflowID:            workflowID,
+		StageDefinitionID:     stageDefinitionID,
+		WorkflowStageRunLevel: destinationRunLevel,
+	}
+
+	return c.save()
+}
+
+//WorkflowStageAddAsNewRunLevel adds a stage to a workflow in a new run level
+func (c *Client) WorkflowStageAddAsNewRunLevel(workflowID int, stageDefinitionID int, destinationRunLevel int) error {
+	return c.workflowStageAdd(workflowID, stageDefinitionID, destinationRunLevel, true)
+}
+
+//WorkflowStageAddIntoRunLevel adds a stage to an existing run level of a workflow
+func (c *Client) WorkflowStageAddIntoRunLevel(workflowID int, stageDefinitionID int, destinationRunLevel int) error {
+	return c.workflowStageAdd(workflowID, stageDefinitionID, destinationRunLevel, false)
+}
+
+func (c *Client) workflowMove(workflowID int, stageDefinitionID int, sourceRunLevel int, destinationRunLevel int, asNewRunLevel bool) error {
+
+	if _, ok := c.state.Workflows[workflowID]; !ok {
+		return notFound("Workflow", workflowID)
+	}
+
+	for _, id := range sortedIDs(c.state.WorkflowStages) {
+		s := c.state.WorkflowStages[id]
+		if s.WorkflowID != workflowID || s.StageDefinitionID != stageDefinitionID || s.WorkflowStageRunLevel != sourceRunLevel {
+			continue
+		}
+
+		if asNewRunLevel {
+			c.shiftRunLevels(workflowID, destinationRunLevel)
+			s = c.state.WorkflowStages[id]
+		}
+
+		s.WorkflowStageRunLevel = destinationRunLevel
+		c.state.WorkflowStages[id] = s
+
+		return c.save()
+	}
+
+	return fmt.Errorf("Stage definition %d not found in run level %d of workflow %d", stageDefinitionID, sourceRunLevel, workflowID)
+}
+
+//WorkflowMoveAsNewRunLevel moves a stage of a workflow to a new run level
+func (c *Client) WorkflowMoveAsNewRunLevel(workflowID int, stageDefinitionID int, sourceRunLevel int, destinationRunLevel int) error {
+	return c.workflowMove(workflowID, stageDefinitionID, sourceRunLevel, destinationRunLevel, true)
+}
+
+//WorkflowMoveIntoRunLevel moves a stage of a workflow to an existing run level
+func (c *Client) WorkflowMoveIntoRunLevel(workflowID int, stageDefinitionID int, sourceRunLevel int, destinationRunLevel int) error {
+	return c.workflowMove(workflowID, stageDefinitionID, sourceRunLevel, destinationRunLevel, false)
+}
+
+//WorkflowStageDelete removes a stage from a workflow
+func (c *Client) WorkflowStageDelete(workflowStageID int) error {
+
+	if _, ok := c.state.WorkflowStages[workflowStageID]; !ok {
+		return notFound("Workflow stage", workflowStageID)
+	}
+
+	delete(c.state.WorkflowStages, workflowStageID)
+
+	return c.save()
+}
@@ -0,0 +1,797 @@
+package fake
+
+import (
+	"fmt"
+
+	metalcloud "github.com/bigstepinc/metal-cloud-sdk-go"
+)
+
+const (
+	defaultStorageType     = "iscsi_ssd"
+	defaultDriveSizeMBytes = 40960
+)
+
+func (c *Client) driveArray(driveArrayID int) (*metalcloud.DriveArray, error) {
+	da, ok := c.state.DriveArrays[driveArrayID]
+	if !ok {
+		return nil, notFound("Drive array", driveArrayID)
+	}
+	return &da, nil
+}
+
+func (c *Client) driveArrayIDByLabel(driveArrayLabel string) (int, error) {
+	for _, id := range sortedIDs(c.state.DriveArrays) {
+		if c.state.DriveArrays[id].DriveArrayLabel == driveArrayLabel {
+			return id, nil
+		}
+	}
+	return 0, notFound("Drive array", driveArrayLabel)
+}
+
+//checkInstanceArrayInInfrastructure returns an error if the instance array is set and not part of the infrastructure
+func (c *Client) checkInstanceArrayInInfrastructure(instanceArrayID int, infrastructureID int) error {
+
+	if instanceArrayID == 0 {
+		return nil
+	}
+
+	ia, err := c.instanceArray(instanceArrayID)
+	if err != nil {
+		return err
+	}
+
+	if ia.InfrastructureID != infrastructureID {
+		return fmt.Errorf("Instance array %d is not in infrastructure %d", instanceArrayID, infrastructureID)
+	}
+
+	return nil
+}
+
+//removeDriveArray deletes a drive array with its drives and their snapshots
+func (c *Client) removeDriveArray(driveArrayID int) {
+
+	for id, d := range c.state.Drives {
+		if d.DriveArrayID == driveArrayID {
+			c.removeDrive(id)
+		}
+	}
+
+	delete(c.state.DriveArrays, driveArrayID)
+}
+
+func (c *Client) removeDrive(driveID int) {
+
+	for id, s := range c.state.Snapshots {
+		if s.DriveID == driveID {
+			delete(c.state.Snapshots, id)
+		}
+	}
+
+	delete(c.state.Drives, driveID)
+}
+
+//deployDrives creates or removes drives so that their number matches the drive array and attaches them to the instances
+func (c *Client) deployDrives(da metalcloud.DriveArray) {
+
+	count := da.DriveArrayCount
+	instanceIDs := []int{}
+	if da.InstanceArrayID != 0 {
+		instanceIDs = c.instanceArrayInstanceIDs(da.InstanceArrayID)
+		if da.DriveArrayExpandWithInstanceArray {
+			count = len(instanceIDs)
+		}
+	}
+
+	ids := []int{}
+	for _, id := range sortedIDs(c.state.Drives) {
+		if c.state.Drives[id].DriveArrayID == da.DriveArrayID {
+			ids = append(ids, id)
+		}
+	}
+
+	for len(ids) > count {
+		c.removeDrive(ids[len(ids)-1])
+		ids = ids[:len(ids)-1]
+	}
+
+	for i := len(ids); i < count; i++ {
+		id := c.nextID()
+		c.state.Drives[id] = metalcloud.Drive{
+			DriveID:               id,
+			DriveLabel:            fmt.Sprintf("drive-%d", id),
+			DriveArrayID:          da.DriveArrayID,
+			InfrastructureID:      da.InfrastructureID,
+			DriveSizeMBytes:       da.DriveSizeMBytesDefault,
+			DriveStorageType:      da.DriveArrayStorageType,
+			TemplateIDOrigin:      da.VolumeTemplateID,
+			DriveServiceStatus:    serviceStatusActive,
+			DriveCreatedTimestamp: now(),
+		}
+		ids = append(ids, id)
+	}
+
+	for i, id := range ids {
+		d := c.state.Drives[id]
+		d.InstanceID = 0
+		if i < len(instanceIDs) {
+			d.InstanceID = instanceIDs[i]
+		}
+		//drives only grow
+		if d.DriveSizeMBytes < da.DriveSizeMBytesDefault {
+			d.DriveSizeMBytes = da.DriveSizeMBytesDefault
+		}
+		d.DriveUpdatedTimestamp = now()
+		c.state.Drives[id] = d
+	}
+}
+
+//DriveArrayCreate creates a drive array. The drives are created on deploy.
+func (c *Client) DriveArrayCreate(infrastructureID int, driveArray metalcloud.DriveArray) (*metalcloud.DriveArray, error) {
+
+	if _, err := c.infrastructure(infrastructureID); err != nil {
+		return nil, err
+	}
+
+	if err := c.checkInstanceArrayInInfrastructure(driveArray.InstanceArrayID, infrastructureID); err != nil {
+		return nil, err
+	}
+
+	if driveArray.VolumeTemplateID != 0 {
+		if _, ok := c.state.VolumeTemplates[driveArray.VolumeTemplateID]; !ok {
+			return nil, notFound("Volume template", driveArray.VolumeTemplateID)
+		}
+	}
+
+	driveArray.DriveArrayID = c.nextID()
+	driveArray.InfrastructureID = infrastructureID
+	driveArray.DriveArrayServiceStatus = serviceStatusOrdered
+
+	if driveArray.DriveArrayLabel == "" {
+		driveArray.DriveArrayLabel = fmt.Sprintf("drive-array-%d", driveArray.DriveArrayID)
+	}
+
+	for _, da := range c.state.DriveArrays {
+		if da.InfrastructureID == infrastructureID && da.DriveArrayLabel == driveArray.DriveArrayLabel {
+			return nil, fmt.Errorf("Drive array label %s is already in use", driveArray.DriveArrayLabel)
+		}
+	}
+
+	if driveArray.DriveArrayStorageType == "" {
+		driveArray.DriveArrayStorageType = defaultStorageType
+	}
+	if driveArray.DriveSizeMBytesDefault == 0 {
+		driveArray.DriveSizeMBytesDefault = defaultDriveSizeMBytes
+	}
+	if driveArray.DriveArrayCount == 0 {
+		driveArray.DriveArrayCount = 1
+	}
+
+	dao := metalcloud.DriveArrayOperation{}
+	copyFields(&dao, &driveArray)
+	dao.DriveArrayDeployType = deployTypeCreate
+	dao.DriveArrayDeployStatus = deployStatusNotStarted
+	driveArray.DriveArrayOperation = &dao
+
+	c.state.DriveArrays[driveArray.DriveArrayID] = driveArray
+	c.markInfrastructureChanged(infrastructureID)
+
+	return &driveArray, c.save()
+}
+
+//DriveArrayCreateByLabel creates a drive array
+func (c *Client) DriveArrayCreateByLabel(infrastructureLabel string, driveArray metalcloud.DriveArray) (*metalcloud.DriveArray, error) {
+	id, err := c.infrastructureIDByLabel(infrastructureLabel)
+	if err != nil {
+		return nil, err
+	}
+	return c.DriveArrayCreate(id, driveArray)
+}
+
+//DriveArrayEdit replaces the operation of a drive array
+func (c *Client) DriveArrayEdit(driveArrayID int, driveArrayOperation metalcloud.DriveArrayOperation) (*metalcloud.DriveArray, error) {
+
+	da, err := c.driveArray(driveArrayID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := c.checkInstanceArrayInInfrastructure(driveArrayOperation.InstanceArrayID, da.InfrastructureID); err != nil {
+		return nil, err
+	}
+
+	driveArrayOperation.DriveArrayID = driveArrayID
+	driveArrayOperation.InfrastructureID = da.InfrastructureID
+	driveArrayOperation.DriveArrayDeployType = deployTypeFor(da.DriveArrayServiceStatus)
+	driveArrayOperation.DriveArrayDeployStatus = deployStatusNotStarted
+	da.DriveArrayOperation = &driveArrayOperation
+
+	c.state.DriveArrays[driveArrayID] = *da
+	c.markInfrastructureChanged(da.InfrastructureID)
+
+	return da, c.save()
+}
+
+//DriveArrayEditByLabel replaces the operation of a drive array
+func (c *Client) DriveArrayEditByLabel(driveArrayLabel string, driveArrayOperation metalcloud.DriveArrayOperation) (*metalcloud.DriveArray, error) {
+	id, err := c.driveArrayIDByLabel(driveArrayLabel)
+	if err != nil {
+		return nil, err
+	}
+	return c.DriveArrayEdit(id, driveArrayOperation)
+}
+
+//DriveArrayDelete deletes a drive array on the next deploy, or immediately if it was never deployed
+func (c *Client) DriveArrayDelete(driveArrayID int) error {
+
+	da, err := c.driveArray(driveArrayID)
+	if err != nil {
+		return err
+	}
+
+	if da.DriveArrayServiceStatus == serviceStatusOrdered {
+		c.removeDriveArray(driveArrayID)
+	} else {
+		da.DriveArrayOperation.DriveArrayDeployType = deployTypeDelete
+		da.DriveArrayOperation.DriveArrayDeployStatus = deployStatusNotStarted
+		c.state.DriveArrays[driveArrayID] = *da
+	}
+
+	c.markInfrastructureChanged(da.InfrastructureID)
+
+	return c.save()
+}
+
+//DriveArrayDeleteByLabel deletes a drive array
+func (c *Client) DriveArrayDeleteByLabel(driveArrayLabel string) error {
+	id, err := c.driveArrayIDByLabel(driveArrayLabel)
+	if err != nil {
+		return err
+	}
+	return c.DriveArrayDelete(id)
+}
+
+//DriveArrayGet returns a drive array
+func (c *Client) DriveArrayGet(driveArrayID int) (*metalcloud.DriveArray, error) {
+	return c.driveArray(driveArrayID)
+}
+
+//DriveArrayGetByLabel returns a drive array
+func (c *Client) DriveArrayGetByLabel(driveArrayLabel string) (*metalcloud.DriveArray, error) {
+	id, err := c.driveArrayIDByLabel(driveArrayLabel)
+	if err != nil {
+		return nil, err
+	}
+	return c.DriveArrayGet(id)
+}
+
+//DriveArrays returns the drive arrays of an infrastructure
+func (c *Client) DriveArrays(infrastructureID int) (*map[string]metalcloud.DriveArray, error) {
+
+	if _, err := c.infrastructure(infrastructureID); err != nil {
+		return nil, err
+	}
+
+	res := map[string]metalcloud.DriveArray{}
+	for _, da := range c.state.DriveArrays {
+		if da.InfrastructureID == infrastructureID {
+			res[da.DriveArrayLabel] = da
+		}
+	}
+
+	return &res, nil
+}
+
+//DriveArraysByLabel returns the drive arrays of an infrastructure
+func (c *Client) DriveArraysByLabel(infrastructureLabel string) (*map[string]metalcloud.DriveArray, error) {
+	id, err := c.infrastructureIDByLabel(infrastructureLabel)
+	if err != nil {
+		return nil, err
+	}
+	return c.DriveArrays(id)
+}
+
+//DriveArrayDrives returns the drives of a drive array
+func (c *Client) DriveArrayDrives(driveArray int) (*map[string]metalcloud.Drive, error) {
+
+	if _, err := c.driveArray(driveArray); err != nil {
+		return nil, err
+	}
+
+	res := map[string]metalcloud.Drive{}
+	for _, d := range c.state.Drives {
+		if d.DriveArrayID == driveArray {
+			res[d.DriveLabel] = d
+		}
+	}
+
+	return &res, nil
+}
+
+//DriveArrayDrivesByLabel returns the drives of a drive array
+func (c *Client) DriveArrayDrivesByLabel(driveArrLabel string) (*map[string]metalcloud.Drive, error) {
+	id, err := c.driveArrayIDByLabel(driveArrLabel)
+	if err != nil {
+		return nil, err
+	}
+	return c.DriveArrayDrives(id)
+}
+
+//DriveSnapshotCreate creates a snapshot of a drive
+func (c *Client) DriveSnapshotCreate(driveID int) (*metalcloud.Snapshot, error) {
+
+	if _, ok := c.state.Drives[driveID]; !ok {
+		return nil, notFound("Drive", driveID)
+	}
+
+	id := c.nextID()
+	s := metalcloud.Snapshot{
+		DriveSnapshotID:               id,
+		DriveSnapshotLabel:            fmt.Sprintf("snapshot-%d", id),
+		DriveID:                       driveID,
+		DriveSnapshotCreatedTimestamp: now(),
+	}
+	c.state.Snapshots[id] = s
+
+	return &s, c.save()
+}
+
+//DriveSnapshotDelete deletes a snapshot
+func (c *Client) DriveSnapshotDelete(driveSnapshotID int) error {
+
+	if _, ok := c.state.Snapshots[driveSnapshotID]; !ok {
+		return notFound("Drive snapshot", driveSnapshotID)
+	}
+
+	delete(c.state.Snapshots, driveSnapshotID)
+
+	return c.save()
+}
+
+//DriveSnapshotRollback restores a drive from a snapshot. The contents of drives are not kept so only the snapshot is checked.
+func (c *Client) DriveSnapshotRollback(driveSnapshotID int) error {
+
+	if _, ok := c.state.Snapshots[driveSnapshotID]; !ok {
+		return notFound("Drive snapshot", driveSnapshotID)
+	}
+
+	return nil
+}
+
+//DriveSnapshotGet returns a snapshot
+func (c *Client) DriveSnapshotGet(driveSnapshotID int) (*metalcloud.Snapshot, error) {
+
+	s, ok := c.state.Snapshots[driveSnapshotID]
+	if !ok {
+		return nil, notFound("Drive snapshot", driveSnapshotID)
+	}
+
+	return &s, nil
+}
+
+//DriveSnapshots returns the snapshots of a drive
+func (c *Client) DriveSnapshots(driveID int) (*map[string]metalcloud.Snapshot, error) {
+
+	if _, ok := c.state.Drives[driveID]; !ok {
+		return nil, notFound("Drive", driveID)
+	}
+
+	res := map[string]metalcloud.Snapshot{}
+	for _, s := range c.state.Snapshots {
+		if s.DriveID == driveID {
+			res[s.DriveSnapshotLabel] = s
+		}
+	}
+
+	return &res, nil
+}
+
+func (c *Client) network(networkID int) (*metalcloud.Network, error) {
+	n, ok := c.state.Networks[networkID]
+	if !ok {
+		return nil, notFound("Network", networkID)
+	}
+	return &n, nil
+}
+
+func (c *Client) networkIDByLabel(networkLabel string) (int, error) {
+	for _, id := range sortedIDs(c.state.Networks) {
+		if c.state.Networks[id].NetworkLabel == networkLabel {
+			return id, nil
+		}
+	}
+	return 0, notFound("Network", networkLabel)
+}
+
+//removeNetwork deletes a network and detaches the interfaces connected to it
+func (c *Client) removeNetwork(networkID int) {
+	c.moveInterfaces(networkID, 0)
+	delete(c.state.Networks, networkID)
+}
+
+//moveInterfaces connects the instance array interfaces attached to a network to another one, or detaches them if the other one is 0
+func (c *Client) moveInterfaces(fromNetworkID int, toNetworkID int) {
+
+	for id, ia := range c.state.InstanceArrays {
+		changed := false
+		for i := range ia.InstanceArrayInterfaces {
+			if ia.InstanceArrayInterfaces[i].NetworkID == fromNetworkID {
+				ia.InstanceArrayInterfaces[i].NetworkID = toNetworkID
+				changed = true
+			}
+		}
+		if ia.InstanceArrayOperation != nil {
+			for i := range ia.InstanceArrayOperation.InstanceArrayInterfaces {
+				if ia.InstanceArrayOperation.InstanceArrayInterfaces[i].NetworkID == fromNetworkID {
+					ia.InstanceArrayOperation.InstanceArrayInterfaces[i].NetworkID = toNetworkID
+					changed = true
+				}
+			}
+		}
+		if changed {
+			c.state.InstanceArrays[id] = ia
+		}
+	}
+}
+
+func (c *Client) networkCreate(infrastructureID int, network metalcloud.Network) (*metalcloud.Network, error) {
+
+	infra, err := c.infrastructure(infrastructureID)
+	if err != nil {
+		return nil, err
+	}
+
+	switch network.NetworkType {
+	case "lan":
+	case "wan", "san":
+		for _, n := range c.state.Networks {
+			if n.InfrastructureID == infrastructureID && n.NetworkType == network.NetworkType {
+				return nil, fmt.Errorf("Infrastructure %d already has a %s network", infrastructureID, network.NetworkType)
+			}
+		}
+	default:
+		return nil, fmt.Errorf("Invalid network type %s", network.NetworkType)
+	}
+
+	network.NetworkID = c.nextID()
+	network.InfrastructureID = infrastructureID
+	network.NetworkCreatedTimestamp = now()
+	network.NetworkUpdatedTimestamp = network.NetworkCreatedTimestamp
+
+	if network.NetworkLabel == "" {
+		network.NetworkLabel = fmt.Sprintf("%s-%d", network.NetworkType, network.NetworkID)
+	}
+
+	for _, n := range c.state.Networks {
+		if n.InfrastructureID == infrastructureID && n.NetworkLabel == network.NetworkLabel {
+			return nil, fmt.Errorf("Network label %s is already in use", network.NetworkLabel)
+		}
+	}
+
+	network.NetworkSubdomain = fmt.Sprintf("%s.%s", network.NetworkLabel, infra.InfrastructureSubdomain)
+
+	no := metalcloud.NetworkOperation{}
+	copyFields(&no, &network)
+	no.NetworkDeployType = deployTypeCreate
+	network.NetworkOperation = &no
+
+	c.state.Networks[network.NetworkID] = network
+
+	return &network, nil
+}
+
+//NetworkCreate creates a network. Each infrastructure has a single wan and san network.
+func (c *Client) NetworkCreate(infrastructureID int, network metalcloud.Network) (*metalcloud.Network, error) {
+
+	n, err := c.networkCreate(infrastructureID, network)
+	if err != nil {
+		return nil, err
+	}
+
+	c.markInfrastructureChanged(infrastructureID)
+
+	return n, c.save()
+}
+
+//NetworkCreateByLabel creates a network
+func (c *Client) NetworkCreateByLabel(infrastructureLabel string, network metalcloud.Network) (*metalcloud.Network, error) {
+	id, err := c.infrastructureIDByLabel(infrastructureLabel)
+	if err != nil {
+		return nil, err
+	}
+	return c.NetworkCreate(id, network)
+}
+
+//NetworkEdit replaces the operation of a network
+func (c *Client) NetworkEdit(networkID int, networkOperation metalcloud.NetworkOperation) (*metalcloud.Network, error) {
+
+	n, err := c.network(networkID)
+	if err != nil {
+		return nil, err
+	}
+
+	if networkOperation.NetworkType != n.NetworkType {
+		return nil, fmt.Errorf("The type of network %d cannot be changed", networkID)
+	}
+
+	deployType := deployTypeEdit
+	if n.NetworkOperation != nil && n.NetworkOperation.NetworkDeployType == deployTypeCreate {
+		deployType = deployTypeCreate
+	}
+
+	networkOperation.NetworkID = networkID
+	networkOperation.InfrastructureID = n.InfrastructureID
+	networkOperation.NetworkDeployType = deployType
+	n.NetworkOperation = &networkOperation
+
+	c.state.Networks[networkID] = *n
+	c.markInfrastructureChanged(n.InfrastructureID)
+
+	return n, c.save()
+}
+
+//NetworkEditByLabel replaces the operation of a network
+func (c *Client) NetworkEditByLabel(networkLabel string, networkOperation metalcloud.NetworkOperation) (*metalcloud.Network, error) {
+	id, err := c.networkIDByLabel(networkLabel)
+	if err != nil {
+		return nil, err
+	}
+	return c.NetworkEdit(id, networkOperation)
+}
+
+//NetworkDelete deletes a network on the next deploy, or immediately if it was never deployed
+func (c *Client) NetworkDelete(networkID int) error {
+
+	n, err := c.network(networkID)
+	if err != nil {
+		return err
+	}
+
+	if n.NetworkOperation == nil || n.NetworkOperation.NetworkDeployType == deployTypeCreate {
+		c.removeNetwork(networkID)
+	} else {
+		n.NetworkOperation.NetworkDeployType = deployTypeDelete
+		c.state.Networks[networkID] = *n
+	}
+
+	c.markInfrastructureChanged(n.InfrastructureID)
+
+	return c.save()
+}
+
+//NetworkDeleteByLabel deletes a network
+func (c *Client) NetworkDeleteByLabel(networkLabel string) error {
+	id, err := c.networkIDByLabel(networkLabel)
+	if err != nil {
+		return err
+	}
+	return c.NetworkDelete(id)
+}
+
+//NetworkJoin moves the interfaces of a network to another network of the same infrastructure and deletes it
+func (c *Client) NetworkJoin(networkID int, networkToBeDeletedID int) error {
+
+	n, err := c.network(networkID)
+	if err != nil {
+		return err
+	}
+
+	other, err := c.network(networkToBeDeletedID)
+	if err != nil {
+		return err
+	}
+
+	if n.InfrastructureID != other.InfrastructureID {
+		return fmt.Errorf("Networks %d and %d are not in the same infrastructure", networkID, networkToBeDeletedID)
+	}
+
+	c.moveInterfaces(networkToBeDeletedID, networkID)
+	delete(c.state.Networks, networkToBeDeletedID)
+	c.markInfrastructureChanged(n.InfrastructureID)
+
+	return c.save()
+}
+
+//NetworkJoinByLabel moves the interfaces of a network to another network and deletes it
+func (c *Client) NetworkJoinByLabel(networkLabel string, networkToBeDeletedID int) error {
+	id, err := c.networkIDByLabel(networkLabel)
+	if err != nil {
+		return err
+	}
+	return c.NetworkJoin(id, networkToBeDeletedID)
+}
+
+//NetworkGet returns a network
+func (c *Client) NetworkGet(networkID int) (*metalcloud.Network, error) {
+	return c.network(networkID)
+}
+
+//NetworkGetByLabel returns a network
+func (c *Client) NetworkGetByLabel(networkLabel string) (*metalcloud.Network, error) {
+	id, err := c.networkIDByLabel(networkLabel)
+	if err != nil {
+		return nil, err
+	}
+	return c.NetworkGet(id)
+}
+
+//Networks returns the networks of an infrastructure
+func (c *Client) Networks(infrastructureID int) (*map[string]metalcloud.Network, error) {
+
+	if _, err := c.infrastructure(infrastructureID); err != nil {
+		return nil, err
+	}
+
+	res := map[string]metalcloud.Network{}
+	for _, n := range c.state.Networks {
+		if n.InfrastructureID == infrastructureID {
+			res[n.NetworkLabel] = n
+		}
+	}
+
+	return &res, nil
+}
+
+//NetworksByLabel returns the networks of an infrastructure
+func (c *Client) NetworksByLabel(infrastructureLabel string) (*map[string]metalcloud.Network, error) {
+	id, err := c.infrastructureIDByLabel(infrastructureLabel)
+	if err != nil {
+		return nil, err
+	}
+	return c.Networks(id)
+}
+
+func (c *Client) sharedDrive(sharedDriveID int) (*metalcloud.SharedDrive, error) {
+	sd, ok := c.state.SharedDrives[sharedDriveID]
+	if !ok {
+		return nil, notFound("Shared drive", sharedDriveID)
+	}
+	return &sd, nil
+}
+
+func (c *Client) sharedDriveIDByLabel(sharedDriveLabel string) (int, error) {
+	for _, id := range sortedIDs(c.state.SharedDrives) {
+		if c.state.SharedDrives[id].SharedDriveLabel == sharedDriveLabel {
+			return id, nil
+		}
+	}
+	return 0, notFound("Shared drive", sharedDriveLabel)
+}
+
+//SharedDriveCreate creates a shared drive
+func (c *Client) SharedDriveCreate(infrastructureID int, sharedDrive metalcloud.SharedDrive) (*metalcloud.SharedDrive, error) {
+
+	infra, err := c.infrastructure(infrastructureID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, iaID := range sharedDrive.SharedDriveAttachedInstanceArrays {
+		if err := c.checkInstanceArrayInInfrastructure(iaID, infrastructureID); err != nil {
+			return nil, err
+		}
+	}
+
+	sharedDrive.SharedDriveID = c.nextID()
+	sharedDrive.InfrastructureID = infrastructureID
+	sharedDrive.SharedDriveServiceStatus = serviceStatusOrdered
+	sharedDrive.SharedDriveCreatedTimestamp = now()
+	sharedDrive.SharedDriveUpdatedTimestamp = sharedDrive.SharedDriveCreatedTimestamp
+
+	if sharedDrive.SharedDriveLabel == "" {
+		sharedDrive.SharedDriveLabel = fmt.Sprintf("shared-drive-%d", sharedDrive.SharedDriveID)
+	}
+
+	for _, sd := range c.state.SharedDrives {
+		if sd.InfrastructureID == infrastructureID && sd.SharedDriveLabel == sharedDrive.SharedDriveLabel {
+			return nil, fmt.Errorf("Shared drive label %s is already in use", sharedDrive.SharedDriveLabel)
+		}
+	}
+
+	if sharedDrive.SharedDriveStorageType == "" {
+		sharedDrive.SharedDriveStorageType = defaultStorageType
+	}
+	if sharedDrive.SharedDriveSizeMbytes == 0 {
+		sharedDrive.SharedDriveSizeMbytes = defaultDriveSizeMBytes
+	}
+	sharedDrive.SharedDriveSubdomain = fmt.Sprintf("%s.%s", sharedDrive.SharedDriveLabel, infra.InfrastructureSubdomain)
+
+	sdo := metalcloud.SharedDriveOperation{}
+	copyFields(&sdo, &sharedDrive)
+	sdo.SharedDriveDepoloyType = deployTypeCreate
+	sdo.SharedDriveDeployStatus = deployStatusNotStarted
+	sharedDrive.SharedDriveOperation = sdo
+
+	c.state.SharedDrives[sharedDrive.SharedDriveID] = sharedDrive
+	c.markInfrastructureChanged(infrastructureID)
+
+	return &sharedDrive, c.save()
+}
+
+//SharedDriveCreateByLabel creates a shared drive
+func (c *Client) SharedDriveCreateByLabel(infrastructureLabel string, sharedDrive metalcloud.SharedDrive) (*metalcloud.SharedDrive, error) {
+	id, err := c.infrastructureIDByLabel(infrastructureLabel)
+	if err != nil {
+		return nil, err
+	}
+	return c.SharedDriveCreate(id, sharedDrive)
+}
+
+//SharedDriveEdit replaces the operation of a shared drive
+func (c *Client) SharedDriveEdit(sharedDriveID int, sharedDriveOperation metalcloud.SharedDriveOperation) (*metalcloud.SharedDrive, error) {
+
+	sd, err := c.sharedDrive(sharedDriveID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, iaID := range sharedDriveOperation.SharedDriveAttachedInstanceArrays {
+		if err := c.checkInstanceArrayInInfrastructure(iaID, sd.InfrastructureID); err != nil {
+			return nil, err
+		}
+	}
+
+	sharedDriveOperation.SharedDriveID = sharedDriveID
+	sharedDriveOperation.InfrastructureID = sd.InfrastructureID
+	sharedDriveOperation.SharedDriveServiceStatus = sd.SharedDriveServiceStatus
+	sharedDriveOperation.SharedDriveDepoloyType = deployTypeFor(sd.SharedDriveServiceStatus)
+	sharedDriveOperation.SharedDriveDeployStatus = deployStatusNotStarted
+	sd.SharedDriveOperation = sharedDriveOperation
+
+	c.state.SharedDrives[sharedDriveID] = *sd
+	c.markInfrastructureChanged(sd.InfrastructureID)
+
+	return sd, c.save()
+}
+
+//SharedDriveEditByLabel replaces the operation of a shared drive
+func (c *Client) SharedDriveEditByLabel(sharedDriveLabel string, sharedDriveOperation metalcloud.SharedDriveOperation) (*metalcloud.SharedDrive, error) {
+	id, err := c.sharedDriveIDByLabel(sharedDriveLabel)
+	if err != nil {
+		return nil, err
+	}
+	return c.SharedDriveEdit(id, sharedDriveOperation)
+}
+
+//SharedDriveDelete deletes a shared drive on the next deploy, or immediately if it was never deployed
+func (c *Client) SharedDriveDelete(sharedDriveID int) error {
+
+	sd, err := c.sharedDrive(sharedDriveID)
+	if err != nil {
+		return err
+	}
+
+	if sd.SharedDriveServiceStatus == serviceStatusOrdered {
+		delete(c.state.SharedDrives, sharedDriveID)
+	} else {
+		sd.SharedDriveOperation.SharedDriveDepoloyType = deployTypeDelete
+		sd.SharedDriveOperation.SharedDriveDeployStatus = deployStatusNotStarted
+		c.state.SharedDrives[sharedDriveID] = *sd
+	}
+
+	c.markInfrastructureChanged(sd.InfrastructureID)
+
+	return c.save()
+}
+
+//SharedDriveDeleteByLabel deletes a shared drive
+func (c *Client) SharedDriveDeleteByLabel(sharedDriveLabel string) error {
+	id, err := c.sharedDriveIDByLabel(sharedDriveLabel)
+	if err != nil {
+		return err
+	}
+	return c.SharedDriveDelete(id)
+}
+
+//SharedDriveGet returns a shared drive
+func (c *Client) SharedDriveGet(sharedDriveID int) (*metalcloud.SharedDrive, error) {
+	return c.sharedDrive(sharedDriveID)
+}
+
+//SharedDriveGetByLabel returns a shared drive
+func (c *Client) SharedDriveGetByLabel(sharedDriveLabel string) (*metalcloud.SharedDrive, error) {
+	id, err := c.sharedDriveIDByLabel(sharedDriveLabel)
+	if err != nil {
+		return nil, err
+	}
+	return c.SharedDriveGet(id)
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"time"
+
+	interfaces "github.com/bigstepinc/metalcloud-cli/interfaces"
+)
+
+//GlobalFlags holds the flags that apply to all commands. They are given before the subject and predicate.
+type GlobalFlags struct {
+	Format     string
+	Profile    string
+	Datacenter string
+	Quiet      bool
+	Debug      bool
+	Timeout    time.Duration
+	Columns    string
+	SortBy     string
+	Where      string
+}
+
+//globalFlags holds the global flags of the current execution
+var globalFlags GlobalFlags
+
+func newGlobalFlagSet(g *GlobalFlags) *flag.FlagSet {
+	fs := flag.NewFlagSet("global flags", flag.ContinueOnError)
+	fs.SetOutput(ioutil.Discard)
+
+	fs.StringVar(&g.Format, "format", "", "The default output format of all commands. Supported values are 'json','csv','yaml','template=<go template>','jsonpath=<expression>'. A command's own -format takes precedence.")
+	fs.StringVar(&g.Profile, "profile", "", "The configuration profile to use. Overrides METALCLOUD_PROFILE.")
+	fs.StringVar(&g.Datacenter, "datacenter", "", "The default datacenter. Overrides METALCLOUD_DATACENTER.")
+	fs.BoolVar(&g.Quiet, "quiet", false, "(Flag) If set the output of the command is not printed. Errors are still reported.")
+	fs.BoolVar(&g.Debug, "debug", false, "(Flag) If set the API calls are logged.")
+	fs.DurationVar(&g.Timeout, "timeout", 0, "Maximum duration of the command such as '30s' or '5m'. Unlimited by default.")
+	fs.Bool("no-color", false, "(Flag) Accepted for compatibility. The output is never colored.")
+	addTableFlags(fs, g)
+
+	return fs
+}
+
+var tableFlagsUsage = map[string]string{
+	"columns": "Comma separated list of the columns to show, in order. Ex: 'ID,LABEL,STATUS'",
+	"sort-by": "Comma separated list of the columns to sort by. Prefix a column with - to sort in descending order. Ex: 'STATUS,-ID'",
+	"where":   "Comma separated list of conditions the rows must satisfy. Operators are =, <, > and ~ (glob pattern). Ex: 'STATUS=active,LABEL~web-*'",
+}
+
+//addTableFlags adds the -columns, -sort-by and -where flags to the flag set
+func addTableFlags(fs *flag.FlagSet, g *GlobalFlags) {
+
+	vars := map[string]*string{
+		"columns": &g.Columns,
+		"sort-by": &g.SortBy,
+		"where":   &g.Where,
+	}
+
+	for _, name := range []string{"columns", "sort-by", "where"} {
+		fs.StringVar(vars[name], name, *vars[name], tableFlagsUsage[name])
+	}
+}
+
+//formatUsage is the usage of the -format flag of the commands that are Formatted
+const formatUsage = "The output format. Supported values are 'json','csv','yaml','template=<go template>','jsonpath=<expression>'. The default format is human readable."
+
+//addFormatFlags adds the -format flag and the table flags to commands that are Formatted, after their own flags are initialized
+func addFormatFlags(c *Command, g *GlobalFlags) {
+
+	if !c.Formatted {
+		return
+	}
+
+	if c.Arguments == nil {
+		c.Arguments = map[string]interface{}{}
+	}
+
+	c.Arguments["format"] = c.FlagSet.String("format", "", formatUsage)
+
+	addTableFlags(c.FlagSet, g)
+}
+
+//parseGlobalFlags parses the flags given before the subject and returns the remaining arguments, starting with the program name
+func parseGlobalFlags(args []string) (*GlobalFlags, []string, error) {
+
+	g := GlobalFlags{}
+
+	if len(args) < 2 {
+		return &g, args, nil
+	}
+
+	fs := newGlobalFlagSet(&g)
+
+	err := fs.Parse(args[1:])
+	if err == flag.ErrHelp {
+		return &g, []string{args[0], "help"}, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return &g, append([]string{args[0]}, fs.Args()...), nil
+}
+
+//applyGlobalFlags makes the global flags available to the rest of the program.
+//The datacenter and debug flags are passed through the environment as the client and the commands' defaults read them from there.
+func applyGlobalFlags(g GlobalFlags) {
+
+	globalFlags = g
+
+	if g.Datacenter != "" {
+		os.Setenv("METALCLOUD_DATACENTER", g.Datacenter)
+	}
+
+	if g.Debug {
+		os.Setenv("METALCLOUD_LOGGING_ENABLED", "true")
+	}
+}
+
+//applyGlobalFormat sets the global format on Formatted commands that do not have their own -format set.
+//Other commands either have no output format or, like infrastructure export, formats of their own.
+func applyGlobalFormat(c *Command) {
+
+	if globalFlags.Format == "" || !c.Formatted {
+		return
+	}
+
+	if getStringParam(c.Arguments["format"]) != "" {
+		return
+	}
+
+	if c.Arguments == nil {
+		c.Arguments = map[string]interface{}{}
+	}
+
+	format := globalFlags.Format
+	c.Arguments["format"] = &format
+}
+
+//executeWithTimeout executes the command and returns an error if it does not finish within the global timeout
+func executeWithTimeout(c *Command, client interfaces.MetalCloudClient) (string, error) {
+
+	if globalFlags.Timeout <= 0 {
+		return c.ExecuteFunc(c, client)
+	}
+
+	type result struct {
+		ret string
+		err error
+	}
+
+	done := make(chan result, 1)
+
+	go func() {
+		ret, err := c.ExecuteFunc(c, client)
+		done <- result{ret, err}
+	}()
+
+	select {
+	case r := <-done:
+		return r.ret, r.err
+	case <-time.After(globalFlags.Timeout):
+		return "", fmt.Errorf("Command did not finish within %s", globalFlags.Timeout)
+	}
+}
+
+func getGlobalFlagsHelp() string {
+	var sb strings.Builder
+
+	g := GlobalFlags{}
+	newGlobalFlagSet(&g).VisitAll(func(f *flag.Flag) {
+		sb.WriteString(getArgumentHelp(f))
+	})
+
+	return sb.String()
+}
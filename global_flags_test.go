@@ -0,0 +1,190 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"testing"
+	"time"
+
+	mock_metalcloud "github.com/bigstepinc/metalcloud-cli/helpers"
+	interfaces "github.com/bigstepinc/metalcloud-cli/interfaces"
+	gomock "github.com/golang/mock/gomock"
+	. "github.com/onsi/gomega"
+)
+
+func TestParseGlobalFlags(t *testing.T) {
+	RegisterTestingT(t)
+
+	g, args, err := parseGlobalFlags([]string{"metalcloud-cli", "-profile", "staging", "-format", "json", "-quiet", "-no-color", "-timeout", "30s", "infra", "list", "-format", "csv"})
+	Expect(err).To(BeNil())
+	Expect(g.Profile).To(Equal("staging"))
+	Expect(g.Format).To(Equal("json"))
+	Expect(g.Quiet).To(BeTrue())
+	Expect(g.Debug).To(BeFalse())
+	Expect(g.Timeout).To(Equal(30 * time.Second))
+	Expect(args).To(Equal([]string{"metalcloud-cli", "infra", "list", "-format", "csv"}))
+
+	g, args, err = parseGlobalFlags([]string{"metalcloud-cli", "infra", "list"})
+	Expect(err).To(BeNil())
+	Expect(*g).To(Equal(GlobalFlags{}))
+	Expect(args).To(Equal([]string{"metalcloud-cli", "infra", "list"}))
+
+	_, args, err = parseGlobalFlags([]string{"metalcloud-cli", "-h"})
+	Expect(err).To(BeNil())
+	Expect(args).To(Equal([]string{"metalcloud-cli", "help"}))
+
+	_, _, err = parseGlobalFlags([]string{"metalcloud-cli", "-unknown", "infra", "list"})
+	Expect(err).NotTo(BeNil())
+
+	_, _, err = parseGlobalFlags([]string{"metalcloud-cli", "-timeout", "soon", "infra", "list"})
+	Expect(err).NotTo(BeNil())
+}
+
+func TestApplyGlobalFlags(t *testing.T) {
+	RegisterTestingT(t)
+
+	currentDatacenter := os.Getenv("METALCLOUD_DATACENTER")
+	currentLogging, currentLoggingSet := os.LookupEnv("METALCLOUD_LOGGING_ENABLED")
+
+	applyGlobalFlags(GlobalFlags{
+		Datacenter: "dc-global",
+		Debug:      true,
+	})
+
+	Expect(GetDatacenter()).To(Equal("dc-global"))
+	Expect(isLoggingEnabled()).To(BeTrue())
+
+	applyGlobalFlags(GlobalFlags{})
+	os.Setenv("METALCLOUD_DATACENTER", currentDatacenter)
+	if currentLoggingSet {
+		os.Setenv("METALCLOUD_LOGGING_ENABLED", currentLogging)
+	} else {
+		os.Unsetenv("METALCLOUD_LOGGING_ENABLED")
+	}
+}
+
+func TestExecuteCommandWithGlobalFlags(t *testing.T) {
+	RegisterTestingT(t)
+
+	var format string
+
+	//the flag sets are recreated for each execution as InitFunc can only be called once per flag set
+	newCommands := func() []Command {
+		return []Command{
+			{
+				Subject:   "tests",
+				Predicate: "format",
+				FlagSet:   flag.NewFlagSet(RandStringBytes(10), flag.ExitOnError),
+				InitFunc: func(c *Command) {
+					c.Arguments = map[string]interface{}{}
+				},
+				ExecuteFunc: func(c *Command, client interfaces.MetalCloudClient) (string, error) {
+					format = getStringParam(c.Arguments["format"])
+					return "output", nil
+				},
+				Formatted: true,
+			},
+			{
+				Subject:   "tests",
+				Predicate: "noformat",
+				FlagSet:   flag.NewFlagSet(RandStringBytes(10), flag.ExitOnError),
+				InitFunc: func(c *Command) {
+					c.Arguments = map[string]interface{}{}
+				},
+				ExecuteFunc: func(c *Command, client interfaces.MetalCloudClient) (string, error) {
+					format = getStringParam(c.Arguments["format"])
+					return "", nil
+				},
+			},
+			{
+				Subject:   "tests",
+				Predicate: "ownformat",
+				FlagSet:   flag.NewFlagSet(RandStringBytes(10), flag.ExitOnError),
+				InitFunc: func(c *Command) {
+					c.Arguments = map[string]interface{}{
+						"format": c.FlagSet.String("format", _nilDefaultStr, "yaml or json"),
+					}
+				},
+				ExecuteFunc: func(c *Command, client interfaces.MetalCloudClient) (string, error) {
+					format = getStringParam(c.Arguments["format"])
+					return "", nil
+				},
+			},
+			{
+				Subject:   "tests",
+				Predicate: "slow",
+				FlagSet:   flag.NewFlagSet(RandStringBytes(10), flag.ExitOnError),
+				InitFunc: func(c *Command) {
+					c.Arguments = map[string]interface{}{}
+				},
+				ExecuteFunc: func(c *Command, client interfaces.MetalCloudClient) (string, error) {
+					time.Sleep(200 * time.Millisecond)
+					return "", nil
+				},
+			},
+		}
+	}
+
+	ctrl := gomock.NewController(t)
+	client := mock_metalcloud.NewMockMetalCloudClient(ctrl)
+	clients := map[string]interfaces.MetalCloudClient{
+		"": client,
+	}
+
+	var stdin, stdout bytes.Buffer
+	SetConsoleIOChannel(&stdin, &stdout)
+	defer SetConsoleIOChannel(os.Stdin, os.Stdout)
+	defer applyGlobalFlags(GlobalFlags{})
+
+	//the global format is used when the command's own format is not set
+	applyGlobalFlags(GlobalFlags{Format: "json"})
+
+	err := executeCommand([]string{"", "tests", "format"}, newCommands(), clients)
+	Expect(err).To(BeNil())
+	Expect(format).To(Equal("json"))
+	Expect(stdout.String()).To(Equal("output"))
+
+	err = executeCommand([]string{"", "tests", "format", "-format", "csv"}, newCommands(), clients)
+	Expect(err).To(BeNil())
+	Expect(format).To(Equal("csv"))
+
+	//commands that are not formatted do not receive it, including the ones with formats of their own
+	err = executeCommand([]string{"", "tests", "noformat"}, newCommands(), clients)
+	Expect(err).To(BeNil())
+	Expect(format).To(Equal(""))
+
+	err = executeCommand([]string{"", "tests", "ownformat"}, newCommands(), clients)
+	Expect(err).To(BeNil())
+	Expect(format).To(Equal(""))
+
+	err = executeCommand([]string{"", "tests", "ownformat", "-format", "yaml"}, newCommands(), clients)
+	Expect(err).To(BeNil())
+	Expect(format).To(Equal("yaml"))
+
+	//quiet suppresses the output
+	stdout.Reset()
+	applyGlobalFlags(GlobalFlags{Quiet: true})
+
+	err = executeCommand([]string{"", "tests", "format"}, newCommands(), clients)
+	Expect(err).To(BeNil())
+	Expect(stdout.String()).To(BeEmpty())
+
+	//timeout
+	applyGlobalFlags(GlobalFlags{Timeout: 10 * time.Millisecond})
+
+	err = executeCommand([]string{"", "tests", "slow"}, newCommands(), clients)
+	Expect(err).NotTo(BeNil())
+
+	err = executeCommand([]string{"", "tests", "format"}, newCommands(), clients)
+	Expect(err).To(BeNil())
+
+	//the format and table flags are accepted after the predicate by commands that are formatted
+	applyGlobalFlags(GlobalFlags{})
+
+	err = executeCommand([]string{"", "tests", "format", "-columns", "ID", "-sort-by", "-ID", "-where", "ID>1"}, newCommands(), clients)
+	Expect(err).To(BeNil())
+	Expect(globalFlags.Columns).To(Equal("ID"))
+	Expect(globalFlags.SortBy).To(Equal("-ID"))
+	Expect(globalFlags.Where).To(Equal("ID>1"))
+}
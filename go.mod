@@ -14,7 +14,7 @@ require (
 	golang.org/x/sys v0.0.0-20191204072324-ce4227a45e2e // indirect
 	golang.org/x/text v0.3.2 // indirect
 	gopkg.in/check.v1 v1.0.0-20190902080502-41f04d3bba15 // indirect
-	gopkg.in/yaml.v2 v2.2.5 // indirect
+	gopkg.in/yaml.v2 v2.2.5
 )
 
 //replace github.com/bigstepinc/metal-cloud-sdk-go => /Users/alex/go/src/github.com/bigstepinc/metal-cloud-sdk-go
@@ -0,0 +1,1028 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	metalcloud "github.com/bigstepinc/metal-cloud-sdk-go"
+	interfaces "github.com/bigstepinc/metalcloud-cli/interfaces"
+	yaml "gopkg.in/yaml.v2"
+)
+
+//InfrastructureManifest describes an infrastructure and its elements. Elements are identified by their labels.
+type InfrastructureManifest struct {
+	Label          string                  `json:"label" yaml:"label"`
+	Datacenter     string                  `json:"datacenter,omitempty" yaml:"datacenter,omitempty"`
+	Networks       []NetworkManifest       `json:"networks,omitempty" yaml:"networks,omitempty"`
+	InstanceArrays []InstanceArrayManifest `json:"instance_arrays,omitempty" yaml:"instance_arrays,omitempty"`
+	DriveArrays    []DriveArrayManifest    `json:"drive_arrays,omitempty" yaml:"drive_arrays,omitempty"`
+	CustomStages   []CustomStageManifest   `json:"custom_stages,omitempty" yaml:"custom_stages,omitempty"`
+}
+
+//NetworkManifest describes a network. Networks are only created, never changed.
+type NetworkManifest struct {
+	Label string `json:"label" yaml:"label"`
+	Type  string `json:"type" yaml:"type"`
+}
+
+//InstanceArrayManifest describes an instance array. Zero values leave the current value unchanged.
+type InstanceArrayManifest struct {
+	Label              string                 `json:"label" yaml:"label"`
+	InstanceCount      int                    `json:"instance_count,omitempty" yaml:"instance_count,omitempty"`
+	RAMGbytes          int                    `json:"ram_gbytes,omitempty" yaml:"ram_gbytes,omitempty"`
+	ProcessorCount     int                    `json:"processor_count,omitempty" yaml:"processor_count,omitempty"`
+	ProcessorCoreCount int                    `json:"processor_core_count,omitempty" yaml:"processor_core_count,omitempty"`
+	ProcessorCoreMHZ   int                    `json:"processor_core_mhz,omitempty" yaml:"processor_core_mhz,omitempty"`
+	DiskCount          int                    `json:"disk_count,omitempty" yaml:"disk_count,omitempty"`
+	DiskSizeMBytes     int                    `json:"disk_size_mbytes,omitempty" yaml:"disk_size_mbytes,omitempty"`
+	DiskTypes          []string               `json:"disk_types,omitempty" yaml:"disk_types,omitempty"`
+	BootMethod         string                 `json:"boot_method,omitempty" yaml:"boot_method,omitempty"`
+	VolumeTemplate     string                 `json:"volume_template,omitempty" yaml:"volume_template,omitempty"`
+	ServerType         string                 `json:"server_type,omitempty" yaml:"server_type,omitempty"`
+	FirewallManaged    *bool                  `json:"firewall_managed,omitempty" yaml:"firewall_managed,omitempty"`
+	FirewallRules      []FirewallRuleManifest `json:"firewall_rules,omitempty" yaml:"firewall_rules,omitempty"`
+	Interfaces         []InterfaceManifest    `json:"interfaces,omitempty" yaml:"interfaces,omitempty"`
+}
+
+//InterfaceManifest attaches an interface of the instance array, given by its index, to a network given by its label
+type InterfaceManifest struct {
+	Index   int    `json:"index" yaml:"index"`
+	Network string `json:"network" yaml:"network"`
+}
+
+//FirewallRuleManifest describes a firewall rule of an instance array. Rules are enabled unless stated otherwise.
+type FirewallRuleManifest struct {
+	Description                    string `json:"description,omitempty" yaml:"description,omitempty"`
+	Protocol                       string `json:"protocol,omitempty" yaml:"protocol,omitempty"`
+	PortRangeStart                 int    `json:"port_range_start,omitempty" yaml:"port_range_start,omitempty"`
+	PortRangeEnd                   int    `json:"port_range_end,omitempty" yaml:"port_range_end,omitempty"`
+	SourceIPAddressRangeStart      string `json:"source_ip_address_range_start,omitempty" yaml:"source_ip_address_range_start,omitempty"`
+	SourceIPAddressRangeEnd        string `json:"source_ip_address_range_end,omitempty" yaml:"source_ip_address_range_end,omitempty"`
+	DestinationIPAddressRangeStart string `json:"destination_ip_address_range_start,omitempty" yaml:"destination_ip_address_range_start,omitempty"`
+	DestinationIPAddressRangeEnd   string `json:"destination_ip_address_range_end,omitempty" yaml:"destination_ip_address_range_end,omitempty"`
+	IPAddressType                  string `json:"ip_address_type,omitempty" yaml:"ip_address_type,omitempty"`
+	Enabled                        *bool  `json:"enabled,omitempty" yaml:"enabled,omitempty"`
+}
+
+//DriveArrayManifest describes a drive array. The instance array is given by its label. Zero values leave the current value unchanged.
+type DriveArrayManifest struct {
+	Label                   string `json:"label" yaml:"label"`
+	InstanceArray           string `json:"instance_array,omitempty" yaml:"instance_array,omitempty"`
+	VolumeTemplate          string `json:"volume_template,omitempty" yaml:"volume_template,omitempty"`
+	StorageType             string `json:"storage_type,omitempty" yaml:"storage_type,omitempty"`
+	DriveSizeMBytes         int    `json:"drive_size_mbytes,omitempty" yaml:"drive_size_mbytes,omitempty"`
+	Count                   int    `json:"count,omitempty" yaml:"count,omitempty"`
+	ExpandWithInstanceArray *bool  `json:"expand_with_instance_array,omitempty" yaml:"expand_with_instance_array,omitempty"`
+}
+
+//CustomStageManifest associates a stage definition, given by id or label, with the deploy of the infrastructure
+type CustomStageManifest struct {
+	Stage     string `json:"stage" yaml:"stage"`
+	Runlevel  int    `json:"runlevel,omitempty" yaml:"runlevel,omitempty"`
+	RunMoment string `json:"run_moment,omitempty" yaml:"run_moment,omitempty"`
+}
+
+const defaultStageRunMoment = "post_deploy"
+
+//loadInfrastructureManifest reads a manifest. Files with the .json extension are parsed as json, all others as yaml.
+func loadInfrastructureManifest(path string) (*InfrastructureManifest, error) {
+
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var manifest InfrastructureManifest
+
+	if strings.ToLower(filepath.Ext(path)) == ".json" {
+		//unknown fields are errors as with yaml so that misspelled keys are not ignored
+		decoder := json.NewDecoder(bytes.NewReader(content))
+		decoder.DisallowUnknownFields()
+		err = decoder.Decode(&manifest)
+	} else {
+		err = yaml.UnmarshalStrict(content, &manifest)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Could not parse manifest %s: %v", path, err)
+	}
+
+	return &manifest, manifest.validate()
+}
+
+func (m *InfrastructureManifest) validate() error {
+
+	if m.Label == "" {
+		return fmt.Errorf("The manifest must have a label")
+	}
+
+	labels := map[string]bool{}
+
+	checkLabel := func(kind string, label string) error {
+		if label == "" {
+			return fmt.Errorf("All %s in the manifest must have a label", kind)
+		}
+		if labels[kind+label] {
+			return fmt.Errorf("Label %s is used by more than one of the %s in the manifest", label, kind)
+		}
+		labels[kind+label] = true
+		return nil
+	}
+
+	for _, n := range m.Networks {
+		if err := checkLabel("networks", n.Label); err != nil {
+			return err
+		}
+		if n.Type == "" {
+			return fmt.Errorf("Network %s must have a type", n.Label)
+		}
+	}
+
+	for _, ia := range m.InstanceArrays {
+		if err := checkLabel("instance arrays", ia.Label); err != nil {
+			return err
+		}
+		for _, i := range ia.Interfaces {
+			if i.Index < 0 || i.Network == "" {
+				return fmt.Errorf("Interfaces of instance array %s must have a non-negative index and a network", ia.Label)
+			}
+		}
+	}
+
+	for _, da := range m.DriveArrays {
+		if err := checkLabel("drive arrays", da.Label); err != nil {
+			return err
+		}
+	}
+
+	for _, s := range m.CustomStages {
+		if s.Stage == "" {
+			return fmt.Errorf("All custom stages in the manifest must have a stage")
+		}
+	}
+
+	return nil
+}
+
+//manifestChange is an object created, updated or left unchanged by an apply
+type manifestChange struct {
+	ObjectType string
+	Label      string
+	ID         int
+	Action     string
+}
+
+const (
+	manifestActionCreated   = "created"
+	manifestActionUpdated   = "updated"
+	manifestActionUnchanged = "unchanged"
+)
+
+//manifestApplier makes the live infrastructure match the manifest
+type manifestApplier struct {
+	manifest *InfrastructureManifest
+	client   interfaces.MetalCloudClient
+	infra    *metalcloud.Infrastructure
+	changes  []manifestChange
+}
+
+func (a *manifestApplier) record(objectType string, label string, id int, action string) {
+	a.changes = append(a.changes, manifestChange{
+		ObjectType: objectType,
+		Label:      label,
+		ID:         id,
+		Action:     action,
+	})
+}
+
+//changed returns true if the apply created or updated any object
+func (a *manifestApplier) changed() bool {
+	for _, c := range a.changes {
+		if c.Action != manifestActionUnchanged {
+			return true
+		}
+	}
+	return false
+}
+
+//createdObjects returns a description of the objects created so far, such as "Network wan1 (12), InstanceArray web (45)"
+func (a *manifestApplier) createdObjects() string {
+	created := []string{}
+	for _, c := range a.changes {
+		if c.Action == manifestActionCreated {
+			created = append(created, fmt.Sprintf("%s %s (%d)", c.ObjectType, c.Label, c.ID))
+		}
+	}
+	return strings.Join(created, ", ")
+}
+
+//apply creates or edits the objects of the manifest. Objects not present in the manifest are left untouched.
+func (a *manifestApplier) apply() error {
+
+	steps := []func() error{
+		a.applyInfrastructure,
+		a.applyNetworks,
+		a.applyInstanceArrays,
+		a.applyInterfaces,
+		a.applyDriveArrays,
+		a.applyCustomStages,
+	}
+
+	for _, step := range steps {
+		if err := step(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (a *manifestApplier) applyInfrastructure() error {
+
+	infras, err := a.client.Infrastructures()
+	if err != nil {
+		return err
+	}
+
+	for _, infra := range *infras {
+		if infra.InfrastructureLabel == a.manifest.Label {
+			if a.manifest.Datacenter != "" && a.manifest.Datacenter != infra.DatacenterName {
+				return fmt.Errorf("Infrastructure %s (%d) is in datacenter %s, not in datacenter %s given by the manifest. Infrastructures cannot be moved, use infrastructure clone instead",
+					infra.InfrastructureLabel, infra.InfrastructureID, infra.DatacenterName, a.manifest.Datacenter)
+			}
+			i := infra
+			a.infra = &i
+			a.record("Infrastructure", i.InfrastructureLabel, i.InfrastructureID, manifestActionUnchanged)
+			return nil
+		}
+	}
+
+	datacenter := a.manifest.Datacenter
+	if datacenter == "" {
+		datacenter = GetDatacenter()
+	}
+
+	a.infra, err = a.client.InfrastructureCreate(metalcloud.Infrastructure{
+		InfrastructureLabel: a.manifest.Label,
+		DatacenterName:      datacenter,
+	})
+	if err != nil {
+		return err
+	}
+
+	a.record("Infrastructure", a.infra.InfrastructureLabel, a.infra.InfrastructureID, manifestActionCreated)
+
+	return nil
+}
+
+func (a *manifestApplier) applyNetworks() error {
+
+	if len(a.manifest.Networks) == 0 {
+		return nil
+	}
+
+	networks, err := a.client.Networks(a.infra.InfrastructureID)
+	if err != nil {
+		return err
+	}
+
+	for _, nm := range a.manifest.Networks {
+		var existing *metalcloud.Network
+		for _, n := range *networks {
+			if n.NetworkLabel == nm.Label {
+				existing = &n
+				break
+			}
+		}
+
+		//an infrastructure has a single wan and a single san network, created with the infrastructure,
+		//so these are matched by type as their labels differ between infrastructures
+		if existing == nil && (nm.Type == "wan" || nm.Type == "san") {
+			for _, n := range *networks {
+				if n.NetworkType == nm.Type {
+					existing = &n
+					break
+				}
+			}
+		}
+
+		if existing != nil {
+			if existing.NetworkType != nm.Type {
+				return fmt.Errorf("Network %s is of type %s and cannot be changed to %s", nm.Label, existing.NetworkType, nm.Type)
+			}
+			a.record("Network", nm.Label, existing.NetworkID, manifestActionUnchanged)
+			continue
+		}
+
+		n, err := a.client.NetworkCreate(a.infra.InfrastructureID, metalcloud.Network{
+			NetworkLabel: nm.Label,
+			NetworkType:  nm.Type,
+		})
+		if err != nil {
+			return err
+		}
+
+		a.record("Network", nm.Label, n.NetworkID, manifestActionCreated)
+	}
+
+	return nil
+}
+
+func (a *manifestApplier) getVolumeTemplateID(volumeTemplate string) (int, error) {
+	return getIDOrDo(volumeTemplate, func(label string) (int, error) {
+		vt, err := a.client.VolumeTemplateGetByLabel(label)
+		if err != nil {
+			return 0, err
+		}
+		return vt.VolumeTemplateID, nil
+	})
+}
+
+func (r FirewallRuleManifest) toFirewallRule() metalcloud.FirewallRule {
+	return metalcloud.FirewallRule{
+		FirewallRuleDescription:                    r.Description,
+		FirewallRuleProtocol:                       r.Protocol,
+		FirewallRulePortRangeStart:                 r.PortRangeStart,
+		FirewallRulePortRangeEnd:                   r.PortRangeEnd,
+		FirewallRuleSourceIPAddressRangeStart:      r.SourceIPAddressRangeStart,
+		FirewallRuleSourceIPAddressRangeEnd:        r.SourceIPAddressRangeEnd,
+		FirewallRuleDestinationIPAddressRangeStart: r.DestinationIPAddressRangeStart,
+		FirewallRuleDestinationIPAddressRangeEnd:   r.DestinationIPAddressRangeEnd,
+		FirewallRuleIPAddressType:                  r.IPAddressType,
+		FirewallRuleEnabled:                        r.Enabled == nil || *r.Enabled,
+	}
+}
+
+//toInstanceArrayOperation applies the values set in the manifest over the given operation
+func (m InstanceArrayManifest) toInstanceArrayOperation(iao *metalcloud.InstanceArrayOperation, volumeTemplateID int) {
+
+	iao.InstanceArrayLabel = m.Label
+
+	updateIfNotZero := func(v int, p *int) {
+		if v != 0 {
+			*p = v
+		}
+	}
+
+	updateIfNotZero(m.InstanceCount, &iao.InstanceArrayInstanceCount)
+	updateIfNotZero(m.RAMGbytes, &iao.InstanceArrayRAMGbytes)
+	updateIfNotZero(m.ProcessorCount, &iao.InstanceArrayProcessorCount)
+	updateIfNotZero(m.ProcessorCoreCount, &iao.InstanceArrayProcessorCoreCount)
+	updateIfNotZero(m.ProcessorCoreMHZ, &iao.InstanceArrayProcessorCoreMHZ)
+	updateIfNotZero(m.DiskCount, &iao.InstanceArrayDiskCount)
+	updateIfNotZero(m.DiskSizeMBytes, &iao.InstanceArrayDiskSizeMBytes)
+	updateIfNotZero(volumeTemplateID, &iao.VolumeTemplateID)
+
+	if m.DiskTypes != nil {
+		iao.InstanceArrayDiskTypes = m.DiskTypes
+	}
+
+	if m.BootMethod != "" {
+		iao.InstanceArrayBootMethod = m.BootMethod
+	}
+
+	if m.FirewallManaged != nil {
+		iao.InstanceArrayFirewallManaged = *m.FirewallManaged
+	}
+
+	if m.FirewallRules != nil {
+		rules := []metalcloud.FirewallRule{}
+		for _, r := range m.FirewallRules {
+			rules = append(rules, r.toFirewallRule())
+		}
+		iao.InstanceArrayFirewallRules = rules
+	}
+}
+
+func (a *manifestApplier) getServerTypeID(serverType string) (int, error) {
+	return getIDOrDo(serverType, func(label string) (int, error) {
+		st, err := a.client.ServerTypeGetByLabel(label)
+		if err != nil {
+			return 0, err
+		}
+		return st.ServerTypeID, nil
+	})
+}
+
+//getInstanceArrayServerTypeID returns the server type used by all the instances of the array or 0 if they use different ones
+func getInstanceArrayServerTypeID(instanceArrayID int, client interfaces.MetalCloudClient) (int, error) {
+
+	instances, err := client.InstanceArrayInstances(instanceArrayID)
+	if err != nil {
+		return 0, err
+	}
+
+	serverTypeID := 0
+	for _, i := range *instances {
+		if serverTypeID != 0 && i.ServerTypeID != serverTypeID {
+			return 0, nil
+		}
+		serverTypeID = i.ServerTypeID
+	}
+
+	return serverTypeID, nil
+}
+
+//getServerTypeMatches requests all the instances of the array to use the given server type
+func getServerTypeMatches(serverTypeID int, instanceCount int) *metalcloud.ServerTypeMatches {
+	return &metalcloud.ServerTypeMatches{
+		ServerTypes: map[int]metalcloud.ServerTypeMatch{
+			serverTypeID: {
+				ServerCount: instanceCount,
+			},
+		},
+	}
+}
+
+func (a *manifestApplier) applyInstanceArrays() error {
+
+	iaList, err := a.client.InstanceArrays(a.infra.InfrastructureID)
+	if err != nil {
+		return err
+	}
+
+	for _, iam := range a.manifest.InstanceArrays {
+
+		volumeTemplateID := 0
+		if iam.VolumeTemplate != "" {
+			volumeTemplateID, err = a.getVolumeTemplateID(iam.VolumeTemplate)
+			if err != nil {
+				return err
+			}
+		}
+
+		serverTypeID := 0
+		if iam.ServerType != "" {
+			serverTypeID, err = a.getServerTypeID(iam.ServerType)
+			if err != nil {
+				return err
+			}
+		}
+
+		var existing *metalcloud.InstanceArray
+		for _, ia := range *iaList {
+			if ia.InstanceArrayLabel == iam.Label {
+				existing = &ia
+				break
+			}
+		}
+
+		if existing == nil {
+			iao := metalcloud.InstanceArrayOperation{}
+			iam.toInstanceArrayOperation(&iao, volumeTemplateID)
+
+			ia, err := a.client.InstanceArrayCreate(a.infra.InfrastructureID, metalcloud.InstanceArray{
+				InstanceArrayLabel:              iao.InstanceArrayLabel,
+				InstanceArrayInstanceCount:      iao.InstanceArrayInstanceCount,
+				InstanceArrayRAMGbytes:          iao.InstanceArrayRAMGbytes,
+				InstanceArrayProcessorCount:     iao.InstanceArrayProcessorCount,
+				InstanceArrayProcessorCoreCount: iao.InstanceArrayProcessorCoreCount,
+				InstanceArrayProcessorCoreMHZ:   iao.InstanceArrayProcessorCoreMHZ,
+				InstanceArrayDiskCount:          iao.InstanceArrayDiskCount,
+				InstanceArrayDiskSizeMBytes:     iao.InstanceArrayDiskSizeMBytes,
+				InstanceArrayDiskTypes:          iao.InstanceArrayDiskTypes,
+				InstanceArrayBootMethod:         iao.InstanceArrayBootMethod,
+				VolumeTemplateID:                iao.VolumeTemplateID,
+				InstanceArrayFirewallManaged:    iao.InstanceArrayFirewallManaged,
+				InstanceArrayFirewallRules:      iao.InstanceArrayFirewallRules,
+			})
+			if err != nil {
+				return err
+			}
+
+			//the server type can only be requested when editing
+			if serverTypeID != 0 && ia.InstanceArrayOperation != nil {
+				_, err = a.client.InstanceArrayEdit(ia.InstanceArrayID, *ia.InstanceArrayOperation, nil, nil, getServerTypeMatches(serverTypeID, ia.InstanceArrayOperation.InstanceArrayInstanceCount), nil)
+				if err != nil {
+					return err
+				}
+			}
+
+			a.record("InstanceArray", iam.Label, ia.InstanceArrayID, manifestActionCreated)
+			continue
+		}
+
+		current := getInstanceArrayOperation(*existing)
+
+		desired := current
+		iam.toInstanceArrayOperation(&desired, volumeTemplateID)
+
+		var serverTypeMatches *metalcloud.ServerTypeMatches
+		if serverTypeID != 0 {
+			currentServerTypeID, err := getInstanceArrayServerTypeID(existing.InstanceArrayID, a.client)
+			if err != nil {
+				return err
+			}
+			if currentServerTypeID != serverTypeID || desired.InstanceArrayInstanceCount != current.InstanceArrayInstanceCount {
+				serverTypeMatches = getServerTypeMatches(serverTypeID, desired.InstanceArrayInstanceCount)
+			}
+		}
+
+		if reflect.DeepEqual(current, desired) && serverTypeMatches == nil {
+			a.record("InstanceArray", iam.Label, existing.InstanceArrayID, manifestActionUnchanged)
+			continue
+		}
+
+		_, err = a.client.InstanceArrayEdit(existing.InstanceArrayID, desired, nil, nil, serverTypeMatches, nil)
+		if err != nil {
+			return err
+		}
+
+		a.record("InstanceArray", iam.Label, existing.InstanceArrayID, manifestActionUpdated)
+	}
+
+	return nil
+}
+
+//getInstanceArrayOperation returns the pending operation of the instance array or, if it has none, an operation with its live settings
+func getInstanceArrayOperation(ia metalcloud.InstanceArray) metalcloud.InstanceArrayOperation {
+
+	if ia.InstanceArrayOperation != nil {
+		return *ia.InstanceArrayOperation
+	}
+
+	iao := metalcloud.InstanceArrayOperation{}
+	copyInstanceArrayToOperation(ia, &iao)
+
+	return iao
+}
+
+//getInstanceArrayInterfaceNetworks returns the id of the network attached to each interface index, including pending changes
+func getInstanceArrayInterfaceNetworks(ia metalcloud.InstanceArray) map[int]int {
+
+	networks := map[int]int{}
+
+	if ia.InstanceArrayOperation != nil {
+		for _, i := range ia.InstanceArrayOperation.InstanceArrayInterfaces {
+			networks[i.InstanceArrayInterfaceIndex] = i.NetworkID
+		}
+		return networks
+	}
+
+	for _, i := range ia.InstanceArrayInterfaces {
+		networks[i.InstanceArrayInterfaceIndex] = i.NetworkID
+	}
+
+	return networks
+}
+
+func (a *manifestApplier) applyInterfaces() error {
+
+	attachments := 0
+	for _, iam := range a.manifest.InstanceArrays {
+		attachments += len(iam.Interfaces)
+	}
+	if attachments == 0 {
+		return nil
+	}
+
+	networks, err := a.client.Networks(a.infra.InfrastructureID)
+	if err != nil {
+		return err
+	}
+
+	//networks are looked up by the label used in the manifest as wan and san networks are matched by type
+	networkIDs := map[string]int{}
+	for _, nm := range a.manifest.Networks {
+		for _, c := range a.changes {
+			if c.ObjectType == "Network" && c.Label == nm.Label {
+				networkIDs[nm.Label] = c.ID
+			}
+		}
+	}
+	for _, n := range *networks {
+		if _, ok := networkIDs[n.NetworkLabel]; !ok {
+			networkIDs[n.NetworkLabel] = n.NetworkID
+		}
+	}
+
+	iaList, err := a.client.InstanceArrays(a.infra.InfrastructureID)
+	if err != nil {
+		return err
+	}
+
+	for _, iam := range a.manifest.InstanceArrays {
+
+		var ia *metalcloud.InstanceArray
+		for _, i := range *iaList {
+			if i.InstanceArrayLabel == iam.Label {
+				ia = &i
+				break
+			}
+		}
+		if ia == nil {
+			return fmt.Errorf("Instance array %s not found", iam.Label)
+		}
+
+		current := getInstanceArrayInterfaceNetworks(*ia)
+
+		for _, im := range iam.Interfaces {
+			networkID, ok := networkIDs[im.Network]
+			if !ok {
+				return fmt.Errorf("Network %s of instance array %s not found", im.Network, iam.Label)
+			}
+
+			label := fmt.Sprintf("%s/%d", iam.Label, im.Index)
+
+			if current[im.Index] == networkID {
+				a.record("Interface", label, ia.InstanceArrayID, manifestActionUnchanged)
+				continue
+			}
+
+			_, err = a.client.InstanceArrayInterfaceAttachNetwork(ia.InstanceArrayID, im.Index, networkID)
+			if err != nil {
+				return err
+			}
+
+			a.record("Interface", label, ia.InstanceArrayID, manifestActionUpdated)
+		}
+	}
+
+	return nil
+}
+
+//toDriveArrayOperation applies the values set in the manifest over the given operation
+func (m DriveArrayManifest) toDriveArrayOperation(dao *metalcloud.DriveArrayOperation, instanceArrayID int, volumeTemplateID int) {
+
+	dao.DriveArrayLabel = m.Label
+
+	updateIfNotZero := func(v int, p *int) {
+		if v != 0 {
+			*p = v
+		}
+	}
+
+	updateIfNotZero(instanceArrayID, &dao.InstanceArrayID)
+	updateIfNotZero(volumeTemplateID, &dao.VolumeTemplateID)
+	updateIfNotZero(m.DriveSizeMBytes, &dao.DriveSizeMBytesDefault)
+	updateIfNotZero(m.Count, &dao.DriveArrayCount)
+
+	if m.StorageType != "" {
+		dao.DriveArrayStorageType = m.StorageType
+	}
+
+	if m.ExpandWithInstanceArray != nil {
+		dao.DriveArrayExpandWithInstanceArray = *m.ExpandWithInstanceArray
+	}
+}
+
+//getDriveArrayOperation returns the pending operation of the drive array or, if it has none, an operation with its live settings
+func getDriveArrayOperation(da metalcloud.DriveArray) metalcloud.DriveArrayOperation {
+
+	if da.DriveArrayOperation != nil {
+		return *da.DriveArrayOperation
+	}
+
+	return metalcloud.DriveArrayOperation{
+		DriveArrayID:                      da.DriveArrayID,
+		DriveArrayLabel:                   da.DriveArrayLabel,
+		InstanceArrayID:                   da.InstanceArrayID,
+		InfrastructureID:                  da.InfrastructureID,
+		VolumeTemplateID:                  da.VolumeTemplateID,
+		DriveArrayStorageType:             da.DriveArrayStorageType,
+		DriveSizeMBytesDefault:            da.DriveSizeMBytesDefault,
+		DriveArrayCount:                   da.DriveArrayCount,
+		DriveArrayExpandWithInstanceArray: da.DriveArrayExpandWithInstanceArray,
+	}
+}
+
+func (a *manifestApplier) applyDriveArrays() error {
+
+	if len(a.manifest.DriveArrays) == 0 {
+		return nil
+	}
+
+	//the instance arrays are read again to include the ones created by this apply
+	iaList, err := a.client.InstanceArrays(a.infra.InfrastructureID)
+	if err != nil {
+		return err
+	}
+
+	daList, err := a.client.DriveArrays(a.infra.InfrastructureID)
+	if err != nil {
+		return err
+	}
+
+	for _, dam := range a.manifest.DriveArrays {
+
+		instanceArrayID := 0
+		if dam.InstanceArray != "" {
+			for _, ia := range *iaList {
+				if ia.InstanceArrayLabel == dam.InstanceArray {
+					instanceArrayID = ia.InstanceArrayID
+				}
+			}
+			if instanceArrayID == 0 {
+				return fmt.Errorf("Instance array %s of drive array %s not found in infrastructure %s", dam.InstanceArray, dam.Label, a.infra.InfrastructureLabel)
+			}
+		}
+
+		volumeTemplateID := 0
+		if dam.VolumeTemplate != "" {
+			volumeTemplateID, err = a.getVolumeTemplateID(dam.VolumeTemplate)
+			if err != nil {
+				return err
+			}
+		}
+
+		var existing *metalcloud.DriveArray
+		for _, da := range *daList {
+			if da.DriveArrayLabel == dam.Label {
+				existing = &da
+				break
+			}
+		}
+
+		if existing == nil {
+			dao := metalcloud.DriveArrayOperation{}
+			dam.toDriveArrayOperation(&dao, instanceArrayID, volumeTemplateID)
+
+			da, err := a.client.DriveArrayCreate(a.infra.InfrastructureID, metalcloud.DriveArray{
+				DriveArrayLabel:                   dao.DriveArrayLabel,
+				InstanceArrayID:                   dao.InstanceArrayID,
+				VolumeTemplateID:                  dao.VolumeTemplateID,
+				DriveArrayStorageType:             dao.DriveArrayStorageType,
+				DriveSizeMBytesDefault:            dao.DriveSizeMBytesDefault,
+				DriveArrayCount:                   dao.DriveArrayCount,
+				DriveArrayExpandWithInstanceArray: dao.DriveArrayExpandWithInstanceArray,
+			})
+			if err != nil {
+				return err
+			}
+
+			a.record("DriveArray", dam.Label, da.DriveArrayID, manifestActionCreated)
+			continue
+		}
+
+		current := getDriveArrayOperation(*existing)
+
+		desired := current
+		dam.toDriveArrayOperation(&desired, instanceArrayID, volumeTemplateID)
+
+		if reflect.DeepEqual(current, desired) {
+			a.record("DriveArray", dam.Label, existing.DriveArrayID, manifestActionUnchanged)
+			continue
+		}
+
+		_, err = a.client.DriveArrayEdit(existing.DriveArrayID, desired)
+		if err != nil {
+			return err
+		}
+
+		a.record("DriveArray", dam.Label, existing.DriveArrayID, manifestActionUpdated)
+	}
+
+	return nil
+}
+
+func (a *manifestApplier) applyCustomStages() error {
+
+	for _, sm := range a.manifest.CustomStages {
+
+		runMoment := sm.RunMoment
+		if runMoment == "" {
+			runMoment = defaultStageRunMoment
+		}
+
+		stageIDOrLabel := sm.Stage
+		stage, err := getStageDefinitionByIDOrLabel(&stageIDOrLabel, a.client)
+		if err != nil {
+			return err
+		}
+
+		associations, err := a.client.InfrastructureDeployCustomStages(a.infra.InfrastructureID, runMoment)
+		if err != nil {
+			return err
+		}
+
+		found := false
+		for _, s := range *associations {
+			if s.StageDefinitionID == stage.StageDefinitionID && s.InfrastructureDeployCustomStageRunLevel == sm.Runlevel {
+				found = true
+				break
+			}
+		}
+
+		label := fmt.Sprintf("%s (%s, runlevel %d)", stage.StageDefinitionLabel, runMoment, sm.Runlevel)
+
+		if found {
+			a.record("CustomStage", label, stage.StageDefinitionID, manifestActionUnchanged)
+			continue
+		}
+
+		err = a.client.InfrastructureDeployCustomStageAddIntoRunlevel(a.infra.InfrastructureID, stage.StageDefinitionID, sm.Runlevel, runMoment)
+		if err != nil {
+			return err
+		}
+
+		a.record("CustomStage", label, stage.StageDefinitionID, manifestActionCreated)
+	}
+
+	return nil
+}
+
+//exportInfrastructureManifest builds a manifest describing the current design of the infrastructure.
+//Pending changes are included. Objects are referenced by label so that the manifest can be applied in other datacenters.
+func exportInfrastructureManifest(infra *metalcloud.Infrastructure, client interfaces.MetalCloudClient) (*InfrastructureManifest, error) {
+
+	manifest := InfrastructureManifest{
+		Label:      infra.InfrastructureLabel,
+		Datacenter: infra.DatacenterName,
+	}
+
+	networks, err := client.Networks(infra.InfrastructureID)
+	if err != nil {
+		return nil, err
+	}
+
+	networkLabels := map[int]string{}
+
+	for _, n := range *networks {
+		networkLabels[n.NetworkID] = n.NetworkLabel
+		manifest.Networks = append(manifest.Networks, NetworkManifest{
+			Label: n.NetworkLabel,
+			Type:  n.NetworkType,
+		})
+	}
+
+	volumeTemplateLabels := map[int]string{}
+	getVolumeTemplateLabel := func(id int) (string, error) {
+		if id == 0 {
+			return "", nil
+		}
+		if label, ok := volumeTemplateLabels[id]; ok {
+			return label, nil
+		}
+		vt, err := client.VolumeTemplateGet(id)
+		if err != nil {
+			return "", err
+		}
+		volumeTemplateLabels[id] = vt.VolumeTemplateLabel
+		return vt.VolumeTemplateLabel, nil
+	}
+
+	iaList, err := client.InstanceArrays(infra.InfrastructureID)
+	if err != nil {
+		return nil, err
+	}
+
+	instanceArrayLabels := map[int]string{}
+
+	for _, ia := range *iaList {
+
+		iao := getInstanceArrayOperation(ia)
+
+		instanceArrayLabels[ia.InstanceArrayID] = iao.InstanceArrayLabel
+
+		volumeTemplate, err := getVolumeTemplateLabel(iao.VolumeTemplateID)
+		if err != nil {
+			return nil, err
+		}
+
+		serverType := ""
+		serverTypeID, err := getInstanceArrayServerTypeID(ia.InstanceArrayID, client)
+		if err != nil {
+			return nil, err
+		}
+		if serverTypeID != 0 {
+			st, err := client.ServerTypeGet(serverTypeID)
+			if err != nil {
+				return nil, err
+			}
+			serverType = st.ServerTypeLabel
+		}
+
+		firewallManaged := iao.InstanceArrayFirewallManaged
+
+		iam := InstanceArrayManifest{
+			Label:              iao.InstanceArrayLabel,
+			InstanceCount:      iao.InstanceArrayInstanceCount,
+			RAMGbytes:          iao.InstanceArrayRAMGbytes,
+			ProcessorCount:     iao.InstanceArrayProcessorCount,
+			ProcessorCoreCount: iao.InstanceArrayProcessorCoreCount,
+			ProcessorCoreMHZ:   iao.InstanceArrayProcessorCoreMHZ,
+			DiskCount:          iao.InstanceArrayDiskCount,
+			DiskSizeMBytes:     iao.InstanceArrayDiskSizeMBytes,
+			DiskTypes:          iao.InstanceArrayDiskTypes,
+			BootMethod:         iao.InstanceArrayBootMethod,
+			VolumeTemplate:     volumeTemplate,
+			ServerType:         serverType,
+			FirewallManaged:    &firewallManaged,
+		}
+
+		for _, r := range iao.InstanceArrayFirewallRules {
+			iam.FirewallRules = append(iam.FirewallRules, firewallRuleToManifest(r))
+		}
+
+		for index, networkID := range getInstanceArrayInterfaceNetworks(ia) {
+			if networkID == 0 {
+				continue
+			}
+			iam.Interfaces = append(iam.Interfaces, InterfaceManifest{
+				Index:   index,
+				Network: networkLabels[networkID],
+			})
+		}
+
+		sort.Slice(iam.Interfaces, func(i, j int) bool {
+			return iam.Interfaces[i].Index < iam.Interfaces[j].Index
+		})
+
+		manifest.InstanceArrays = append(manifest.InstanceArrays, iam)
+	}
+
+	daList, err := client.DriveArrays(infra.InfrastructureID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, da := range *daList {
+
+		dao := getDriveArrayOperation(da)
+
+		volumeTemplate, err := getVolumeTemplateLabel(dao.VolumeTemplateID)
+		if err != nil {
+			return nil, err
+		}
+
+		expand := dao.DriveArrayExpandWithInstanceArray
+
+		manifest.DriveArrays = append(manifest.DriveArrays, DriveArrayManifest{
+			Label:                   dao.DriveArrayLabel,
+			InstanceArray:           instanceArrayLabels[dao.InstanceArrayID],
+			VolumeTemplate:          volumeTemplate,
+			StorageType:             dao.DriveArrayStorageType,
+			DriveSizeMBytes:         dao.DriveSizeMBytesDefault,
+			Count:                   dao.DriveArrayCount,
+			ExpandWithInstanceArray: &expand,
+		})
+	}
+
+	for _, runMoment := range []string{"pre_deploy", "post_deploy"} {
+		stages, err := client.InfrastructureDeployCustomStages(infra.InfrastructureID, runMoment)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, s := range *stages {
+			stage, err := client.StageDefinitionGet(s.StageDefinitionID)
+			if err != nil {
+				return nil, err
+			}
+
+			manifest.CustomStages = append(manifest.CustomStages, CustomStageManifest{
+				Stage:     stage.StageDefinitionLabel,
+				Runlevel:  s.InfrastructureDeployCustomStageRunLevel,
+				RunMoment: runMoment,
+			})
+		}
+	}
+
+	manifest.sort()
+
+	return &manifest, nil
+}
+
+func firewallRuleToManifest(r metalcloud.FirewallRule) FirewallRuleManifest {
+
+	rule := FirewallRuleManifest{
+		Description:                    r.FirewallRuleDescription,
+		Protocol:                       r.FirewallRuleProtocol,
+		PortRangeStart:                 r.FirewallRulePortRangeStart,
+		PortRangeEnd:                   r.FirewallRulePortRangeEnd,
+		SourceIPAddressRangeStart:      r.FirewallRuleSourceIPAddressRangeStart,
+		SourceIPAddressRangeEnd:        r.FirewallRuleSourceIPAddressRangeEnd,
+		DestinationIPAddressRangeStart: r.FirewallRuleDestinationIPAddressRangeStart,
+		DestinationIPAddressRangeEnd:   r.FirewallRuleDestinationIPAddressRangeEnd,
+		IPAddressType:                  r.FirewallRuleIPAddressType,
+	}
+
+	//rules are enabled by default so only disabled ones are marked
+	if !r.FirewallRuleEnabled {
+		enabled := false
+		rule.Enabled = &enabled
+	}
+
+	return rule
+}
+
+//sort orders the elements by label as the api returns them as maps. This keeps exports stable between runs.
+func (m *InfrastructureManifest) sort() {
+
+	sort.Slice(m.Networks, func(i, j int) bool {
+		return m.Networks[i].Label < m.Networks[j].Label
+	})
+
+	sort.Slice(m.InstanceArrays, func(i, j int) bool {
+		return m.InstanceArrays[i].Label < m.InstanceArrays[j].Label
+	})
+
+	sort.Slice(m.DriveArrays, func(i, j int) bool {
+		return m.DriveArrays[i].Label < m.DriveArrays[j].Label
+	})
+}
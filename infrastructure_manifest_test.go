@@ -0,0 +1,111 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func writeTempFile(content string, suffix string) (string, error) {
+	f, err := ioutil.TempFile("", "manifest*"+suffix)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	_, err = f.WriteString(content)
+
+	return f.Name(), err
+}
+
+func TestLoadInfrastructureManifest(t *testing.T) {
+	RegisterTestingT(t)
+
+	path, err := writeTempFile(`
+label: test-infra
+datacenter: uk-reading
+networks:
+  - label: lan2
+    type: lan
+instance_arrays:
+  - label: master
+    instance_count: 2
+    ram_gbytes: 16
+    firewall_managed: true
+    firewall_rules:
+      - protocol: tcp
+        port_range_start: 22
+        port_range_end: 22
+drive_arrays:
+  - label: master-da
+    instance_array: master
+    drive_size_mbytes: 40960
+custom_stages:
+  - stage: setup
+    runlevel: 1
+`, ".yaml")
+	Expect(err).To(BeNil())
+	defer os.Remove(path)
+
+	m, err := loadInfrastructureManifest(path)
+	Expect(err).To(BeNil())
+	Expect(m.Label).To(Equal("test-infra"))
+	Expect(m.InstanceArrays[0].InstanceCount).To(Equal(2))
+	Expect(*m.InstanceArrays[0].FirewallManaged).To(BeTrue())
+	Expect(m.InstanceArrays[0].FirewallRules[0].toFirewallRule().FirewallRuleEnabled).To(BeTrue())
+	Expect(m.DriveArrays[0].InstanceArray).To(Equal("master"))
+	Expect(m.CustomStages[0].Runlevel).To(Equal(1))
+
+	jsonPath, err := writeTempFile(`{"label": "test-infra", "instance_arrays": [{"label": "master"}]}`, ".json")
+	Expect(err).To(BeNil())
+	defer os.Remove(jsonPath)
+
+	m, err = loadInfrastructureManifest(jsonPath)
+	Expect(err).To(BeNil())
+	Expect(m.InstanceArrays[0].Label).To(Equal("master"))
+
+	//misspelled keys are reported in json manifests as in yaml ones
+	jsonPath, err = writeTempFile(`{"label": "test-infra", "instance_arrays": [{"label": "master", "instance_cuont": 2}]}`, ".json")
+	Expect(err).To(BeNil())
+	defer os.Remove(jsonPath)
+
+	_, err = loadInfrastructureManifest(jsonPath)
+	Expect(err).NotTo(BeNil())
+	Expect(err.Error()).To(ContainSubstring("instance_cuont"))
+
+	invalid := []string{
+		"datacenter: uk-reading\n",
+		"label: test\nunknown_field: 1\n",
+		"label: test\ninstance_arrays:\n  - instance_count: 1\n",
+		"label: test\ninstance_arrays:\n  - label: a\n  - label: a\n",
+		"label: test\nnetworks:\n  - label: a\n",
+		"label: test\ncustom_stages:\n  - runlevel: 1\n",
+		"label: test\ninstance_arrays:\n  - label: a\n    interfaces:\n      - index: 0\n",
+	}
+
+	for _, content := range invalid {
+		p, err := writeTempFile(content, ".yaml")
+		Expect(err).To(BeNil())
+
+		_, err = loadInfrastructureManifest(p)
+		Expect(err).NotTo(BeNil(), content)
+
+		os.Remove(p)
+	}
+}
+
+func TestManifestApplierCreatedObjects(t *testing.T) {
+	RegisterTestingT(t)
+
+	applier := manifestApplier{}
+	Expect(applier.createdObjects()).To(Equal(""))
+
+	applier.record("Infrastructure", "test", 10, manifestActionCreated)
+	applier.record("Network", "wan", 11, manifestActionUnchanged)
+	applier.record("InstanceArray", "master", 12, manifestActionCreated)
+	applier.record("InstanceArray", "master/0", 12, manifestActionUpdated)
+
+	Expect(applier.createdObjects()).To(Equal("Infrastructure test (10), InstanceArray master (12)"))
+}
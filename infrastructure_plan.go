@@ -0,0 +1,299 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	metalcloud "github.com/bigstepinc/metal-cloud-sdk-go"
+	interfaces "github.com/bigstepinc/metalcloud-cli/interfaces"
+)
+
+//planChange is a pending change of an element of an infrastructure. Edits have one change per altered field.
+type planChange struct {
+	ID         int
+	ObjectType string
+	Label      string
+	Change     string
+	Field      string
+	Current    string
+	Pending    string
+}
+
+const deployStatusNotStarted = "not_started"
+
+//planIgnoredFields are present in both the live object and its operation but do not describe the design
+var planIgnoredFields = map[string]bool{
+	"infrastructure_id":        true,
+	"instance_array_id":        true,
+	"drive_array_id":           true,
+	"network_id":               true,
+	"cluster_id":               true,
+	"user_id_owner":            true,
+	"infrastructure_change_id": true,
+	"infrastructure_deploy_id": true,
+
+	"infrastructure_updated_timestamp": true,
+	"instance_array_service_status":    true,
+}
+
+var infrastructurePlanSchema = []SchemaField{
+	{
+		FieldName: "ID",
+		FieldType: TypeInt,
+		FieldSize: 6,
+	},
+	{
+		FieldName: "OBJECT_TYPE",
+		FieldType: TypeString,
+		FieldSize: 15,
+	},
+	{
+		FieldName: "LABEL",
+		FieldType: TypeString,
+		FieldSize: 10,
+	},
+	{
+		FieldName: "CHANGE",
+		FieldType: TypeString,
+		FieldSize: 6,
+	},
+	{
+		FieldName: "FIELD",
+		FieldType: TypeString,
+		FieldSize: 10,
+	},
+	{
+		FieldName: "CURRENT",
+		FieldType: TypeString,
+		FieldSize: 10,
+	},
+	{
+		FieldName: "PENDING",
+		FieldType: TypeString,
+		FieldSize: 10,
+	},
+}
+
+//getJSONFieldName returns the name of the field as given by its json tag
+func getJSONFieldName(f reflect.StructField) string {
+	return strings.Split(f.Tag.Get("json"), ",")[0]
+}
+
+//formatPlanValue prints slices of objects, such as firewall rules, as their count
+func formatPlanValue(v reflect.Value) string {
+	if v.Kind() == reflect.Slice {
+		if v.Type().Elem().Kind() == reflect.Struct {
+			return fmt.Sprintf("%d items", v.Len())
+		}
+		items := []string{}
+		for i := 0; i < v.Len(); i++ {
+			items = append(items, fmt.Sprintf("%v", v.Index(i).Interface()))
+		}
+		return strings.Join(items, ",")
+	}
+	return fmt.Sprintf("%v", v.Interface())
+}
+
+//diffOperation compares the fields of the live object with the fields having the same json name in the operation.
+//Only fields of the same type are compared. The prefix is removed from the field names.
+func diffOperation(live interface{}, operation interface{}, prefix string) []planChange {
+
+	changes := []planChange{}
+
+	lv := reflect.Indirect(reflect.ValueOf(live))
+	ov := reflect.Indirect(reflect.ValueOf(operation))
+
+	liveFields := map[string]reflect.Value{}
+	for i := 0; i < lv.NumField(); i++ {
+		liveFields[getJSONFieldName(lv.Type().Field(i))] = lv.Field(i)
+	}
+
+	for i := 0; i < ov.NumField(); i++ {
+		name := getJSONFieldName(ov.Type().Field(i))
+		if planIgnoredFields[name] {
+			continue
+		}
+
+		lf, ok := liveFields[name]
+		if !ok || lf.Type() != ov.Field(i).Type() {
+			continue
+		}
+
+		if reflect.DeepEqual(lf.Interface(), ov.Field(i).Interface()) {
+			continue
+		}
+
+		changes = append(changes, planChange{
+			Change:  "edit",
+			Field:   strings.TrimPrefix(name, prefix),
+			Current: formatPlanValue(lf),
+			Pending: formatPlanValue(ov.Field(i)),
+		})
+	}
+
+	return changes
+}
+
+//getPlanChanges returns the changes of an element given its service status and its operation's deploy type and status
+func getPlanChanges(objectType string, id int, label string, serviceStatus string, deployType string, deployStatus string, live interface{}, operation interface{}, prefix string) []planChange {
+
+	if deployStatus != deployStatusNotStarted {
+		return []planChange{}
+	}
+
+	changes := []planChange{}
+
+	switch {
+	case serviceStatus == "ordered" || deployType == "create":
+		changes = append(changes, planChange{Change: "create"})
+	case deployType == "edit":
+		changes = diffOperation(live, operation, prefix)
+	case deployType != "":
+		changes = append(changes, planChange{Change: deployType})
+	}
+
+	for i := range changes {
+		changes[i].ID = id
+		changes[i].ObjectType = objectType
+		changes[i].Label = label
+	}
+
+	return changes
+}
+
+//getInfrastructurePlan returns the changes that the next deploy of the infrastructure will apply
+func getInfrastructurePlan(infra *metalcloud.Infrastructure, client interfaces.MetalCloudClient) ([]planChange, error) {
+
+	plan := []planChange{}
+
+	infraOperation := infra.InfrastructureOperation
+	if infra.InfrastructureServiceStatus != "ordered" {
+		plan = append(plan, getPlanChanges(
+			"Infrastructure",
+			infra.InfrastructureID,
+			infra.InfrastructureLabel,
+			infra.InfrastructureServiceStatus,
+			infraOperation.InfrastructureDeployType,
+			infraOperation.InfrastructureDeployStatus,
+			infra,
+			&infraOperation,
+			"infrastructure_")...)
+	}
+
+	iaList, err := client.InstanceArrays(infra.InfrastructureID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, ia := range *iaList {
+		if ia.InstanceArrayOperation == nil {
+			continue
+		}
+		iao := ia.InstanceArrayOperation
+		plan = append(plan, getPlanChanges(
+			"InstanceArray",
+			ia.InstanceArrayID,
+			iao.InstanceArrayLabel,
+			ia.InstanceArrayServiceStatus,
+			iao.InstanceArrayDeployType,
+			iao.InstanceArrayDeployStatus,
+			&ia,
+			iao,
+			"instance_array_")...)
+	}
+
+	daList, err := client.DriveArrays(infra.InfrastructureID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, da := range *daList {
+		if da.DriveArrayOperation == nil {
+			continue
+		}
+		dao := da.DriveArrayOperation
+		plan = append(plan, getPlanChanges(
+			"DriveArray",
+			da.DriveArrayID,
+			dao.DriveArrayLabel,
+			da.DriveArrayServiceStatus,
+			dao.DriveArrayDeployType,
+			dao.DriveArrayDeployStatus,
+			&da,
+			dao,
+			"drive_array_")...)
+	}
+
+	networks, err := client.Networks(infra.InfrastructureID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, n := range *networks {
+		if n.NetworkOperation == nil {
+			continue
+		}
+		no := n.NetworkOperation
+
+		//networks have neither a service status nor a deploy status so a pending create cannot be told apart
+		//from a deployed network. Only edits and deletes are shown.
+		deployType := no.NetworkDeployType
+		if deployType == "create" {
+			deployType = "edit"
+		}
+
+		plan = append(plan, getPlanChanges(
+			"Network",
+			n.NetworkID,
+			no.NetworkLabel,
+			"",
+			deployType,
+			deployStatusNotStarted,
+			&n,
+			no,
+			"network_")...)
+	}
+
+	//the elements are returned as maps so they are sorted for a stable output
+	sort.SliceStable(plan, func(i, j int) bool {
+		if plan[i].ObjectType != plan[j].ObjectType {
+			return plan[i].ObjectType < plan[j].ObjectType
+		}
+		return plan[i].ID < plan[j].ID
+	})
+
+	return plan, nil
+}
+
+func planToTable(plan []planChange) [][]interface{} {
+	data := [][]interface{}{}
+	for _, c := range plan {
+		data = append(data, []interface{}{
+			c.ID,
+			c.ObjectType,
+			c.Label,
+			c.Change,
+			c.Field,
+			c.Current,
+			c.Pending,
+		})
+	}
+	return data
+}
+
+//renderInfrastructurePlan returns the plan as a table rendered with render, or a message if there are no pending changes
+func renderInfrastructurePlan(infra *metalcloud.Infrastructure, plan []planChange, format string, render tableRenderer) (string, error) {
+
+	if len(plan) == 0 && format == "" {
+		return fmt.Sprintf("Infrastructure %s (%d) has no pending changes.\n", infra.InfrastructureLabel, infra.InfrastructureID), nil
+	}
+
+	schema := make([]SchemaField, len(infrastructurePlanSchema))
+	copy(schema, infrastructurePlanSchema)
+
+	topLine := fmt.Sprintf("Pending changes of infrastructure %s (%d):", infra.InfrastructureLabel, infra.InfrastructureID)
+
+	return render("changes", topLine, format, planToTable(plan), schema)
+}
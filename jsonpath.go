@@ -0,0 +1,286 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+//jsonPathNode is an element of a parsed jsonpath template: literal text, a path expression or a range block
+type jsonPathNode struct {
+	text     string
+	path     []jsonPathSegment
+	isPath   bool
+	isRange  bool
+	children []jsonPathNode
+}
+
+//jsonPathSegment selects a field of an object or an element of an array. A wildcard selects all of them.
+type jsonPathSegment struct {
+	field    string
+	index    int
+	isIndex  bool
+	wildcard bool
+}
+
+//parseJSONPath parses a kubectl style jsonpath template such as '{[*].ID}' or '{range [*]}{.ID}{"\n"}{end}'
+func parseJSONPath(expr string) ([]jsonPathNode, error) {
+
+	nodes, _, err := parseJSONPathNodes(expr, false)
+
+	return nodes, err
+}
+
+func parseJSONPathNodes(expr string, inRange bool) ([]jsonPathNode, string, error) {
+
+	nodes := []jsonPathNode{}
+
+	for len(expr) > 0 {
+		start := strings.Index(expr, "{")
+		if start == -1 {
+			nodes = append(nodes, jsonPathNode{text: expr})
+			expr = ""
+			break
+		}
+
+		if start > 0 {
+			nodes = append(nodes, jsonPathNode{text: expr[:start]})
+		}
+
+		end := indexUnquoted(expr[start:], '}')
+		if end == -1 {
+			return nil, "", fmt.Errorf("jsonpath: unclosed { in %s", expr)
+		}
+		end += start
+
+		action := strings.TrimSpace(expr[start+1 : end])
+		expr = expr[end+1:]
+
+		switch {
+		case action == "end":
+			if !inRange {
+				return nil, "", fmt.Errorf("jsonpath: {end} without {range}")
+			}
+			return nodes, expr, nil
+
+		case strings.HasPrefix(action, "range "):
+			path, err := parseJSONPathSegments(strings.TrimSpace(strings.TrimPrefix(action, "range ")))
+			if err != nil {
+				return nil, "", err
+			}
+
+			children, rest, err := parseJSONPathNodes(expr, true)
+			if err != nil {
+				return nil, "", err
+			}
+
+			nodes = append(nodes, jsonPathNode{path: path, isRange: true, children: children})
+			expr = rest
+
+		case strings.HasPrefix(action, "\""):
+			s, err := strconv.Unquote(action)
+			if err != nil {
+				return nil, "", fmt.Errorf("jsonpath: invalid string literal %s", action)
+			}
+			nodes = append(nodes, jsonPathNode{text: s})
+
+		default:
+			path, err := parseJSONPathSegments(action)
+			if err != nil {
+				return nil, "", err
+			}
+			nodes = append(nodes, jsonPathNode{path: path, isPath: true})
+		}
+	}
+
+	if inRange {
+		return nil, "", fmt.Errorf("jsonpath: {range} without {end}")
+	}
+
+	return nodes, "", nil
+}
+
+//parseJSONPathSegments parses a path such as '$[*].ID', '.items[0].LABEL' or "['ID']"
+func parseJSONPathSegments(s string) ([]jsonPathSegment, error) {
+
+	segments := []jsonPathSegment{}
+
+	s = strings.TrimPrefix(s, "$")
+
+	for len(s) > 0 {
+		switch s[0] {
+		case '.':
+			s = s[1:]
+			n := strings.IndexAny(s, ".[")
+			if n == -1 {
+				n = len(s)
+			}
+			name := s[:n]
+			s = s[n:]
+
+			switch name {
+			case "":
+				//allows '.[0]' and a single '.' denoting the current element
+				continue
+			case "*":
+				segments = append(segments, jsonPathSegment{wildcard: true})
+			default:
+				segments = append(segments, jsonPathSegment{field: name})
+			}
+
+		case '[':
+			n := indexUnquoted(s, ']')
+			if n == -1 {
+				return nil, fmt.Errorf("jsonpath: unclosed [ in %s", s)
+			}
+			sel := strings.TrimSpace(s[1:n])
+			s = s[n+1:]
+
+			switch {
+			case strings.HasPrefix(sel, "?"):
+				return nil, fmt.Errorf("jsonpath: filter expressions such as %s are not supported", sel)
+			case sel == "*":
+				segments = append(segments, jsonPathSegment{wildcard: true})
+			case len(sel) >= 2 && (sel[0] == '\'' || sel[0] == '"') && sel[len(sel)-1] == sel[0]:
+				segments = append(segments, jsonPathSegment{field: sel[1 : len(sel)-1]})
+			default:
+				i, err := strconv.Atoi(sel)
+				if err != nil {
+					return nil, fmt.Errorf("jsonpath: invalid index %s", sel)
+				}
+				segments = append(segments, jsonPathSegment{index: i, isIndex: true})
+			}
+
+		default:
+			return nil, fmt.Errorf("jsonpath: unexpected character %q in %s", s[0], s)
+		}
+	}
+
+	return segments, nil
+}
+
+//indexUnquoted returns the index of the first c in s that is not inside a single or double quoted string, or -1
+func indexUnquoted(s string, c byte) int {
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		if quote != 0 {
+			switch s[i] {
+			case '\\':
+				i++
+			case quote:
+				quote = 0
+			}
+			continue
+		}
+
+		switch s[i] {
+		case '"', '\'':
+			quote = s[i]
+		case c:
+			return i
+		}
+	}
+	return -1
+}
+
+//evaluateJSONPathSegments returns all the values selected by the path starting from v
+func evaluateJSONPathSegments(v interface{}, path []jsonPathSegment) []interface{} {
+
+	current := []interface{}{v}
+
+	for _, seg := range path {
+		next := []interface{}{}
+
+		for _, c := range current {
+			switch t := c.(type) {
+			case []interface{}:
+				switch {
+				case seg.wildcard:
+					next = append(next, t...)
+				case seg.isIndex:
+					i := seg.index
+					if i < 0 {
+						i += len(t)
+					}
+					if i >= 0 && i < len(t) {
+						next = append(next, t[i])
+					}
+				}
+			case map[string]interface{}:
+				switch {
+				case seg.wildcard:
+					keys := []string{}
+					for k := range t {
+						keys = append(keys, k)
+					}
+					sort.Strings(keys)
+					for _, k := range keys {
+						next = append(next, t[k])
+					}
+				case !seg.isIndex:
+					if e, ok := t[seg.field]; ok {
+						next = append(next, e)
+					}
+				}
+			}
+		}
+
+		current = next
+	}
+
+	return current
+}
+
+func jsonPathValueToString(v interface{}) (string, error) {
+	switch t := v.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return t, nil
+	case json.Number:
+		return t.String(), nil
+	case bool:
+		return strconv.FormatBool(t), nil
+	default:
+		b, err := json.Marshal(t)
+		if err != nil {
+			return "", err
+		}
+		return string(b), nil
+	}
+}
+
+func executeJSONPathNodes(sb *strings.Builder, nodes []jsonPathNode, v interface{}) error {
+
+	for _, node := range nodes {
+		switch {
+		case node.isRange:
+			for _, e := range evaluateJSONPathSegments(v, node.path) {
+				err := executeJSONPathNodes(sb, node.children, e)
+				if err != nil {
+					return err
+				}
+			}
+
+		case node.isPath:
+			values := evaluateJSONPathSegments(v, node.path)
+			for i, e := range values {
+				s, err := jsonPathValueToString(e)
+				if err != nil {
+					return err
+				}
+				if i > 0 {
+					sb.WriteString(" ")
+				}
+				sb.WriteString(s)
+			}
+
+		default:
+			sb.WriteString(node.text)
+		}
+	}
+
+	return nil
+}
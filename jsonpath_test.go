@@ -0,0 +1,78 @@
+package main
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestGetTableAsJSONPathString(t *testing.T) {
+	RegisterTestingT(t)
+
+	type nested struct {
+		Name  string `json:"name"`
+		Count int    `json:"count"`
+	}
+
+	schema := []SchemaField{
+		{
+			FieldName: "ID",
+			FieldType: TypeInt,
+			FieldSize: 3,
+		},
+		{
+			FieldName: "CONFIG",
+			FieldType: TypeInterface,
+			FieldSize: 5,
+		},
+	}
+
+	data := [][]interface{}{
+		{1, nested{Name: "a", Count: 1000000}},
+		{2, nested{Name: "b", Count: 2}},
+	}
+
+	cases := []struct {
+		expr     string
+		expected string
+	}{
+		{"{[*].ID}", "1 2"},
+		{"{$[0].CONFIG.name}", "a"},
+		{"{[0].CONFIG.count}", "1000000"},
+		{"{[-1]['ID']}", "2"},
+		{"{range [*]}{.ID}={.CONFIG.name}{\"\\n\"}{end}", "1=a\n2=b\n"},
+		{"ids: {[*].ID}", "ids: 1 2"},
+		{"{[1].CONFIG}", "{\"count\":2,\"name\":\"b\"}"},
+		{"{[5].ID}", ""},
+	}
+
+	for _, c := range cases {
+		s, err := GetTableAsJSONPathString(data, schema, c.expr)
+		Expect(err).To(BeNil())
+		Expect(s).To(Equal(c.expected), c.expr)
+	}
+
+	for _, expr := range []string{"{[*].ID", "{range [*]}{.ID}", "{end}", "{[a]}", "{ID}", "{[?(@.ID==1)].ID}"} {
+		_, err := GetTableAsJSONPathString(data, schema, expr)
+		Expect(err).NotTo(BeNil(), expr)
+	}
+}
+
+func TestParseJSONPathQuotedStrings(t *testing.T) {
+	RegisterTestingT(t)
+
+	//braces and brackets inside quoted strings do not close the expression
+	nodes, err := parseJSONPath(`{"}"}{['a]b'].c}{"\\"}`)
+	Expect(err).To(BeNil())
+	Expect(nodes).To(HaveLen(3))
+	Expect(nodes[0].text).To(Equal("}"))
+	Expect(nodes[1].path).To(Equal([]jsonPathSegment{{field: "a]b"}, {field: "c"}}))
+	Expect(nodes[2].text).To(Equal("\\"))
+
+	_, err = parseJSONPath(`{.items[?(@.label=="a}b")]}`)
+	Expect(err).NotTo(BeNil())
+	Expect(err.Error()).To(ContainSubstring("filter expressions"))
+
+	Expect(indexUnquoted(`{"a\"}"}`, '}')).To(Equal(7))
+	Expect(indexUnquoted(`{'}`, '}')).To(Equal(-1))
+}
@@ -11,11 +11,13 @@ import (
 	"flag"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"os"
 	"regexp"
 	"strings"
 
 	metalcloud "github.com/bigstepinc/metal-cloud-sdk-go"
+	fake "github.com/bigstepinc/metalcloud-cli/fake"
 	interfaces "github.com/bigstepinc/metalcloud-cli/interfaces"
 
 	"syscall"
@@ -39,6 +41,9 @@ const ExtendedEndpoint = "extended"
 //DeveloperEndpoint exposes admin functions
 const DeveloperEndpoint = "developer"
 
+//LocalEndpoint marks commands that do not connect to the API
+const LocalEndpoint = "local"
+
 //GetUserEmail returns the API key's owner
 func GetUserEmail() string {
 	return os.Getenv("METALCLOUD_USER_EMAIL")
@@ -53,35 +58,79 @@ func main() {
 
 	SetConsoleIOChannel(os.Stdin, os.Stdout)
 
-	clients, err := initClients()
+	globals, args, err := parseGlobalFlags(os.Args)
 	if err != nil {
-		fmt.Fprintf(GetStdout(), "Could not initialize metal cloud client %s\n", err)
+		fmt.Fprintf(GetStdout(), "Error: %s. Use %s help for more details.\n", err, os.Args[0])
 		os.Exit(-1)
 	}
 
-	if len(os.Args) < 2 {
-		fmt.Fprintf(GetStdout(), "Error: Syntax error. Use %s help for more details.\n", os.Args[0])
+	applyGlobalFlags(*globals)
+
+	err = loadProfile(globals.Profile)
+	if err != nil {
+		fmt.Fprintf(GetStdout(), "Could not load configuration %s\n", err)
 		os.Exit(-1)
 	}
 
-	if os.Args[1] == "help" {
-		fmt.Fprintf(GetStdout(), "%s\n", getHelp(clients, false))
-		os.Exit(0)
+	clients, err := initClients()
+	if err != nil {
+		//the local commands must work without a client, the config commands are used to fix the configuration
+		if !isLocalCommand(args) {
+			fmt.Fprintf(GetStdout(), "Could not initialize metal cloud client %s\n", err)
+			os.Exit(-1)
+		}
+		clients = map[string]interfaces.MetalCloudClient{}
 	}
 
-	if len(os.Args) == 2 {
-		fmt.Fprintf(GetStdout(), "Error: Syntax error. Use %s help for more details.\n", os.Args[0])
-		os.Exit(-1)
+	if len(args) < 2 {
+		fmt.Fprintf(GetStdout(), "Error: Syntax error. Use %s help for more details.\n", args[0])
+		exit(-1)
+	}
+
+	if args[1] == "help" {
+		help, err := getHelpForArgs(args[2:], clients)
+		if err != nil {
+			fmt.Fprintf(GetStdout(), "Error: %s\n", err)
+			exit(-1)
+		}
+		fmt.Fprintf(GetStdout(), "%s\n", help)
+		exit(0)
+	}
+
+	if args[1] == completeCommand {
+		for _, s := range getCompletions(args[2:], clients) {
+			fmt.Fprintln(GetStdout(), s)
+		}
+		exit(0)
+	}
+
+	//a subject without a predicate shows the subject's commands
+	if len(args) == 2 {
+		help, err := getSubjectHelp(args[1], getCommands(clients), false)
+		if err != nil {
+			fmt.Fprintf(GetStdout(), "Error: Syntax error. Use %s help for more details.\n", args[0])
+			exit(-1)
+		}
+		fmt.Fprintf(GetStdout(), "%s\n", help)
+		exit(0)
 	}
 
 	commands := getCommands(clients)
 
-	err = executeCommand(os.Args, commands, clients)
+	err = executeCommand(args, commands, clients)
 
 	if err != nil {
 		fmt.Fprintf(GetStdout(), "%s\n", err)
-		os.Exit(-2)
+		exit(-2)
 	}
+
+	stopCassetteServer()
+}
+
+//exit stops the local server of a cassette, which would otherwise be left running, and exits with the given code
+func exit(code int) {
+	stopCassetteServer()
+	os.Exit(code)
 }
 
 func executeCommand(args []string, commands []Command, clients map[string]interfaces.MetalCloudClient) error {
@@ -95,6 +144,7 @@ func executeCommand(args []string, commands []Command, clients map[string]interf
 	}
 
 	cmd.InitFunc(cmd)
+	addFormatFlags(cmd, &globalFlags)
 
 	//disable default usage
 	cmd.FlagSet.Usage = func() {}
@@ -111,20 +161,34 @@ func executeCommand(args []string, commands []Command, clients map[string]interf
 	}
 
 	client, ok := clients[cmd.Endpoint]
-	if !ok {
+	if !ok && cmd.Endpoint != LocalEndpoint {
 		return fmt.Errorf("Client not set for endpoint %s on command %s %s", cmd.Endpoint, subject, predicate)
 	}
 
-	ret, err := cmd.ExecuteFunc(cmd, client)
+	applyGlobalFormat(cmd)
+
+	ret, err := executeWithTimeout(cmd, client)
 	if err != nil {
 		return fmt.Errorf("%s Use '%s %s -h' for syntax help", err, subject, predicate)
 	}
 
-	fmt.Fprintf(GetStdout(), ret)
+	if !globalFlags.Quiet {
+		fmt.Fprintf(GetStdout(), ret)
+	}
 
 	return nil
 }
 
+//isLocalCommand returns true if the command given in the arguments does not connect to the API
+func isLocalCommand(args []string) bool {
+	if len(args) > 1 && args[1] == completeCommand {
+		return true
+	}
+
+	//without clients only the local commands are returned
+	return len(args) > 2 && locateCommand(args[2], args[1], getCommands(map[string]interfaces.MetalCloudClient{})) != nil
+}
+
 //identifies command, returns nil if no matching command found
 func locateCommand(predicate string, subject string, commands []Command) *Command {
 	for _, c := range commands {
@@ -140,41 +204,191 @@ func getArgumentHelp(f *flag.Flag) string {
 	return fmt.Sprintf("\t  -%-25s %s\n", f.Name, f.Usage)
 }
 
+//isRequiredFlag returns true for the flags documented as required
+func isRequiredFlag(f *flag.Flag) bool {
+	return strings.HasPrefix(f.Usage, "(Required)")
+}
+
+//getFlagSyntax returns the flag as given on the command line, such as -id <string>
+func getFlagSyntax(f *flag.Flag) string {
+	if isBoolFlag(f) {
+		return "-" + f.Name
+	}
+
+	name, _ := flag.UnquoteUsage(f)
+	if name == "" {
+		name = "value"
+	}
+	return fmt.Sprintf("-%s <%s>", f.Name, name)
+}
+
+//getCommandAliases returns how the command can be shortened, if it can
+func getCommandAliases(cmd Command) string {
+	if cmd.AltSubject == cmd.Subject && cmd.AltPredicate == cmd.Predicate {
+		return ""
+	}
+	return fmt.Sprintf(" (alternatively use \"%s %s\")", cmd.AltSubject, cmd.AltPredicate)
+}
+
+//getCommandUsage returns the command line of a command with its required flags, such as: metalcloud-cli infrastructure get -id <string> [flags]
+func getCommandUsage(cmd Command, program string) string {
+	usage := []string{program, cmd.Subject, cmd.Predicate}
+	cmd.FlagSet.VisitAll(func(f *flag.Flag) {
+		if isRequiredFlag(f) {
+			usage = append(usage, getFlagSyntax(f))
+		}
+	})
+	usage = append(usage, "[flags]")
+
+	return strings.Join(usage, " ")
+}
+
+//initCommandCopy returns a copy of the command with its arguments, including the format and table flags, initialized on a new flag set.
+//The command's own flag set is shared with the rest of the program and cannot be initialized twice.
+func initCommandCopy(cmd Command) Command {
+	c := cmd
+	c.FlagSet = flag.NewFlagSet(cmd.FlagSet.Name(), flag.ContinueOnError)
+	c.FlagSet.SetOutput(ioutil.Discard)
+	c.InitFunc(&c)
+	addFormatFlags(&c, &GlobalFlags{})
+	return c
+}
+
 func getCommandHelp(cmd Command, showArguments bool) string {
 	var sb strings.Builder
 
 	c := fmt.Sprintf("%s %s", cmd.Subject, cmd.Predicate)
 
-	if showArguments {
-		sb.WriteString(fmt.Sprintf("Command: %-40s %s (alternatively use \"%s %s\")\n", c, cmd.Description, cmd.AltSubject, cmd.AltPredicate))
-		cmd.FlagSet.VisitAll(func(f *flag.Flag) {
-			sb.WriteString(getArgumentHelp(f))
-		})
+	if !showArguments {
+		sb.WriteString(fmt.Sprintf("\t%-40s %-24s", c, cmd.Description))
+		return sb.String()
+	}
 
-		h := flag.Flag{
-			Name:  "h",
-			Usage: "Show command help and exit.",
+	sb.WriteString(fmt.Sprintf("Command: %-40s %s%s\n", c, cmd.Description, getCommandAliases(cmd)))
+
+	required := []*flag.Flag{}
+	optional := []*flag.Flag{}
+	cmd.FlagSet.VisitAll(func(f *flag.Flag) {
+		if isRequiredFlag(f) {
+			required = append(required, f)
+		} else {
+			optional = append(optional, f)
 		}
+	})
 
-		sb.WriteString(getArgumentHelp(&h))
-	} else {
-		sb.WriteString(fmt.Sprintf("\t%-40s %-24s", c, cmd.Description))
+	sb.WriteString(fmt.Sprintf("Usage: %s\n", getCommandUsage(cmd, os.Args[0])))
+
+	if len(required) > 0 {
+		sb.WriteString("Required flags:\n")
+		for _, f := range required {
+			sb.WriteString(getArgumentHelp(f))
+		}
+	}
+
+	sb.WriteString("Flags:\n")
+	for _, f := range optional {
+		sb.WriteString(getArgumentHelp(f))
+	}
+
+	h := flag.Flag{
+		Name:  "h",
+		Usage: "Show command help and exit.",
+	}
+
+	sb.WriteString(getArgumentHelp(&h))
+
+	if cmd.Example != "" {
+		example := strings.ReplaceAll(cmd.Example, "metalcloud-cli", os.Args[0])
+		sb.WriteString(fmt.Sprintf("Example:\n\t%s\n", strings.ReplaceAll(example, "\n", "\n\t")))
 	}
 
 	return sb.String()
 }
 
+//getSubjects returns the subjects of the commands in the order of their first command
+func getSubjects(commands []Command) []string {
+	subjects := []string{}
+	seen := map[string]bool{}
+	for _, c := range commands {
+		if !c.Hidden && !seen[c.Subject] {
+			seen[c.Subject] = true
+			subjects = append(subjects, c.Subject)
+		}
+	}
+	return subjects
+}
+
+//getSubjectHelp lists the commands of a subject, given by its name or alias
+func getSubjectHelp(subject string, commands []Command, showArguments bool) (string, error) {
+	var sb strings.Builder
+
+	found := false
+	for _, c := range commands {
+		if c.Hidden || c.Subject != subject && c.AltSubject != subject {
+			continue
+		}
+
+		if !found {
+			found = true
+			subject = c.Subject
+			if c.AltSubject != c.Subject {
+				sb.WriteString(fmt.Sprintf("%s (alias: %s)\n", c.Subject, c.AltSubject))
+			} else {
+				sb.WriteString(fmt.Sprintf("%s\n", c.Subject))
+			}
+		}
+
+		if showArguments {
+			sb.WriteString(fmt.Sprintln(getCommandHelp(initCommandCopy(c), true)))
+			continue
+		}
+
+		predicate := c.Predicate
+		if c.AltPredicate != c.Predicate {
+			predicate = fmt.Sprintf("%s (%s)", c.Predicate, c.AltPredicate)
+		}
+		sb.WriteString(fmt.Sprintf("\t%-32s %s\n", predicate, c.Description))
+	}
+
+	if !found {
+		return "", fmt.Errorf("%s is not a valid subject. Use %s help for more details", subject, os.Args[0])
+	}
+
+	return sb.String(), nil
+}
+
 func getHelp(clients map[string]interfaces.MetalCloudClient, showArguments bool) string {
 	var sb strings.Builder
 	cmds := getCommands(clients)
-	for _, c := range cmds {
-		c.InitFunc(&c)
+	sb.WriteString(fmt.Sprintf("Syntax: %s [global flags] <subject> <predicate> [args]\nGlobal flags:\n", os.Args[0]))
+	sb.WriteString(getGlobalFlagsHelp())
+	sb.WriteString("Accepted commands:\n")
+	for _, subject := range getSubjects(cmds) {
+		s, _ := getSubjectHelp(subject, cmds, showArguments)
+		sb.WriteString(s)
+	}
+	sb.WriteString(fmt.Sprintf("Use \"%[1]s help <subject>\" or \"%[1]s help <subject> <predicate>\" for more details.\n", os.Args[0]))
+	return sb.String()
+}
+
+//getHelpForArgs returns the help for the arguments given after help: none, a subject or a subject and a predicate
+func getHelpForArgs(args []string, clients map[string]interfaces.MetalCloudClient) (string, error) {
+
+	cmds := getCommands(clients)
+
+	switch len(args) {
+	case 0:
+		return getHelp(clients, false), nil
+	case 1:
+		return getSubjectHelp(args[0], cmds, false)
 	}
-	sb.WriteString(fmt.Sprintf("Syntax: %s <command> [args]\nAccepted commands:\n", os.Args[0]))
-	for _, c := range cmds {
-		sb.WriteString(fmt.Sprintln(getCommandHelp(c, false)))
+
+	cmd := locateCommand(args[1], args[0], cmds)
+	if cmd == nil {
+		return "", fmt.Errorf("%s %s is not a valid command. Use %s help for more details", args[0], args[1], os.Args[0])
 	}
-	return sb.String()
+
+	return getCommandHelp(initCommandCopy(*cmd), true), nil
 }
 
 func isLoggingEnabled() bool {
@@ -186,16 +400,29 @@ func isAdmin() bool {
 	return os.Getenv("METALCLOUD_ADMIN") == "true"
 }
 
+//endpointSuffixes are the paths of the API endpoints, relative to METALCLOUD_ENDPOINT
+var endpointSuffixes = map[string]string{
+	DeveloperEndpoint: "/api/developer/developer",
+	ExtendedEndpoint:  "/metal-cloud/extended",
+	UserEndpoint:      "/metal-cloud",
+	"":                "/metal-cloud",
+}
+
 func initClients() (map[string]interfaces.MetalCloudClient, error) {
 
-	clients := map[string]interfaces.MetalCloudClient{}
-	endpointSuffixes := map[string]string{
-		DeveloperEndpoint: "/api/developer/developer",
-		ExtendedEndpoint:  "/metal-cloud/extended",
-		UserEndpoint:      "/metal-cloud",
-		"":                "/metal-cloud",
+	if endpoint := os.Getenv("METALCLOUD_ENDPOINT"); fake.IsFakeEndpoint(endpoint) {
+		return initFakeClients(endpoint)
+	}
+
+	if dir := os.Getenv("METALCLOUD_REPLAY"); dir != "" {
+		return initReplayClients(dir)
 	}
 
+	if dir := os.Getenv("METALCLOUD_RECORD"); dir != "" {
+		return initRecordingClients(dir)
+	}
+
+	clients := map[string]interfaces.MetalCloudClient{}
 	for clientName, suffix := range endpointSuffixes {
 
 		if (clientName == DeveloperEndpoint || clientName == ExtendedEndpoint) && !isAdmin() {
@@ -211,6 +438,34 @@ func initClients() (map[string]interfaces.MetalCloudClient, error) {
 	return clients, nil
 }
 
+//initFakeClients serves all endpoints from the same in-memory client so that no network access or API key is needed
+func initFakeClients(endpoint string) (map[string]interfaces.MetalCloudClient, error) {
+	if v := os.Getenv("METALCLOUD_USER_EMAIL"); v == "" {
+		return nil, fmt.Errorf("METALCLOUD_USER_EMAIL must be set")
+	}
+
+	if GetDatacenter() == "" {
+		return nil, fmt.Errorf("METALCLOUD_DATACENTER must be set")
+	}
+
+	client, err := fake.NewClientFromEndpoint(endpoint, GetUserEmail(), GetDatacenter())
+	if err != nil {
+		return nil, err
+	}
+
+	clients := map[string]interfaces.MetalCloudClient{
+		UserEndpoint: client,
+		"":           client,
+	}
+
+	if isAdmin() {
+		clients[DeveloperEndpoint] = client
+		clients[ExtendedEndpoint] = client
+	}
+
+	return clients, nil
+}
+
 func initClient(endpointSuffix string) (interfaces.MetalCloudClient, error) {
 	if v := os.Getenv("METALCLOUD_USER_EMAIL"); v == "" {
 		return nil, fmt.Errorf("METALCLOUD_USER_EMAIL must be set")
@@ -248,22 +503,24 @@ func initClient(endpointSuffix string) (interfaces.MetalCloudClient, error) {
 func fitlerCommandSet(commandSet []Command, clients map[string]interfaces.MetalCloudClient) []Command {
 	filteredCommands := []Command{}
 	for _, command := range commandSet {
-		if _, ok := clients[command.Endpoint]; ok {
+		if _, ok := clients[command.Endpoint]; ok || command.Endpoint == LocalEndpoint {
 			filteredCommands = append(filteredCommands, command)
 		}
 	}
 	return filteredCommands
 }
 
-func getCommands(clients map[string]interfaces.MetalCloudClient) []Command {
-
-	commands := [][]Command{
+//getCommandSets returns all commands, grouped by subject
+func getCommandSets() [][]Command {
+	return [][]Command{
 		datacenterCmds,
 		infrastructureCmds,
 		instanceArrayCmds,
 		instanceCmds,
 		driveArrayCmds,
 		driveSnapshotCmds,
+		networkCmds,
+		sharedDriveCmds,
 		volumeTemplateyCmds,
 		firewallRuleCmds,
 		secretsCmds,
@@ -271,13 +528,21 @@ func getCommands(clients map[string]interfaces.MetalCloudClient) []Command {
 		osAssetsCmds,
 		osTemplatesCmds,
 		serversCmds,
+		serverTypeCmds,
 		stageDefinitionsCmds,
 		workflowCmds,
+		userCmds,
+		configCmds,
+		completionCmds,
+		docsCmds,
 		versionCmds,
 	}
+}
+
+func getCommands(clients map[string]interfaces.MetalCloudClient) []Command {
 
 	filteredCommands := []Command{}
-	for _, commandSet := range commands {
+	for _, commandSet := range getCommandSets() {
 		commands := fitlerCommandSet(commandSet, clients)
 		filteredCommands = append(filteredCommands, commands...)
 	}
@@ -141,6 +141,56 @@ func TestInitClients(t *testing.T) {
 	}
 }
 
+func TestInitFakeClients(t *testing.T) {
+	RegisterTestingT(t)
+
+	envs := []string{
+		"METALCLOUD_USER_EMAIL",
+		"METALCLOUD_API_KEY",
+		"METALCLOUD_ENDPOINT",
+		"METALCLOUD_ADMIN",
+		"METALCLOUD_DATACENTER",
+	}
+
+	currentEnvVals := map[string]string{}
+	for _, e := range envs {
+		if v, ok := os.LookupEnv(e); ok {
+			currentEnvVals[e] = v
+		}
+		os.Unsetenv(e)
+	}
+
+	os.Setenv("METALCLOUD_ENDPOINT", "fake://")
+
+	_, err := initClients()
+	Expect(err).NotTo(BeNil())
+
+	//no api key is needed
+	os.Setenv("METALCLOUD_USER_EMAIL", "user@user.com")
+	os.Setenv("METALCLOUD_DATACENTER", "test")
+
+	clients, err := initClients()
+	Expect(err).To(BeNil())
+	Expect(clients[UserEndpoint]).To(Not(BeNil()))
+	Expect(clients[UserEndpoint].GetEndpoint()).To(Equal("fake://"))
+	Expect(clients[DeveloperEndpoint]).To(BeNil())
+
+	os.Setenv("METALCLOUD_ADMIN", "true")
+
+	clients, err = initClients()
+	Expect(err).To(BeNil())
+	Expect(clients[DeveloperEndpoint]).To(Equal(clients[UserEndpoint]))
+	Expect(clients[ExtendedEndpoint]).To(Equal(clients[UserEndpoint]))
+
+	//put back the env values
+	for _, e := range envs {
+		os.Unsetenv(e)
+	}
+	for k, v := range currentEnvVals {
+		os.Setenv(k, v)
+	}
+}
+
 func TestExecuteCommand(t *testing.T) {
 	RegisterTestingT(t)
 
@@ -245,7 +295,86 @@ func TestGetCommandHelp(t *testing.T) {
 	s := getCommandHelp(cmd, true)
 	Expect(s).To(ContainSubstring(cmd.Description))
 	Expect(s).To(ContainSubstring("Random param"))
+	Expect(s).NotTo(ContainSubstring("Required flags:"))
 
+	cmd = Command{
+		Description:  "Get test",
+		Subject:      "tests",
+		AltSubject:   "s",
+		Predicate:    "testp",
+		AltPredicate: "p",
+		FlagSet:      flag.NewFlagSet(RandStringBytes(10), flag.ExitOnError),
+		InitFunc: func(c *Command) {
+			c.Arguments = map[string]interface{}{
+				"id":      c.FlagSet.String("id", _nilDefaultStr, "(Required) Test's id"),
+				"verbose": c.FlagSet.Bool("verbose", false, "(Flag) Verbose output"),
+			}
+		},
+		Example: "metalcloud-cli tests testp -id 10",
+	}
+
+	cmd.InitFunc(&cmd)
+	s = getCommandHelp(cmd, true)
+	Expect(s).To(ContainSubstring("tests testp -id <string> [flags]"))
+	Expect(s).To(ContainSubstring("Required flags:\n\t  -id"))
+	Expect(s).To(ContainSubstring("Flags:\n\t  -verbose"))
+	Expect(s).To(ContainSubstring("Example:\n\t" + os.Args[0] + " tests testp -id 10"))
+	Expect(s).To(ContainSubstring(`(alternatively use "s p")`))
+}
+
+func TestGetSubjectHelp(t *testing.T) {
+	RegisterTestingT(t)
+	ctrl := gomock.NewController(t)
+	client := mock_metalcloud.NewMockMetalCloudClient(ctrl)
+	clients := map[string]interfaces.MetalCloudClient{
+		"":           client,
+		UserEndpoint: client,
+	}
+	cmds := getCommands(clients)
+
+	s, err := getSubjectHelp("infra", cmds, false)
+	Expect(err).To(BeNil())
+	Expect(s).To(HavePrefix("infrastructure (alias: infra)\n"))
+	Expect(s).To(ContainSubstring("get (show)"))
+	Expect(s).NotTo(ContainSubstring("instance-array"))
+
+	//the flags are shown on copies so the help can be shown more than once
+	for i := 0; i < 2; i++ {
+		s, err = getSubjectHelp("infrastructure", cmds, true)
+		Expect(err).To(BeNil())
+		Expect(s).To(ContainSubstring("Command: infrastructure get"))
+	}
+
+	_, err = getSubjectHelp("nothing", cmds, false)
+	Expect(err).NotTo(BeNil())
+}
+
+func TestGetHelpForArgs(t *testing.T) {
+	RegisterTestingT(t)
+	ctrl := gomock.NewController(t)
+	client := mock_metalcloud.NewMockMetalCloudClient(ctrl)
+	clients := map[string]interfaces.MetalCloudClient{
+		"":           client,
+		UserEndpoint: client,
+	}
+
+	s, err := getHelpForArgs([]string{}, clients)
+	Expect(err).To(BeNil())
+	Expect(s).To(ContainSubstring("Accepted commands:"))
+	Expect(s).To(ContainSubstring("infrastructure (alias: infra)"))
+
+	s, err = getHelpForArgs([]string{"ia"}, clients)
+	Expect(err).To(BeNil())
+	Expect(s).To(HavePrefix("instance-array (alias: ia)"))
+
+	s, err = getHelpForArgs([]string{"infra", "show"}, clients)
+	Expect(err).To(BeNil())
+	Expect(s).To(ContainSubstring("Usage: " + os.Args[0] + " infrastructure get -id <string> [flags]"))
+	Expect(s).To(ContainSubstring("-columns"))
+	Expect(s).To(ContainSubstring("Example:"))
+
+	_, err = getHelpForArgs([]string{"infra", "nothing"}, clients)
+	Expect(err).NotTo(BeNil())
 }
 
 func TestGetHelp(t *testing.T) {
@@ -259,7 +388,22 @@ func TestGetHelp(t *testing.T) {
 
 	s := getHelp(clients, true)
 	for _, c := range cmds {
-		Expect(s).To(ContainSubstring(c.Description))
+		if !c.Hidden {
+			Expect(s).To(ContainSubstring(c.Description))
+		}
+	}
+}
+
+func TestCommandExamples(t *testing.T) {
+	RegisterTestingT(t)
+
+	//the list, get and create commands of every subject show an example in their help
+	for _, set := range getCommandSets() {
+		for _, c := range set {
+			if c.Predicate == "list" || c.Predicate == "get" || c.Predicate == "create" {
+				Expect(c.Example).To(ContainSubstring("metalcloud-cli "+c.Subject+" "+c.Predicate), c.Subject+" "+c.Predicate)
+			}
+		}
 	}
 }
 
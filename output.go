@@ -10,6 +10,9 @@ import (
 	"os"
 	"strings"
 	"sync"
+	"text/template"
+
+	yaml "gopkg.in/yaml.v2"
 )
 
 //ConsoleIOChannel represents an IO channel, typically stdin and stdout but could be anything
@@ -174,7 +177,18 @@ func printTable(data [][]interface{}, schema []SchemaField) {
 
 //GetTableAsJSONString returns a MarshalIndent string for the given data
 func GetTableAsJSONString(data [][]interface{}, schema []SchemaField) (string, error) {
-	dataAsMap := make([]interface{}, len(data))
+
+	ret, err := json.MarshalIndent(getTableAsMaps(data, schema), "", "\t")
+	if err != nil {
+		return "", err
+	}
+
+	return string(ret), nil
+}
+
+//getTableAsMaps returns the rows as maps having the schema's field names as keys
+func getTableAsMaps(data [][]interface{}, schema []SchemaField) []map[string]interface{} {
+	dataAsMap := make([]map[string]interface{}, len(data))
 
 	for k, row := range data {
 		rowAsMap := make(map[string]interface{}, len(schema))
@@ -184,7 +198,99 @@ func GetTableAsJSONString(data [][]interface{}, schema []SchemaField) (string, e
 		dataAsMap[k] = rowAsMap
 	}
 
-	ret, err := json.MarshalIndent(dataAsMap, "", "\t")
+	return dataAsMap
+}
+
+//GetTableAsTemplateString executes a go template on the rows, as built by GetTableAsJSONString. Ex: '{{range .}}{{.ID}}{{"\n"}}{{end}}'
+func GetTableAsTemplateString(data [][]interface{}, schema []SchemaField, tmpl string) (string, error) {
+
+	t, err := template.New("format").Parse(tmpl)
+	if err != nil {
+		return "", fmt.Errorf("Could not parse template: %v", err)
+	}
+
+	var sb strings.Builder
+
+	err = t.Execute(&sb, getTableAsMaps(data, schema))
+	if err != nil {
+		return "", fmt.Errorf("Could not execute template: %v", err)
+	}
+
+	return sb.String(), nil
+}
+
+//GetTableAsJSONPathString evaluates a jsonpath template on the rows, as built by GetTableAsJSONString. Ex: '{[*].ID}'
+func GetTableAsJSONPathString(data [][]interface{}, schema []SchemaField, expr string) (string, error) {
+
+	nodes, err := parseJSONPath(expr)
+	if err != nil {
+		return "", err
+	}
+
+	ret, err := GetTableAsJSONString(data, schema)
+	if err != nil {
+		return "", err
+	}
+
+	//going through json keeps the keys given by the json tags of nested objects
+	decoder := json.NewDecoder(strings.NewReader(ret))
+	decoder.UseNumber()
+
+	var v interface{}
+	err = decoder.Decode(&v)
+	if err != nil {
+		return "", err
+	}
+
+	var sb strings.Builder
+
+	err = executeJSONPathNodes(&sb, nodes, v)
+	if err != nil {
+		return "", err
+	}
+
+	return sb.String(), nil
+}
+
+//getTableAsExpressionString handles the 'template=' and 'jsonpath=' formats. The second return value is false for other formats.
+func getTableAsExpressionString(format string, data [][]interface{}, schema []SchemaField) (string, bool, error) {
+
+	switch {
+	case strings.HasPrefix(format, "template="):
+		ret, err := GetTableAsTemplateString(data, schema, strings.TrimPrefix(format, "template="))
+		return ret, true, err
+	case strings.HasPrefix(format, "jsonpath="):
+		ret, err := GetTableAsJSONPathString(data, schema, strings.TrimPrefix(format, "jsonpath="))
+		return ret, true, err
+	}
+
+	return "", false, nil
+}
+
+//GetTableAsYAMLString returns a yaml document using the same keys as GetTableAsJSONString
+func GetTableAsYAMLString(data [][]interface{}, schema []SchemaField) (string, error) {
+
+	ret, err := GetTableAsJSONString(data, schema)
+	if err != nil {
+		return "", err
+	}
+
+	return convertJSONToYAMLString([]byte(ret))
+}
+
+//convertJSONToYAMLString converts a json document into yaml. Going through json keeps the keys given by the json tags of nested objects.
+func convertJSONToYAMLString(b []byte) (string, error) {
+
+	decoder := json.NewDecoder(bytes.NewReader(b))
+	decoder.UseNumber()
+
+	var v interface{}
+	err := decoder.Decode(&v)
+	if err != nil {
+		return "", err
+	}
+
+	ret, err := yaml.Marshal(convertJSONNumbers(v))
 	if err != nil {
 		return "", err
 	}
@@ -192,6 +298,29 @@ func GetTableAsJSONString(data [][]interface{}, schema []SchemaField) (string, e
 	return string(ret), nil
 }
 
+//convertJSONNumbers replaces json.Number values with ints or floats so that they are not quoted in yaml
+func convertJSONNumbers(v interface{}) interface{} {
+	switch t := v.(type) {
+	case json.Number:
+		if i, err := t.Int64(); err == nil {
+			return i
+		}
+		if f, err := t.Float64(); err == nil {
+			return f
+		}
+		return t.String()
+	case map[string]interface{}:
+		for k, e := range t {
+			t[k] = convertJSONNumbers(e)
+		}
+	case []interface{}:
+		for k, e := range t {
+			t[k] = convertJSONNumbers(e)
+		}
+	}
+	return v
+}
+
 //GetTableAsCSVString returns a csv
 func GetTableAsCSVString(data [][]interface{}, schema []SchemaField) (string, error) {
 	var buf bytes.Buffer
@@ -239,9 +368,28 @@ func truncateString(s string, length int) string {
 	return ""
 }
 
+//tableRenderer is the signature of renderTable and formatTable
+type tableRenderer func(tableName string, topLine string, format string, data [][]interface{}, schema []SchemaField) (string, error)
+
+//renderTable renders the output of a command. The rows and columns are selected according to the -where, -sort-by and -columns flags.
 func renderTable(tableName string, topLine string, format string, data [][]interface{}, schema []SchemaField) (string, error) {
+
+	data, schema, err := applyTableOptions(data, schema)
+	if err != nil {
+		return "", err
+	}
+
+	return formatTable(tableName, topLine, format, data, schema)
+}
+
+//formatTable renders a table as it is. It is used for tables that are not the output of a command, such as the ones in confirmation prompts.
+func formatTable(tableName string, topLine string, format string, data [][]interface{}, schema []SchemaField) (string, error) {
 	var sb strings.Builder
 
+	if ret, ok, err := getTableAsExpressionString(format, data, schema); ok {
+		return ret, err
+	}
+
 	switch format {
 	case "json", "JSON":
 		ret, err := GetTableAsJSONString(data, schema)
@@ -255,6 +403,12 @@ func renderTable(tableName string, topLine string, format string, data [][]inter
 			return "", err
 		}
 		sb.WriteString(ret)
+	case "yaml", "YAML":
+		ret, err := GetTableAsYAMLString(data, schema)
+		if err != nil {
+			return "", err
+		}
+		sb.WriteString(ret)
 
 	default:
 		if topLine != "" {
@@ -317,13 +471,18 @@ func convertToStringTable(data [][]interface{}) [][]interface{} {
 	return dataS
 }
 
-//renderTransposedTable renders the text format as a key-value table. json and csv formats remain the same as render table
+//renderTransposedTable renders the text format as a key-value table. json, csv and yaml formats remain the same as render table
 func renderTransposedTable(tableName string, topLine string, format string, data [][]interface{}, schema []SchemaField) (string, error) {
 
 	if format != "" {
 		return renderTable(tableName, topLine, format, data, schema)
 	}
 
+	data, schema, err := applyTableOptions(data, schema)
+	if err != nil {
+		return "", err
+	}
+
 	headerRow := []interface{}{}
 	for _, s := range schema {
 		headerRow = append(headerRow, s.FieldName)
@@ -336,7 +495,11 @@ func renderTransposedTable(tableName string, topLine string, format string, data
 		newData = append(newData, row)
 	}
 
-	dataTransposed := transposeTable(newData)
+	//without rows, such as when all are filtered out by -where, the keys have no values to be shown with
+	dataTransposed := [][]interface{}{}
+	if len(data) > 0 {
+		dataTransposed = transposeTable(newData)
+	}
 
 	newSchema := []SchemaField{
 		{
@@ -351,6 +514,6 @@ func renderTransposedTable(tableName string, topLine string, format string, data
 		},
 	}
 
-	return renderTable(tableName, topLine, format, dataTransposed, newSchema)
+	return formatTable(tableName, topLine, format, dataTransposed, newSchema)
 
 }
@@ -8,6 +8,7 @@ import (
 	"testing"
 
 	. "github.com/onsi/gomega"
+	yaml "gopkg.in/yaml.v2"
 )
 
 func TestGetTableHeader(t *testing.T) {
@@ -188,6 +189,60 @@ func TestGetTableAsJSONString(t *testing.T) {
 
 }
 
+func TestGetTableAsYAMLString(t *testing.T) {
+	RegisterTestingT(t)
+	schema := []SchemaField{
+		{
+			FieldName: "ID",
+			FieldType: TypeInt,
+			FieldSize: 6,
+		},
+		{
+			FieldName: "LABEL",
+			FieldType: TypeString,
+			FieldSize: 20,
+		},
+		{
+			FieldName:      "INST.",
+			FieldType:      TypeFloat,
+			FieldSize:      6,
+			FieldPrecision: 2,
+		},
+		{
+			FieldName: "CONFIG",
+			FieldType: TypeInterface,
+			FieldSize: 6,
+		},
+	}
+
+	type nested struct {
+		VLANRange string `json:"VLANRange"`
+		Count     int    `json:"count"`
+	}
+
+	data := [][]interface{}{
+		{4, "str", 20.1, nested{"1-10", 1000000}},
+		{5, "st11r", 22.1, nested{"11-20", 2}},
+	}
+
+	ret, err := GetTableAsYAMLString(data, schema)
+	Expect(err).To(BeNil())
+
+	var m []map[string]interface{}
+	err = yaml.Unmarshal([]byte(ret), &m)
+	Expect(err).To(BeNil())
+
+	Expect(m[0]["ID"]).To(Equal(4))
+	Expect(m[0]["LABEL"]).To(Equal("str"))
+	Expect(m[1]["INST."]).To(Equal(22.1))
+
+	//nested objects keep their json keys and numbers are not converted to exponent notation
+	config := m[0]["CONFIG"].(map[interface{}]interface{})
+	Expect(config["VLANRange"]).To(Equal("1-10"))
+	Expect(config["count"]).To(Equal(1000000))
+	Expect(ret).To(ContainSubstring("count: 1000000"))
+}
+
 func TestGetTableAsCSVString(t *testing.T) {
 
 	schema := []SchemaField{
@@ -321,6 +376,28 @@ func TestRenderTable(t *testing.T) {
 
 	s, err = renderTable("test", "", "csv", data, schema)
 	Expect(err).To(BeNil())
+
+	s, err = renderTable("test", "", "yaml", data, schema)
+	Expect(err).To(BeNil())
+	var y []map[string]interface{}
+	err = yaml.Unmarshal([]byte(s), &y)
+	Expect(err).To(BeNil())
+	Expect(y[2]["LABEL"]).To(Equal("123456789"))
+
+	s, err = renderTable("test", "", "template={{range .}}{{.ID}} {{.LABEL}}{{\"\\n\"}}{{end}}", data, schema)
+	Expect(err).To(BeNil())
+	Expect(s).To(Equal("4 12345\n5 12\n6 123456789\n"))
+
+	s, err = renderTable("test", "", "template={{range .}", data, schema)
+	Expect(err).NotTo(BeNil())
+
+	s, err = renderTable("test", "", "jsonpath={[*].ID}", data, schema)
+	Expect(err).To(BeNil())
+	Expect(s).To(Equal("4 5 6"))
+
+	s, err = renderTable("test", "", "jsonpath={[1].LABEL}", data, schema)
+	Expect(err).To(BeNil())
+	Expect(s).To(Equal("12"))
 }
 
 func JSONUnmarshal(jsonString string) ([]interface{}, error) {
@@ -465,3 +542,51 @@ func TestRenderTransposedTable(t *testing.T) {
 	s, err = renderTransposedTable("test", "", "csv", data, schema)
 	Expect(err).To(BeNil())
 }
+
+func TestFormatTableIgnoresTableOptions(t *testing.T) {
+	RegisterTestingT(t)
+	defer applyGlobalFlags(GlobalFlags{})
+
+	schema := []SchemaField{
+		{
+			FieldName: "ID",
+			FieldType: TypeInt,
+			FieldSize: 6,
+		},
+		{
+			FieldName: "LABEL",
+			FieldType: TypeString,
+			FieldSize: 10,
+		},
+	}
+
+	data := [][]interface{}{
+		{1, "web"},
+		{2, "db"},
+	}
+
+	applyGlobalFlags(GlobalFlags{Columns: "LABEL", Where: "ID>1"})
+
+	//the output of the command is filtered
+	ret, err := renderTable("rows", "", "csv", data, schema)
+	Expect(err).To(BeNil())
+	Expect(ret).To(Equal("LABEL\ndb\n"))
+
+	ret, err = renderTransposedTable("rows", "", "", data[1:], schema)
+	Expect(err).To(BeNil())
+	Expect(ret).To(ContainSubstring("LABEL"))
+	Expect(ret).NotTo(ContainSubstring("ID"))
+
+	_, err = renderTransposedTable("rows", "", "", data[:1], schema)
+	Expect(err).To(BeNil())
+
+	//tables of confirmation prompts are not
+	ret, err = formatTable("rows", "", "csv", data, schema)
+	Expect(err).To(BeNil())
+	Expect(ret).To(Equal("ID,LABEL\n1,web\n2,db\n"))
+
+	applyGlobalFlags(GlobalFlags{Columns: "UNKNOWN"})
+
+	_, err = formatTable("rows", "", "", data, schema)
+	Expect(err).To(BeNil())
+}
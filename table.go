@@ -2,7 +2,10 @@ package main
 
 import (
 	"fmt"
+	"path"
 	"sort"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -94,13 +97,16 @@ func TableSorter(schema []SchemaField) *MultiSorter {
 	}
 }
 
-//OrderBy specifies the order
+//OrderBy specifies the order. A field name prefixed with - sorts that field in descending order.
 func (ms *MultiSorter) OrderBy(fieldNames ...string) *MultiSorter {
 
 	ms.less = make([]lessFunc, len(fieldNames))
 	ms.indexes = make([]int, len(fieldNames))
 
 	for k, fn := range fieldNames {
+		descending := strings.HasPrefix(fn, "-")
+		fn = strings.TrimPrefix(fn, "-")
+
 		var field *SchemaField
 		for i, f := range ms.schema {
 
@@ -138,15 +144,14 @@ func (ms *MultiSorter) OrderBy(fieldNames ...string) *MultiSorter {
 					layout = field.FieldFormat
 				}
 
+				//values that are not dates, such as empty ones, are left in place
 				ta, err := time.Parse(layout, a.(string))
 				if err != nil {
-					fmt.Printf("could not convert string %s to date time", a.(string))
 					return false
 				}
 
 				tb, err := time.Parse(layout, b.(string))
 				if err != nil {
-					fmt.Printf("could not convert string %s to date time", b.(string))
 					return false
 				}
 
@@ -154,12 +159,329 @@ func (ms *MultiSorter) OrderBy(fieldNames ...string) *MultiSorter {
 			}
 		case TypeBool:
 			ms.less[k] = func(a, b interface{}, field *SchemaField) bool {
-				return a.(bool) != b.(bool)
+				return !a.(bool) && b.(bool)
+			}
+		case TypeInterface:
+			ms.less[k] = func(a, b interface{}, field *SchemaField) bool {
+				return fmt.Sprintf("%v", a) < fmt.Sprintf("%v", b)
 			}
 		default:
 			fmt.Printf("could not find type %d", field.FieldType)
 		}
+
+		if descending && ms.less[k] != nil {
+			less := ms.less[k]
+			ms.less[k] = func(a, b interface{}, field *SchemaField) bool {
+				return less(b, a, field)
+			}
+		}
 	}
 
 	return ms
 }
+
+//getSchemaFieldIndex returns the index of the field with the given name, ignoring case, or -1 if not found
+func getSchemaFieldIndex(schema []SchemaField, fieldName string) int {
+	for i, f := range schema {
+		if strings.EqualFold(f.FieldName, fieldName) {
+			return i
+		}
+	}
+	return -1
+}
+
+func getSchemaFieldNames(schema []SchemaField) string {
+	names := []string{}
+	for _, f := range schema {
+		names = append(names, f.FieldName)
+	}
+	return strings.Join(names, ",")
+}
+
+//selectTableColumns returns a table containing only the given columns, in the given order
+func selectTableColumns(data [][]interface{}, schema []SchemaField, columns []string) ([][]interface{}, []SchemaField, error) {
+
+	indexes := []int{}
+	newSchema := []SchemaField{}
+
+	for _, c := range columns {
+		i := getSchemaFieldIndex(schema, c)
+		if i == -1 {
+			return nil, nil, fmt.Errorf("Unknown column %s. Valid columns are %s", c, getSchemaFieldNames(schema))
+		}
+		indexes = append(indexes, i)
+		newSchema = append(newSchema, schema[i])
+	}
+
+	newData := [][]interface{}{}
+	for _, row := range data {
+		newRow := make([]interface{}, len(indexes))
+		for k, i := range indexes {
+			newRow[k] = row[i]
+		}
+		newData = append(newData, newRow)
+	}
+
+	return newData, newSchema, nil
+}
+
+//sortTable sorts the table by the given columns. Columns prefixed with - are sorted in descending order.
+func sortTable(data [][]interface{}, schema []SchemaField, columns []string) error {
+
+	fieldNames := []string{}
+
+	for _, c := range columns {
+		prefix := ""
+		if strings.HasPrefix(c, "-") {
+			prefix = "-"
+		}
+
+		i := getSchemaFieldIndex(schema, strings.TrimPrefix(c, "-"))
+		if i == -1 {
+			return fmt.Errorf("Unknown column %s. Valid columns are %s", strings.TrimPrefix(c, "-"), getSchemaFieldNames(schema))
+		}
+
+		fieldNames = append(fieldNames, prefix+schema[i].FieldName)
+	}
+
+	sort.Stable(TableSorter(schema).OrderBy(fieldNames...).withData(data))
+
+	return nil
+}
+
+func (ms *MultiSorter) withData(data [][]interface{}) *MultiSorter {
+	ms.data = data
+	return ms
+}
+
+//tableFilter is a condition such as STATUS=active, LABEL~web-* or ID>100
+type tableFilter struct {
+	index    int
+	operator string
+	value    string
+}
+
+const tableFilterOperators = "=~<>"
+
+//parseTableFilters parses a comma separated list of conditions
+func parseTableFilters(filter string, schema []SchemaField) ([]tableFilter, error) {
+
+	filters := []tableFilter{}
+
+	for _, cond := range strings.Split(filter, ",") {
+		if strings.TrimSpace(cond) == "" {
+			continue
+		}
+
+		n := strings.IndexAny(cond, tableFilterOperators)
+		if n <= 0 {
+			return nil, fmt.Errorf("Invalid filter %s. Use <column><operator><value> where the operator is one of =, ~, <, >", cond)
+		}
+
+		name := strings.TrimSpace(cond[:n])
+		i := getSchemaFieldIndex(schema, name)
+		if i == -1 {
+			return nil, fmt.Errorf("Unknown column %s. Valid columns are %s", name, getSchemaFieldNames(schema))
+		}
+
+		f := tableFilter{
+			index:    i,
+			operator: cond[n : n+1],
+			value:    cond[n+1:],
+		}
+
+		if f.operator == "~" {
+			if _, err := path.Match(f.value, ""); err != nil {
+				return nil, fmt.Errorf("Invalid pattern %s: %v", f.value, err)
+			}
+		}
+
+		filters = append(filters, f)
+	}
+
+	return filters, nil
+}
+
+//compareTableValue compares a value of the table with a string converted to the field's type. Returns -1, 0 or 1.
+func compareTableValue(v interface{}, s string, field *SchemaField) (int, error) {
+
+	switch field.FieldType {
+	case TypeInt:
+		a, ok := v.(int)
+		if !ok {
+			break
+		}
+		b, err := strconv.Atoi(s)
+		if err != nil {
+			return 0, fmt.Errorf("Column %s expects an integer, got %s", field.FieldName, s)
+		}
+		return compareFloats(float64(a), float64(b)), nil
+
+	case TypeFloat:
+		a, ok := v.(float64)
+		if !ok {
+			break
+		}
+		b, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return 0, fmt.Errorf("Column %s expects a number, got %s", field.FieldName, s)
+		}
+		return compareFloats(a, b), nil
+
+	case TypeBool:
+		a, ok := v.(bool)
+		if !ok {
+			break
+		}
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return 0, fmt.Errorf("Column %s expects true or false, got %s", field.FieldName, s)
+		}
+		if a == b {
+			return 0, nil
+		}
+		if b {
+			return -1, nil
+		}
+		return 1, nil
+
+	case TypeDateTime:
+		a, ok := v.(string)
+		if !ok {
+			break
+		}
+
+		layout := defaultTimeFormat
+		if field.FieldFormat != "" {
+			layout = field.FieldFormat
+		}
+
+		ta, err := time.Parse(layout, a)
+		if err != nil {
+			break
+		}
+		tb, err := time.Parse(layout, s)
+		if err != nil {
+			//allows comparing with a date only
+			tb, err = time.Parse("2006-01-02", s)
+			if err != nil {
+				return 0, fmt.Errorf("Column %s expects a date such as %s, got %s", field.FieldName, layout, s)
+			}
+		}
+
+		switch {
+		case ta.Before(tb):
+			return -1, nil
+		case ta.After(tb):
+			return 1, nil
+		}
+		return 0, nil
+	}
+
+	return strings.Compare(fmt.Sprintf("%v", v), s), nil
+}
+
+func compareFloats(a float64, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+//matches returns true if the row satisfies the condition. Patterns (~) are matched against the printed value.
+func (f tableFilter) matches(row []interface{}, schema []SchemaField) (bool, error) {
+
+	v := row[f.index]
+
+	if f.operator == "~" {
+		return path.Match(f.value, fmt.Sprintf("%v", v))
+	}
+
+	c, err := compareTableValue(v, f.value, &schema[f.index])
+	if err != nil {
+		return false, err
+	}
+
+	switch f.operator {
+	case "<":
+		return c < 0, nil
+	case ">":
+		return c > 0, nil
+	}
+
+	return c == 0, nil
+}
+
+//filterTable returns the rows that satisfy all the conditions of the filter
+func filterTable(data [][]interface{}, schema []SchemaField, filter string) ([][]interface{}, error) {
+
+	filters, err := parseTableFilters(filter, schema)
+	if err != nil {
+		return nil, err
+	}
+
+	newData := [][]interface{}{}
+
+	for _, row := range data {
+		keep := true
+
+		for _, f := range filters {
+			ok, err := f.matches(row, schema)
+			if err != nil {
+				return nil, err
+			}
+			if !ok {
+				keep = false
+				break
+			}
+		}
+
+		if keep {
+			newData = append(newData, row)
+		}
+	}
+
+	return newData, nil
+}
+
+func splitColumns(s string) []string {
+	columns := []string{}
+	for _, c := range strings.Split(s, ",") {
+		if c = strings.TrimSpace(c); c != "" {
+			columns = append(columns, c)
+		}
+	}
+	return columns
+}
+
+//applyTableOptions filters, sorts and selects the columns of the table according to the -where, -sort-by and -columns flags
+func applyTableOptions(data [][]interface{}, schema []SchemaField) ([][]interface{}, []SchemaField, error) {
+
+	var err error
+
+	if globalFlags.Where != "" {
+		data, err = filterTable(data, schema, globalFlags.Where)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if globalFlags.SortBy != "" {
+		err = sortTable(data, schema, splitColumns(globalFlags.SortBy))
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if globalFlags.Columns != "" {
+		data, schema, err = selectTableColumns(data, schema, splitColumns(globalFlags.Columns))
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return data, schema, nil
+}
@@ -264,3 +264,98 @@ func TestGetTableAsJSONRegressionTest1(t *testing.T) {
 	Expect(m[0].(map[string]interface{})["INDEX"]).ToNot(Equal(m[2].(map[string]interface{})["INDEX"]))
 	Expect(m[1].(map[string]interface{})["INDEX"]).ToNot(Equal(m[2].(map[string]interface{})["INDEX"]))
 }
+
+func TestApplyTableOptions(t *testing.T) {
+	RegisterTestingT(t)
+	defer applyGlobalFlags(GlobalFlags{})
+
+	schema := []SchemaField{
+		{
+			FieldName: "ID",
+			FieldType: TypeInt,
+			FieldSize: 6,
+		},
+		{
+			FieldName: "LABEL",
+			FieldType: TypeString,
+			FieldSize: 20,
+		},
+		{
+			FieldName: "STATUS",
+			FieldType: TypeString,
+			FieldSize: 20,
+		},
+		{
+			FieldName: "CREATED",
+			FieldType: TypeDateTime,
+			FieldSize: 20,
+		},
+	}
+
+	newData := func() [][]interface{} {
+		return [][]interface{}{
+			{9, "web-1", "active", "2019-03-28T15:23:08Z"},
+			{10, "web-2", "ordered", "2019-11-01T10:00:00Z"},
+			{100, "db-1", "active", "2019-02-01T10:00:00Z"},
+		}
+	}
+
+	//ints are compared as numbers, not as strings
+	applyGlobalFlags(GlobalFlags{SortBy: "-ID"})
+	data, _, err := applyTableOptions(newData(), schema)
+	Expect(err).To(BeNil())
+	Expect(data[0][0]).To(Equal(100))
+	Expect(data[2][0]).To(Equal(9))
+
+	applyGlobalFlags(GlobalFlags{SortBy: "status,-created"})
+	data, _, err = applyTableOptions(newData(), schema)
+	Expect(err).To(BeNil())
+	Expect(data[0][1]).To(Equal("web-1"))
+	Expect(data[1][1]).To(Equal("db-1"))
+	Expect(data[2][1]).To(Equal("web-2"))
+
+	//dates that cannot be parsed do not prevent sorting
+	applyGlobalFlags(GlobalFlags{SortBy: "CREATED"})
+	data, _, err = applyTableOptions(append(newData(), []interface{}{11, "web-3", "ordered", ""}), schema)
+	Expect(err).To(BeNil())
+	Expect(data).To(HaveLen(4))
+	Expect(data[0][1]).To(Equal("db-1"))
+
+	applyGlobalFlags(GlobalFlags{Where: "STATUS=active,LABEL~web-*"})
+	data, _, err = applyTableOptions(newData(), schema)
+	Expect(err).To(BeNil())
+	Expect(data).To(HaveLen(1))
+	Expect(data[0][0]).To(Equal(9))
+
+	applyGlobalFlags(GlobalFlags{Where: "ID>9"})
+	data, _, err = applyTableOptions(newData(), schema)
+	Expect(err).To(BeNil())
+	Expect(data).To(HaveLen(2))
+
+	applyGlobalFlags(GlobalFlags{Where: "CREATED<2019-03-01"})
+	data, _, err = applyTableOptions(newData(), schema)
+	Expect(err).To(BeNil())
+	Expect(data).To(HaveLen(1))
+	Expect(data[0][1]).To(Equal("db-1"))
+
+	applyGlobalFlags(GlobalFlags{Columns: "LABEL,ID"})
+	data, newSchema, err := applyTableOptions(newData(), schema)
+	Expect(err).To(BeNil())
+	Expect(newSchema).To(HaveLen(2))
+	Expect(newSchema[0].FieldName).To(Equal("LABEL"))
+	Expect(data[0]).To(Equal([]interface{}{"web-1", 9}))
+	Expect(schema).To(HaveLen(4))
+
+	for _, g := range []GlobalFlags{
+		{Columns: "ID,UNKNOWN"},
+		{SortBy: "UNKNOWN"},
+		{Where: "UNKNOWN=1"},
+		{Where: "ID"},
+		{Where: "ID=abc"},
+		{Where: "LABEL~[web"},
+	} {
+		applyGlobalFlags(g)
+		_, _, err = applyTableOptions(newData(), schema)
+		Expect(err).NotTo(BeNil())
+	}
+}